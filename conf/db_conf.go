@@ -0,0 +1,15 @@
+package conf
+
+// DbConf a db instance: the db section of tcpserver.yaml, or one of shard.instances
+type DbConf struct {
+    Driver string `yaml:"driver"`
+    Path   string `yaml:"path"`
+    Host   string `yaml:"host"`
+    User   string `yaml:"user"`
+    Passwd string `yaml:"passwd"`
+    Db     string `yaml:"db"`
+    Conn struct {
+        Maxidle  int `yaml:"maxidle"`
+        Maxopen int `yaml:"maxopen"`
+    }
+}
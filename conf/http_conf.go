@@ -5,6 +5,8 @@ type HTTPConf struct {
     Server struct {
         Port int    `yaml:"port"`
         IP   string `yaml:"ip"`
+        // Trustedproxies ips or cidrs of reverse proxies whose X-Real-Ip is the client ip, none by default
+        Trustedproxies []string `yaml:"trustedproxies"`
     }
     Image struct {
         Prefixurl string `yaml:"prefixurl"`
@@ -14,6 +16,7 @@ type HTTPConf struct {
     Logic struct {
         Tokenexpire int `yaml:"tokenexpire"`
     }
+    Token TokenConf
     Log struct {
         Logfile  string `yaml:"logfile"`
         Loglevel string `yaml:"loglevel"`
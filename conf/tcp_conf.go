@@ -10,24 +10,104 @@ type TCPConf struct {
         Loglevel string `yaml:"loglevel"`
         Maxdays  string `yaml:"maxdays"`
     }
-    Db struct {
-        Host   string `yaml:"host"`
-        User   string `yaml:"user"`
-        Passwd string `yaml:"passwd"`
-        Db     string `yaml:"db"`
-        Conn struct {
-            Maxidle  int `yaml:"maxidle"`
-            Maxopen int `yaml:"maxopen"`
+    Db DbConf
+    Shard struct {
+        Hash      string   `yaml:"hash"`
+        Tables    int      `yaml:"tables"`
+        Tablename string   `yaml:"tablename"`
+        Directory string   `yaml:"directory"`
+        Renames   string   `yaml:"renames"`
+        Instances []DbConf `yaml:"instances"`
+        Previous  struct {
+            Enabled   bool     `yaml:"enabled"`
+            Hash      string   `yaml:"hash"`
+            Tables    int      `yaml:"tables"`
+            Tablename string   `yaml:"tablename"`
+            Instances []DbConf `yaml:"instances"`
         }
     }
+    Uid struct {
+        Node int `yaml:"node"`
+    }
     Redis struct {
         Addr     string `yaml:"addr"`
         Db       int    `yaml:"db"`
         Passwd   string `yaml:"passwd"`
 	    Poolsize int    `yaml:"poolsize"`
         Cache struct {
-            Tokenexpired int  `yaml:"tokenexpired"`
-            Userexpired  int  `yaml:"userexpired"`
+            Tokenexpired     int  `yaml:"tokenexpired"`
+            Userexpired      int  `yaml:"userexpired"`
+            Sliding          bool `yaml:"sliding"`
+            Tokenmaxlifetime int  `yaml:"tokenmaxlifetime"`
+        }
+    }
+    Store struct {
+        Users    string `yaml:"users"`
+        Sessions string `yaml:"sessions"`
+    }
+    Token TokenConf
+    Image struct {
+        Savepath string `yaml:"savepath"`
+    }
+    Mail struct {
+        Sender string `yaml:"sender"`
+        From   string `yaml:"from"`
+        SMTP   struct {
+            Addr     string `yaml:"addr"`
+            Username string `yaml:"username"`
+            Passwd   string `yaml:"passwd"`
+        } `yaml:"smtp"`
+        File         string `yaml:"file"`
+        Verifyurl    string `yaml:"verifyurl"`
+        Verifyexpire int    `yaml:"verifyexpire"`
+        Reseturl     string `yaml:"reseturl"`
+        Resetexpire  int    `yaml:"resetexpire"`
+        Maxresets    int    `yaml:"maxresets"`
+    }
+    Rbac struct {
+        Roles  map[string][]string `yaml:"roles"`
+        Admins []int64             `yaml:"admins"`
+    }
+    Audit struct {
+        Sink  string `yaml:"sink"`
+        Table string `yaml:"table"`
+        File  string `yaml:"file"`
+    }
+    Account struct {
+        Graceperiod   int `yaml:"graceperiod"`
+        Sweepinterval int `yaml:"sweepinterval"`
+        Renamereserve int `yaml:"renamereserve"`
+    }
+    Security struct {
+        Login struct {
+            Window          int `yaml:"window"`
+            Maxuserfailures int `yaml:"maxuserfailures"`
+            Maxipfailures   int `yaml:"maxipfailures"`
+            Baselockout     int `yaml:"baselockout"`
+            Maxlockout      int `yaml:"maxlockout"`
+        }
+        Twofactor struct {
+            Issuer          string `yaml:"issuer"`
+            Skew            int    `yaml:"skew"`
+            Challengeexpire int    `yaml:"challengeexpire"`
+            Maxattempts     int    `yaml:"maxattempts"`
+            Recoverycodes   int    `yaml:"recoverycodes"`
+        }
+    }
+    Passwd struct {
+        Algorithm string `yaml:"algorithm"`
+        Bcrypt struct {
+            Cost int `yaml:"cost"`
+        }
+        Scrypt struct {
+            Ln int `yaml:"ln"`
+            R  int `yaml:"r"`
+            P  int `yaml:"p"`
+        }
+        Argon2id struct {
+            Memory  uint32 `yaml:"memory"`
+            Time    uint32 `yaml:"time"`
+            Threads uint8  `yaml:"threads"`
         }
     }
 }
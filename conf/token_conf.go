@@ -0,0 +1,19 @@
+package conf
+
+// TokenConf token section shared by httpserver.yaml and tcpserver.yaml
+type TokenConf struct {
+    Length   int    `yaml:"length"`
+    Encoding string `yaml:"encoding"`
+    Mode     string `yaml:"mode"`
+    Signed   struct {
+        Expire    int    `yaml:"expire"`
+        Activekid string `yaml:"activekid"`
+        Keys      []struct {
+            Kid        string `yaml:"kid"`
+            Alg        string `yaml:"alg"`
+            Secret     string `yaml:"secret"`
+            Privatekey string `yaml:"privatekey"`
+            Publickey  string `yaml:"publickey"`
+        }
+    }
+}
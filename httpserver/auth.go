@@ -0,0 +1,45 @@
+package main
+
+import (
+    "time"
+
+    "user-management-system/type/code"
+    "user-management-system/utils"
+    "user-management-system/utils/jwt"
+)
+
+// keys to verify signed access tokens, nil if tokens are opaque
+var keySet *jwt.KeySet
+
+// initAccessToken load keys of signed access tokens
+func initAccessToken() error {
+    if config.Token.Mode != jwt.TokenModeSigned {
+        return nil
+    }
+    var err error
+    keySet, err = jwt.NewKeySetFromConf(&config.Token)
+    return err
+}
+
+// checkToken check format of token from cookie: an opaque token or, in signed mode, a signed access token
+func checkToken(token string) bool {
+    return utils.CheckToken(token) || (keySet != nil && jwt.IsSigned(token))
+}
+
+// localAuth verify a signed access token of username without calling tcpserver,
+// ok is false if the token isn't signed and should be checked by tcpserver
+func localAuth(username, token string) (claims jwt.Claims, tcpCode int, ok bool) {
+    if keySet == nil || !jwt.IsSigned(token) {
+        return claims, code.CodeSucc, false
+    }
+    claims, err := keySet.Verify(token, time.Now())
+    switch {
+    case err == jwt.ErrExpired:
+        return claims, code.CodeTCPTokenExpired, true
+    case err != nil:
+        return claims, code.CodeTCPInvalidToken, true
+    case claims.Subject != username:
+        return claims, code.CodeTCPUserInfoNotMatch, true
+    }
+    return claims, code.CodeSucc, true
+}
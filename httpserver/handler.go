@@ -3,6 +3,7 @@ package main
 import (
     "net/http"
     "path"
+    "strconv"
     "strings"
 
     "user-management-system/type/code"
@@ -14,65 +15,555 @@ import (
 )
 
 // generate upload image file name
-func generateImgName(fname, postfix string) string {
+// generateImgName name uploads after the uid of the uploader, tcpserver only lets a user claim its own
+func generateImgName(fname, postfix string, uid int64) string {
     ext := path.Ext(fname)
     fileName := strings.TrimSuffix(fname, ext)
     fileName = utils.Md5String(fileName + postfix)
 
-    return fileName + ext
+    return strconv.FormatInt(uid, 10) + "_" + fileName + ext
+}
+
+// setTokenCookies set cookies of new tokens, cookies live as long as tcpserver keeps the tokens
+func setTokenCookies(c *gin.Context, tokens rpcclient.Tokens) {
+    expire := tokens.Expire
+    if expire <= 0 {
+        expire = config.Logic.Tokenexpire
+    }
+    c.SetCookie("token", tokens.Token, expire, "/", config.Server.IP, false, true)
+
+    // token is a signed access token, the session lives in refresh token
+    if tokens.Refreshtoken != "" {
+        refreshExpire := tokens.Refreshexpire
+        if refreshExpire <= 0 {
+            refreshExpire = config.Logic.Tokenexpire
+        }
+        c.SetCookie("refreshtoken", tokens.Refreshtoken, refreshExpire, "/", config.Server.IP, false, true)
+    }
+}
+
+// clearTokenCookies drop cookies of tokens once their session is gone
+func clearTokenCookies(c *gin.Context) {
+    c.SetCookie("token", "", -1, "/", config.Server.IP, false, true)
+    c.SetCookie("refreshtoken", "", -1, "/", config.Server.IP, false, true)
 }
 
 // login
 func loginHandler(c *gin.Context) {
+    passwdLogin(c, rpcclient.Login)
+}
+
+// restore a deactivated account within grace period and login
+func restoreHandler(c *gin.Context) {
+    passwdLogin(c, rpcclient.RestoreAccount)
+}
+
+// passwdLogin login with username and passwd through rpc login or restore
+func passwdLogin(c *gin.Context, login func(map[string]string) (int, rpcclient.Tokens, map[string]interface{})) {
     // check params
     username := c.PostForm("username")
     passwd := c.PostForm("passwd")
 
-    // this should be done by FE
-    passwd = utils.Md5String(passwd)
-
-    if len(passwd) != 32 {
-        log.Error("Invalid passwd:", passwd)
+    if !utils.CheckPasswd(passwd) {
+        log.Error("Invalid passwd for user:", username)
         c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidPasswd, "", nil))
         return
     }
 
-    uuid := utils.GenerateToken(username)
-    log.Debug(uuid, " -- loginHandler access from:", username, "@", passwd)
+    uuid := utils.GenerateUUID()
+    log.Debug(uuid, " -- loginHandler access from:", username, " path:", c.FullPath())
 
     // communicate with rcp server
-    ret, token, rsp := rpcclient.Login(map[string]string{"username":username, "passwd":passwd, "uuid":uuid})
+    ret, tokens, rsp := login(map[string]string{"username":username, "passwd":passwd, "uuid":uuid,
+                                       "clientip":c.ClientIP(), "useragent":c.Request.UserAgent()})
     // set cookie
-    if ret == http.StatusOK && token != "" {
-        c.SetCookie("token", token, config.Logic.Tokenexpire, "/", config.Server.IP, false, true)
-        log.Debug(uuid, " -- Set token ", token, "with expire:", config.Logic.Tokenexpire)
+    if ret == http.StatusOK && tokens.Token != "" {
+        setTokenCookies(c, tokens)
+        log.Debug(uuid, " -- Set token ", tokens.Token, "with expire:", tokens.Expire)
+    }
+    // locked by too many failed logins
+    if data, ok := rsp["data"].(map[string]string); ok && data["retryafter"] != "" {
+        c.Header("Retry-After", data["retryafter"])
     }
 
     log.Debug(uuid, " -- Succ get response from backend with", rsp["code"], " and msg:", rsp["msg"])
     c.JSON(ret, rsp)
 }
 
+// finish a 2fa login: exchange challenge from login and a totp/recovery code for tokens
+func verifyTwoFactorHandler(c *gin.Context) {
+    // check params
+    challenge := c.PostForm("challenge")
+    passcode := strings.TrimSpace(c.PostForm("passcode"))
+    if !utils.CheckToken(challenge) || passcode == "" {
+        log.Error("Invalid 2fa challenge or passcode")
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidPasscode, "", nil))
+        return
+    }
+
+    uuid := utils.GenerateUUID()
+    log.Debug(uuid, " -- verifyTwoFactorHandler access")
+
+    // communicate with rcp server
+    ret, tokens, rsp := rpcclient.VerifyTwoFactor(map[string]string{"challenge":challenge, "passcode":passcode, "uuid":uuid,
+                                       "clientip":c.ClientIP(), "useragent":c.Request.UserAgent()})
+    // set cookie
+    if ret == http.StatusOK && tokens.Token != "" {
+        setTokenCookies(c, tokens)
+        log.Debug(uuid, " -- Set token ", tokens.Token, "with expire:", tokens.Expire)
+    }
+    if data, ok := rsp["data"].(map[string]string); ok && data["retryafter"] != "" {
+        c.Header("Retry-After", data["retryafter"])
+    }
+
+    log.Debug(uuid, " -- Succ get response from backend with", rsp["code"], " and msg:", rsp["msg"])
+    c.JSON(ret, rsp)
+}
+
+// refresh token: retire current token (refresh token in signed mode) and set new ones
+func refreshHandler(c *gin.Context) {
+    // check params
+    username := c.PostForm("username")
+    cookie := "token"
+    if keySet != nil {
+        cookie = "refreshtoken"
+    }
+    token, err := c.Cookie(cookie)
+    if err != nil {
+        log.Error("Failed to get ", cookie, " from cookie, err:", err.Error())
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeTokenNotFound, "", nil))
+        return
+    }
+
+    if !utils.CheckToken(token) {
+        log.Error("Invalid token :", token)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidToken, "", nil))
+        return
+    }
+
+    uuid := utils.GenerateUUID()
+    log.Debug(uuid, " -- refreshHandler access from:", username, " with token:", token)
+
+    // communicate with rcp server
+    ret, tokens, rsp := rpcclient.RefreshToken(map[string]string{"username":username, "token":token, "uuid":uuid, "clientip":c.ClientIP()})
+    if ret == http.StatusOK && tokens.Token != "" {
+        setTokenCookies(c, tokens)
+        log.Debug(uuid, " -- Set token ", tokens.Token, "with expire:", tokens.Expire)
+    }
+
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp["code"], " and msg:", rsp["msg"])
+    c.JSON(ret, rsp)
+}
+
+// register
+func registerHandler(c *gin.Context) {
+    // check params
+    username := c.PostForm("username")
+    passwd := c.PostForm("passwd")
+    nickname := c.PostForm("nickname")
+    // optional, a verification link is mailed to it
+    email := c.PostForm("email")
+
+    if !utils.CheckUsername(username) {
+        log.Error("Invalid username:", username)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidUsername, "", nil))
+        return
+    }
+    if !utils.CheckPasswd(passwd) {
+        log.Error("Invalid passwd for user:", username)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidPasswd, "", nil))
+        return
+    }
+
+    uuid := utils.GenerateUUID()
+    log.Debug(uuid, " -- registerHandler access from:", username, " with nickname:", nickname)
+
+    // communicate with rcp server
+    ret, rsp := rpcclient.Register(map[string]string{"username":username, "passwd":passwd, "nickname":nickname, "email":email, "uuid":uuid, "clientip":c.ClientIP()})
+
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp["code"], " and msg:", rsp["msg"])
+    c.JSON(ret, rsp)
+}
+
 // logout
 func logoutHandler(c* gin.Context) {
     // check params
     username := c.PostForm("username")
     token, err := c.Cookie("token")
+    // signed access tokens can't be revoked, logout the session of refresh token instead
+    if keySet != nil {
+        token, err = c.Cookie("refreshtoken")
+    }
     if err != nil {
         log.Error("Failed to get token from cookie, err:", err.Error())
         c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeTokenNotFound, "", nil))
         return
     }
 
-    if len(token) != 32 {
+    if !utils.CheckToken(token) {
         log.Error("Invalid token :", token)
         c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidToken, "", nil))
         return
     }
-    uuid := utils.GenerateToken(username)
+    uuid := utils.GenerateUUID()
     log.Debug(uuid, " -- logoutHandler access from:", username, " with token:", token)
 
     // communicate with rcp server
-    ret, rsp := rpcclient.Logout(map[string]string{"username":username, "token":token, "uuid":uuid})
+    ret, rsp := rpcclient.Logout(map[string]string{"username":username, "token":token, "uuid":uuid, "clientip":c.ClientIP()})
+
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp["code"], " and msg:", rsp["msg"])
+    c.JSON(ret, rsp)
+}
+
+// deactivate account, it can be restored by logging in at restore within grace period
+func deactivateHandler(c* gin.Context) {
+    accountHandler(c, rpcclient.DeactivateAccount)
+}
+
+// delete account at once
+func deleteAccountHandler(c* gin.Context) {
+    accountHandler(c, rpcclient.DeleteAccount)
+}
+
+// accountHandler verify token and passwd, then deactivate or delete account through rpc
+func accountHandler(c* gin.Context, rpc func(map[string]string) (int, map[string]interface{})) {
+    // check params
+    username := c.PostForm("username")
+    passwd := c.PostForm("passwd")
+    token, err := c.Cookie("token")
+    if err != nil {
+        log.Error("Failed to get token from cookie, err:", err.Error())
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeTokenNotFound, "", nil))
+        return
+    }
+
+    if !checkToken(token) {
+        log.Error("Invalid token :", token)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidToken, "", nil))
+        return
+    }
+    if !utils.CheckPasswd(passwd) {
+        log.Error("Invalid passwd for user:", username)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidPasswd, "", nil))
+        return
+    }
+
+    uuid := utils.GenerateUUID()
+    log.Debug(uuid, " -- accountHandler access from:", username, " with token:", token, " path:", c.FullPath())
+
+    // communicate with rcp server
+    ret, rsp := rpc(map[string]string{"username":username, "token":token, "passwd":passwd, "uuid":uuid, "clientip":c.ClientIP()})
+    // all sessions are revoked
+    if rsp["code"] == code.CodeSucc {
+        clearTokenCookies(c)
+    }
+
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp["code"], " and msg:", rsp["msg"])
+    c.JSON(ret, rsp)
+}
+
+// change passwd
+func changePasswdHandler(c* gin.Context) {
+    // check params
+    username := c.PostForm("username")
+    oldPasswd := c.PostForm("oldpasswd")
+    newPasswd := c.PostForm("newpasswd")
+    keepSession := c.DefaultPostForm("keepsession", "false")
+    token, err := c.Cookie("token")
+    if err != nil {
+        log.Error("Failed to get token from cookie, err:", err.Error())
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeTokenNotFound, "", nil))
+        return
+    }
+
+    if !checkToken(token) {
+        log.Error("Invalid token :", token)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidToken, "", nil))
+        return
+    }
+    if !utils.CheckPasswd(oldPasswd) || !utils.CheckPasswd(newPasswd) {
+        log.Error("Invalid passwd for user:", username)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidPasswd, "", nil))
+        return
+    }
+
+    uuid := utils.GenerateUUID()
+    log.Debug(uuid, " -- changePasswdHandler access from:", username, " with token:", token, " keepsession:", keepSession)
+
+    // communicate with rcp server
+    ret, rsp := rpcclient.ChangePasswd(map[string]string{"username":username, "token":token, "oldpasswd":oldPasswd,
+                                       "newpasswd":newPasswd, "keepsession":keepSession, "uuid":uuid, "clientip":c.ClientIP()})
+    // current session is revoked as well
+    if keep, _ := strconv.ParseBool(keepSession); rsp["code"] == code.CodeSucc && !keep {
+        c.SetCookie("token", "", -1, "/", config.Server.IP, false, true)
+    }
+
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp["code"], " and msg:", rsp["msg"])
+    c.JSON(ret, rsp)
+}
+
+// change username, sessions go with it and the old name leads to the new one for a while
+func changeUsernameHandler(c* gin.Context) {
+    // check params
+    username := c.PostForm("username")
+    newname := c.PostForm("newname")
+    token, err := c.Cookie("token")
+    if err != nil {
+        log.Error("Failed to get token from cookie, err:", err.Error())
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeTokenNotFound, "", nil))
+        return
+    }
+
+    if !checkToken(token) {
+        log.Error("Invalid token :", token)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidToken, "", nil))
+        return
+    }
+    if !utils.CheckUsername(newname) {
+        log.Error("Invalid username:", newname)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidUsername, "", nil))
+        return
+    }
+
+    uuid := utils.GenerateUUID()
+    log.Debug(uuid, " -- changeUsernameHandler access from:", username, " with token:", token, " new username:", newname)
+
+    // communicate with rcp server
+    ret, tokens, rsp := rpcclient.ChangeUsername(map[string]string{"username":username, "token":token, "newname":newname, "uuid":uuid, "clientip":c.ClientIP()})
+    // signed access token of the old username is replaced
+    if ret == http.StatusOK && tokens.Token != "" {
+        setTokenCookies(c, tokens)
+        log.Debug(uuid, " -- Set token ", tokens.Token, "with expire:", tokens.Expire)
+    }
+
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp["code"], " and msg:", rsp["msg"])
+    c.JSON(ret, rsp)
+}
+
+// start 2fa enrolment: get a pending totp secret and its otpauth uri
+func setupTwoFactorHandler(c* gin.Context) {
+    // check params
+    username := c.PostForm("username")
+    token, err := c.Cookie("token")
+    if err != nil {
+        log.Error("Failed to get token from cookie, err:", err.Error())
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeTokenNotFound, "", nil))
+        return
+    }
+
+    if !checkToken(token) {
+        log.Error("Invalid token :", token)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidToken, "", nil))
+        return
+    }
+
+    uuid := utils.GenerateUUID()
+    log.Debug(uuid, " -- setupTwoFactorHandler access from:", username, " with token:", token)
+
+    // communicate with rcp server
+    ret, rsp := rpcclient.SetupTwoFactor(map[string]string{"username":username, "token":token, "uuid":uuid, "clientip":c.ClientIP()})
+
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp["code"], " and msg:", rsp["msg"])
+    c.JSON(ret, rsp)
+}
+
+// confirm 2fa enrolment with a first totp code, recovery codes are returned only once
+func confirmTwoFactorHandler(c* gin.Context) {
+    // check params
+    username := c.PostForm("username")
+    passcode := strings.TrimSpace(c.PostForm("passcode"))
+    token, err := c.Cookie("token")
+    if err != nil {
+        log.Error("Failed to get token from cookie, err:", err.Error())
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeTokenNotFound, "", nil))
+        return
+    }
+
+    if !checkToken(token) {
+        log.Error("Invalid token :", token)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidToken, "", nil))
+        return
+    }
+    if passcode == "" {
+        log.Error("Missing 2fa passcode for user:", username)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidPasscode, "", nil))
+        return
+    }
+
+    uuid := utils.GenerateUUID()
+    log.Debug(uuid, " -- confirmTwoFactorHandler access from:", username, " with token:", token)
+
+    // communicate with rcp server
+    ret, rsp := rpcclient.ConfirmTwoFactor(map[string]string{"username":username, "token":token, "passcode":passcode, "uuid":uuid, "clientip":c.ClientIP()})
+
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp["code"], " and msg:", rsp["msg"])
+    c.JSON(ret, rsp)
+}
+
+// disable 2fa with a totp code or a recovery code
+func disableTwoFactorHandler(c* gin.Context) {
+    // check params
+    username := c.PostForm("username")
+    passcode := strings.TrimSpace(c.PostForm("passcode"))
+    token, err := c.Cookie("token")
+    if err != nil {
+        log.Error("Failed to get token from cookie, err:", err.Error())
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeTokenNotFound, "", nil))
+        return
+    }
+
+    if !checkToken(token) {
+        log.Error("Invalid token :", token)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidToken, "", nil))
+        return
+    }
+    if passcode == "" {
+        log.Error("Missing 2fa passcode for user:", username)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidPasscode, "", nil))
+        return
+    }
+
+    uuid := utils.GenerateUUID()
+    log.Debug(uuid, " -- disableTwoFactorHandler access from:", username, " with token:", token)
+
+    // communicate with rcp server
+    ret, rsp := rpcclient.DisableTwoFactor(map[string]string{"username":username, "token":token, "passcode":passcode, "uuid":uuid, "clientip":c.ClientIP()})
+
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp["code"], " and msg:", rsp["msg"])
+    c.JSON(ret, rsp)
+}
+
+// list sessions
+func listSessionsHandler(c* gin.Context) {
+    // check params
+    username := c.Query("username")
+    token, err := c.Cookie("token")
+    if err != nil {
+        log.Error("Failed to get token from cookie, err:", err.Error())
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeTokenNotFound, "", nil))
+        return
+    }
+
+    if !checkToken(token) {
+        log.Error("Invalid token :", token)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidToken, "", nil))
+        return
+    }
+
+    uuid := utils.GenerateUUID()
+    log.Debug(uuid, " -- listSessionsHandler access from:", username, " with token:", token)
+
+    // communicate with rcp server
+    ret, rsp := rpcclient.ListSessions(map[string]string{"username":username, "token":token, "uuid":uuid, "clientip":c.ClientIP()})
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp["code"], " and msg:", rsp["msg"])
+    c.JSON(ret, rsp)
+}
+
+// list users for admins, filtered by username prefix, nickname and uptime range, page by page
+func adminUsersHandler(c* gin.Context) {
+    // check params
+    username := c.Query("username")
+    limit := c.Query("limit")
+    upfrom := c.Query("upfrom")
+    upto := c.Query("upto")
+    token, err := c.Cookie("token")
+    if err != nil {
+        log.Error("Failed to get token from cookie, err:", err.Error())
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeTokenNotFound, "", nil))
+        return
+    }
+
+    if !checkToken(token) {
+        log.Error("Invalid token :", token)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidToken, "", nil))
+        return
+    }
+
+    // numbers are optional, but not malformed
+    if !checkNumber(limit, 32) || !checkNumber(upfrom, 63) || !checkNumber(upto, 63) {
+        log.Error("Invalid limit or uptime range :", limit, " ", upfrom, " ", upto)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidParam, "", nil))
+        return
+    }
+
+    uuid := utils.GenerateUUID()
+    log.Debug(uuid, " -- adminUsersHandler access from:", username, " with token:", token)
+
+    // communicate with rcp server
+    ret, rsp := rpcclient.ListUsers(map[string]string{"username":username, "token":token, "cursor":c.Query("cursor"), "limit":limit,
+                                                     "prefix":c.Query("prefix"), "nickname":c.Query("nickname"),
+                                                     "upfrom":upfrom, "upto":upto, "uuid":uuid, "clientip":c.ClientIP()})
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp["code"], " and msg:", rsp["msg"])
+    c.JSON(ret, rsp)
+}
+
+// query the audit log for admins, filtered by target user, action and time range, newest first page by page
+func auditLogHandler(c* gin.Context) {
+    // check params
+    username := c.Query("username")
+    limit := c.Query("limit")
+    from := c.Query("from")
+    to := c.Query("to")
+    uid := c.Query("uid")
+    token, err := c.Cookie("token")
+    if err != nil {
+        log.Error("Failed to get token from cookie, err:", err.Error())
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeTokenNotFound, "", nil))
+        return
+    }
+
+    if !checkToken(token) {
+        log.Error("Invalid token :", token)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidToken, "", nil))
+        return
+    }
+
+    // numbers are optional, but not malformed
+    if !checkNumber(limit, 32) || !checkNumber(from, 63) || !checkNumber(to, 63) || !checkNumber(uid, 63) {
+        log.Error("Invalid limit, time range or uid :", limit, " ", from, " ", to, " ", uid)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidParam, "", nil))
+        return
+    }
+
+    uuid := utils.GenerateUUID()
+    log.Debug(uuid, " -- auditLogHandler access from:", username, " with token:", token)
+
+    // communicate with rcp server
+    ret, rsp := rpcclient.QueryAuditLog(map[string]string{"username":username, "token":token, "target":c.Query("target"),
+                                                         "targetuid":uid, "action":c.Query("action"), "from":from, "to":to, "cursor":c.Query("cursor"),
+                                                         "limit":limit, "uuid":uuid, "clientip":c.ClientIP()})
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp["code"], " and msg:", rsp["msg"])
+    c.JSON(ret, rsp)
+}
+
+// revoke one session by id, or all sessions with all=true
+func revokeSessionHandler(c* gin.Context) {
+    // check params
+    username := c.PostForm("username")
+    sessionID := c.PostForm("sessionid")
+    all := c.DefaultPostForm("all", "false")
+    token, err := c.Cookie("token")
+    if err != nil {
+        log.Error("Failed to get token from cookie, err:", err.Error())
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeTokenNotFound, "", nil))
+        return
+    }
+
+    if !checkToken(token) {
+        log.Error("Invalid token :", token)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidToken, "", nil))
+        return
+    }
+
+    uuid := utils.GenerateUUID()
+    log.Debug(uuid, " -- revokeSessionHandler access from:", username, " with token:", token, " session:", sessionID, " all:", all)
+
+    // communicate with rcp server
+    ret, rsp := rpcclient.RevokeSession(map[string]string{"username":username, "token":token, "sessionid":sessionID, "all":all, "uuid":uuid, "clientip":c.ClientIP()})
+    // current session is revoked as well
+    if revokeAll, _ := strconv.ParseBool(all); rsp["code"] == code.CodeSucc && revokeAll {
+        c.SetCookie("token", "", -1, "/", config.Server.IP, false, true)
+    }
 
     log.Debug(uuid, " -- Succ to get response from backend with ", rsp["code"], " and msg:", rsp["msg"])
     c.JSON(ret, rsp)
@@ -91,17 +582,153 @@ func editNicknameHandler(c* gin.Context) {
     }
     log.Debug("access from:", username, " with token:", token, " and newname:", nickname)
 
-    if len(token) != 32 {
+    if !checkToken(token) {
         log.Error("Invalid token :", token)
         c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidToken, "", nil))
         return
     }
 
-    uuid := utils.GenerateToken(username)
+    uuid := utils.GenerateUUID()
     log.Debug(uuid, " -- editNicknameHandler access from:", username, " with token:", token, " new nickname:", nickname)
 
     // communicate with rcp server
-    ret, rsp := rpcclient.EditUserinfo(map[string]string{"username":username, "token":token, "nickname":nickname, "headurl":"", "mode": "1", "uuid":uuid})
+    ret, rsp := rpcclient.EditUserinfo(map[string]string{"username":username, "token":token, "nickname":nickname, "headurl":"", "mask":"nickname", "uuid":uuid, "clientip":c.ClientIP()})
+
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp["code"], " and msg:", rsp["msg"])
+    c.JSON(ret, rsp)
+}
+
+// update profile fields named in mask, e.g. mask=email,phone; a masked field left empty is cleared
+func updateProfileHandler(c* gin.Context) {
+    // check params
+    username := c.PostForm("username")
+    token, err := c.Cookie("token")
+    if err != nil {
+        log.Error("Failed to get token from cookie, err:", err.Error())
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeTokenNotFound, "", nil))
+        return
+    }
+
+    if !checkToken(token) {
+        log.Error("Invalid token :", token)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidToken, "", nil))
+        return
+    }
+
+    // mask can be repeated or comma separated
+    var mask []string
+    for _, fields := range c.PostFormArray("mask") {
+        for _, field := range strings.Split(fields, ",") {
+            if field = strings.TrimSpace(field); field != "" {
+                mask = append(mask, field)
+            }
+        }
+    }
+    uuid := utils.GenerateUUID()
+    args := map[string]string{"username":username, "token":token, "uuid":uuid, "clientip":c.ClientIP()}
+    for _, field := range mask {
+        // unknown fields are rejected by tcpserver, they can't override the ones above
+        if _, ok := args[field]; !ok {
+            args[field] = c.PostForm(field)
+        }
+    }
+    log.Debug(uuid, " -- updateProfileHandler access from:", username, " with token:", token, " mask:", mask)
+
+    // communicate with rcp server, fields are validated there
+    ret, rsp := rpcclient.UpdateProfile(args, mask)
+
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp["code"], " and msg:", rsp["msg"])
+    c.JSON(ret, rsp)
+}
+
+// mail a new email verification link
+func sendVerifyEmailHandler(c* gin.Context) {
+    // check params
+    username := c.PostForm("username")
+    token, err := c.Cookie("token")
+    if err != nil {
+        log.Error("Failed to get token from cookie, err:", err.Error())
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeTokenNotFound, "", nil))
+        return
+    }
+
+    if !checkToken(token) {
+        log.Error("Invalid token :", token)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidToken, "", nil))
+        return
+    }
+
+    uuid := utils.GenerateUUID()
+    log.Debug(uuid, " -- sendVerifyEmailHandler access from:", username, " with token:", token)
+
+    // communicate with rcp server
+    ret, rsp := rpcclient.SendVerifyEmail(map[string]string{"username":username, "token":token, "uuid":uuid, "clientip":c.ClientIP()})
+
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp["code"], " and msg:", rsp["msg"])
+    c.JSON(ret, rsp)
+}
+
+// verify email, the target of mailed links, the token in the link is all it takes
+func verifyEmailHandler(c* gin.Context) {
+    // check params
+    token := c.Query("token")
+    if !utils.CheckToken(token) {
+        log.Error("Invalid email verification token :", token)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidToken, "", nil))
+        return
+    }
+
+    uuid := utils.GenerateUUID()
+    log.Debug(uuid, " -- verifyEmailHandler access with token:", token)
+
+    // communicate with rcp server
+    ret, rsp := rpcclient.VerifyEmail(map[string]string{"token":token, "uuid":uuid, "clientip":c.ClientIP()})
+
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp["code"], " and msg:", rsp["msg"])
+    c.JSON(ret, rsp)
+}
+
+// ask for a passwd reset link, the response is the same whether or not the user exists
+func requestPasswdResetHandler(c* gin.Context) {
+    // check params
+    username := c.PostForm("username")
+    if !utils.CheckUsername(username) {
+        log.Error("Invalid username :", username)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidUsername, "", nil))
+        return
+    }
+
+    uuid := utils.GenerateUUID()
+    log.Debug(uuid, " -- requestPasswdResetHandler access from:", username)
+
+    // communicate with rcp server
+    ret, rsp := rpcclient.RequestPasswordReset(map[string]string{"username":username, "uuid":uuid, "clientip":c.ClientIP()})
+
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp["code"], " and msg:", rsp["msg"])
+    c.JSON(ret, rsp)
+}
+
+// set a new passwd by the token of a mailed reset link, all sessions of the user are revoked
+func resetPasswdHandler(c* gin.Context) {
+    // check params
+    token := c.PostForm("token")
+    newPasswd := c.PostForm("newpasswd")
+    if !utils.CheckToken(token) {
+        log.Error("Invalid passwd reset token :", token)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidToken, "", nil))
+        return
+    }
+    if !utils.CheckPasswd(newPasswd) {
+        log.Error("Invalid new passwd of reset token :", token)
+        c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidPasswd, "", nil))
+        return
+    }
+
+    uuid := utils.GenerateUUID()
+    log.Debug(uuid, " -- resetPasswdHandler access with token:", token)
+
+    // communicate with rcp server
+    ret, rsp := rpcclient.ResetPassword(map[string]string{"token":token, "newpasswd":newPasswd, "uuid":uuid, "clientip":c.ClientIP()})
 
     log.Debug(uuid, " -- Succ to get response from backend with ", rsp["code"], " and msg:", rsp["msg"])
     c.JSON(ret, rsp)
@@ -120,11 +747,16 @@ func uploadHeadurlHandler(c* gin.Context) {
         return
     }
 
-    uuid := utils.GenerateToken(username)
+    uuid := utils.GenerateUUID()
     log.Debug(uuid, " -- uploadHeadurlHandler access from:", username, " with token:", token)
 
-    // step 1 : auth
-    httpCode, tcpCode, msg := rpcclient.Auth(map[string]string{"username":username, "token":token, "uuid":uuid})
+    // step 1 : auth, signed access tokens are verified locally
+    httpCode, tcpCode, msg, uid := http.StatusOK, code.CodeSucc, "", int64(0)
+    if claims, localCode, ok := localAuth(username, token); ok {
+        tcpCode, uid = localCode, claims.Uid
+    } else {
+        httpCode, tcpCode, msg, uid = rpcclient.Auth(map[string]string{"username":username, "token":token, "uuid":uuid, "clientip":c.ClientIP()})
+    }
     if httpCode != http.StatusOK || tcpCode != 0 {
         log.Error(uuid, " -- uploadHeadurlHandler Auth failed, msg:", msg)
         c.JSON(httpCode, rpcclient.FormatResponse(tcpCode, msg, nil))
@@ -161,7 +793,7 @@ func uploadHeadurlHandler(c* gin.Context) {
     log.Debug(uuid, " -- uploadHeadurlHandler CheckImage succ")
 
     // save
-    imageName := generateImgName(image.Filename, username)
+    imageName := generateImgName(image.Filename, username, uid)
     fullPath  := config.Image.Savepath + imageName
 
     if err = c.SaveUploadedFile(image, fullPath); err != nil {
@@ -173,7 +805,7 @@ func uploadHeadurlHandler(c* gin.Context) {
 
     // step 3 : update picture info
     imageURL := config.Image.Prefixurl + "/" + fullPath
-    ret, editRsp := rpcclient.EditUserinfo(map[string]string{"username": username, "token": token, "nickname": "", "headurl": imageURL, "mode": "2", "uuid":uuid})
+    ret, editRsp := rpcclient.EditUserinfo(map[string]string{"username": username, "token": token, "nickname": "", "headurl": imageURL, "mask": "headurl", "avatar": imageName, "uuid":uuid, "clientip":c.ClientIP()})
     log.Debug(uuid, " -- editUserInfo response:", ret)
     c.JSON(ret, editRsp)
 }
@@ -190,18 +822,38 @@ func getUserinfoHandler(c* gin.Context) {
         return
     }
 
-    if len(token) != 32 {
+    if !checkToken(token) {
         log.Error("Invalid token :", token)
         c.JSON(http.StatusBadRequest, rpcclient.FormatResponse(code.CodeInvalidToken, "", nil))
         return
     }
 
-    uuid := utils.GenerateToken(username)
+    uuid := utils.GenerateUUID()
     log.Debug(uuid, " -- getUserinfoHandler access from:", username, " with token:", token)
 
+    // signed access token carries userinfo, a renamed user asking by its old name is left to tcpserver
+    if claims, tcpCode, ok := localAuth(username, token); ok && tcpCode != code.CodeTCPUserInfoNotMatch {
+        var data map[string]string
+        if tcpCode == code.CodeSucc {
+            data = map[string]string{"uid":strconv.FormatInt(claims.Uid, 10), "username":claims.Subject, "nickname":claims.Nickname, "headurl":claims.Headurl}
+        }
+        log.Debug(uuid, " -- Local auth of signed token with ", tcpCode)
+        c.JSON(http.StatusOK, rpcclient.FormatResponse(tcpCode, "", data))
+        return
+    }
+
     // communicate with rcp server
-    ret, rsp := rpcclient.GetUserinfo(map[string]string{"username":username, "token":token, "uuid":uuid})
+    ret, rsp := rpcclient.GetUserinfo(map[string]string{"username":username, "token":token, "uuid":uuid, "clientip":c.ClientIP()})
     log.Debug(uuid, " -- Succ to get response from backend with ", rsp["code"], " and msg:", rsp["msg"])
     c.JSON(ret, rsp)
 }
 
+
+// checkNumber an optional param should be an unsigned number of at most bits
+func checkNumber(value string, bits int) bool {
+    if value == "" {
+        return true
+    }
+    _, err := strconv.ParseUint(value, 10, bits)
+    return err == nil
+}
@@ -40,6 +40,17 @@ func init() {
 	log.SetLogFuncCallDepth(3)
 	log.Async()
 
+	// token format shared with tcpserver
+	err = utils.SetTokenFormat(config.Token.Length, config.Token.Encoding)
+	if err != nil {
+		log.Critical("Invalid token config, err:", err.Error())
+		os.Exit(-1)
+	}
+	if err = initAccessToken(); err != nil {
+		log.Critical("Invalid signed token config, err:", err.Error())
+		os.Exit(-1)
+	}
+
 	// init rpcclient pool
 	err = rpcclient.InitPool(config.Rpcserver.Addr, config.Pool.Initsize, config.Pool.Capacity, time.Duration(config.Pool.Maxidle)*time.Second)
 	if err != nil {
@@ -62,17 +73,43 @@ func main() {
 	gin.DefaultWriter = ioutil.Discard
 
 	engine := gin.Default()
+	// X-Real-Ip is only trusted from configured proxies, the first X-Forwarded-For hop is whatever clients sent
+	engine.TrustedProxies = config.Server.Trustedproxies
+	engine.RemoteIPHeaders = []string{"X-Real-Ip"}
 	engine.Any("/api/v1/welcome", webRoot)
 	engine.POST("/api/v1/login", loginHandler)
+	engine.POST("/api/v1/verify2fa", verifyTwoFactorHandler)
+	engine.POST("/api/v1/register", registerHandler)
+	engine.POST("/api/v1/restore", restoreHandler)
+	engine.POST("/api/v1/refresh", refreshHandler)
 	engine.POST("/api/v1/logout", logoutHandler)
 	engine.GET("/api/v1/getuserinfo", getUserinfoHandler)
 	engine.POST("/api/v1/editnickname", editNicknameHandler)
+	engine.POST("/api/v1/updateprofile", updateProfileHandler)
+	engine.POST("/api/v1/sendverifyemail", sendVerifyEmailHandler)
+	engine.GET("/api/v1/verifyemail", verifyEmailHandler)
+	engine.POST("/api/v1/changepasswd", changePasswdHandler)
+	engine.POST("/api/v1/requestpasswdreset", requestPasswdResetHandler)
+	engine.POST("/api/v1/resetpasswd", resetPasswdHandler)
+	engine.POST("/api/v1/changeusername", changeUsernameHandler)
+	engine.GET("/api/v1/sessions", listSessionsHandler)
+	engine.POST("/api/v1/revokesession", revokeSessionHandler)
+	engine.GET("/api/v1/admin/users", adminUsersHandler)
+	engine.GET("/api/v1/admin/audit", auditLogHandler)
+	engine.POST("/api/v1/setup2fa", setupTwoFactorHandler)
+	engine.POST("/api/v1/confirm2fa", confirmTwoFactorHandler)
+	engine.POST("/api/v1/disable2fa", disableTwoFactorHandler)
+	engine.POST("/api/v1/deactivate", deactivateHandler)
+	engine.POST("/api/v1/deleteaccount", deleteAccountHandler)
 	engine.POST("/api/v1/uploadpic", uploadHeadurlHandler)
 
 	engine.Static("/api/v1/static/", "./static/")
 	engine.Static("/api/v1/upload/images/", "./upload/images/")
 
-	engine.Run(fmt.Sprintf(":%d", config.Server.Port))
+	if err := engine.Run(fmt.Sprintf(":%d", config.Server.Port)); err != nil {
+		log.Critical("httpserver stopped, err:", err.Error())
+		os.Exit(-3)
+	}
 }
 
 func webRoot(context *gin.Context) {
@@ -4,6 +4,7 @@ import (
     "context"
     "net/http"
     "strconv"
+    "strings"
     "time"
 
     gpool "user-management-system/httpserver/rpcclient/gpool"
@@ -11,6 +12,7 @@ import (
     pb "user-management-system/type/proto"
 
     "github.com/gin-gonic/gin"
+    "google.golang.org/genproto/protobuf/field_mask"
     "google.golang.org/grpc"
     "google.golang.org/grpc/metadata"
     log "github.com/beego/beego/v2/adapter/logs"
@@ -74,7 +76,7 @@ func freeRPCClient(wrap* clientWrap) {
  *   }
  * }
  */
-func FormatResponse(c int, msg string, data map[string]string) map[string]interface{} {
+func FormatResponse(c int, msg string, data interface{}) map[string]interface{} {
     if msg == "" {
         msg = code.CodeMsg[c]
     }
@@ -82,33 +84,160 @@ func FormatResponse(c int, msg string, data map[string]string) map[string]interf
     return gin.H{"code": c, "msg": msg, "data": data}
 }
 
-// Login : userlogin handler
-func Login(args map[string]string) (int, string, map[string]interface{}) {
+// Tokens tokens issued by login or refresh, empty on failure
+type Tokens struct {
+    Token         string
+    Expire        int   // seconds token can be kept
+    Refreshtoken  string // signed mode only
+    Refreshexpire int
+}
+
+// tokensOf get tokens from a login response
+func tokensOf(rsp *pb.LoginResponse) Tokens {
+    if rsp.Code != code.CodeSucc || rsp.Token == "" {
+        return Tokens{}
+    }
+    return Tokens{Token: rsp.Token, Expire: int(rsp.Expire), Refreshtoken: rsp.Refreshtoken, Refreshexpire: int(rsp.Refreshexpire)}
+}
+
+// userData userinfo and profile of a login response
+func userData(rsp *pb.LoginResponse) map[string]string {
+    return map[string]string{"uid":strconv.FormatInt(rsp.Uid, 10), "username":rsp.Username, "nickname":rsp.Nickname, "headurl":rsp.Headurl,
+                             "email":rsp.Email, "phone":rsp.Phone, "bio":rsp.Bio, "locale":rsp.Locale, "timezone":rsp.Timezone, "birthday":rsp.Birthday,
+                             "emailverified":strconv.FormatBool(rsp.Emailverified), "roles":strings.Join(rsp.Roles, ",")}
+}
+
+// Login : userlogin handler, return http code, tokens and response
+func Login(args map[string]string) (int, Tokens, map[string]interface{}) {
+    return login(args, false)
+}
+
+// RestoreAccount : restore a deactivated account and login, return http code, tokens and response
+func RestoreAccount(args map[string]string) (int, Tokens, map[string]interface{}) {
+    return login(args, true)
+}
+
+// login : login or restore through rpc
+func login(args map[string]string, restore bool) (int, Tokens, map[string]interface{}) {
     // get uuid
     uuid := args["uuid"]
     // communicate with rcp server
     client, err := getRPCClient()
     if err != nil {
         log.Error(uuid, " -- Failed to getRPCClient, err:", err.Error())
-        return http.StatusInternalServerError, "", FormatResponse(code.CodeInternalErr, "", nil)
+        return http.StatusInternalServerError, Tokens{}, FormatResponse(code.CodeInternalErr, "", nil)
     }
     defer freeRPCClient(client)
 
-    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid)
-    rsp, err := client.client.Login(ctx, &pb.LoginRequest{Username: args["username"], Passwd: args["passwd"]})
+    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid,
+                                            "clientip", args["clientip"], "useragent", args["useragent"])
+    call := client.client.Login
+    if restore {
+        call = client.client.RestoreAccount
+    }
+    rsp, err := call(ctx, &pb.LoginRequest{Username: args["username"], Passwd: args["passwd"]})
     if err != nil {
         log.Error(uuid, " -- Failed to communicate with TCP server, err:", err.Error())
-        return http.StatusOK, "", FormatResponse(code.CodeErrBackend, "", nil)
+        return http.StatusOK, Tokens{}, FormatResponse(code.CodeErrBackend, "", nil)
     }
 
     log.Debug(uuid, " -- Succ get token:", rsp.Token, " code:", rsp.Code)
 
-    var token string
-    if rsp.Code == code.CodeSucc && rsp.Token != "" {
-        token = rsp.Token
+    data := userData(rsp)
+    if rsp.Retryafter > 0 {
+        data["retryafter"] = strconv.FormatInt(rsp.Retryafter, 10)
+    }
+    if rsp.Challenge != "" {
+        data["challenge"] = rsp.Challenge
+    }
+    if rsp.Purgetime > 0 {
+        data["purgetime"] = strconv.FormatInt(rsp.Purgetime, 10)
     }
+    return http.StatusOK, tokensOf(rsp), FormatResponse(int(rsp.Code), rsp.Msg, data)
+}
 
-    return http.StatusOK, token, FormatResponse(int(rsp.Code), rsp.Msg, map[string]string{"username":rsp.Username, "nickname":rsp.Nickname, "headurl":rsp.Headurl})
+// VerifyTwoFactor : finish a 2fa login with challenge and passcode, return http code, tokens and response
+func VerifyTwoFactor(args map[string]string) (int, Tokens, map[string]interface{}) {
+    // get uuid
+    uuid := args["uuid"]
+    // communicate with rcp server
+    client, err := getRPCClient()
+    if err != nil {
+        log.Error(uuid, " -- Failed to getRPCClient, err:", err.Error())
+        return http.StatusInternalServerError, Tokens{}, FormatResponse(code.CodeInternalErr, "", nil)
+    }
+    defer freeRPCClient(client)
+
+    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid,
+                                            "clientip", args["clientip"], "useragent", args["useragent"])
+    rsp, err := client.client.VerifyTwoFactor(ctx, &pb.VerifyTwoFactorRequest{Challenge: args["challenge"], Passcode: args["passcode"]})
+    if err != nil {
+        log.Error(uuid, " -- Failed to communicate with TCP server, err:", err.Error())
+        return http.StatusOK, Tokens{}, FormatResponse(code.CodeErrBackend, "", nil)
+    }
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp.Code, " and msg:", rsp.Msg)
+
+    var data map[string]string
+    if rsp.Code == code.CodeSucc {
+        data = userData(rsp)
+    } else if rsp.Retryafter > 0 {
+        data = map[string]string{"retryafter":strconv.FormatInt(rsp.Retryafter, 10)}
+    }
+    return http.StatusOK, tokensOf(rsp), FormatResponse(int(rsp.Code), rsp.Msg, data)
+}
+
+// RefreshToken : retire a session token (refresh token in signed mode) and get new tokens
+func RefreshToken(args map[string]string) (int, Tokens, map[string]interface{}) {
+    // get uuid
+    uuid := args["uuid"]
+    // communicate with rcp server
+    client, err := getRPCClient()
+    if err != nil {
+        log.Error(uuid, " -- Failed to getRPCClient, err:", err.Error())
+        return http.StatusInternalServerError, Tokens{}, FormatResponse(code.CodeInternalErr, "", nil)
+    }
+    defer freeRPCClient(client)
+
+    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid, "clientip", args["clientip"])
+    rsp, err := client.client.RefreshToken(ctx, &pb.CommRequest{Token: args["token"], Username: args["username"]})
+    if err != nil {
+        log.Error(uuid, " -- Failed to communicate with TCP server, err:", err.Error())
+        return http.StatusOK, Tokens{}, FormatResponse(code.CodeErrBackend, "", nil)
+    }
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp.Code, " and msg:", rsp.Msg)
+
+    var data map[string]string
+    if rsp.Code == code.CodeSucc {
+        data = userData(rsp)
+    }
+    return http.StatusOK, tokensOf(rsp), FormatResponse(int(rsp.Code), rsp.Msg, data)
+}
+
+// Register : user register
+func Register(args map[string]string) (int, map[string]interface{}) {
+    // get uuid
+    uuid := args["uuid"]
+    // communicate with rcp server
+    client, err := getRPCClient()
+    if err != nil {
+        log.Error(uuid, " -- Failed to getRPCClient, err:", err.Error())
+        return http.StatusInternalServerError, FormatResponse(code.CodeInternalErr, "", nil)
+    }
+    defer freeRPCClient(client)
+
+    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid, "clientip", args["clientip"])
+    rsp, err := client.client.Register(ctx, &pb.RegisterRequest{Username: args["username"], Passwd: args["passwd"], Nickname: args["nickname"], Email: args["email"]})
+    if err != nil {
+        log.Error(uuid, " -- Failed to communicate with TCP server, err:", err.Error())
+        return http.StatusOK, FormatResponse(code.CodeErrBackend, "", nil)
+    }
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp.Code, " and msg:", rsp.Msg)
+
+    var data map[string]string
+    if rsp.Code == code.CodeSucc {
+        data = userData(rsp)
+    }
+    return http.StatusOK, FormatResponse(int(rsp.Code), rsp.Msg, data)
 }
 
 // Logout : user logout
@@ -123,7 +252,7 @@ func Logout(args map[string]string) (int, map[string]interface{}) {
     }
     defer freeRPCClient(client)
 
-    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid)
+    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid, "clientip", args["clientip"])
     rsp, err := client.client.Logout(ctx, &pb.CommRequest{Token: args["token"], Username: args["username"]})
     if err != nil {
         log.Error(uuid, " -- Failed to communicate with TCP server, err:", err.Error())
@@ -134,7 +263,7 @@ func Logout(args map[string]string) (int, map[string]interface{}) {
     return http.StatusOK, FormatResponse(int(rsp.Code), rsp.Msg, nil)
 }
 
-// EditUserinfo  edit user nickname/headurl
+// EditUserinfo  edit user nickname/headurl named in args["mask"], comma separated
 func EditUserinfo(args map[string]string) (int, map[string]interface{}) {
     // get uuid
     uuid := args["uuid"]
@@ -149,10 +278,11 @@ func EditUserinfo(args map[string]string) (int, map[string]interface{}) {
     defer freeRPCClient(client)
 
     // update userinfo
-    mode, _ := strconv.Atoi(args["mode"])
-    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid)
+    mask := &field_mask.FieldMask{Paths: strings.Split(args["mask"], ",")}
+    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid, "clientip", args["clientip"])
     editRsp, err := client.client.EditUserInfo(ctx,
-                          &pb.EditRequest{Username: args["username"], Token: args["token"], Nickname: args["nickname"], Headurl: headurl, Mode: uint32(mode)})
+                          &pb.EditRequest{Username: args["username"], Token: args["token"], Nickname: args["nickname"], Headurl: headurl,
+                          Mask: mask, Avatar: args["avatar"]})
     if err != nil {
         log.Error(uuid, " -- Failed to communicate with TCP server, err:", err.Error())
         return http.StatusOK, FormatResponse(code.CodeErrBackend, "", nil)
@@ -165,6 +295,420 @@ func EditUserinfo(args map[string]string) (int, map[string]interface{}) {
     return http.StatusOK, FormatResponse(int(editRsp.Code), editRsp.Msg, data)
 }
 
+// UpdateProfile update profile fields named in mask, return http code and the updated profile
+func UpdateProfile(args map[string]string, mask []string) (int, map[string]interface{}) {
+    // get uuid
+    uuid := args["uuid"]
+    // communicate with rcp server
+    client, err := getRPCClient()
+    if err != nil {
+        log.Error(uuid, " -- Failed to getRPCClient, err:", err.Error())
+        return http.StatusInternalServerError, FormatResponse(code.CodeInternalErr, "", nil)
+    }
+    defer freeRPCClient(client)
+
+    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid, "clientip", args["clientip"])
+    rsp, err := client.client.UpdateProfile(ctx, &pb.ProfileRequest{Username: args["username"], Token: args["token"],
+                                            Nickname: args["nickname"], Headurl: args["headurl"], Email: args["email"], Phone: args["phone"],
+                                            Bio: args["bio"], Locale: args["locale"], Timezone: args["timezone"], Birthday: args["birthday"], Mask: mask})
+    if err != nil {
+        log.Error(uuid, " -- Failed to communicate with TCP server, err:", err.Error())
+        return http.StatusOK, FormatResponse(code.CodeErrBackend, "", nil)
+    }
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp.Code, " and msg:", rsp.Msg)
+
+    var data map[string]string
+    if rsp.Code == code.CodeSucc {
+        data = userData(rsp)
+    }
+    return http.StatusOK, FormatResponse(int(rsp.Code), rsp.Msg, data)
+}
+
+// SendVerifyEmail mail a new verification link to the email of user
+func SendVerifyEmail(args map[string]string) (int, map[string]interface{}) {
+    // get uuid
+    uuid := args["uuid"]
+    // communicate with rcp server
+    client, err := getRPCClient()
+    if err != nil {
+        log.Error(uuid, " -- Failed to getRPCClient, err:", err.Error())
+        return http.StatusInternalServerError, FormatResponse(code.CodeInternalErr, "", nil)
+    }
+    defer freeRPCClient(client)
+
+    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid, "clientip", args["clientip"])
+    rsp, err := client.client.SendVerifyEmail(ctx, &pb.CommRequest{Token: args["token"], Username: args["username"]})
+    if err != nil {
+        log.Error(uuid, " -- Failed to communicate with TCP server, err:", err.Error())
+        return http.StatusOK, FormatResponse(code.CodeErrBackend, "", nil)
+    }
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp.Code, " and msg:", rsp.Msg)
+
+    return http.StatusOK, FormatResponse(int(rsp.Code), rsp.Msg, nil)
+}
+
+// VerifyEmail verify email by the token of a mailed link
+func VerifyEmail(args map[string]string) (int, map[string]interface{}) {
+    // get uuid
+    uuid := args["uuid"]
+    // communicate with rcp server
+    client, err := getRPCClient()
+    if err != nil {
+        log.Error(uuid, " -- Failed to getRPCClient, err:", err.Error())
+        return http.StatusInternalServerError, FormatResponse(code.CodeInternalErr, "", nil)
+    }
+    defer freeRPCClient(client)
+
+    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid, "clientip", args["clientip"])
+    rsp, err := client.client.VerifyEmail(ctx, &pb.VerifyEmailRequest{Token: args["token"]})
+    if err != nil {
+        log.Error(uuid, " -- Failed to communicate with TCP server, err:", err.Error())
+        return http.StatusOK, FormatResponse(code.CodeErrBackend, "", nil)
+    }
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp.Code, " and msg:", rsp.Msg)
+
+    return http.StatusOK, FormatResponse(int(rsp.Code), rsp.Msg, nil)
+}
+
+// RequestPasswordReset mail a passwd reset link to the verified email of user, succ whether or not it's sent
+func RequestPasswordReset(args map[string]string) (int, map[string]interface{}) {
+    // get uuid
+    uuid := args["uuid"]
+    // communicate with rcp server
+    client, err := getRPCClient()
+    if err != nil {
+        log.Error(uuid, " -- Failed to getRPCClient, err:", err.Error())
+        return http.StatusInternalServerError, FormatResponse(code.CodeInternalErr, "", nil)
+    }
+    defer freeRPCClient(client)
+
+    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid, "clientip", args["clientip"])
+    rsp, err := client.client.RequestPasswordReset(ctx, &pb.PasswordResetRequest{Username: args["username"]})
+    if err != nil {
+        log.Error(uuid, " -- Failed to communicate with TCP server, err:", err.Error())
+        return http.StatusOK, FormatResponse(code.CodeErrBackend, "", nil)
+    }
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp.Code, " and msg:", rsp.Msg)
+
+    return http.StatusOK, FormatResponse(int(rsp.Code), rsp.Msg, nil)
+}
+
+// ResetPassword set a new passwd by the token of a mailed reset link
+func ResetPassword(args map[string]string) (int, map[string]interface{}) {
+    // get uuid
+    uuid := args["uuid"]
+    // communicate with rcp server
+    client, err := getRPCClient()
+    if err != nil {
+        log.Error(uuid, " -- Failed to getRPCClient, err:", err.Error())
+        return http.StatusInternalServerError, FormatResponse(code.CodeInternalErr, "", nil)
+    }
+    defer freeRPCClient(client)
+
+    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid, "clientip", args["clientip"])
+    rsp, err := client.client.ResetPassword(ctx, &pb.ResetPasswordRequest{Token: args["token"], Newpasswd: args["newpasswd"]})
+    if err != nil {
+        log.Error(uuid, " -- Failed to communicate with TCP server, err:", err.Error())
+        return http.StatusOK, FormatResponse(code.CodeErrBackend, "", nil)
+    }
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp.Code, " and msg:", rsp.Msg)
+
+    return http.StatusOK, FormatResponse(int(rsp.Code), rsp.Msg, nil)
+}
+
+// ChangePasswd change user passwd
+func ChangePasswd(args map[string]string) (int, map[string]interface{}) {
+    // get uuid
+    uuid := args["uuid"]
+    // communicate with rcp server
+    client, err := getRPCClient()
+    if err != nil {
+        log.Error(uuid, " -- Failed to getRPCClient, err:", err.Error())
+        return http.StatusInternalServerError, FormatResponse(code.CodeInternalErr, "", nil)
+    }
+    defer freeRPCClient(client)
+
+    keepSession, _ := strconv.ParseBool(args["keepsession"])
+    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid, "clientip", args["clientip"])
+    rsp, err := client.client.ChangePasswd(ctx, &pb.ChangePasswdRequest{Username: args["username"], Token: args["token"],
+                          Oldpasswd: args["oldpasswd"], Newpasswd: args["newpasswd"], Keepsession: keepSession})
+    if err != nil {
+        log.Error(uuid, " -- Failed to communicate with TCP server, err:", err.Error())
+        return http.StatusOK, FormatResponse(code.CodeErrBackend, "", nil)
+    }
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp.Code, " and msg:", rsp.Msg)
+
+    return http.StatusOK, FormatResponse(int(rsp.Code), rsp.Msg, nil)
+}
+
+// ChangeUsername : move user to a new username, tokens are only returned if the access token was reissued
+func ChangeUsername(args map[string]string) (int, Tokens, map[string]interface{}) {
+    // get uuid
+    uuid := args["uuid"]
+    // communicate with rcp server
+    client, err := getRPCClient()
+    if err != nil {
+        log.Error(uuid, " -- Failed to getRPCClient, err:", err.Error())
+        return http.StatusInternalServerError, Tokens{}, FormatResponse(code.CodeInternalErr, "", nil)
+    }
+    defer freeRPCClient(client)
+
+    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid, "clientip", args["clientip"])
+    rsp, err := client.client.ChangeUsername(ctx, &pb.ChangeUsernameRequest{Username: args["username"], Token: args["token"], Newname: args["newname"]})
+    if err != nil {
+        log.Error(uuid, " -- Failed to communicate with TCP server, err:", err.Error())
+        return http.StatusOK, Tokens{}, FormatResponse(code.CodeErrBackend, "", nil)
+    }
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp.Code, " and msg:", rsp.Msg)
+
+    var data map[string]string
+    if rsp.Code == code.CodeSucc {
+        data = userData(rsp)
+    }
+    return http.StatusOK, tokensOf(rsp), FormatResponse(int(rsp.Code), rsp.Msg, data)
+}
+
+// SetupTwoFactor : generate a pending totp secret of user
+func SetupTwoFactor(args map[string]string) (int, map[string]interface{}) {
+    // get uuid
+    uuid := args["uuid"]
+    // communicate with rcp server
+    client, err := getRPCClient()
+    if err != nil {
+        log.Error(uuid, " -- Failed to getRPCClient, err:", err.Error())
+        return http.StatusInternalServerError, FormatResponse(code.CodeInternalErr, "", nil)
+    }
+    defer freeRPCClient(client)
+
+    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid, "clientip", args["clientip"])
+    rsp, err := client.client.SetupTwoFactor(ctx, &pb.CommRequest{Token: args["token"], Username: args["username"]})
+    if err != nil {
+        log.Error(uuid, " -- Failed to communicate with TCP server, err:", err.Error())
+        return http.StatusOK, FormatResponse(code.CodeErrBackend, "", nil)
+    }
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp.Code, " and msg:", rsp.Msg)
+
+    var data map[string]string
+    if rsp.Code == code.CodeSucc {
+        data = map[string]string{"secret":rsp.Secret, "uri":rsp.Uri}
+    }
+    return http.StatusOK, FormatResponse(int(rsp.Code), rsp.Msg, data)
+}
+
+// ConfirmTwoFactor : enable 2fa with a first code, recovery codes are only returned here
+func ConfirmTwoFactor(args map[string]string) (int, map[string]interface{}) {
+    // get uuid
+    uuid := args["uuid"]
+    // communicate with rcp server
+    client, err := getRPCClient()
+    if err != nil {
+        log.Error(uuid, " -- Failed to getRPCClient, err:", err.Error())
+        return http.StatusInternalServerError, FormatResponse(code.CodeInternalErr, "", nil)
+    }
+    defer freeRPCClient(client)
+
+    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid, "clientip", args["clientip"])
+    rsp, err := client.client.ConfirmTwoFactor(ctx, &pb.TwoFactorRequest{Username: args["username"], Token: args["token"], Passcode: args["passcode"]})
+    if err != nil {
+        log.Error(uuid, " -- Failed to communicate with TCP server, err:", err.Error())
+        return http.StatusOK, FormatResponse(code.CodeErrBackend, "", nil)
+    }
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp.Code, " and msg:", rsp.Msg)
+
+    var data gin.H
+    if rsp.Code == code.CodeSucc {
+        data = gin.H{"recoverycodes": rsp.Recoverycodes}
+    }
+    return http.StatusOK, FormatResponse(int(rsp.Code), rsp.Msg, data)
+}
+
+// DisableTwoFactor : disable 2fa with a totp code or a recovery code
+func DisableTwoFactor(args map[string]string) (int, map[string]interface{}) {
+    // get uuid
+    uuid := args["uuid"]
+    // communicate with rcp server
+    client, err := getRPCClient()
+    if err != nil {
+        log.Error(uuid, " -- Failed to getRPCClient, err:", err.Error())
+        return http.StatusInternalServerError, FormatResponse(code.CodeInternalErr, "", nil)
+    }
+    defer freeRPCClient(client)
+
+    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid, "clientip", args["clientip"])
+    rsp, err := client.client.DisableTwoFactor(ctx, &pb.TwoFactorRequest{Username: args["username"], Token: args["token"], Passcode: args["passcode"]})
+    if err != nil {
+        log.Error(uuid, " -- Failed to communicate with TCP server, err:", err.Error())
+        return http.StatusOK, FormatResponse(code.CodeErrBackend, "", nil)
+    }
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp.Code, " and msg:", rsp.Msg)
+
+    return http.StatusOK, FormatResponse(int(rsp.Code), rsp.Msg, nil)
+}
+
+// DeactivateAccount : deactivate account, it can be restored within grace period
+func DeactivateAccount(args map[string]string) (int, map[string]interface{}) {
+    return account(args, false)
+}
+
+// DeleteAccount : delete account at once
+func DeleteAccount(args map[string]string) (int, map[string]interface{}) {
+    return account(args, true)
+}
+
+// account : deactivate or delete account through rpc
+func account(args map[string]string, remove bool) (int, map[string]interface{}) {
+    // get uuid
+    uuid := args["uuid"]
+    // communicate with rcp server
+    client, err := getRPCClient()
+    if err != nil {
+        log.Error(uuid, " -- Failed to getRPCClient, err:", err.Error())
+        return http.StatusInternalServerError, FormatResponse(code.CodeInternalErr, "", nil)
+    }
+    defer freeRPCClient(client)
+
+    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid, "clientip", args["clientip"])
+    call := client.client.DeactivateAccount
+    if remove {
+        call = client.client.DeleteAccount
+    }
+    rsp, err := call(ctx, &pb.AccountRequest{Username: args["username"], Token: args["token"], Passwd: args["passwd"]})
+    if err != nil {
+        log.Error(uuid, " -- Failed to communicate with TCP server, err:", err.Error())
+        return http.StatusOK, FormatResponse(code.CodeErrBackend, "", nil)
+    }
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp.Code, " and msg:", rsp.Msg)
+
+    return http.StatusOK, FormatResponse(int(rsp.Code), rsp.Msg, nil)
+}
+
+// ListSessions list active sessions of user
+func ListSessions(args map[string]string) (int, map[string]interface{}) {
+    // get uuid
+    uuid := args["uuid"]
+    // communicate with rcp server
+    client, err := getRPCClient()
+    if err != nil {
+        log.Error(uuid, " -- Failed to getRPCClient, err:", err.Error())
+        return http.StatusInternalServerError, FormatResponse(code.CodeInternalErr, "", nil)
+    }
+    defer freeRPCClient(client)
+
+    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid, "clientip", args["clientip"])
+    rsp, err := client.client.ListSessions(ctx, &pb.CommRequest{Token: args["token"], Username: args["username"]})
+    if err != nil {
+        log.Error(uuid, " -- Failed to communicate with TCP server, err:", err.Error())
+        return http.StatusOK, FormatResponse(code.CodeErrBackend, "", nil)
+    }
+    if rsp.Code != code.CodeSucc {
+        return http.StatusOK, FormatResponse(int(rsp.Code), rsp.Msg, nil)
+    }
+
+    sessions := make([]gin.H, 0, len(rsp.Sessions))
+    for _, session := range rsp.Sessions {
+        sessions = append(sessions, gin.H{"id": session.Id, "createtime": session.Createtime, "lastseen": session.Lastseen,
+                                          "ip": session.Ip, "useragent": session.Useragent, "current": session.Current})
+    }
+    return http.StatusOK, FormatResponse(int(rsp.Code), rsp.Msg, gin.H{"sessions": sessions})
+}
+
+// RevokeSession revoke one or all sessions of user
+func RevokeSession(args map[string]string) (int, map[string]interface{}) {
+    // get uuid
+    uuid := args["uuid"]
+    // communicate with rcp server
+    client, err := getRPCClient()
+    if err != nil {
+        log.Error(uuid, " -- Failed to getRPCClient, err:", err.Error())
+        return http.StatusInternalServerError, FormatResponse(code.CodeInternalErr, "", nil)
+    }
+    defer freeRPCClient(client)
+
+    all, _ := strconv.ParseBool(args["all"])
+    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid, "clientip", args["clientip"])
+    rsp, err := client.client.RevokeSession(ctx, &pb.RevokeSessionRequest{Token: args["token"], Username: args["username"],
+                                            Sessionid: args["sessionid"], All: all})
+    if err != nil {
+        log.Error(uuid, " -- Failed to communicate with TCP server, err:", err.Error())
+        return http.StatusOK, FormatResponse(code.CodeErrBackend, "", nil)
+    }
+    log.Debug(uuid, " -- Succ to get response from backend with ", rsp.Code, " and msg:", rsp.Msg)
+
+    return http.StatusOK, FormatResponse(int(rsp.Code), rsp.Msg, nil)
+}
+
+// ListUsers a filtered page of all users for admins, args are checked by the handler
+func ListUsers(args map[string]string) (int, map[string]interface{}) {
+    // get uuid
+    uuid := args["uuid"]
+    // communicate with rcp server
+    client, err := getRPCClient()
+    if err != nil {
+        log.Error(uuid, " -- Failed to getRPCClient, err:", err.Error())
+        return http.StatusInternalServerError, FormatResponse(code.CodeInternalErr, "", nil)
+    }
+    defer freeRPCClient(client)
+
+    limit, _ := strconv.ParseUint(args["limit"], 10, 32)
+    upfrom, _ := strconv.ParseInt(args["upfrom"], 10, 64)
+    upto, _ := strconv.ParseInt(args["upto"], 10, 64)
+    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid, "clientip", args["clientip"])
+    rsp, err := client.client.ListUsers(ctx, &pb.ListUsersRequest{Username: args["username"], Token: args["token"], Cursor: args["cursor"],
+                                        Limit: uint32(limit), Prefix: args["prefix"], Nickname: args["nickname"], Upfrom: upfrom, Upto: upto})
+    if err != nil {
+        log.Error(uuid, " -- Failed to communicate with TCP server, err:", err.Error())
+        return http.StatusOK, FormatResponse(code.CodeErrBackend, "", nil)
+    }
+    if rsp.Code != code.CodeSucc {
+        return http.StatusOK, FormatResponse(int(rsp.Code), rsp.Msg, nil)
+    }
+
+    users := make([]map[string]string, 0, len(rsp.Users))
+    for _, user := range rsp.Users {
+        // admins see whether accounts are locked or deactivated
+        data := userData(user)
+        data["status"] = strconv.Itoa(int(user.Status))
+        users = append(users, data)
+    }
+    return http.StatusOK, FormatResponse(int(rsp.Code), rsp.Msg, gin.H{"users": users, "nextcursor": rsp.Nextcursor})
+}
+
+// QueryAuditLog a page of audit events for admins, args are checked by the handler
+func QueryAuditLog(args map[string]string) (int, map[string]interface{}) {
+    // get uuid
+    uuid := args["uuid"]
+    // communicate with rcp server
+    client, err := getRPCClient()
+    if err != nil {
+        log.Error(uuid, " -- Failed to getRPCClient, err:", err.Error())
+        return http.StatusInternalServerError, FormatResponse(code.CodeInternalErr, "", nil)
+    }
+    defer freeRPCClient(client)
+
+    limit, _ := strconv.ParseUint(args["limit"], 10, 32)
+    from, _ := strconv.ParseInt(args["from"], 10, 64)
+    to, _ := strconv.ParseInt(args["to"], 10, 64)
+    targetuid, _ := strconv.ParseInt(args["targetuid"], 10, 64)
+    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid, "clientip", args["clientip"])
+    rsp, err := client.client.QueryAuditLog(ctx, &pb.AuditLogRequest{Username: args["username"], Token: args["token"], Target: args["target"],
+                                            Targetuid: targetuid, Action: args["action"], From: from, To: to, Cursor: args["cursor"], Limit: uint32(limit)})
+    if err != nil {
+        log.Error(uuid, " -- Failed to communicate with TCP server, err:", err.Error())
+        return http.StatusOK, FormatResponse(code.CodeErrBackend, "", nil)
+    }
+    if rsp.Code != code.CodeSucc {
+        return http.StatusOK, FormatResponse(int(rsp.Code), rsp.Msg, nil)
+    }
+
+    events := make([]map[string]interface{}, 0, len(rsp.Events))
+    for _, event := range rsp.Events {
+        events = append(events, map[string]interface{}{"id": event.Id, "time": event.Time, "username": event.Username,
+            "uid": strconv.FormatInt(event.Uid, 10), "actor": event.Actor, "action": event.Action, "ip": event.Ip, "uuid": event.Uuid, "outcome": event.Outcome,
+            "resultcode": event.Resultcode, "detail": event.Detail})
+    }
+    return http.StatusOK, FormatResponse(int(rsp.Code), rsp.Msg, gin.H{"events": events, "nextcursor": rsp.Nextcursor})
+}
+
 // GetUserinfo get userinfo handler
 func GetUserinfo(args map[string]string) (int, map[string]interface{}) {
     // get uuid
@@ -177,38 +721,38 @@ func GetUserinfo(args map[string]string) (int, map[string]interface{}) {
     }
     defer freeRPCClient(client)
 
-    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid)
+    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid, "clientip", args["clientip"])
     rsp, err := client.client.GetUserInfo(ctx, &pb.CommRequest{Token: args["token"], Username: args["username"]})
     if err != nil {
         log.Error(uuid, " -- Failed to communicate with TCP server, err:", err.Error())
         return http.StatusOK, FormatResponse(code.CodeErrBackend, "", nil)
     }
-    response := FormatResponse(int(rsp.Code), rsp.Msg, map[string]string{"username":rsp.Username, "nickname":rsp.Nickname, "headurl":rsp.Headurl})
+    response := FormatResponse(int(rsp.Code), rsp.Msg, userData(rsp))
 
     return http.StatusOK, response
 }
 
-// Auth user getUserInfo to
-func Auth(args map[string]string) (int, int, string) {
+// Auth user getUserInfo to, return the uid of the user on success
+func Auth(args map[string]string) (int, int, string, int64) {
     // get uuid
     uuid := args["uuid"]
     // communicate with rcp server
     client, err := getRPCClient()
     if err != nil {
         log.Error(uuid, " -- Failed to getRPCClient, err:", err.Error())
-        return http.StatusInternalServerError, code.CodeInternalErr, code.CodeMsg[code.CodeInternalErr]
+        return http.StatusInternalServerError, code.CodeInternalErr, code.CodeMsg[code.CodeInternalErr], 0
     }
     defer freeRPCClient(client)
 
-    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid)
+    ctx := metadata.AppendToOutgoingContext(context.Background(), "uuid", uuid, "clientip", args["clientip"])
     rsp, err := client.client.GetUserInfo(ctx, &pb.CommRequest{Token: args["token"], Username: args["username"]})
     if err != nil {
         log.Error(uuid, " -- Failed to communicate with TCP server, err:", err.Error())
-        return http.StatusOK, code.CodeErrBackend, code.CodeMsg[code.CodeErrBackend]
+        return http.StatusOK, code.CodeErrBackend, code.CodeMsg[code.CodeErrBackend], 0
     }
     if rsp.Code == 0 {
-        return http.StatusOK, code.CodeSucc, code.CodeMsg[code.CodeSucc]
+        return http.StatusOK, code.CodeSucc, code.CodeMsg[code.CodeSucc], rsp.Uid
     }
 
-    return http.StatusOK, int(rsp.Code), rsp.Msg
+    return http.StatusOK, int(rsp.Code), rsp.Msg, 0
 }
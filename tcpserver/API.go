@@ -1,118 +1,425 @@
 package tcpserver
 
 import (
-	"os"
     "fmt"
+	"path"
+	"strings"
+	"time"
 
 	"user-management-system/conf"
-	"user-management-system/tcpserver/cache"
+	"user-management-system/tcpserver/audit"
 	"user-management-system/tcpserver/consts"
-	"user-management-system/tcpserver/db"
+	"user-management-system/tcpserver/hasher"
+	"user-management-system/tcpserver/rbac"
+	"user-management-system/tcpserver/snowflake"
+	"user-management-system/tcpserver/store"
 	"user-management-system/tcpserver/types"
+	"user-management-system/utils"
+	"user-management-system/utils/jwt"
 
 	log "github.com/beego/beego/v2/adapter/logs"
 )
 
 // API
 type API struct {
-	redisClient *cache.RedisClient
-	dbClient    *db.DBClient
+	sessions  store.SessionStore
+	users     store.UserStore
+	hasher    *hasher.Hasher
+	throttle  *loginThrottle
+	twoFactor *twoFactorPolicy
+	account   *accountPolicy
+	email     *emailPolicy
+	rbac      *rbac.Policy
+	auditLog  audit.Sink
+	uids      *snowflake.Generator
+	// nil unless tokens are signed
+	keySet       *jwt.KeySet
+	accessExpire int64
 }
 
-// NewAPI new a API
-func NewAPI(config *conf.TCPConf) *API {
-	// init redis
-	redisClient, err := cache.NewRedisClient(config)
+// NewAPI new a API with stores of config, errors are returned instead of exiting
+func NewAPI(config *conf.TCPConf) (*API, error) {
+	// init session store (redis by default)
+	sessions, err := store.NewSessionStore(config)
 	if err != nil {
-		log.Critical("initRedisConn failed:", err.Error())
-		os.Exit(-1)
+		return nil, fmt.Errorf("init session store failed: %s", err.Error())
 	}
-    log.Info("newRedisClient successfully, client: %v\n", redisClient)
+	log.Info("new session store successfully:", config.Store.Sessions)
 
-	// init db
-	dbClient, err := db.NewDBClient(config)
+	// init user store (mysql by default)
+	users, err := store.NewUserStore(config)
 	if err != nil {
-		log.Critical("initDbConn failed:", err.Error())
-        fmt.Printf("newDBClient failed, error: %v\n", err)
-		os.Exit(-1)
+		sessions.CloseCache()
+		return nil, fmt.Errorf("init user store failed: %s", err.Error())
 	}
-    log.Info("newDBClient supccessfully, client: %v\n", dbClient)
+	log.Info("new user store successfully:", config.Store.Users)
 	log.Info("cache and db init successfully!")
 
-	return &API{
-		redisClient: redisClient,
-		dbClient:    dbClient,
+	api, err := newAPI(config, users, sessions)
+	if err != nil {
+		sessions.CloseCache()
+		users.CloseDB()
+		return nil, err
 	}
+	return api, nil
+}
+
+// newAPI new a API on given stores
+func newAPI(config *conf.TCPConf, users store.UserStore, sessions store.SessionStore) (*API, error) {
+	// init passwd hasher
+	passwdHasher, err := hasher.NewHasher(config)
+	if err != nil {
+		return nil, fmt.Errorf("new hasher failed: %s", err.Error())
+	}
+
+	// init uid generator
+	uids, err := snowflake.NewGenerator(config.Uid.Node)
+	if err != nil {
+		return nil, err
+	}
+
+	// init verification mailer
+	email, err := newEmailPolicy(config)
+	if err != nil {
+		return nil, fmt.Errorf("new mailer failed: %s", err.Error())
+	}
+
+	// init role permissions
+	policy, err := rbac.NewPolicyFromConf(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// init audit log
+	auditLog, err := audit.NewSinkFromConf(config, users)
+	if err != nil {
+		return nil, err
+	}
+
+	api := &API{
+		sessions:  sessions,
+		users:     users,
+		hasher:    passwdHasher,
+		throttle:  newLoginThrottle(config),
+		twoFactor: newTwoFactorPolicy(config),
+		account:   newAccountPolicy(config),
+		email:     email,
+		rbac:      policy,
+		auditLog:  auditLog,
+		uids:      uids,
+	}
+
+	// init access token signer
+	if config.Token.Mode == jwt.TokenModeSigned {
+		api.keySet, err = jwt.NewKeySetFromConf(&config.Token)
+		if err != nil {
+			auditLog.Close()
+			return nil, fmt.Errorf("new key set failed: %s", err.Error())
+		}
+		api.accessExpire = int64(config.Token.Signed.Expire)
+		log.Info("tokens are signed, access token expire:", api.accessExpire)
+	}
+
+	return api, nil
 }
 
 // Finalize clean up the cache and db resources
 func (a *API) Finalize() {
-	a.redisClient.CloseCache()
-	a.dbClient.CloseDB()
+	close(a.account.stop)
+	a.email.pending.Wait()
+	a.auditLog.Close()
+	a.sessions.CloseCache()
+	a.users.CloseDB()
 }
 
 // GetUserInfo get user info
 func (a *API) GetUserInfo(username string) (types.User, error) {
     log.Debug("try to get userinfo from cache...") 
 	// try cache
-	user, err := a.redisClient.GetUserCacheInfo(username)
+	user, err := a.sessions.GetUserCacheInfo(username)
 	if err == nil && user.Username == username {
 		return user, err
 	}
 
 	// get from db
     log.Debug("try to get userinfo from db...") 
-	user, err = a.dbClient.GetDbUserInfo(username)
+	user, err = a.users.GetDbUserInfo(username)
 	if err != nil {
 		return user, err
 	}
 
 	// update cache
-	if err := a.redisClient.SetUserCacheInfo(user); err != nil {
+	if err := a.sessions.SetUserCacheInfo(user); err != nil {
 		log.Error("cache userinfo failed for user:", user.Username, " with err:", err.Error())
 	}
 
 	return user, err
 }
 
-// EditUserInfo edit user info
-func (a *API) EditUserInfo(username, nickname, headurl, token string, mode uint32) int64 {
-	// update db info
-	var affectedRows int64
-	switch mode {
-	case consts.EditUsername:
-		affectedRows = a.dbClient.UpdateDbNickname(username, nickname)
-	case consts.EditHeadurl:
-		affectedRows = a.dbClient.UpdateDbHeadurl(username, headurl)
-	case consts.EditBoth:
-		affectedRows = a.dbClient.UpdateDbUserinfo(username, nickname, headurl)
-	default:
-		// do nothing
-		break
-	}
-
-	// on successing, update cache or delete it if updating failed
-	if affectedRows == 1 {
-		user, err := a.dbClient.GetDbUserInfo(username)
+// GetUserByUid get user info by global uid
+func (a *API) GetUserByUid(uid int64) (types.User, error) {
+	return a.users.GetDbUserByUid(uid)
+}
+
+// Register create a new user with a fresh skey, db.ErrUserExists is returned if username is taken.
+// email is optional, a verification link is mailed to it
+func (a *API) Register(username, passwd, nickname, email string) (types.User, error) {
+	var user types.User
+	hash, err := a.hasher.Hash(passwd)
+	if err != nil {
+		return user, err
+	}
+	skey, err := utils.GenerateSkey()
+	if err != nil {
+		return user, err
+	}
+	user = types.User{
+		Uid:      a.uids.Next(),
+		Username: username,
+		Nickname: nickname,
+		Email:    email,
+		Passwd:   hash,
+		Skey:     skey,
+		Uptime:   time.Now().Unix(),
+	}
+	if err = a.users.CreateDbUser(&user); err != nil {
+		return user, err
+	}
+	if email != "" {
+		a.sendVerifyEmail(user)
+	}
+	return user, nil
+}
+
+// VerifyPasswd check passwd of user, legacy or outdated hashes are replaced on success
+func (a *API) VerifyPasswd(user types.User, passwd string) bool {
+	ok, rehash, err := a.hasher.Verify(passwd, user.Skey, user.Passwd)
+	if err != nil {
+		log.Error("failed to verify passwd for user:", user.Username, " with err:", err.Error())
+		return false
+	}
+	if !ok || !rehash {
+		return ok
+	}
+
+	// rehash with preferred algorithm, login goes on even if it failed
+	hash, err := a.hasher.Hash(passwd)
+	if err != nil {
+		log.Error("failed to rehash passwd for user:", user.Username, " with err:", err.Error())
+		return true
+	}
+	if a.users.UpdateDbPasswd(user.Username, hash, user.Skey) == 1 {
+		log.Info("rehash passwd for user:", user.Username)
+		a.sessions.DelUserCacheInfo(user.Username)
+	} else {
+		log.Error("failed to update rehashed passwd for user:", user.Username)
+	}
+	return true
+}
+
+// ChangePasswd set a new passwd hash and skey, then revoke all sessions of user except keepToken
+func (a *API) ChangePasswd(username, passwd, keepToken string) error {
+	hash, err := a.hasher.Hash(passwd)
+	if err != nil {
+		return err
+	}
+	skey, err := utils.GenerateSkey()
+	if err != nil {
+		return err
+	}
+	if a.users.UpdateDbPasswd(username, hash, skey) != 1 {
+		return fmt.Errorf("failed to update passwd of user(%s)", username)
+	}
+	a.sessions.DelUserCacheInfo(username)
+
+	// revoke sessions
+	deleted, err := a.sessions.DelUserTokens(username, keepToken)
+	if err != nil {
+		log.Error("failed to revoke tokens of user:", username, " with err:", err.Error())
+	}
+	log.Info("passwd changed for user:", username, ", revoked tokens:", deleted)
+
+	// refresh the kept session with new userinfo
+	if keepToken != "" {
+		user, err := a.users.GetDbUserInfo(username)
 		if err == nil {
-			a.redisClient.UpdateCachedUserinfo(user)
-			if token != "" {
-				err = a.redisClient.SetTokenInfo(user, token)
-				if err != nil {
-					log.Error("update token failed:", err.Error())
-					a.redisClient.DelTokenInfo(token)
-				}
-			}
-		} else {
-			log.Error("Failed to get dbUserInfo for cache, username:", username, " with err:", err.Error())
+			err = a.sessions.SetTokenInfo(user, keepToken)
+		}
+		if err != nil {
+			log.Error("failed to refresh kept token:", err.Error())
+			a.sessions.DelTokenInfo(keepToken)
 		}
 	}
-	return affectedRows
+	return nil
+}
+
+// editModes masks of the edit modes older clients send instead of a mask
+var editModes = map[uint32][]string{
+	consts.EditUsername: {"nickname"},
+	consts.EditHeadurl:  {"headurl"},
+	consts.EditBoth:     {"nickname", "headurl"},
+}
+
+// EditUserInfo edit nickname and headurl by mask, an empty mask is translated from mode.
+// avatar is the file name of an uploaded headurl, recorded as the user's to be deleted with it.
+// uploads are named after the uid of the uploader, so only its own files can be claimed
+func (a *API) EditUserInfo(username, nickname, headurl, avatar, token string, mask []string, mode uint32) (types.User, error) {
+	if len(mask) == 0 {
+		mask = editModes[mode]
+	}
+	for _, field := range mask {
+		if field != "nickname" && field != "headurl" {
+			return types.User{}, &ProfileError{Field: field, Reason: "not editable by EditUserInfo"}
+		}
+	}
+	if avatar != "" {
+		if !inMask(mask, "headurl") || path.Base(avatar) != avatar || strings.HasPrefix(avatar, ".") || !strings.HasSuffix(headurl, "/"+avatar) {
+			return types.User{}, &ProfileError{Field: "avatar", Reason: "not the uploaded file of headurl"}
+		}
+		user, err := a.GetUserInfo(username)
+		if err != nil {
+			return types.User{}, err
+		}
+		if !ownAvatar(user, avatar) {
+			return types.User{}, &ProfileError{Field: "avatar", Reason: "not uploaded by the user"}
+		}
+		// recorded first, an avatar left by a failed update is still deleted with its user
+		if a.users.UpdateDbAvatar(username, avatar) != 1 {
+			return types.User{}, fmt.Errorf("failed to record avatar of user(%s)", username)
+		}
+	}
+	return a.UpdateProfile(username, token, types.User{Nickname: nickname, Headurl: headurl}, mask)
+}
+
+// refreshUser reload updated username into cache and the session of token
+func (a *API) refreshUser(username, token string) (types.User, error) {
+	user, err := a.users.GetDbUserInfo(username)
+	if err != nil {
+		log.Error("Failed to get dbUserInfo for cache, username:", username, " with err:", err.Error())
+		a.sessions.DelUserCacheInfo(username)
+		return user, err
+	}
+	a.sessions.UpdateCachedUserinfo(user)
+	if token != "" {
+		err = a.sessions.SetTokenInfo(user, token)
+		if err != nil {
+			log.Error("update token failed:", err.Error())
+			a.sessions.DelTokenInfo(token)
+		}
+	}
+	return user, nil
+}
+
+// RotateToken retire token and move its session to a new token, false if token has expired
+func (a *API) RotateToken(username, token string) (string, bool, error) {
+	newToken, err := utils.GenerateToken()
+	if err != nil {
+		return "", false, err
+	}
+	ok, err := a.sessions.RotateSession(username, token, newToken)
+	return newToken, ok, err
+}
+
+// Signed whether login issues signed access tokens
+func (a *API) Signed() bool {
+	return a.keySet != nil
+}
+
+// IssueAccessToken sign a short-lived access token for the session of sessionToken
+func (a *API) IssueAccessToken(user types.User, sessionToken string) (string, error) {
+	now := time.Now().Unix()
+	return a.keySet.Sign(jwt.Claims{
+		Subject:   user.Username,
+		Uid:       user.Uid,
+		Nickname:  user.Nickname,
+		Headurl:   user.Headurl,
+		Roles:     user.RoleList(),
+		SessionID: SessionID(sessionToken),
+		IssuedAt:  now,
+		ExpiresAt: now + a.accessExpire,
+	})
+}
+
+// TokenUser get user of token, signed access tokens are verified locally and opaque ones are looked up in cache
+func (a *API) TokenUser(token string) (types.User, error) {
+	if a.keySet == nil || !jwt.IsSigned(token) {
+		return a.sessions.GetTokenInfo(token)
+	}
+	claims, err := a.keySet.Verify(token, time.Now())
+	if err != nil {
+		return types.User{}, err
+	}
+	return types.User{Uid: claims.Uid, Username: claims.Subject, Nickname: claims.Nickname, Headurl: claims.Headurl,
+		Roles: strings.Join(claims.Roles, ",")}, nil
+}
+
+// SessionUser get user of the session behind token. unlike TokenUser, a signed access token is looked up
+// by its session, so logouts and role changes count at once instead of when the access token expires
+func (a *API) SessionUser(token string) (types.User, error) {
+	if a.keySet == nil || !jwt.IsSigned(token) {
+		return a.sessions.GetTokenInfo(token)
+	}
+	sessionToken := a.SessionToken(token)
+	if sessionToken == "" {
+		return types.User{}, ErrSessionRevoked
+	}
+	return a.sessions.GetTokenInfo(sessionToken)
+}
+
+// SessionToken opaque token of the session behind token, signed access tokens are mapped by session id
+func (a *API) SessionToken(token string) string {
+	if a.keySet == nil || !jwt.IsSigned(token) {
+		return token
+	}
+	claims, err := a.keySet.Verify(token, time.Now())
+	if err != nil {
+		return ""
+	}
+	sessions, err := a.sessions.GetUserSessions(claims.Subject)
+	if err != nil {
+		return ""
+	}
+	for _, session := range sessions {
+		if SessionID(session.Token) == claims.SessionID {
+			return session.Token
+		}
+	}
+	return ""
+}
+
+// SessionID public id of the session of token, tokens are never exposed in session list
+func SessionID(token string) string {
+	return utils.Md5String(token)[0:16]
+}
+
+// ListSessions list active sessions of username
+func (a *API) ListSessions(username string) ([]types.Session, error) {
+	return a.sessions.GetUserSessions(username)
+}
+
+// RevokeSession revoke the session with id of username, false if it's not found
+func (a *API) RevokeSession(username, sessionID string) (bool, error) {
+	sessions, err := a.sessions.GetUserSessions(username)
+	if err != nil {
+		return false, err
+	}
+	for _, session := range sessions {
+		if SessionID(session.Token) == sessionID {
+			return true, a.sessions.DelTokenInfo(session.Token)
+		}
+	}
+	return false, nil
+}
+
+// RevokeAllSessions revoke all sessions of username, return the number of revoked sessions
+func (a *API) RevokeAllSessions(username string) (int, error) {
+	return a.sessions.DelUserTokens(username, "")
 }
 
-// Auth authenticate username
+// Auth authenticate username by the session of token, rpcs changing state turn away signed
+// access tokens of sessions logged out, or revoked by passwd changes, resets and deactivation
 func (a *API) Auth(username, token string) bool {
-	user, err := a.redisClient.GetTokenInfo(token)
+	user, err := a.SessionUser(token)
 	if err != nil {
 		log.Error("failed to getTokenInfo, token:", token)
 		return false
@@ -0,0 +1,159 @@
+package tcpserver
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"user-management-system/conf"
+	"user-management-system/tcpserver/consts"
+	"user-management-system/tcpserver/types"
+
+	log "github.com/beego/beego/v2/adapter/logs"
+)
+
+// users purged from a table in one query of sweeper
+const sweepBatch = 100
+
+var (
+	// ErrAccountState account is already deactivated, or isn't deactivated
+	ErrAccountState = errors.New("account is not in the right state")
+	// ErrAccountExpired grace period of a deactivated account is over
+	ErrAccountExpired = errors.New("grace period of account is over")
+)
+
+// accountPolicy retention settings of config
+type accountPolicy struct {
+	gracePeriod   int64
+	renameReserve int64
+	sweepInterval time.Duration
+	imagePath     string
+	stop          chan struct{}
+}
+
+func newAccountPolicy(config *conf.TCPConf) *accountPolicy {
+	return &accountPolicy{
+		gracePeriod:   int64(config.Account.Graceperiod),
+		renameReserve: int64(config.Account.Renamereserve),
+		sweepInterval: time.Duration(config.Account.Sweepinterval) * time.Second,
+		imagePath:     config.Image.Savepath,
+		stop:          make(chan struct{}),
+	}
+}
+
+// PurgeTime unix time a deactivated user will be purged at
+func (a *API) PurgeTime(user types.User) int64 {
+	return user.Deactivatetime + a.account.gracePeriod
+}
+
+// DeactivateAccount deactivate username and revoke all its sessions, it can be restored within grace period
+func (a *API) DeactivateAccount(username string) error {
+	if a.users.DeactivateDbUser(username, time.Now().Unix()) != 1 {
+		return ErrAccountState
+	}
+	a.sessions.DelUserCacheInfo(username)
+	revoked, err := a.sessions.DelUserTokens(username, "")
+	if err != nil {
+		log.Error("failed to revoke tokens of user:", username, " with err:", err.Error())
+	}
+	log.Info("account deactivated:", username, ", revoked tokens:", revoked)
+	return nil
+}
+
+// RestoreAccount restore a deactivated user within grace period
+func (a *API) RestoreAccount(user types.User) error {
+	if user.Status != types.StatusDeactivated {
+		return ErrAccountState
+	}
+	if time.Now().Unix() >= a.PurgeTime(user) {
+		return ErrAccountExpired
+	}
+	if a.users.RestoreDbUser(user.Username) != 1 {
+		return ErrAccountState
+	}
+	a.sessions.DelUserCacheInfo(user.Username)
+	log.Info("account restored:", user.Username)
+	return nil
+}
+
+// DeleteAccount delete user at once, its row, cache, sessions and avatar are all removed
+func (a *API) DeleteAccount(user types.User) error {
+	if a.users.DeleteDbUser(user.Username) != 1 {
+		return errors.New("failed to delete user " + user.Username)
+	}
+	a.purgeUserData(user)
+	log.Info("account deleted:", user.Username)
+	return nil
+}
+
+// purgeUserData remove what's left of a deleted user
+func (a *API) purgeUserData(user types.User) {
+	a.sessions.DelUserCacheInfo(user.Username)
+	if _, err := a.sessions.DelUserTokens(user.Username, ""); err != nil {
+		log.Error("failed to revoke tokens of user:", user.Username, " with err:", err.Error())
+	}
+	a.sessions.DelLoginFailures(consts.LoginKindUser, user.Username)
+
+	// only the file uploaded by the user, headurl may be any url including avatars of others
+	if !ownAvatar(user, user.Avatar) || a.account.imagePath == "" {
+		return
+	}
+	image := filepath.Join(a.account.imagePath, filepath.Base(user.Avatar))
+	if err := os.Remove(image); err != nil && !os.IsNotExist(err) {
+		log.Error("failed to remove avatar of user:", user.Username, " with err:", err.Error())
+	}
+}
+
+// ownAvatar whether avatar is a file uploaded by user, httpserver names uploads "<uid>_<md5>.<ext>"
+func ownAvatar(user types.User, avatar string) bool {
+	return user.Uid != 0 && strings.HasPrefix(avatar, strconv.FormatInt(user.Uid, 10)+"_")
+}
+
+// SweepAccounts purge deactivated users whose grace period is over, return the number of purged users
+func (a *API) SweepAccounts() int {
+	before := time.Now().Unix() - a.account.gracePeriod
+	var purged int
+	for i := 0; i < a.users.TableNum(); i++ {
+		users, err := a.users.GetDbDeactivatedUsers(i, before, sweepBatch)
+		if err != nil {
+			log.Error("failed to query deactivated users of table:", i, " with err:", err.Error())
+			continue
+		}
+		for _, user := range users {
+			if err = a.DeleteAccount(user); err != nil {
+				log.Error("failed to purge user:", user.Username, " with err:", err.Error())
+				continue
+			}
+			purged++
+		}
+	}
+	return purged
+}
+
+// StartAccountSweeper purge expired accounts and free expired username reservations every sweep interval until Finalize
+func (a *API) StartAccountSweeper() {
+	if a.account.sweepInterval <= 0 {
+		log.Info("account sweeper disabled")
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(a.account.sweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if purged := a.SweepAccounts(); purged > 0 {
+					log.Info("account sweeper purged users:", purged)
+				}
+				if freed := a.users.DelDbExpiredRenames(time.Now().Unix()); freed > 0 {
+					log.Info("account sweeper freed reserved usernames:", freed)
+				}
+			case <-a.account.stop:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,190 @@
+package tcpserver
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"user-management-system/tcpserver/consts"
+	"user-management-system/tcpserver/types"
+
+	log "github.com/beego/beego/v2/adapter/logs"
+)
+
+const (
+	// users per page of ListUsers if limit isn't given
+	defaultListLimit = 20
+	// max users per page of ListUsers
+	maxListLimit = 100
+)
+
+var (
+	// ErrUnknownRole role isn't configured in rbac
+	ErrUnknownRole = errors.New("unknown role")
+	// ErrCursor cursor isn't the nextcursor of a page
+	ErrCursor = errors.New("invalid cursor")
+	// ErrSessionRevoked the session of a signed access token is logged out
+	ErrSessionRevoked = errors.New("session is revoked")
+
+	prefixRegexp = regexp.MustCompile(`^[a-zA-Z0-9_]{0,64}$`)
+)
+
+// FilterError a condition of ListUsers is invalid
+type FilterError struct {
+	Field  string
+	Reason string
+}
+
+func (e *FilterError) Error() string {
+	return e.Field + ": " + e.Reason
+}
+
+// Allowed whether roles of user grant perm
+func (a *API) Allowed(user types.User, perm string) bool {
+	return a.rbac.Allowed(user.Uid, user.RoleList(), perm)
+}
+
+// ListUsers a page of at most limit users passing filter in username order, starting after cursor.
+// all tables are queried at once and their pages merged, the cursor of the next page is the last
+// username of this one, so pages don't shift as users come and go. it's empty after the last page
+func (a *API) ListUsers(filter types.UserFilter, cursor string, limit int) ([]types.User, string, error) {
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+	if err := checkFilter(filter); err != nil {
+		return nil, "", err
+	}
+	after, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, "", ErrCursor
+	}
+
+	// one more user than asked tells whether there's a next page. while resharding, users
+	// not moved yet are listed from tables of the previous layout after the current ones
+	tables := a.users.TableNum()
+	total := tables + a.users.PreviousTableNum()
+	pages := make([][]types.User, total)
+	errs := make([]error, total)
+	var wg sync.WaitGroup
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			if index < tables {
+				pages[index], errs[index] = a.users.ListDbUsers(index, filter, string(after), limit+1)
+			} else {
+				pages[index], errs[index] = a.users.ListDbPreviousUsers(index-tables, filter, string(after), limit+1)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var users []types.User
+	for i, page := range pages {
+		if errs[i] != nil {
+			return nil, "", errs[i]
+		}
+		users = append(users, page...)
+	}
+	// a user moved meanwhile may be in both layouts, the current row comes first
+	sort.SliceStable(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+	unique := users[:0]
+	for _, user := range users {
+		if len(unique) == 0 || unique[len(unique)-1].Username != user.Username {
+			unique = append(unique, user)
+		}
+	}
+	users = unique
+	if len(users) <= limit {
+		return users, "", nil
+	}
+	users = users[:limit]
+	return users, base64.RawURLEncoding.EncodeToString([]byte(users[limit-1].Username)), nil
+}
+
+// checkFilter filter should be a username prefix, a nickname substring and an uptime range
+func checkFilter(filter types.UserFilter) error {
+	if !prefixRegexp.MatchString(filter.Prefix) {
+		return &FilterError{Field: "prefix", Reason: "not letters, digits or '_'"}
+	}
+	if reason := checkText(filter.Nickname, 128, false); reason != "" {
+		return &FilterError{Field: "nickname", Reason: reason}
+	}
+	if filter.Upfrom < 0 || filter.Upto < 0 || (filter.Upto > 0 && filter.Upto <= filter.Upfrom) {
+		return &FilterError{Field: "upto", Reason: "not after upfrom"}
+	}
+	return nil
+}
+
+// LockUser lock an active account and revoke all its sessions, it can't login until unlocked
+func (a *API) LockUser(username string) error {
+	if a.users.LockDbUser(username, true) != 1 {
+		return ErrAccountState
+	}
+	a.sessions.DelUserCacheInfo(username)
+	revoked, err := a.sessions.DelUserTokens(username, "")
+	if err != nil {
+		log.Error("failed to revoke tokens of user:", username, " with err:", err.Error())
+	}
+	log.Info("account locked:", username, ", revoked tokens:", revoked)
+	return nil
+}
+
+// UnlockUser unlock a locked account
+func (a *API) UnlockUser(username string) error {
+	if a.users.LockDbUser(username, false) != 1 {
+		return ErrAccountState
+	}
+	a.sessions.DelUserCacheInfo(username)
+	log.Info("account unlocked:", username)
+	return nil
+}
+
+// SetRoles replace roles of username, its sessions are revoked so new roles are carried from next login
+func (a *API) SetRoles(username string, roles []string) (types.User, error) {
+	var unique []string
+	seen := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		if !a.rbac.Known(role) {
+			return types.User{}, ErrUnknownRole
+		}
+		if !seen[role] {
+			seen[role] = true
+			unique = append(unique, role)
+		}
+	}
+	if a.users.UpdateDbRoles(username, unique) != 1 {
+		return types.User{}, fmt.Errorf("failed to update roles of user(%s)", username)
+	}
+	revoked, err := a.sessions.DelUserTokens(username, "")
+	if err != nil {
+		log.Error("failed to revoke tokens of user:", username, " with err:", err.Error())
+	}
+	log.Info("roles of user:", username, " set to:", unique, ", revoked tokens:", revoked)
+	return a.refreshUser(username, "")
+}
+
+// DbUserInfo userinfo of username read from db, cached userinfo may be stale
+func (a *API) DbUserInfo(username string) (types.User, error) {
+	return a.users.GetDbUserInfo(username)
+}
+
+// FlushUser drop cached userinfo and failed logins of username, userinfo is reloaded from db on next read
+func (a *API) FlushUser(username string) error {
+	if err := a.sessions.DelUserCacheInfo(username); err != nil {
+		return err
+	}
+	if err := a.sessions.DelLoginFailures(consts.LoginKindUser, username); err != nil {
+		return err
+	}
+	log.Info("cache flushed for user:", username)
+	return nil
+}
@@ -0,0 +1,158 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"user-management-system/conf"
+	"user-management-system/tcpserver/types"
+)
+
+const (
+	// SinkDB events are rows of an append-only table on the db section
+	SinkDB = "db"
+	// SinkFile events are json lines appended to a file, for a single tcpserver
+	SinkFile = "file"
+)
+
+// Sink append-only audit log
+type Sink interface {
+	// Append set ID of event and append it
+	Append(event *types.AuditEvent) error
+	// Query at most limit events passing filter with ids below before, newest first. before is 0 for the newest
+	Query(filter types.AuditFilter, before int64, limit int) ([]types.AuditEvent, error)
+	Close() error
+}
+
+// Store audit table of a user store, implemented by db.DBClient
+type Store interface {
+	AppendDbAudit(event *types.AuditEvent) error
+	QueryDbAudit(filter types.AuditFilter, before int64, limit int) ([]types.AuditEvent, error)
+}
+
+// NewSinkFromConf sink of Audit.Sink, the audit table of users by default
+func NewSinkFromConf(config *conf.TCPConf, users Store) (Sink, error) {
+	switch config.Audit.Sink {
+	case "", SinkDB:
+		return storeSink{users}, nil
+	case SinkFile:
+		if config.Audit.File == "" {
+			return nil, fmt.Errorf("audit: file sink needs audit.file")
+		}
+		return NewFileSink(config.Audit.File)
+	}
+	return nil, fmt.Errorf("audit: unsupported sink '%s'", config.Audit.Sink)
+}
+
+// storeSink events in the audit table of a user store, which is closed along with the store
+type storeSink struct {
+	store Store
+}
+
+func (s storeSink) Append(event *types.AuditEvent) error {
+	return s.store.AppendDbAudit(event)
+}
+
+func (s storeSink) Query(filter types.AuditFilter, before int64, limit int) ([]types.AuditEvent, error) {
+	return s.store.QueryDbAudit(filter, before, limit)
+}
+
+func (s storeSink) Close() error {
+	return nil
+}
+
+// FileSink events as json lines of a file, the ID of an event is its line number.
+// queries read the whole file, and only one process may append to it
+type FileSink struct {
+	mu     sync.Mutex
+	path   string
+	file   *os.File
+	lastID int64
+}
+
+// NewFileSink open path for appending, events already in it are counted for ids
+func NewFileSink(path string) (*FileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileSink{path: path, file: file}
+	err = s.scan(func(int64, []byte) {})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// scan call fn with every line and its id, lastID is set to the id of the last line
+func (s *FileSink) scan(fn func(id int64, line []byte)) error {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var id int64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		id++
+		fn(id, scanner.Bytes())
+	}
+	s.lastID = id
+	return scanner.Err()
+}
+
+// Append write event as a line of the file
+func (s *FileSink) Append(event *types.AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	event.ID = s.lastID + 1
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if _, err = s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.lastID = event.ID
+	return nil
+}
+
+// Query read the file for events passing filter, a line that isn't an event is skipped
+func (s *FileSink) Query(filter types.AuditFilter, before int64, limit int) ([]types.AuditEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events []types.AuditEvent
+	err := s.scan(func(id int64, line []byte) {
+		var event types.AuditEvent
+		if (before > 0 && id >= before) || json.Unmarshal(line, &event) != nil || !filter.Match(event) {
+			return
+		}
+		event.ID = id
+		events = append(events, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// newest first
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+// Close close the file
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
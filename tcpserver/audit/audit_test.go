@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"user-management-system/conf"
+	"user-management-system/tcpserver/types"
+)
+
+func Test_FileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "audit.log")
+	s, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal("NewFileSink failed:", err.Error())
+	}
+	for i, username := range []string{"alice", "bob", "alice", "alice"} {
+		event := types.AuditEvent{Time: int64(100 + i), Username: username, Action: "login", Outcome: types.AuditSuccess}
+		if err = s.Append(&event); err != nil || event.ID != int64(i+1) {
+			t.Fatal("append failed:", event.ID, err)
+		}
+	}
+	s.Close()
+
+	// ids go on after reopening, and a broken line keeps its id
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString("{broken\n")
+	f.Close()
+	s, err = NewFileSink(path)
+	if err != nil {
+		t.Fatal("reopen failed:", err.Error())
+	}
+	defer s.Close()
+	event := types.AuditEvent{Time: 104, Username: "alice", Action: "logout"}
+	if s.Append(&event); event.ID != 6 {
+		t.Error("ids should go on after reopening:", event.ID)
+	}
+
+	events, err := s.Query(types.AuditFilter{Username: "alice"}, 0, 10)
+	if err != nil || len(events) != 4 || events[0].ID != 6 || events[3].ID != 1 {
+		t.Error("events of alice should be queried newest first:", events, err)
+	}
+	if events, _ = s.Query(types.AuditFilter{Username: "alice"}, 4, 1); len(events) != 1 || events[0].ID != 3 {
+		t.Error("events should be paged by id:", events)
+	}
+	if events, _ = s.Query(types.AuditFilter{From: 101, To: 104, Action: "login"}, 0, 10); len(events) != 3 {
+		t.Error("events should be filtered by time and action:", events)
+	}
+}
+
+func Test_NewSinkFromConf(t *testing.T) {
+	var config conf.TCPConf
+	config.Audit.Sink = SinkFile
+	if _, err := NewSinkFromConf(&config, nil); err == nil {
+		t.Error("file sink without a file should be refused")
+	}
+	config.Audit.File = filepath.Join(t.TempDir(), "audit.log")
+	if s, err := NewSinkFromConf(&config, nil); err != nil {
+		t.Error("file sink failed:", err)
+	} else {
+		s.Close()
+	}
+	config.Audit.Sink = "kafka"
+	if _, err := NewSinkFromConf(&config, nil); err == nil {
+		t.Error("unknown sink should be refused")
+	}
+}
@@ -0,0 +1,243 @@
+package tcpserver
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"user-management-system/tcpserver/types"
+	"user-management-system/type/code"
+	pb "user-management-system/type/proto"
+
+	log "github.com/beego/beego/v2/adapter/logs"
+	"google.golang.org/grpc"
+)
+
+const (
+	// events per page of AuditLog if limit isn't given
+	defaultAuditLimit = 50
+	// max events per page of AuditLog
+	maxAuditLimit = 500
+	// max length of detail of an event
+	maxAuditDetail = 256
+)
+
+// auditActions audited rpcs by full method name and the actions they're recorded as, reads aren't audited
+var auditActions = map[string]string{
+	"/proto.UserService/Login":                "login",
+	"/proto.UserService/VerifyTwoFactor":      "login.2fa",
+	"/proto.UserService/RestoreAccount":       "account.restore",
+	"/proto.UserService/Logout":               "logout",
+	"/proto.UserService/Register":             "register",
+	"/proto.UserService/EditUserInfo":         "profile.update",
+	"/proto.UserService/UpdateProfile":        "profile.update",
+	"/proto.UserService/VerifyEmail":          "email.verify",
+	"/proto.UserService/RequestPasswordReset": "passwd.resetrequest",
+	"/proto.UserService/ResetPassword":        "passwd.reset",
+	"/proto.UserService/ChangePasswd":         "passwd.change",
+	"/proto.UserService/ChangeUsername":       "username.change",
+	"/proto.UserService/RevokeSession":        "session.revoke",
+	"/proto.UserService/SetupTwoFactor":       "2fa.setup",
+	"/proto.UserService/ConfirmTwoFactor":     "2fa.enable",
+	"/proto.UserService/DisableTwoFactor":     "2fa.disable",
+	"/proto.UserService/DeactivateAccount":    "account.deactivate",
+	"/proto.UserService/DeleteAccount":        "account.delete",
+	"/proto.UserService/LockUser":             "admin.lock",
+	"/proto.UserService/UnlockUser":           "admin.unlock",
+	"/proto.UserService/ForceLogout":          "admin.logout",
+	"/proto.UserService/AdminUpdateProfile":   "admin.profile",
+	"/proto.UserService/SetUserRoles":         "admin.roles",
+}
+
+// actionAvatar action of EditUserInfo when only headurl is edited, as httpserver does for uploads
+const actionAvatar = "avatar.upload"
+
+// Audit append event to the audit log, a failure is logged and never fails the request
+func (a *API) Audit(event types.AuditEvent) {
+	if event.Time == 0 {
+		event.Time = time.Now().Unix()
+	}
+	// columns are bounded, usernames of failed logins and metadata are whatever clients sent
+	event.Username, event.Actor = clip(event.Username, 64), clip(event.Actor, 64)
+	event.IP, event.UUID = clip(event.IP, 64), clip(event.UUID, 64)
+	event.Detail = clip(event.Detail, maxAuditDetail)
+	if err := a.auditLog.Append(&event); err != nil {
+		log.Error(event.UUID, " -- Failed to append audit event:", event.Action, " of user:", event.Username, " with err:", err.Error())
+	}
+}
+
+// AuditLog a page of at most limit events passing filter newest first, starting after cursor.
+// the cursor of the next page is the id of the last event of this one, it's empty after the last page
+func (a *API) AuditLog(filter types.AuditFilter, cursor string, limit int) ([]types.AuditEvent, string, error) {
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	if limit > maxAuditLimit {
+		limit = maxAuditLimit
+	}
+	if filter.From < 0 || filter.To < 0 || (filter.To > 0 && filter.To <= filter.From) {
+		return nil, "", &FilterError{Field: "to", Reason: "not after from"}
+	}
+	var before int64
+	if cursor != "" {
+		var err error
+		if before, err = strconv.ParseInt(cursor, 10, 64); err != nil || before <= 0 {
+			return nil, "", ErrCursor
+		}
+	}
+
+	// one more event than asked tells whether there's a next page
+	events, err := a.auditLog.Query(filter, before, limit+1)
+	if err != nil || len(events) <= limit {
+		return events, "", err
+	}
+	events = events[:limit]
+	return events, strconv.FormatInt(events[limit-1].ID, 10), nil
+}
+
+// clip s to at most n runes
+func clip(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
+
+// auditKey context key of the event AuditInterceptor is recording
+type auditKey struct{}
+
+// codeResponse responses carry a result code and msg
+type codeResponse interface {
+	GetCode() uint32
+	GetMsg() string
+}
+
+// uidResponse responses of rpcs logging in or registering a user carry its uid
+type uidResponse interface {
+	GetUid() int64
+}
+
+// AuditInterceptor record an event of each audited rpc with its outcome, whether it's let in by AuthInterceptor or not
+func (s *UserServer) AuditInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	action, ok := auditActions[info.FullMethod]
+	if !ok {
+		return handler(ctx, req)
+	}
+	event := &types.AuditEvent{Action: action, IP: getMetadata(ctx, "clientip"), UUID: getUUID(ctx)}
+	auditSubject(event, req)
+	// the actor is the user of the session of token, never the username sent along with it.
+	// uids are found before the rpc, renames and deletions change what the username resolves to
+	if caller, ok := s.auditCaller(req); ok {
+		event.Actor = caller.Username
+		if caller.Username == event.Username {
+			event.Uid = caller.Uid
+		} else if event.Username != "" {
+			event.Uid = s.API.auditUid(event.Username, true)
+		}
+	}
+
+	rsp, err := handler(context.WithValue(ctx, auditKey{}, event), req)
+	var msg string
+	if r, ok := rsp.(codeResponse); ok && err == nil {
+		event.Code, msg = r.GetCode(), r.GetMsg()
+	} else {
+		event.Code, msg = code.CodeTCPInternelErr, code.CodeMsg[code.CodeTCPInternelErr]
+	}
+	switch event.Code {
+	case code.CodeSucc:
+		event.Outcome = types.AuditSuccess
+	case code.CodeTCPTwoFactorRequired:
+		event.Outcome = types.AuditChallenged
+	default:
+		event.Outcome = types.AuditFailure
+		if event.Detail != "" {
+			msg = event.Detail + "; " + msg
+		}
+		event.Detail = msg
+	}
+	// logins and registrations prove the user by its passwd, 2fa code or mailed link
+	if r, ok := rsp.(uidResponse); ok && err == nil && r.GetUid() != 0 && event.Outcome != types.AuditFailure {
+		event.Actor, event.Uid = event.Username, r.GetUid()
+	}
+	if event.Uid == 0 && event.Username != "" {
+		// unless the actor is known, only users the rpc has cached are found, usernames of
+		// failed logins are whatever clients sent
+		event.Uid = s.API.auditUid(event.Username, event.Actor != "")
+	}
+	s.API.Audit(*event)
+	return rsp, err
+}
+
+// auditCaller user of the session of the token a request is sent with, false if it has none or it doesn't pass
+func (s *UserServer) auditCaller(req interface{}) (types.User, bool) {
+	in, ok := req.(interface {
+		GetUsername() string
+		GetToken() string
+	})
+	if !ok || in.GetToken() == "" {
+		return types.User{}, false
+	}
+	user, err := s.API.SessionUser(in.GetToken())
+	return user, err == nil && user.Username != ""
+}
+
+// auditSubject set user and detail of event from the request, admins act on targets
+func auditSubject(event *types.AuditEvent, req interface{}) {
+	if in, ok := req.(interface{ GetUsername() string }); ok {
+		event.Username = in.GetUsername()
+	}
+	switch in := req.(type) {
+	case *pb.AdminRequest:
+		event.Username = in.Target
+	case *pb.SetRolesRequest:
+		event.Username, event.Detail = in.Target, "roles: "+strings.Join(in.Roles, ",")
+	case *pb.AdminProfileRequest:
+		event.Username, event.Detail = in.GetProfile().GetUsername(), "fields: "+strings.Join(in.GetProfile().GetMask(), ",")
+	case *pb.ProfileRequest:
+		event.Detail = "fields: " + strings.Join(in.Mask, ",")
+	case *pb.EditRequest:
+		mask := in.GetMask().GetPaths()
+		if len(mask) == 0 {
+			mask = editModes[in.Mode]
+		}
+		if len(mask) == 1 && mask[0] == "headurl" {
+			event.Action = actionAvatar
+		}
+		event.Detail = "fields: " + strings.Join(mask, ",")
+	case *pb.ChangeUsernameRequest:
+		event.Detail = "newname: " + in.Newname
+	case *pb.RevokeSessionRequest:
+		event.Detail = "session: " + in.Sessionid
+		if in.All {
+			event.Detail = "all sessions"
+		}
+	}
+}
+
+// auditUid uid of username, 0 if there's no such user. the cache is only read, and
+// unless db is set, users who aren't cached aren't looked up
+func (a *API) auditUid(username string, db bool) int64 {
+	if user, err := a.sessions.GetUserCacheInfo(username); err == nil && user.Username == username {
+		return user.Uid
+	}
+	if !db {
+		return 0
+	}
+	if user, err := a.users.GetDbUserInfo(username); err == nil {
+		return user.Uid
+	}
+	return 0
+}
+
+// auditUser set the user of the event being recorded for the request of ctx, for requests
+// that don't name it, such as the ones of mailed links and 2fa challenges
+func auditUser(ctx context.Context, username string) {
+	if event, ok := ctx.Value(auditKey{}).(*types.AuditEvent); ok && event.Username == "" {
+		event.Username, event.Actor = username, username
+	}
+}
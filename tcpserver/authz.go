@@ -0,0 +1,92 @@
+package tcpserver
+
+import (
+	"context"
+
+	"user-management-system/tcpserver/rbac"
+	"user-management-system/tcpserver/types"
+	"user-management-system/type/code"
+	pb "user-management-system/type/proto"
+
+	log "github.com/beego/beego/v2/adapter/logs"
+	"google.golang.org/grpc"
+)
+
+// adminRequest requests of admin rpcs carry username and token of the admin
+type adminRequest interface {
+	GetUsername() string
+	GetToken() string
+}
+
+// adminMethod permission an admin rpc needs, and its response when the caller is turned away
+type adminMethod struct {
+	perm   string
+	denied func(c uint32, msg string) interface{}
+}
+
+func editDenied(c uint32, msg string) interface{} {
+	return &pb.EditResponse{Code: c, Msg: msg}
+}
+
+func loginDenied(c uint32, msg string) interface{} {
+	return &pb.LoginResponse{Code: c, Msg: msg}
+}
+
+func usersDenied(c uint32, msg string) interface{} {
+	return &pb.UsersResponse{Code: c, Msg: msg}
+}
+
+func auditDenied(c uint32, msg string) interface{} {
+	return &pb.AuditLogResponse{Code: c, Msg: msg}
+}
+
+// adminMethods admin rpcs by full method name, other rpcs authenticate callers by themselves
+var adminMethods = map[string]adminMethod{
+	"/proto.UserService/ListUsers":          {rbac.PermListUsers, usersDenied},
+	"/proto.UserService/LockUser":           {rbac.PermLockUsers, editDenied},
+	"/proto.UserService/UnlockUser":         {rbac.PermLockUsers, editDenied},
+	"/proto.UserService/ForceLogout":        {rbac.PermRevokeSessions, editDenied},
+	"/proto.UserService/AdminUpdateProfile": {rbac.PermEditUsers, loginDenied},
+	"/proto.UserService/SetUserRoles":       {rbac.PermManageRoles, loginDenied},
+	"/proto.UserService/QueryAuditLog":      {rbac.PermReadAudit, auditDenied},
+}
+
+// callerKey context key of the admin AuthInterceptor let in
+type callerKey struct{}
+
+// AuthInterceptor let callers of admin rpcs in only if their token is valid and its roles grant the rpc
+func (s *UserServer) AuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	method, ok := adminMethods[info.FullMethod]
+	if !ok {
+		return handler(ctx, req)
+	}
+	uuid := getUUID(ctx)
+	in, ok := req.(adminRequest)
+	if !ok {
+		log.Error(uuid, " -- Admin rpc without username and token:", info.FullMethod)
+		return method.denied(code.CodeTCPInternelErr, code.CodeMsg[code.CodeTCPInternelErr]), nil
+	}
+
+	// roles are the ones carried by the session of token, signed access tokens aren't trusted on their own
+	caller, err := s.API.SessionUser(in.GetToken())
+	if err != nil {
+		log.Error(uuid, " -- Failed to auth admin:", in.GetUsername(), " with token:", in.GetToken())
+		return method.denied(code.CodeTCPTokenExpired, code.CodeMsg[code.CodeTCPTokenExpired]), nil
+	}
+	if caller.Username != in.GetUsername() {
+		log.Error(uuid, " -- Error: token info not match:", in.GetUsername(), " while cache:", caller.Username)
+		return method.denied(code.CodeTCPUserInfoNotMatch, code.CodeMsg[code.CodeTCPUserInfoNotMatch]), nil
+	}
+	if !s.API.Allowed(caller, method.perm) {
+		log.Error(uuid, " -- Permission denied:", info.FullMethod, " for user:", caller.Username, " of roles:", caller.Roles)
+		return method.denied(code.CodeTCPPermissionDenied, code.CodeMsg[code.CodeTCPPermissionDenied]), nil
+	}
+	log.Info(uuid, " -- Admin rpc:", info.FullMethod, " by user:", caller.Username)
+	return handler(context.WithValue(ctx, callerKey{}, caller), req)
+}
+
+// adminCaller admin AuthInterceptor let in for the request of ctx
+func adminCaller(ctx context.Context) types.User {
+	caller, _ := ctx.Value(callerKey{}).(types.User)
+	return caller
+}
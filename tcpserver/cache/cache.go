@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
 	"time"
 
 	redis "github.com/go-redis/redis/v8"
@@ -15,9 +17,87 @@ import (
     log "github.com/beego/beego/v2/adapter/logs"
 )
 
+// ttl of a session: tokenexpired, capped by what's left of its max lifetime
+// KEYS: session key, ARGV: now, tokenexpired, max lifetime (0 for unlimited)
+const sessionTTLLua = `
+local function sessionTTL(sessionKey, now, expired, maxLifetime)
+	if maxLifetime <= 0 then
+		return expired
+	end
+	local created = tonumber(redis.call("HGET", sessionKey, "createtime") or now)
+	return math.min(expired, created + maxLifetime - now)
+end
+`
+
+// update last seen time of a session only if it's still alive, in sliding mode
+// token, session and sessions of user are extended as well
+// KEYS: session key, token key, sessions of user; ARGV: now, sliding, tokenexpired, max lifetime
+var touchSessionScript = redis.NewScript(sessionTTLLua + `
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+local now = tonumber(ARGV[1])
+redis.call("HSET", KEYS[1], "lastseen", now)
+if ARGV[2] == "1" then
+	local ttl = sessionTTL(KEYS[1], now, tonumber(ARGV[3]), tonumber(ARGV[4]))
+	if ttl > 0 then
+		redis.call("EXPIRE", KEYS[1], ttl)
+		redis.call("EXPIRE", KEYS[2], ttl)
+		if redis.call("TTL", KEYS[3]) < ttl then
+			redis.call("EXPIRE", KEYS[3], ttl)
+		end
+	end
+end
+return 1
+`)
+
+// move a session from old token to new token atomically, the old token is retired
+// KEYS: old token key, old session key, new token key, new session key, sessions of user
+// ARGV: old token, new token, now, tokenexpired, max lifetime
+var rotateSessionScript = redis.NewScript(sessionTTLLua + `
+local val = redis.call("GET", KEYS[1])
+if not val then
+	return 0
+end
+local now = tonumber(ARGV[3])
+local ttl = sessionTTL(KEYS[2], now, tonumber(ARGV[4]), tonumber(ARGV[5]))
+if ttl <= 0 then
+	return 0
+end
+local fields = redis.call("HGETALL", KEYS[2])
+redis.call("SET", KEYS[3], val, "EX", ttl)
+if #fields > 0 then
+	redis.call("HSET", KEYS[4], unpack(fields))
+	redis.call("HSET", KEYS[4], "lastseen", now)
+	redis.call("EXPIRE", KEYS[4], ttl)
+end
+redis.call("DEL", KEYS[1], KEYS[2])
+redis.call("SREM", KEYS[5], ARGV[1])
+redis.call("SADD", KEYS[5], ARGV[2])
+if redis.call("TTL", KEYS[5]) < ttl then
+	redis.call("EXPIRE", KEYS[5], ttl)
+end
+return 1
+`)
+
+// replace userinfo of a token only if it's still alive, keeping its ttl, so a token expiring
+// or revoked meanwhile isn't brought back
+// KEYS: token key; ARGV: userinfo
+var setTokenScript = redis.NewScript(`
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl <= 0 then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ttl)
+return 1
+`)
+
 type cacheConfig struct {
 	tokenExpired int
 	userExpired  int
+	// extend token ttl on use, up to tokenMaxLifetime from login
+	sliding          bool
+	tokenMaxLifetime int
 }
 
 type RedisClient struct {
@@ -46,8 +126,10 @@ func NewRedisClient(conf *conf.TCPConf) (*RedisClient, error) {
 	client := &RedisClient{
 		client: redisConn,
 		cacheConfig: &cacheConfig{
-			tokenExpired: conf.Redis.Cache.Tokenexpired,
-			userExpired:  conf.Redis.Cache.Userexpired,
+			tokenExpired:     conf.Redis.Cache.Tokenexpired,
+			userExpired:      conf.Redis.Cache.Userexpired,
+			sliding:          conf.Redis.Cache.Sliding,
+			tokenMaxLifetime: conf.Redis.Cache.Tokenmaxlifetime,
 		},
 	}
 	return client, nil
@@ -85,8 +167,162 @@ func (c *RedisClient) SetUserCacheInfo(user types.User) error {
 	return err
 }
 
-// get token info
+// count a failed login of kind (consts.LoginKindUser or LoginKindIP) and name within window seconds
+func (c *RedisClient) IncrLoginFailures(kind, name string, window int) (int, error) {
+	ctx := context.Background()
+	redisKey := consts.LoginFailuresPrefix + kind + name
+	pipe := c.client.TxPipeline()
+	incr := pipe.Incr(ctx, redisKey)
+	pipe.Expire(ctx, redisKey, time.Second*time.Duration(window))
+	_, err := pipe.Exec(ctx)
+	return int(incr.Val()), err
+}
+
+// lock logins of kind and name for seconds
+func (c *RedisClient) SetLoginLock(kind, name string, seconds int) error {
+	redisKey := consts.LoginLockPrefix + kind + name
+	return c.client.Set(context.Background(), redisKey, 1, time.Second*time.Duration(seconds)).Err()
+}
+
+// seconds left of the login lock of kind and name, 0 if not locked
+func (c *RedisClient) GetLoginLock(kind, name string) (int, error) {
+	redisKey := consts.LoginLockPrefix + kind + name
+	ttl, err := c.client.TTL(context.Background(), redisKey).Result()
+	if err != nil || ttl <= 0 {
+		return 0, err
+	}
+	return int(ttl / time.Second), nil
+}
+
+// clear failed logins and lock of kind and name
+func (c *RedisClient) DelLoginFailures(kind, name string) error {
+	ctx := context.Background()
+	return c.client.Del(ctx, consts.LoginFailuresPrefix+kind+name, consts.LoginLockPrefix+kind+name).Err()
+}
+
+// create a 2fa challenge of username which expires in seconds
+func (c *RedisClient) CreateTwoFactorChallenge(challenge, username string, seconds int) error {
+	ctx := context.Background()
+	redisKey := consts.TwoFactorChallengePrefix + challenge
+	pipe := c.client.TxPipeline()
+	pipe.HSet(ctx, redisKey, "username", username, "attempts", 0)
+	pipe.Expire(ctx, redisKey, time.Second*time.Duration(seconds))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// get username of a 2fa challenge, redis.Nil if it has expired
+func (c *RedisClient) GetTwoFactorChallenge(challenge string) (string, error) {
+	redisKey := consts.TwoFactorChallengePrefix + challenge
+	return c.client.HGet(context.Background(), redisKey, "username").Result()
+}
+
+// count a wrong code of a 2fa challenge, return attempts so far
+func (c *RedisClient) FailTwoFactorChallenge(challenge string) (int, error) {
+	redisKey := consts.TwoFactorChallengePrefix + challenge
+	attempts, err := c.client.HIncrBy(context.Background(), redisKey, "attempts", 1).Result()
+	return int(attempts), err
+}
+
+// delete a 2fa challenge, false if it's been deleted by others
+func (c *RedisClient) DelTwoFactorChallenge(challenge string) (bool, error) {
+	redisKey := consts.TwoFactorChallengePrefix + challenge
+	deleted, err := c.client.Del(context.Background(), redisKey).Result()
+	return deleted == 1, err
+}
+
+// create an email verification token of username and email which expires in seconds
+func (c *RedisClient) CreateEmailToken(token, username, email string, seconds int) error {
+	ctx := context.Background()
+	redisKey := consts.EmailTokenPrefix + token
+	pipe := c.client.TxPipeline()
+	pipe.HSet(ctx, redisKey, "username", username, "email", email)
+	pipe.Expire(ctx, redisKey, time.Second*time.Duration(seconds))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// get username and email of a verification token and delete it, redis.Nil if it has expired or been used
+func (c *RedisClient) TakeEmailToken(token string) (string, string, error) {
+	ctx := context.Background()
+	redisKey := consts.EmailTokenPrefix + token
+	pipe := c.client.TxPipeline()
+	get := pipe.HGetAll(ctx, redisKey)
+	pipe.Del(ctx, redisKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", "", err
+	}
+	// both run in one MULTI, so of concurrent takes only one finds the fields
+	fields := get.Val()
+	if fields["username"] == "" {
+		return "", "", redis.Nil
+	}
+	return fields["username"], fields["email"], nil
+}
+
+// create a passwd reset token of username and its skey which expires in seconds
+func (c *RedisClient) CreateResetToken(token, username, skey string, seconds int) error {
+	ctx := context.Background()
+	redisKey := consts.PasswdResetPrefix + token
+	pipe := c.client.TxPipeline()
+	pipe.HSet(ctx, redisKey, "username", username, "skey", skey)
+	pipe.Expire(ctx, redisKey, time.Second*time.Duration(seconds))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// get username and skey of a passwd reset token and delete it, redis.Nil if it has expired or been used
+func (c *RedisClient) TakeResetToken(token string) (string, string, error) {
+	ctx := context.Background()
+	redisKey := consts.PasswdResetPrefix + token
+	pipe := c.client.TxPipeline()
+	get := pipe.HGetAll(ctx, redisKey)
+	pipe.Del(ctx, redisKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", "", err
+	}
+	fields := get.Val()
+	if fields["username"] == "" {
+		return "", "", redis.Nil
+	}
+	return fields["username"], fields["skey"], nil
+}
+
+// mark totp counter of username used for seconds, false if it's been used
+func (c *RedisClient) UseTOTPCounter(username string, counter uint64, seconds int) (bool, error) {
+	redisKey := fmt.Sprintf("%s%s_%d", consts.TOTPUsedPrefix, username, counter)
+	return c.client.SetNX(context.Background(), redisKey, 1, time.Second*time.Duration(seconds)).Result()
+}
+
+// delete cached userinfo
+func (c *RedisClient) DelUserCacheInfo(username string) error {
+	redisKey := consts.UserInfoPrefix + username
+	_, err := c.client.Del(context.Background(), redisKey).Result()
+	return err
+}
+
+// get token info, last seen time of its session is updated as well and,
+// in sliding mode, its ttl is extended
 func (c *RedisClient) GetTokenInfo(token string) (types.User, error) {
+	user, err := c.getTokenUser(token)
+	if err != nil {
+		return user, err
+	}
+	var sliding int
+	if c.cacheConfig.sliding {
+		sliding = 1
+	}
+	keys := []string{consts.SessionInfoPrefix + token, consts.TokenKeyPrefix + token, consts.UserSessionsPrefix + user.Username}
+	err = touchSessionScript.Run(context.Background(), c.client, keys,
+		time.Now().Unix(), sliding, c.cacheConfig.tokenExpired, c.cacheConfig.tokenMaxLifetime).Err()
+	if err != nil && err != redis.Nil {
+		log.Error("failed to touch session, err:", err.Error())
+	}
+	return user, nil
+}
+
+// get token info without touching its session
+func (c *RedisClient) getTokenUser(token string) (types.User, error) {
 	redisKey := consts.TokenKeyPrefix + token
 	val, err := c.client.Get(context.Background(), redisKey).Result()
 	var user types.User
@@ -97,7 +333,7 @@ func (c *RedisClient) GetTokenInfo(token string) (types.User, error) {
 	return user, err
 }
 
-// set cached userinfo
+// set cached userinfo of token, the session keeps its ttl
 func (c *RedisClient) SetTokenInfo(user types.User, token string) error {
 	redisKey := consts.TokenKeyPrefix + token
 	val, err := json.Marshal(user)
@@ -105,11 +341,104 @@ func (c *RedisClient) SetTokenInfo(user types.User, token string) error {
 		return err
 	}
     log.Debug("token redisKey: ", redisKey)
+	set, err := setTokenScript.Run(context.Background(), c.client, []string{redisKey}, val).Int()
+	if err != nil {
+		return err
+	}
+	if set == 0 {
+		return redis.Nil
+	}
+	return nil
+}
+
+// retire oldToken and move its session to newToken, false if oldToken has expired
+func (c *RedisClient) RotateSession(username, oldToken, newToken string) (bool, error) {
+	keys := []string{
+		consts.TokenKeyPrefix + oldToken, consts.SessionInfoPrefix + oldToken,
+		consts.TokenKeyPrefix + newToken, consts.SessionInfoPrefix + newToken,
+		consts.UserSessionsPrefix + username,
+	}
+	rotated, err := rotateSessionScript.Run(context.Background(), c.client, keys,
+		oldToken, newToken, time.Now().Unix(), c.cacheConfig.tokenExpired, c.cacheConfig.tokenMaxLifetime).Int()
+	return rotated == 1, err
+}
+
+// TokenLifetime how long a client may keep a new token: tokenexpired, or the max lifetime in sliding mode
+func (c *RedisClient) TokenLifetime() int {
+	if c.cacheConfig.sliding && c.cacheConfig.tokenMaxLifetime > 0 {
+		return c.cacheConfig.tokenMaxLifetime
+	}
+	if c.cacheConfig.tokenMaxLifetime > 0 && c.cacheConfig.tokenMaxLifetime < c.cacheConfig.tokenExpired {
+		return c.cacheConfig.tokenMaxLifetime
+	}
+	return c.cacheConfig.tokenExpired
+}
+
+// create a session: token info, session metadata and the index in sessions of user
+func (c *RedisClient) CreateSession(user types.User, session types.Session) error {
+	val, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	tokenKey := consts.TokenKeyPrefix + session.Token
+	sessionKey := consts.SessionInfoPrefix + session.Token
+	userKey := consts.UserSessionsPrefix + user.Username
 	expired := time.Second * time.Duration(c.cacheConfig.tokenExpired)
-	_, err = c.client.Set(context.Background(), redisKey, val, expired).Result()
+	if c.cacheConfig.tokenMaxLifetime > 0 && c.cacheConfig.tokenMaxLifetime < c.cacheConfig.tokenExpired {
+		expired = time.Second * time.Duration(c.cacheConfig.tokenMaxLifetime)
+	}
+
+	pipe := c.client.TxPipeline()
+	pipe.Set(ctx, tokenKey, val, expired)
+	pipe.HSet(ctx, sessionKey, map[string]interface{}{
+		"username":   user.Username,
+		"createtime": session.Createtime,
+		"lastseen":   session.Lastseen,
+		"ip":         session.IP,
+		"useragent":  session.Useragent,
+	})
+	pipe.Expire(ctx, sessionKey, expired)
+	pipe.SAdd(ctx, userKey, session.Token)
+	pipe.Expire(ctx, userKey, expired)
+	_, err = pipe.Exec(ctx)
 	return err
 }
 
+// get active sessions of username, stale tokens are removed from the index
+func (c *RedisClient) GetUserSessions(username string) ([]types.Session, error) {
+	ctx := context.Background()
+	userKey := consts.UserSessionsPrefix + username
+	tokens, err := c.client.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []types.Session
+	for _, token := range tokens {
+		fields, err := c.client.HGetAll(ctx, consts.SessionInfoPrefix+token).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) == 0 {
+			c.client.SRem(ctx, userKey, token)
+			continue
+		}
+		createtime, _ := strconv.ParseInt(fields["createtime"], 10, 64)
+		lastseen, _ := strconv.ParseInt(fields["lastseen"], 10, 64)
+		sessions = append(sessions, types.Session{
+			Token:      token,
+			Username:   username,
+			Createtime: createtime,
+			Lastseen:   lastseen,
+			IP:         fields["ip"],
+			Useragent:  fields["useragent"],
+		})
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Createtime > sessions[j].Createtime })
+	return sessions, nil
+}
+
 // update cached userinfo, if failed, try to delete it from cache
 func (c *RedisClient) UpdateCachedUserinfo(user types.User) error {
 	err := c.SetUserCacheInfo(user)
@@ -120,9 +449,90 @@ func (c *RedisClient) UpdateCachedUserinfo(user types.User) error {
 	return err
 }
 
-// delete token cache info
+// delete token cache info and its session
 func (c *RedisClient) DelTokenInfo(token string) error {
-	redisKey := consts.TokenKeyPrefix + token
-	_, err := c.client.Del(context.Background(), redisKey).Result()
+	ctx := context.Background()
+	user, err := c.getTokenUser(token)
+	pipe := c.client.TxPipeline()
+	pipe.Del(ctx, consts.TokenKeyPrefix+token, consts.SessionInfoPrefix+token)
+	if err == nil {
+		pipe.SRem(ctx, consts.UserSessionsPrefix+user.Username, token)
+	}
+	_, err = pipe.Exec(ctx)
 	return err
 }
+
+// delete all sessions of username except the given token, return the number of deleted tokens
+func (c *RedisClient) DelUserTokens(username, except string) (int, error) {
+	ctx := context.Background()
+	userKey := consts.UserSessionsPrefix + username
+	tokens, err := c.client.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int
+	for _, token := range tokens {
+		if token == except {
+			continue
+		}
+		pipe := c.client.TxPipeline()
+		del := pipe.Del(ctx, consts.TokenKeyPrefix+token, consts.SessionInfoPrefix+token)
+		pipe.SRem(ctx, userKey, token)
+		if _, err = pipe.Exec(ctx); err != nil {
+			return deleted, err
+		}
+		if del.Val() > 0 {
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// move sessions of oldname to user: token info is replaced keeping its ttl, and tokens move
+// to sessions of the new username. return the number of moved sessions
+func (c *RedisClient) RenameUserSessions(oldname string, user types.User) (int, error) {
+	ctx := context.Background()
+	val, err := json.Marshal(user)
+	if err != nil {
+		return 0, err
+	}
+	oldKey := consts.UserSessionsPrefix + oldname
+	newKey := consts.UserSessionsPrefix + user.Username
+	tokens, err := c.client.SMembers(ctx, oldKey).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	var moved int
+	var maxTTL time.Duration
+	for _, token := range tokens {
+		tokenKey := consts.TokenKeyPrefix + token
+		ttl, err := c.client.TTL(ctx, tokenKey).Result()
+		if err != nil {
+			return moved, err
+		}
+		if ttl <= 0 {
+			c.client.SRem(ctx, oldKey, token)
+			continue
+		}
+		pipe := c.client.TxPipeline()
+		pipe.Set(ctx, tokenKey, val, ttl)
+		pipe.HSet(ctx, consts.SessionInfoPrefix+token, "username", user.Username)
+		pipe.SAdd(ctx, newKey, token)
+		pipe.SRem(ctx, oldKey, token)
+		if _, err = pipe.Exec(ctx); err != nil {
+			return moved, err
+		}
+		if ttl > maxTTL {
+			maxTTL = ttl
+		}
+		moved++
+	}
+	if moved > 0 {
+		if ttl, err := c.client.TTL(ctx, newKey).Result(); err == nil && ttl < maxTTL {
+			c.client.Expire(ctx, newKey, maxTTL)
+		}
+	}
+	return moved, nil
+}
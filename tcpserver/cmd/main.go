@@ -3,10 +3,8 @@ package main
 import (
 	"flag"
 	"fmt"
-	"math/rand"
 	"net"
 	"os"
-	"time"
 
 	"user-management-system/conf"
 	"user-management-system/utils"
@@ -20,7 +18,8 @@ import (
 // run starts UserServer services
 func run(config *conf.TCPConf, api *tcpserver.API) {
 	userServer := &tcpserver.UserServer{API: api}
-	grpcServer := grpc.NewServer()
+	// audit events are recorded for admin rpcs AuthInterceptor turns away as well
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(userServer.AuditInterceptor, userServer.AuthInterceptor))
 	pb.RegisterUserServiceServer(grpcServer, userServer)
 
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", config.Server.Port))
@@ -60,12 +59,23 @@ func main() {
 	log.Async()
     log.Info("init log finished!")
 
-	aAPI := tcpserver.NewAPI(&config)
+	// token format shared with httpserver
+	err = utils.SetTokenFormat(config.Token.Length, config.Token.Encoding)
+	if err != nil {
+		log.Critical("invalid token config: error: %v\n", err)
+		os.Exit(-1)
+	}
+
+	aAPI, err := tcpserver.NewAPI(&config)
+	if err != nil {
+		log.Critical("new API failed:", err.Error())
+		fmt.Printf("new API failed, error: %v\n", err)
+		os.Exit(-1)
+	}
 	defer aAPI.Finalize()
+	aAPI.StartAccountSweeper()
     log.Debug("new API successfully, new api: %v\n", aAPI)
 
-	// generate random seed global
-	rand.Seed(time.Now().UTC().UnixNano())
 	// start event loop
 	run(&config, aAPI)
 }
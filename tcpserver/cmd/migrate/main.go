@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"user-management-system/conf"
+	"user-management-system/tcpserver/db"
+	"user-management-system/utils"
+)
+
+func usage() {
+	fmt.Fprintln(flag.CommandLine.Output(), "usage: migrate [-c config] [-to version] up|down|status")
+	flag.PrintDefaults()
+}
+
+func main() {
+	var confFile string
+	var to int
+	flag.StringVar(&confFile, "c", "./conf/tcpserver.yaml", "config file, tables of shard.previous are migrated as well if enabled")
+	flag.IntVar(&to, "to", -1, "version to migrate to: the latest for up, one below the current for down")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 1 {
+		usage()
+		os.Exit(-1)
+	}
+
+	var config conf.TCPConf
+	if err := utils.ConfParser(confFile, &config); err != nil {
+		fmt.Println("parser config failed:", err.Error())
+		os.Exit(-1)
+	}
+	dbClient, err := db.NewDBClient(&config)
+	if err != nil {
+		fmt.Println("connect to db failed:", err.Error())
+		os.Exit(-1)
+	}
+	defer dbClient.CloseDB()
+
+	report := func(instance string, m db.Migration) {
+		fmt.Printf("%s: %s %d %s\n", instance, flag.Arg(0), m.Version, m.Name)
+	}
+	switch flag.Arg(0) {
+	case "up":
+		if to < 0 {
+			to = db.LatestVersion()
+		}
+		err = dbClient.MigrateUp(to, report)
+	case "down":
+		if to < 0 {
+			to, err = currentVersion(dbClient)
+			to--
+		}
+		if err == nil {
+			err = dbClient.MigrateDown(to, report)
+		}
+	case "status":
+		err = printStatus(dbClient)
+	default:
+		usage()
+		os.Exit(-1)
+	}
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+}
+
+// currentVersion highest version of all db instances
+func currentVersion(dbClient *db.DBClient) (int, error) {
+	statuses, err := dbClient.SchemaStatus()
+	var version int
+	for _, status := range statuses {
+		if status.Version > version {
+			version = status.Version
+		}
+	}
+	return version, err
+}
+
+// printStatus print version, pending migrations and missing tables of every db instance
+func printStatus(dbClient *db.DBClient) error {
+	statuses, err := dbClient.SchemaStatus()
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		fmt.Printf("%s: version %d of %d\n", status.Instance, status.Version, db.LatestVersion())
+		for _, m := range status.Pending {
+			fmt.Printf("  pending %d %s\n", m.Version, m.Name)
+		}
+		for _, tableName := range status.Missing {
+			fmt.Printf("  missing table %s\n", tableName)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"user-management-system/conf"
+	"user-management-system/tcpserver/db"
+	"user-management-system/utils"
+)
+
+// loadCheckpoint read checkpoint of mode from file, a missing file or another mode starts over
+func loadCheckpoint(file, mode string) (*db.Checkpoint, error) {
+	cp := &db.Checkpoint{Mode: mode}
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var saved db.Checkpoint
+	if err = json.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+	if saved.Mode != mode {
+		fmt.Println("checkpoint of mode", saved.Mode, "ignored, start", mode, "over")
+		return cp, nil
+	}
+	return &saved, nil
+}
+
+// saveCheckpoint write checkpoint to file, it's replaced at once so a crash never leaves half of it
+func saveCheckpoint(file string, cp *db.Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(file+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
+
+func main() {
+	var confFile, mode, checkpoint string
+	var batch int
+	var pause time.Duration
+	flag.StringVar(&confFile, "c", "./conf/tcpserver.yaml", "config file, with shard.previous enabled")
+	flag.StringVar(&mode, "mode", db.ReshardCopy, "copy users to the current layout, verify copies, or cleanup the previous layout")
+	flag.StringVar(&checkpoint, "checkpoint", "reshard.json", "progress file, an interrupted run goes on from it")
+	flag.IntVar(&batch, "batch", 1000, "rows of a table read at a time")
+	flag.DurationVar(&pause, "pause", 0, "sleep between batches to go easy on a live db")
+	flag.Parse()
+
+	var config conf.TCPConf
+	if err := utils.ConfParser(confFile, &config); err != nil {
+		fmt.Println("parser config failed:", err.Error())
+		os.Exit(-1)
+	}
+	if !config.Shard.Previous.Enabled {
+		fmt.Println(db.ErrNotResharding.Error())
+		os.Exit(-1)
+	}
+	dbClient, err := db.NewDBClient(&config)
+	if err != nil {
+		fmt.Println("connect to db failed:", err.Error())
+		os.Exit(-1)
+	}
+	defer dbClient.CloseDB()
+	if err = dbClient.CheckSchema(); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(-1)
+	}
+
+	var step func(cp *db.Checkpoint) error
+	switch mode {
+	case db.ReshardCopy:
+		step = func(cp *db.Checkpoint) error { return dbClient.CopyBatch(cp, batch) }
+	case db.ReshardVerify:
+		step = func(cp *db.Checkpoint) error {
+			return dbClient.VerifyBatch(cp, batch, func(username, problem string) {
+				fmt.Println("user", username, problem)
+			})
+		}
+	case db.ReshardCleanup:
+		step = func(cp *db.Checkpoint) error { return dbClient.CleanupBatch(cp, batch) }
+	default:
+		fmt.Println("unknown mode:", mode)
+		os.Exit(-1)
+	}
+
+	cp, err := loadCheckpoint(checkpoint, mode)
+	if err != nil {
+		fmt.Println("load checkpoint failed:", err.Error())
+		os.Exit(-1)
+	}
+	if dbClient.ReshardDone(cp) {
+		// a finished run is done again from the start
+		cp = &db.Checkpoint{Mode: mode}
+	}
+	for !dbClient.ReshardDone(cp) {
+		table := cp.Table
+		if err = step(cp); err != nil {
+			fmt.Println(mode, "failed at table", cp.Table, "after id", cp.Lastid, ":", err.Error())
+			os.Exit(-1)
+		}
+		if err = saveCheckpoint(checkpoint, cp); err != nil {
+			fmt.Println("save checkpoint failed:", err.Error())
+			os.Exit(-1)
+		}
+		if cp.Table != table {
+			fmt.Printf("table %d done, rows: %d, changed: %d, problems: %d\n", table, cp.Rows, cp.Changed, cp.Problems)
+		}
+		time.Sleep(pause)
+	}
+	fmt.Printf("%s done, rows: %d, changed: %d, problems: %d\n", mode, cp.Rows, cp.Changed, cp.Problems)
+	if cp.Problems > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"user-management-system/tcpserver"
+	"user-management-system/tcpserver/types"
+	"user-management-system/utils"
+)
+
+// command a subcommand of umsctl, run with the args after its name
+type command struct {
+	usage string
+	run   func(api *tcpserver.API, args []string) (view, error)
+}
+
+// commandNames commands in the order of usage
+var commandNames = []string{"create", "show", "list", "lock", "unlock", "delete", "passwd", "sessions", "kill", "flush"}
+
+var commands = map[string]command{
+	"create":   {"[-passwd p] [-nickname n] [-email e] [-roles r1,r2] username: create a user, passwd is generated if not given", createUser},
+	"show":     {"username: show a user as it's in db", showUser},
+	"list":     {"[-prefix p] [-nickname n] [-limit n] [-cursor c]: list users of all tables in username order", listUsers},
+	"lock":     {"username: lock an active user and revoke its sessions", lockUser},
+	"unlock":   {"username: unlock a locked user", unlockUser},
+	"delete":   {"-yes username: delete a user with its sessions and avatar at once", deleteUser},
+	"passwd":   {"[-passwd p] username: reset passwd and revoke all sessions, passwd is generated if not given", resetPasswd},
+	"sessions": {"username: list sessions of a user", listSessions},
+	"kill":     {"username sessionid|all: revoke a session, or all sessions of a user", killSessions},
+	"flush":    {"username: drop cached userinfo and failed logins of a user", flushUser},
+}
+
+// statusNames names of types.User.Status
+var statusNames = map[int8]string{
+	types.StatusActive:      "active",
+	types.StatusDeactivated: "deactivated",
+	types.StatusLocked:      "locked",
+}
+
+// userInfo a user as printed, passwd hashes and skeys are never shown
+type userInfo struct {
+	Uid           int64    `json:"uid"`
+	Username      string   `json:"username"`
+	Nickname      string   `json:"nickname"`
+	Email         string   `json:"email"`
+	Emailverified bool     `json:"emailverified"`
+	Phone         string   `json:"phone"`
+	Roles         []string `json:"roles"`
+	Status        string   `json:"status"`
+	Uptime        int64    `json:"uptime"`
+	// Loginlock seconds left of the lock of failed logins
+	Loginlock int `json:"loginlock"`
+	// Passwd generated passwd, only shown once by create
+	Passwd string `json:"passwd,omitempty"`
+}
+
+func newUserInfo(user types.User) userInfo {
+	return userInfo{Uid: user.Uid, Username: user.Username, Nickname: user.Nickname, Email: user.Email,
+		Emailverified: user.Emailverified, Phone: user.Phone, Roles: user.RoleList(), Status: statusNames[user.Status],
+		Uptime: user.Uptime}
+}
+
+func (u userInfo) view() view {
+	v := fieldsView(u,
+		[]string{"uid", strconv.FormatInt(u.Uid, 10)},
+		[]string{"username", u.Username},
+		[]string{"nickname", u.Nickname},
+		[]string{"email", u.Email},
+		[]string{"emailverified", strconv.FormatBool(u.Emailverified)},
+		[]string{"phone", u.Phone},
+		[]string{"roles", strings.Join(u.Roles, ",")},
+		[]string{"status", u.Status},
+		[]string{"uptime", formatTime(u.Uptime)},
+		[]string{"loginlock", strconv.Itoa(u.Loginlock) + "s"},
+	)
+	if u.Passwd != "" {
+		v.rows = append(v.rows, []string{"passwd", u.Passwd})
+	}
+	return v
+}
+
+// result outcome of a command changing a user
+type result struct {
+	Username string `json:"username"`
+	Action   string `json:"action"`
+	// Passwd generated passwd, only shown once by passwd
+	Passwd string `json:"passwd,omitempty"`
+	// Revoked sessions revoked by kill
+	Revoked int `json:"revoked,omitempty"`
+}
+
+func (r result) view() view {
+	v := fieldsView(r, []string{"username", r.Username}, []string{"action", r.Action})
+	if r.Passwd != "" {
+		v.rows = append(v.rows, []string{"passwd", r.Passwd})
+	}
+	if r.Revoked > 0 {
+		v.rows = append(v.rows, []string{"revoked", strconv.Itoa(r.Revoked)})
+	}
+	return v
+}
+
+// sessionInfo a session as printed, tokens are never shown
+type sessionInfo struct {
+	ID         string `json:"id"`
+	Createtime int64  `json:"createtime"`
+	Lastseen   int64  `json:"lastseen"`
+	IP         string `json:"ip"`
+	Useragent  string `json:"useragent"`
+}
+
+// parseArgs parse flags of fs in args, n positional args should follow them
+func parseArgs(fs *flag.FlagSet, args []string, n int) ([]string, error) {
+	fs.SetOutput(ioutil.Discard)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != n {
+		return nil, fmt.Errorf("%d args expected, got %d", n, fs.NArg())
+	}
+	return fs.Args(), nil
+}
+
+// username the only positional arg of args
+func username(name string, args []string) (string, error) {
+	args, err := parseArgs(flag.NewFlagSet(name, flag.ContinueOnError), args, 1)
+	if err != nil {
+		return "", err
+	}
+	return args[0], nil
+}
+
+// generatePasswd a random passwd of 16 chars
+func generatePasswd() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// checkPasswd passwd given, or a generated one if empty which should be shown
+func checkPasswd(passwd string) (string, bool, error) {
+	if passwd == "" {
+		passwd, err := generatePasswd()
+		return passwd, true, err
+	}
+	if !utils.CheckPasswd(passwd) {
+		return "", false, errors.New("passwd should be 6~64 chars")
+	}
+	return passwd, false, nil
+}
+
+func createUser(api *tcpserver.API, args []string) (view, error) {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	passwd := fs.String("passwd", "", "passwd, generated if empty")
+	nickname := fs.String("nickname", "", "nickname")
+	email := fs.String("email", "", "email, a verification link is mailed to it")
+	roles := fs.String("roles", "", "comma separated roles")
+	args, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return view{}, err
+	}
+	name := args[0]
+	if !utils.CheckUsername(name) {
+		return view{}, errors.New("invalid username: " + name)
+	}
+	pass, generated, err := checkPasswd(*passwd)
+	if err != nil {
+		return view{}, err
+	}
+	if *email != "" {
+		if err = tcpserver.CheckProfile(types.User{Email: *email}, []string{"email"}); err != nil {
+			return view{}, err
+		}
+	}
+
+	user, err := api.Register(name, pass, *nickname, *email)
+	if err != nil {
+		return view{}, err
+	}
+	if *roles != "" {
+		if user, err = api.SetRoles(name, strings.Split(*roles, ",")); err != nil {
+			return view{}, fmt.Errorf("user %s created without roles: %s", name, err.Error())
+		}
+	}
+	info := newUserInfo(user)
+	if generated {
+		info.Passwd = pass
+	}
+	return info.view(), nil
+}
+
+func showUser(api *tcpserver.API, args []string) (view, error) {
+	name, err := username("show", args)
+	if err != nil {
+		return view{}, err
+	}
+	user, err := api.DbUserInfo(name)
+	if err != nil {
+		return view{}, err
+	}
+	info := newUserInfo(user)
+	info.Loginlock, _ = api.LoginLocked(name, "")
+	return info.view(), nil
+}
+
+func listUsers(api *tcpserver.API, args []string) (view, error) {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	var filter types.UserFilter
+	fs.StringVar(&filter.Prefix, "prefix", "", "username prefix")
+	fs.StringVar(&filter.Nickname, "nickname", "", "nickname substring")
+	limit := fs.Int("limit", 20, "users of the page, at most 100")
+	cursor := fs.String("cursor", "", "nextcursor of the previous page")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return view{}, err
+	}
+	users, next, err := api.ListUsers(filter, *cursor, *limit)
+	if err != nil {
+		return view{}, err
+	}
+
+	infos := make([]userInfo, 0, len(users))
+	v := view{header: []string{"uid", "username", "nickname", "email", "roles", "status", "uptime"}}
+	for _, user := range users {
+		info := newUserInfo(user)
+		infos = append(infos, info)
+		v.rows = append(v.rows, []string{strconv.FormatInt(info.Uid, 10), info.Username, info.Nickname, info.Email,
+			strings.Join(info.Roles, ","), info.Status, formatTime(info.Uptime)})
+	}
+	if next != "" {
+		v.footer = "nextcursor: " + next
+	}
+	v.value = struct {
+		Users      []userInfo `json:"users"`
+		Nextcursor string     `json:"nextcursor"`
+	}{infos, next}
+	return v, nil
+}
+
+func lockUser(api *tcpserver.API, args []string) (view, error) {
+	name, err := username("lock", args)
+	if err != nil {
+		return view{}, err
+	}
+	if err = api.LockUser(name); err == tcpserver.ErrAccountState {
+		return view{}, fmt.Errorf("user %s doesn't exist or isn't active", name)
+	}
+	return result{Username: name, Action: "locked"}.view(), err
+}
+
+func unlockUser(api *tcpserver.API, args []string) (view, error) {
+	name, err := username("unlock", args)
+	if err != nil {
+		return view{}, err
+	}
+	if err = api.UnlockUser(name); err == tcpserver.ErrAccountState {
+		return view{}, fmt.Errorf("user %s doesn't exist or isn't locked", name)
+	}
+	return result{Username: name, Action: "unlocked"}.view(), err
+}
+
+func deleteUser(api *tcpserver.API, args []string) (view, error) {
+	fs := flag.NewFlagSet("delete", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "confirm that the user is deleted for good")
+	args, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return view{}, err
+	}
+	if !*yes {
+		return view{}, errors.New("a deleted user can't be restored, confirm with -yes")
+	}
+	user, err := api.DbUserInfo(args[0])
+	if err != nil {
+		return view{}, err
+	}
+	if err = api.DeleteAccount(user); err != nil {
+		return view{}, err
+	}
+	return result{Username: user.Username, Action: "deleted"}.view(), nil
+}
+
+func resetPasswd(api *tcpserver.API, args []string) (view, error) {
+	fs := flag.NewFlagSet("passwd", flag.ContinueOnError)
+	passwd := fs.String("passwd", "", "new passwd, generated if empty")
+	args, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return view{}, err
+	}
+	pass, generated, err := checkPasswd(*passwd)
+	if err != nil {
+		return view{}, err
+	}
+	if err = api.ChangePasswd(args[0], pass, ""); err != nil {
+		return view{}, err
+	}
+	r := result{Username: args[0], Action: "passwd reset"}
+	if generated {
+		r.Passwd = pass
+	}
+	return r.view(), nil
+}
+
+func listSessions(api *tcpserver.API, args []string) (view, error) {
+	name, err := username("sessions", args)
+	if err != nil {
+		return view{}, err
+	}
+	sessions, err := api.ListSessions(name)
+	if err != nil {
+		return view{}, err
+	}
+
+	infos := make([]sessionInfo, 0, len(sessions))
+	v := view{header: []string{"id", "createtime", "lastseen", "ip", "useragent"}}
+	for _, session := range sessions {
+		info := sessionInfo{ID: tcpserver.SessionID(session.Token), Createtime: session.Createtime, Lastseen: session.Lastseen,
+			IP: session.IP, Useragent: session.Useragent}
+		infos = append(infos, info)
+		v.rows = append(v.rows, []string{info.ID, formatTime(info.Createtime), formatTime(info.Lastseen), info.IP, info.Useragent})
+	}
+	v.value = infos
+	return v, nil
+}
+
+func killSessions(api *tcpserver.API, args []string) (view, error) {
+	args, err := parseArgs(flag.NewFlagSet("kill", flag.ContinueOnError), args, 2)
+	if err != nil {
+		return view{}, err
+	}
+	r := result{Username: args[0], Action: "sessions revoked"}
+	if args[1] == "all" {
+		r.Revoked, err = api.RevokeAllSessions(args[0])
+		return r.view(), err
+	}
+	found, err := api.RevokeSession(args[0], args[1])
+	if err != nil {
+		return view{}, err
+	}
+	if !found {
+		return view{}, fmt.Errorf("session %s of user %s not found", args[1], args[0])
+	}
+	r.Revoked = 1
+	return r.view(), nil
+}
+
+func flushUser(api *tcpserver.API, args []string) (view, error) {
+	name, err := username("flush", args)
+	if err != nil {
+		return view{}, err
+	}
+	if err = api.FlushUser(name); err != nil {
+		return view{}, err
+	}
+	return result{Username: name, Action: "cache flushed"}.view(), nil
+}
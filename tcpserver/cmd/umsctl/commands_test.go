@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"user-management-system/conf"
+	"user-management-system/tcpserver"
+	"user-management-system/tcpserver/mailer"
+	"user-management-system/tcpserver/store"
+	"user-management-system/tcpserver/types"
+
+	log "github.com/beego/beego/v2/adapter/logs"
+)
+
+func newTestAPI(t *testing.T) *tcpserver.API {
+	log.SetLevel(log.LevelEmergency)
+	var config conf.TCPConf
+	config.Store.Users, config.Store.Sessions = store.Memory, store.Memory
+	config.Redis.Cache.Tokenexpired = 7200
+	config.Redis.Cache.Userexpired = 300
+	config.Passwd.Algorithm = "bcrypt"
+	config.Passwd.Bcrypt.Cost = 4
+	config.Security.Login.Window = 900
+	config.Security.Login.Maxuserfailures = 1
+	config.Security.Login.Baselockout = 60
+	config.Security.Login.Maxlockout = 3600
+	config.Mail.Sender, config.Mail.File = mailer.SenderFile, filepath.Join(t.TempDir(), "mails.txt")
+	api, err := tcpserver.NewAPI(&config)
+	if err != nil {
+		t.Fatal("NewAPI failed:", err.Error())
+	}
+	t.Cleanup(api.Finalize)
+	return api
+}
+
+// runJSON run command with args and decode its json output into v
+func runJSON(t *testing.T, api *tcpserver.API, v interface{}, name string, args ...string) error {
+	out, err := commands[name].run(api, args)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err = out.print(&buf, formatJSON); err != nil {
+		t.Fatal("print failed:", err.Error())
+	}
+	if err = json.Unmarshal(buf.Bytes(), v); err != nil {
+		t.Fatal("invalid json:", buf.String())
+	}
+	return nil
+}
+
+func Test_Commands(t *testing.T) {
+	api := newTestAPI(t)
+	var info userInfo
+	if err := runJSON(t, api, &info, "create", "-nickname", "alice", "-roles", "admin", "alice"); err != nil {
+		t.Fatal("create failed:", err.Error())
+	}
+	if info.Username != "alice" || len(info.Roles) != 1 || info.Roles[0] != "admin" || len(info.Passwd) != 16 {
+		t.Error("user should be created with roles and a generated passwd:", info)
+	}
+	user, _ := api.DbUserInfo("alice")
+	if !api.VerifyPasswd(user, info.Passwd) {
+		t.Error("generated passwd should be set")
+	}
+	if err := runJSON(t, api, &info, "create", "-passwd", "123", "bob"); err == nil {
+		t.Error("short passwd should be rejected")
+	}
+	if err := runJSON(t, api, &info, "create", "alice"); err == nil {
+		t.Error("taken username should be rejected")
+	}
+
+	// sessions and login failures are cleared by the commands
+	api.LoginFailed("alice", "")
+	info = userInfo{}
+	if runJSON(t, api, &info, "show", "alice"); info.Loginlock <= 0 || info.Passwd != "" {
+		t.Error("login lock should be shown:", info)
+	}
+	var r result
+	if err := runJSON(t, api, &r, "flush", "alice"); err != nil || r.Action != "cache flushed" {
+		t.Error("flush failed:", err)
+	}
+	if lock, _ := api.LoginLocked("alice", ""); lock != 0 {
+		t.Error("login lock should be flushed:", lock)
+	}
+	var sessions []sessionInfo
+	if err := runJSON(t, api, &sessions, "sessions", "alice"); err != nil || len(sessions) != 0 {
+		t.Error("no sessions expected:", sessions, err)
+	}
+	if err := runJSON(t, api, &r, "kill", "alice", "0123456789abcdef"); err == nil {
+		t.Error("unknown session should be reported")
+	}
+
+	if err := runJSON(t, api, &r, "lock", "alice"); err != nil || r.Action != "locked" {
+		t.Error("lock failed:", err)
+	}
+	if runJSON(t, api, &info, "show", "alice"); info.Status != "locked" {
+		t.Error("user should be locked:", info.Status)
+	}
+	if err := runJSON(t, api, &r, "lock", "alice"); err == nil {
+		t.Error("locked user can't be locked again")
+	}
+	if err := runJSON(t, api, &r, "unlock", "alice"); err != nil || r.Action != "unlocked" {
+		t.Error("unlock failed:", err)
+	}
+
+	if err := runJSON(t, api, &r, "passwd", "alice"); err != nil || len(r.Passwd) != 16 {
+		t.Error("passwd failed:", err)
+	}
+	user, _ = api.DbUserInfo("alice")
+	if !api.VerifyPasswd(user, r.Passwd) {
+		t.Error("passwd should be reset")
+	}
+	if err := runJSON(t, api, &r, "passwd", "nobody"); err == nil {
+		t.Error("passwd of unknown user should fail")
+	}
+
+	var page struct {
+		Users      []userInfo `json:"users"`
+		Nextcursor string     `json:"nextcursor"`
+	}
+	api.Register("bob", "123456", "", "")
+	if err := runJSON(t, api, &page, "list", "-limit", "1"); err != nil || len(page.Users) != 1 || page.Nextcursor == "" {
+		t.Fatal("list failed:", page, err)
+	}
+	if runJSON(t, api, &page, "list", "-cursor", page.Nextcursor); len(page.Users) != 1 || page.Users[0].Username != "bob" {
+		t.Error("next page should follow:", page)
+	}
+
+	if err := runJSON(t, api, &r, "delete", "alice"); err == nil {
+		t.Error("delete should be confirmed")
+	}
+	if err := runJSON(t, api, &r, "delete", "-yes", "alice"); err != nil || r.Action != "deleted" {
+		t.Error("delete failed:", err)
+	}
+	if _, err := api.DbUserInfo("alice"); err == nil {
+		t.Error("user should be deleted")
+	}
+}
+
+func Test_PrintTable(t *testing.T) {
+	var buf bytes.Buffer
+	info := newUserInfo(types.User{Uid: 7, Username: "alice", Roles: "admin,support", Status: types.StatusLocked})
+	v := view{header: []string{"uid", "username", "roles"}, rows: [][]string{{"7", info.Username, strings.Join(info.Roles, ",")}},
+		footer: "nextcursor: YWxpY2U", value: info}
+	if err := v.print(&buf, formatTable); err != nil {
+		t.Fatal("print failed:", err.Error())
+	}
+	want := "UID  USERNAME  ROLES\n7    alice     admin,support\nnextcursor: YWxpY2U\n"
+	if buf.String() != want {
+		t.Errorf("unexpected table:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := info.view().print(&buf, formatTable); err != nil || !strings.Contains(buf.String(), "status         locked\n") {
+		t.Errorf("unexpected fields:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"user-management-system/conf"
+	"user-management-system/tcpserver"
+	"user-management-system/utils"
+
+	log "github.com/beego/beego/v2/adapter/logs"
+)
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "usage: umsctl [-c config] [-o table|json] command [flags] args")
+	fmt.Fprintln(out, "\ncommands:")
+	for _, name := range commandNames {
+		fmt.Fprintf(out, "  %-9s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintln(out, "\nflags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	var confFile, format string
+	flag.StringVar(&confFile, "c", "./conf/tcpserver.yaml", "config file, users and sessions are those of its db and redis")
+	flag.StringVar(&format, "o", formatTable, "output format, table or json")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 || (format != formatTable && format != formatJSON) {
+		usage()
+		os.Exit(-1)
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Println("unknown command:", flag.Arg(0))
+		usage()
+		os.Exit(-1)
+	}
+
+	var config conf.TCPConf
+	if err := utils.ConfParser(confFile, &config); err != nil {
+		fmt.Println("parser config failed:", err.Error())
+		os.Exit(-1)
+	}
+
+	// changes are logged next to the log of tcpserver, stdout is left to the output
+	logConfig := fmt.Sprintf(`{"filename":"%s","level":%s,"maxlines":0,"maxsize":0,"daily":true,"maxdays":%s}`,
+		filepath.Join(filepath.Dir(config.Log.Logfile), "umsctl.log"), config.Log.Loglevel, config.Log.Maxdays)
+	if err := log.SetLogger(log.AdapterFile, logConfig); err != nil {
+		fmt.Println("init log failed:", err.Error())
+		os.Exit(-1)
+	}
+
+	api, err := tcpserver.NewAPI(&config)
+	if err != nil {
+		fmt.Println("new API failed:", err.Error())
+		os.Exit(-1)
+	}
+	v, err := cmd.run(api, flag.Args()[1:])
+	if err == nil {
+		log.Info("umsctl command done:", flag.Arg(0))
+		err = v.print(os.Stdout, format)
+	}
+	api.Finalize()
+	if err != nil {
+		fmt.Println(flag.Arg(0), "failed:", err.Error())
+		os.Exit(1)
+	}
+}
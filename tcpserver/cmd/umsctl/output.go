@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	// formatTable aligned columns for people
+	formatTable = "table"
+	// formatJSON indented json for scripts
+	formatJSON = "json"
+)
+
+// view result of a command, rows are printed as a table and value as json
+type view struct {
+	// header of the columns, rows are field and value pairs without it
+	header []string
+	rows   [][]string
+	// footer printed after the table
+	footer string
+	value  interface{}
+}
+
+// fieldsView a view of one object as field and value pairs
+func fieldsView(value interface{}, rows ...[]string) view {
+	return view{rows: rows, value: value}
+}
+
+func (v view) print(w io.Writer, format string) error {
+	if format == formatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v.value)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if v.header != nil {
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(v.header, "\t")))
+	}
+	for _, row := range v.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if v.footer != "" {
+		_, err := fmt.Fprintln(w, v.footer)
+		return err
+	}
+	return nil
+}
+
+// formatTime unix time in local time, "-" if it's not set
+func formatTime(unix int64) string {
+	if unix == 0 {
+		return "-"
+	}
+	return time.Unix(unix, 0).Format("2006-01-02 15:04:05")
+}
@@ -3,6 +3,24 @@ package consts
 const (
 	UserInfoPrefix = "userinfo_"
 	TokenKeyPrefix = "token_"
+	// session metadata of token
+	SessionInfoPrefix = "session_"
+	// set of tokens of username
+	UserSessionsPrefix = "sessions_"
+	// failed login counter and lockout, followed by kind and username/ip
+	LoginFailuresPrefix = "loginfail_"
+	LoginLockPrefix     = "loginlock_"
+	LoginKindUser       = "user_"
+	LoginKindIP         = "ip_"
+	// pending 2fa login of username, and totp codes already used
+	TwoFactorChallengePrefix = "twofactor_"
+	TOTPUsedPrefix           = "totpused_"
+	// pending email verification, username and address of the token
+	EmailTokenPrefix = "emailverify_"
+	// pending passwd reset, username and skey of the token
+	PasswdResetPrefix = "passwdreset_"
+	// passwd reset links mailed to a username, counted like failed logins
+	LoginKindReset = "reset_"
 
 	EditUsername = 1
 	EditHeadurl  = 2
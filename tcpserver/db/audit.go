@@ -0,0 +1,39 @@
+package db
+
+import (
+	"user-management-system/tcpserver/types"
+)
+
+// DefaultAudit table of the audit log if audit.table is empty
+const DefaultAudit = "audit_log"
+
+// AppendDbAudit insert event into the audit table, its ID is set. rows are never updated or deleted
+func (d *DBClient) AppendDbAudit(event *types.AuditEvent) error {
+	return d.clients[0].Table(d.audit).Create(event).Error
+}
+
+// QueryDbAudit at most limit events passing filter with ids below before, newest first. before is 0 for the newest
+func (d *DBClient) QueryDbAudit(filter types.AuditFilter, before int64, limit int) ([]types.AuditEvent, error) {
+	query := d.clients[0].Table(d.audit)
+	if filter.Username != "" {
+		query = query.Where("`username` = ?", filter.Username)
+	}
+	if filter.Uid != 0 {
+		query = query.Where("`uid` = ?", filter.Uid)
+	}
+	if filter.Action != "" {
+		query = query.Where("`action` = ?", filter.Action)
+	}
+	if filter.From > 0 {
+		query = query.Where("`time` >= ?", filter.From)
+	}
+	if filter.To > 0 {
+		query = query.Where("`time` < ?", filter.To)
+	}
+	if before > 0 {
+		query = query.Where("`id` < ?", before)
+	}
+	var events []types.AuditEvent
+	err := query.Order("`id` DESC").Limit(limit).Find(&events).Error
+	return events, err
+}
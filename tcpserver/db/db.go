@@ -1,65 +1,526 @@
 package db
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"user-management-system/conf"
+	"user-management-system/tcpserver/shard"
 	"user-management-system/tcpserver/types"
-	"user-management-system/utils"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/mysql"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
 )
 
+// mysql error number of duplicate entry for unique key
+const errDupEntry = 1062
+
+const (
+	// DriverMySQL users in mysql, tables are created by tcpserver/cmd/migrate
+	DriverMySQL = "mysql"
+	// DriverSQLite users in a sqlite file, tables are migrated up when it's opened
+	DriverSQLite = "sqlite"
+)
+
+// ErrUserExists username has been taken
+var ErrUserExists = errors.New("user already exists")
+
+// DBClient users in tables of db instances routed by shard.Router
 type DBClient struct {
-	client *gorm.DB
+	router  *shard.Router
+	dbs     []conf.DbConf
+	clients []*gorm.DB
+	// uid directory, username reservation and audit log tables, empty in the previous layout
+	directory string
+	renames   string
+	audit     string
+	// layout users are being moved from, nil if not resharding
+	previous *DBClient
 }
 
-// init conn
+// init conn of the db section and shard instances, and of the previous layout if enabled
 func NewDBClient(config *conf.TCPConf) (*DBClient, error) {
-	conninfo := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8", config.Db.User, config.Db.Passwd, config.Db.Host, config.Db.Db)
-	client, err := gorm.Open("mysql", conninfo)
+	router, err := shard.NewRouterFromConf(config)
 	if err != nil {
-		msg := fmt.Sprintf("Failed to connect to db '%s', err: %s", conninfo, err.Error())
-		return nil, errors.New(msg)
+		return nil, err
+	}
+	directory, renames := config.Shard.Directory, config.Shard.Renames
+	if directory == "" {
+		directory = DefaultDirectory
+	}
+	if renames == "" {
+		renames = DefaultRenames
+	}
+	dbClient, err := newDBClient(router, append([]conf.DbConf{config.Db}, config.Shard.Instances...), directory, renames)
+	if err != nil {
+		return nil, err
+	}
+	dbClient.audit = config.Audit.Table
+	if dbClient.audit == "" {
+		dbClient.audit = DefaultAudit
+	}
+
+	prev := config.Shard.Previous
+	if prev.Enabled {
+		router, err = shard.NewRouter(prev.Hash, prev.Tables, prev.Tablename, 1+len(prev.Instances))
+		if err == nil {
+			dbClient.previous, err = newDBClient(router, append([]conf.DbConf{config.Db}, prev.Instances...), "", "")
+		}
+		if err != nil {
+			dbClient.CloseDB()
+			return nil, err
+		}
 	}
 
-	client.DB().SetMaxIdleConns(config.Db.Conn.Maxidle)
-	client.DB().SetMaxOpenConns(config.Db.Conn.Maxopen)
-	//db.LogMode(true)
+	// sqlite dbs are local ones, they're kept up to date without tcpserver/cmd/migrate
+	err = dbClient.migrateUp(0, nil, func(dbConf conf.DbConf) bool { return dbConf.Driver == DriverSQLite })
+	if err != nil {
+		dbClient.CloseDB()
+		return nil, err
+	}
+	return dbClient, nil
+}
 
-	dbClient := &DBClient{client: client}
+// newDBClient connect to dbs of router, directory and renames are empty in a previous layout
+func newDBClient(router *shard.Router, dbs []conf.DbConf, directory, renames string) (*DBClient, error) {
+	dbClient := &DBClient{router: router, dbs: dbs, directory: directory, renames: renames}
+	for i := range dbs {
+		client, err := Open(&dbs[i])
+		if err != nil {
+			dbClient.CloseDB()
+			return nil, err
+		}
+		//db.LogMode(true)
+		dbClient.clients = append(dbClient.clients, client)
+	}
 	return dbClient, nil
 }
 
+// Open connect to a db instance of config.Driver
+func Open(config *conf.DbConf) (*gorm.DB, error) {
+	switch config.Driver {
+	case "", DriverMySQL:
+		conninfo := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8", config.User, config.Passwd, config.Host, config.Db)
+		client, err := gorm.Open("mysql", conninfo)
+		if err != nil {
+			msg := fmt.Sprintf("Failed to connect to db '%s', err: %s", conninfo, err.Error())
+			return nil, errors.New(msg)
+		}
+		client.DB().SetMaxIdleConns(config.Conn.Maxidle)
+		client.DB().SetMaxOpenConns(config.Conn.Maxopen)
+		return client, nil
+	case DriverSQLite:
+		if dir := filepath.Dir(config.Path); dir != "." {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return nil, err
+			}
+		}
+		// wait for the write lock instead of failing with SQLITE_BUSY
+		conninfo := config.Path + "?_pragma=busy_timeout(5000)"
+		conn, err := sql.Open("sqlite", conninfo)
+		if err == nil {
+			err = conn.Ping()
+		}
+		if err != nil {
+			msg := fmt.Sprintf("Failed to open sqlite db '%s', err: %s", config.Path, err.Error())
+			return nil, errors.New(msg)
+		}
+		// sqlite has a single writer, and each conn to :memory: is a db of its own
+		conn.SetMaxOpenConns(1)
+		return gorm.Open("sqlite3", conn)
+	default:
+		return nil, fmt.Errorf("unknown db driver '%s'", config.Driver)
+	}
+}
+
 // cleanup
 func (d *DBClient) CloseDB() error {
-	return d.client.Close()
+	var err error
+	for _, client := range d.clients {
+		if e := client.Close(); e != nil {
+			err = e
+		}
+	}
+	if d.previous != nil {
+		if e := d.previous.CloseDB(); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// TableNum number of user tables
+func (d *DBClient) TableNum() int {
+	return d.router.Tables()
+}
+
+// PreviousTableNum number of user tables of the previous layout, 0 if not resharding
+func (d *DBClient) PreviousTableNum() int {
+	if d.previous == nil {
+		return 0
+	}
+	return d.previous.TableNum()
 }
 
-// query
+// table of username on its db instance
+func (d *DBClient) table(username string) *gorm.DB {
+	instance, tableName := d.router.Route(username)
+	return d.clients[instance].Table(tableName)
+}
+
+// place db instance and name of table index, the same place in two layouts is the same table
+func (d *DBClient) place(index int) (conf.DbConf, string) {
+	return d.dbs[d.router.Instance(index)], d.router.TableName(index)
+}
+
+// tableAt table index on its db instance
+func (d *DBClient) tableAt(index int) *gorm.DB {
+	return d.clients[d.router.Instance(index)].Table(d.router.TableName(index))
+}
+
+// EachTable call fn with every user table and the db instance it's on, stop at the first error
+func (d *DBClient) EachTable(fn func(client *gorm.DB, tableName string) error) error {
+	for i := 0; i < d.router.Tables(); i++ {
+		if err := fn(d.clients[d.router.Instance(i)], d.router.TableName(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// query, users not moved yet are read from the previous layout
 func (d *DBClient) GetDbUserInfo(username string) (types.User, error) {
 	var quser types.User
-	d.client.Table(utils.GetTableName(username)).Where(&types.User{Username: username}).First(&quser)
+	d.table(username).Where("`username` = ?", username).First(&quser)
 	if quser.Username == "" {
+		if d.previous != nil {
+			return d.previous.GetDbUserInfo(username)
+		}
 		return quser, fmt.Errorf("user(%s) not exists", username)
 	}
 	return quser, nil
 }
 
-// update nickname
-func (d *DBClient) UpdateDbNickname(username, nickname string) int64 {
-	return d.client.Table(utils.GetTableName(username)).Model(&types.User{}).Where("`username` = ?", username).Updates(types.User{Nickname: nickname, Uptime: time.Now().Unix()}).RowsAffected
+// insert a new user, return ErrUserExists if username has been taken or is reserved by a rename
+func (d *DBClient) CreateDbUser(user *types.User) error {
+	if d.previous != nil {
+		if _, err := d.previous.GetDbUserInfo(user.Username); err == nil {
+			return ErrUserExists
+		}
+	}
+	if d.renames != "" {
+		if _, err := d.GetDbRenamedUser(user.Username); err == nil {
+			return ErrUserExists
+		}
+	}
+	if user.Uid != 0 && d.directory != "" {
+		if err := d.addUid(user.Uid, user.Username); err != nil {
+			return err
+		}
+	}
+	err := dupEntry(d.table(user.Username).Create(user).Error)
+	if err != nil && user.Uid != 0 && d.directory != "" {
+		d.dir().Where("`uid` = ?", user.Uid).Delete(&uidEntry{})
+	}
+	return err
+}
+
+// dupEntry ErrUserExists if err is a unique or primary key violation
+func dupEntry(err error) error {
+	if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == errDupEntry {
+		return ErrUserExists
+	}
+	if sqliteErr, ok := err.(*sqlite.Error); ok && (sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY) {
+		return ErrUserExists
+	}
+	return err
+}
+
+// query totp settings
+func (d *DBClient) GetDbTwoFactor(username string) (types.TwoFactor, error) {
+	var tf types.TwoFactor
+	err := d.table(username).Select("username, totpsecret, totpenabled, recoverycodes").Where("`username` = ?", username).First(&tf).Error
+	if err == nil && tf.Username == "" {
+		err = fmt.Errorf("user(%s) not exists", username)
+	}
+	if gorm.IsRecordNotFoundError(err) && d.previous != nil {
+		return d.previous.GetDbTwoFactor(username)
+	}
+	return tf, err
+}
+
+// update totp settings, an empty secret disables 2fa
+func (d *DBClient) UpdateDbTwoFactor(username, secret string, enabled bool, recoveryCodes []string) int64 {
+	return d.update(username, func(table *gorm.DB) *gorm.DB {
+		return table.Model(&types.User{}).Where("`username` = ?", username).Updates(map[string]interface{}{
+			"totpsecret":    secret,
+			"totpenabled":   enabled,
+			"recoverycodes": strings.Join(recoveryCodes, ","),
+			"uptime":        time.Now().Unix(),
+		})
+	})
+}
+
+// replace recovery codes only if they're still old ones, so a code can't be used twice
+func (d *DBClient) UpdateDbRecoveryCodes(username string, old, recoveryCodes []string) int64 {
+	return d.update(username, func(table *gorm.DB) *gorm.DB {
+		return table.Model(&types.User{}).Where("`username` = ? AND `recoverycodes` = ?", username, strings.Join(old, ",")).Updates(map[string]interface{}{
+			"recoverycodes": strings.Join(recoveryCodes, ","),
+			"uptime":        time.Now().Unix(),
+		})
+	})
+}
+
+// deactivate an active user at deactivatetime
+func (d *DBClient) DeactivateDbUser(username string, deactivatetime int64) int64 {
+	return d.update(username, func(table *gorm.DB) *gorm.DB {
+		return table.Model(&types.User{}).Where("`username` = ? AND `status` = ?", username, types.StatusActive).Updates(map[string]interface{}{
+			"status":         types.StatusDeactivated,
+			"deactivatetime": deactivatetime,
+			"uptime":         time.Now().Unix(),
+		})
+	})
+}
+
+// restore a deactivated user
+func (d *DBClient) RestoreDbUser(username string) int64 {
+	return d.update(username, func(table *gorm.DB) *gorm.DB {
+		return table.Model(&types.User{}).Where("`username` = ? AND `status` = ?", username, types.StatusDeactivated).Updates(map[string]interface{}{
+			"status":         types.StatusActive,
+			"deactivatetime": 0,
+			"uptime":         time.Now().Unix(),
+		})
+	})
+}
+
+// update row of username with fn, a user not moved yet is copied from the previous layout first
+func (d *DBClient) update(username string, fn func(table *gorm.DB) *gorm.DB) int64 {
+	rows := fn(d.table(username)).RowsAffected
+	if rows == 0 && d.previous != nil && d.moveUser(username) {
+		rows = fn(d.table(username)).RowsAffected
+	}
+	return rows
+}
+
+// moveUser copy username from the previous layout, true if it's in the current one now
+func (d *DBClient) moveUser(username string) bool {
+	var row userTable
+	dbConf, table := d.place(d.router.Table(username))
+	prevConf, prevTable := d.previous.place(d.previous.router.Table(username))
+	if dbConf == prevConf && table == prevTable {
+		return false
+	}
+	if err := d.previous.table(username).Where("`username` = ?", username).First(&row).Error; err != nil {
+		return false
+	}
+	row.ID = 0
+	err := dupEntry(d.table(username).Create(&row).Error)
+	return err == nil || err == ErrUserExists
+}
+
+// delete user row from its table, uid directory and reserved old names, and from the previous layout
+func (d *DBClient) DeleteDbUser(username string) int64 {
+	rows := d.table(username).Where("`username` = ?", username).Delete(&types.User{}).RowsAffected
+	if d.directory != "" {
+		d.dir().Where("`username` = ?", username).Delete(&uidEntry{})
+	}
+	if d.renames != "" {
+		d.clients[0].Table(d.renames).Where("`username` = ?", username).Delete(&renameEntry{})
+	}
+	if d.previous != nil {
+		if prev := d.previous.DeleteDbUser(username); rows == 0 {
+			rows = prev
+		}
+	}
+	return rows
+}
+
+// query at most limit users of table index deactivated before deactivatetime, rows left behind
+// in a table by resharding are skipped
+func (d *DBClient) GetDbDeactivatedUsers(index int, deactivatetime int64, limit int) ([]types.User, error) {
+	var rows []types.User
+	err := d.tableAt(index).Where("`status` = ? AND `deactivatetime` < ?", types.StatusDeactivated, deactivatetime).Limit(limit).Find(&rows).Error
+	users := rows[:0]
+	for _, user := range rows {
+		if d.router.Table(user.Username) == index {
+			users = append(users, user)
+		}
+	}
+	return users, err
+}
+
+// update passwd hash and skey
+func (d *DBClient) UpdateDbPasswd(username, passwd, skey string) int64 {
+	return d.update(username, func(table *gorm.DB) *gorm.DB {
+		return table.Model(&types.User{}).Where("`username` = ?", username).Updates(types.User{Passwd: passwd, Skey: skey, Uptime: time.Now().Unix()})
+	})
+}
+
+// update profile fields of mask to their values in user in one UPDATE, empty values clear them,
+// nothing is updated if mask has a field not in types.ProfileFields
+func (d *DBClient) UpdateDbProfile(username string, user types.User, mask []string) int64 {
+	fields := map[string]interface{}{"uptime": time.Now().Unix()}
+	for _, field := range mask {
+		value, ok := user.Profile(field)
+		if !ok {
+			return 0
+		}
+		fields[field] = value
+		// a new address has to be verified again
+		if field == "email" {
+			fields["emailverified"] = false
+		}
+	}
+	return d.update(username, func(table *gorm.DB) *gorm.DB {
+		return table.Model(&types.User{}).Where("`username` = ?", username).Updates(fields)
+	})
+}
+
+// set file name of the avatar uploaded by username
+func (d *DBClient) UpdateDbAvatar(username, avatar string) int64 {
+	return d.update(username, func(table *gorm.DB) *gorm.DB {
+		return table.Model(&types.User{}).Where("`username` = ?", username).
+			Updates(map[string]interface{}{"avatar": avatar, "uptime": time.Now().Unix()})
+	})
+}
+
+// set roles of username
+func (d *DBClient) UpdateDbRoles(username string, roles []string) int64 {
+	return d.update(username, func(table *gorm.DB) *gorm.DB {
+		return table.Model(&types.User{}).Where("`username` = ?", username).
+			Updates(map[string]interface{}{"roles": strings.Join(roles, ","), "uptime": time.Now().Unix()})
+	})
+}
+
+// lock an active user, or unlock a locked one
+func (d *DBClient) LockDbUser(username string, locked bool) int64 {
+	from, to := types.StatusActive, types.StatusLocked
+	if !locked {
+		from, to = to, from
+	}
+	return d.update(username, func(table *gorm.DB) *gorm.DB {
+		return table.Model(&types.User{}).Where("`username` = ? AND `status` = ?", username, from).
+			Updates(map[string]interface{}{"status": to, "uptime": time.Now().Unix()})
+	})
+}
+
+// query at most limit users of table index passing filter with usernames after after, in bytewise
+// username order. rows left behind in a table by resharding are skipped, fewer than limit users
+// means the table is done
+func (d *DBClient) ListDbUsers(index int, filter types.UserFilter, after string, limit int) ([]types.User, error) {
+	return d.listTable(index, filter, after, limit, nil)
+}
+
+// query at most limit users of table index of the previous layout as ListDbUsers does, users
+// already copied to the current layout are skipped. nothing if not resharding
+func (d *DBClient) ListDbPreviousUsers(index int, filter types.UserFilter, after string, limit int) ([]types.User, error) {
+	if d.previous == nil {
+		return nil, nil
+	}
+	return d.previous.listTable(index, filter, after, limit, d.notMoved)
+}
+
+// users of rows not found in the current layout, rows are looked up by one query per table
+func (d *DBClient) notMoved(rows []types.User) ([]types.User, error) {
+	type place struct {
+		instance  int
+		tableName string
+	}
+	tables := make(map[place][]string)
+	for _, user := range rows {
+		instance, tableName := d.router.Route(user.Username)
+		tables[place{instance, tableName}] = append(tables[place{instance, tableName}], user.Username)
+	}
+	moved := make(map[string]bool)
+	for p, usernames := range tables {
+		var found []types.User
+		err := d.clients[p.instance].Table(p.tableName).Select("username").Where("`username` IN (?)", usernames).Find(&found).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range found {
+			moved[user.Username] = true
+		}
+	}
+	users := rows[:0]
+	for _, user := range rows {
+		if !moved[user.Username] {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+// query users of table index for ListDbUsers, rows of each batch routed to the table are filtered by keep if set
+func (d *DBClient) listTable(index int, filter types.UserFilter, after string, limit int,
+	keep func(rows []types.User) ([]types.User, error)) ([]types.User, error) {
+	query := d.tableAt(index)
+	// mysql collations ignore case, usernames are compared and sorted as go strings are
+	username := "`username`"
+	if query.Dialect().GetName() == "mysql" {
+		username = "BINARY `username`"
+	}
+	if filter.Prefix != "" {
+		query = query.Where("`username` LIKE ? ESCAPE '!'", escapeLike(filter.Prefix)+"%")
+	}
+	if filter.Nickname != "" {
+		query = query.Where("`nickname` LIKE ? ESCAPE '!'", "%"+escapeLike(filter.Nickname)+"%")
+	}
+	if filter.Upfrom > 0 {
+		query = query.Where("`uptime` >= ?", filter.Upfrom)
+	}
+	if filter.Upto > 0 {
+		query = query.Where("`uptime` < ?", filter.Upto)
+	}
+
+	var users []types.User
+	for len(users) < limit {
+		var rows []types.User
+		err := query.Where(username+" > ?", after).Order(username).Limit(limit).Find(&rows).Error
+		if err != nil {
+			return users, err
+		}
+		var batch []types.User
+		for _, user := range rows {
+			after = user.Username
+			if d.router.Table(user.Username) == index {
+				batch = append(batch, user)
+			}
+		}
+		if keep != nil && len(batch) > 0 {
+			if batch, err = keep(batch); err != nil {
+				return users, err
+			}
+		}
+		for _, user := range batch {
+			if len(users) < limit {
+				users = append(users, user)
+			}
+		}
+		if len(rows) < limit {
+			break
+		}
+	}
+	return users, nil
 }
 
-// update headurl
-func (d *DBClient) UpdateDbHeadurl(username, url string) int64 {
-	return d.client.Table(utils.GetTableName(username)).Model(&types.User{}).Where("`username` = ?", username).Updates(types.User{Headurl: url, Uptime: time.Now().Unix()}).RowsAffected
+// escapeLike s matched literally in a LIKE pattern escaped by '!'
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
 }
 
-// update nickname and headurl
-func (d *DBClient) UpdateDbUserinfo(username, nickname, url string) int64 {
-	return d.client.Table(utils.GetTableName(username)).Model(&types.User{}).Where("`username` = ?", username).Updates(types.User{Nickname: nickname, Headurl: url, Uptime: time.Now().Unix()}).RowsAffected
+// mark email of username verified, only if it's still the verified address
+func (d *DBClient) VerifyDbEmail(username, email string) int64 {
+	return d.update(username, func(table *gorm.DB) *gorm.DB {
+		return table.Model(&types.User{}).Where("`username` = ? AND `email` = ? AND `emailverified` = ?", username, email, false).
+			Updates(map[string]interface{}{"emailverified": true, "uptime": time.Now().Unix()})
+	})
 }
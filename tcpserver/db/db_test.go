@@ -0,0 +1,392 @@
+package db
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"user-management-system/conf"
+	"user-management-system/tcpserver/shard"
+	"user-management-system/tcpserver/types"
+
+	"github.com/jinzhu/gorm"
+)
+
+func newTestDBClient(t *testing.T) *DBClient {
+	var config conf.TCPConf
+	config.Db.Driver = DriverSQLite
+	config.Db.Path = filepath.Join(t.TempDir(), "users.db")
+	d, err := NewDBClient(&config)
+	if err != nil {
+		t.Fatal("failed to open sqlite db:", err)
+	}
+	t.Cleanup(func() { d.CloseDB() })
+	return d
+}
+
+func Test_SQLiteTables(t *testing.T) {
+	d := newTestDBClient(t)
+	d.EachTable(func(client *gorm.DB, tableName string) error {
+		if !client.HasTable(tableName) {
+			t.Error("table not created:", tableName)
+		}
+		return nil
+	})
+	// created again on restart
+	if err := d.CreateTables(); err != nil {
+		t.Error("tables should be created only once:", err)
+	}
+}
+
+func Test_SQLiteUser(t *testing.T) {
+	d := newTestDBClient(t)
+	if err := d.CreateDbUser(&types.User{Username: "username8", Nickname: "nickname8", Passwd: "x", Skey: "y"}); err != nil {
+		t.Fatal("failed to create user:", err)
+	}
+	if err := d.CreateDbUser(&types.User{Username: "username8"}); err != ErrUserExists {
+		t.Error("duplicated username should fail, got:", err)
+	}
+	if d.UpdateDbProfile("username8", types.User{Nickname: "nick"}, []string{"nickname"}) != 1 {
+		t.Error("nickname not updated")
+	}
+	if user, err := d.GetDbUserInfo("username8"); err != nil || user.Nickname != "nick" || user.ID == 0 {
+		t.Error("unexpected user:", user, err)
+	}
+	if d.UpdateDbProfile("username8", types.User{Email: "u8@example.com", Bio: "hi", Phone: "+100"}, []string{"email", "bio"}) != 1 ||
+		d.UpdateDbProfile("username8", types.User{}, []string{"bio"}) != 1 {
+		t.Error("profile not updated")
+	}
+	if d.UpdateDbProfile("username8", types.User{Passwd: "x"}, []string{"passwd"}) != 0 {
+		t.Error("fields out of profile should not be updated")
+	}
+	if d.VerifyDbEmail("username8", "old@example.com") != 0 || d.VerifyDbEmail("username8", "u8@example.com") != 1 ||
+		d.VerifyDbEmail("username8", "u8@example.com") != 0 {
+		t.Error("only the unverified current email should be verified")
+	}
+	if user, _ := d.GetDbUserInfo("username8"); !user.Emailverified {
+		t.Error("email should be verified")
+	}
+	if user, _ := d.GetDbUserInfo("username8"); user.Email != "u8@example.com" || user.Bio != "" || user.Phone != "" || user.Nickname != "nick" || user.Passwd != "x" {
+		t.Error("unexpected profile:", user)
+	}
+
+	if d.UpdateDbTwoFactor("username8", "secret", true, []string{"a", "b"}) != 1 {
+		t.Error("2fa not updated")
+	}
+	if d.UpdateDbRecoveryCodes("username8", []string{"a"}, nil) != 0 || d.UpdateDbRecoveryCodes("username8", []string{"a", "b"}, []string{"b"}) != 1 {
+		t.Error("recovery codes should only be replaced if unchanged")
+	}
+	if tf, err := d.GetDbTwoFactor("username8"); err != nil || !tf.Totpenabled || tf.Recoverycodes != "b" {
+		t.Error("unexpected 2fa:", tf, err)
+	}
+
+	if d.UpdateDbRoles("username8", []string{"admin", "support"}) != 1 {
+		t.Error("roles not updated")
+	}
+	if d.LockDbUser("username8", true) != 1 || d.LockDbUser("username8", true) != 0 {
+		t.Error("active user should be locked once")
+	}
+	if user, _ := d.GetDbUserInfo("username8"); user.Status != types.StatusLocked || len(user.RoleList()) != 2 {
+		t.Error("unexpected status or roles:", user)
+	}
+	if d.DeactivateDbUser("username8", 100) != 0 {
+		t.Error("locked user should not be deactivated")
+	}
+	if d.LockDbUser("username8", false) != 1 || d.LockDbUser("username8", false) != 0 {
+		t.Error("locked user should be unlocked once")
+	}
+
+	if d.DeactivateDbUser("username8", 100) != 1 {
+		t.Error("user not deactivated")
+	}
+	var found int
+	for i := 0; i < d.TableNum(); i++ {
+		users, _ := d.GetDbDeactivatedUsers(i, 101, 10)
+		found += len(users)
+	}
+	if found != 1 {
+		t.Error("deactivated user should be found once, got:", found)
+	}
+	if d.DeleteDbUser("username8") != 1 {
+		t.Error("user not deleted")
+	}
+}
+
+func Test_SQLiteListUsers(t *testing.T) {
+	d := newTestDBClient(t)
+	for i := 0; i < 30; i++ {
+		user := types.User{Username: fmt.Sprintf("user%02d", i), Nickname: fmt.Sprintf("Nick%02d", i), Uptime: int64(i)}
+		if err := d.CreateDbUser(&user); err != nil {
+			t.Fatal("failed to create user:", err)
+		}
+	}
+	d.CreateDbUser(&types.User{Username: "User_X", Nickname: "100%"})
+
+	// list all tables page by page
+	list := func(filter types.UserFilter) map[string]bool {
+		seen := make(map[string]bool)
+		for i := 0; i < d.TableNum(); i++ {
+			after := ""
+			for {
+				users, err := d.ListDbUsers(i, filter, after, 2)
+				if err != nil {
+					t.Fatal("failed to list users:", err)
+				}
+				for _, user := range users {
+					if user.Username <= after || d.router.Table(user.Username) != i || seen[user.Username] || !filter.Match(user) {
+						t.Error("users should be listed once, in order of their table:", user.Username)
+					}
+					seen[user.Username], after = true, user.Username
+				}
+				if len(users) < 2 {
+					break
+				}
+			}
+		}
+		return seen
+	}
+	if seen := list(types.UserFilter{}); len(seen) != 31 {
+		t.Error("all users should be listed, got:", len(seen))
+	}
+	for _, c := range []struct {
+		filter types.UserFilter
+		want   int
+	}{
+		{types.UserFilter{Prefix: "user1"}, 10},
+		{types.UserFilter{Prefix: "USER_"}, 1},
+		{types.UserFilter{Nickname: "k2"}, 10},
+		{types.UserFilter{Nickname: "0%"}, 1},
+		{types.UserFilter{Upfrom: 5, Upto: 10}, 5},
+		{types.UserFilter{Prefix: "user2", Nickname: "ICK2", Upto: 25}, 5},
+	} {
+		if seen := list(c.filter); len(seen) != c.want {
+			t.Errorf("%+v should match %d users, got: %d", c.filter, c.want, len(seen))
+		}
+	}
+}
+
+// newTestInstances db client of 4 tables over 2 sqlite dbs
+func newTestInstances(t *testing.T) *DBClient {
+	var config conf.TCPConf
+	config.Db.Driver = DriverSQLite
+	config.Db.Path = filepath.Join(t.TempDir(), "users0.db")
+	config.Shard.Hash = shard.HashJump
+	config.Shard.Tables = 4
+	config.Shard.Tablename = "users_%d"
+	config.Shard.Instances = make([]conf.DbConf, 1)
+	config.Shard.Instances[0].Driver = DriverSQLite
+	config.Shard.Instances[0].Path = filepath.Join(t.TempDir(), "users1.db")
+	d, err := NewDBClient(&config)
+	if err != nil {
+		t.Fatal("failed to open sqlite dbs:", err)
+	}
+	t.Cleanup(func() { d.CloseDB() })
+	return d
+}
+
+// otherInstanceName a username routed to another db instance than username
+func otherInstanceName(d *DBClient, username string) string {
+	newname := "alice"
+	for i := 0; d.router.Instance(d.router.Table(newname)) == d.router.Instance(d.router.Table(username)); i++ {
+		newname = fmt.Sprintf("alice%d", i)
+	}
+	return newname
+}
+
+func Test_SQLiteInstances(t *testing.T) {
+	d := newTestInstances(t)
+
+	for i := 0; i < 20; i++ {
+		username := fmt.Sprintf("username%d", i)
+		d.CreateDbUser(&types.User{Username: username, Skey: "y"})
+		d.DeactivateDbUser(username, 100)
+	}
+	// each instance only has its own tables, and users are found in them
+	var found int
+	for i := 0; i < d.TableNum(); i++ {
+		instance := d.router.Instance(i)
+		if d.clients[1-instance].HasTable(d.router.TableName(i)) {
+			t.Error("table created on other instance:", i)
+		}
+		users, _ := d.GetDbDeactivatedUsers(i, 101, 20)
+		for _, user := range users {
+			if d.router.Table(user.Username) != i {
+				t.Error("user in wrong table:", user.Username, i)
+			}
+		}
+		found += len(users)
+	}
+	if found != 20 {
+		t.Error("expected 20 users over all tables, got:", found)
+	}
+	if user, err := d.GetDbUserInfo("username7"); err != nil || user.Username != "username7" {
+		t.Error("user not found on its instance:", err)
+	}
+
+	// renamed over to the other instance
+	newname := otherInstanceName(d, "username7")
+	if err := d.RenameDbUser("username7", newname, 0); err != nil {
+		t.Fatal("failed to rename over instances:", err)
+	}
+	if _, err := d.GetDbUserInfo("username7"); err == nil {
+		t.Error("old row should be deleted from its instance")
+	}
+	if user, err := d.GetDbUserInfo(newname); err != nil || user.Status != types.StatusDeactivated {
+		t.Error("renamed user not found on the other instance:", user, err)
+	}
+}
+
+func Test_SQLiteRenameInstances(t *testing.T) {
+	d := newTestInstances(t)
+	d.CreateDbUser(&types.User{Uid: 42, Username: "username8", Skey: "y"})
+	newname := otherInstanceName(d, "username8")
+	expiretime := time.Now().Unix() + 100
+
+	// moving fails, the reservation is undone along with the new row
+	instance, tableName := d.router.Route(newname)
+	d.clients[instance].Exec("ALTER TABLE " + tableName + " RENAME TO broken")
+	if err := d.RenameDbUser("username8", newname, expiretime); err == nil {
+		t.Fatal("rename to a broken table should fail")
+	}
+	d.clients[instance].Exec("ALTER TABLE broken RENAME TO " + tableName)
+	if _, err := d.GetDbRenamedUser("username8"); err == nil {
+		t.Error("reservation of a failed rename should be undone")
+	}
+	if user, err := d.GetDbUserByUid(42); err != nil || user.Username != "username8" {
+		t.Error("uid should still lead to the old username:", user, err)
+	}
+
+	// a rename interrupted after the new row was created is completed by a retry
+	row, _ := d.GetDbUserInfo("username8")
+	row.ID, row.Username = 0, newname
+	d.clients[instance].Table(tableName).Create(&row)
+	if err := d.RenameDbUser("username8", newname, expiretime); err != nil {
+		t.Fatal("retried rename should complete:", err)
+	}
+	if _, err := d.GetDbUserInfo("username8"); err == nil {
+		t.Error("old row should be deleted by the retry")
+	}
+	if user, err := d.GetDbUserByUid(42); err != nil || user.Username != newname {
+		t.Error("uid should lead to the new username:", user, err)
+	}
+	if username, err := d.GetDbRenamedUser("username8"); err != nil || username != newname {
+		t.Error("old username should be reserved:", username, err)
+	}
+
+	// a row of another user is never taken over
+	d.CreateDbUser(&types.User{Uid: 43, Username: "username9", Skey: "y"})
+	if err := d.RenameDbUser("username9", newname, 0); err != ErrUserExists {
+		t.Error("rename to a taken username should fail, got:", err)
+	}
+}
+
+func Test_SQLiteUid(t *testing.T) {
+	d := newTestDBClient(t)
+	if err := d.CreateDbUser(&types.User{Uid: 42, Username: "username8", Skey: "y"}); err != nil {
+		t.Fatal("failed to create user:", err)
+	}
+	if err := d.CreateDbUser(&types.User{Uid: 42, Username: "username9"}); err != ErrUserExists {
+		t.Error("duplicated uid should fail, got:", err)
+	}
+	if user, err := d.GetDbUserByUid(42); err != nil || user.Username != "username8" {
+		t.Error("user not found by uid:", user, err)
+	}
+	if d.DeleteDbUser("username8") != 1 {
+		t.Error("user not deleted")
+	}
+	if _, err := d.GetDbUserByUid(42); err == nil {
+		t.Error("uid of deleted user should be gone")
+	}
+
+	// users of older versions have no uid
+	for i := 0; i < 5; i++ {
+		d.CreateDbUser(&types.User{Username: fmt.Sprintf("username%d", i), Skey: "y"})
+	}
+	next := int64(100)
+	assigned, err := d.AssignUids(func() int64 { next++; return next }, 2)
+	if err != nil || assigned != 5 {
+		t.Error("uids not assigned:", assigned, err)
+	}
+	if assigned, _ = d.AssignUids(func() int64 { next++; return next }, 2); assigned != 0 {
+		t.Error("uids should be assigned only once:", assigned)
+	}
+	for uid := int64(101); uid <= 105; uid++ {
+		if user, err := d.GetDbUserByUid(uid); err != nil || user.Uid != uid {
+			t.Error("user not found by assigned uid:", uid, err)
+		}
+	}
+}
+
+func Test_SQLiteRename(t *testing.T) {
+	d := newTestDBClient(t)
+	d.CreateDbUser(&types.User{Uid: 42, Username: "username8", Nickname: "nickname8", Skey: "y"})
+	d.CreateDbUser(&types.User{Uid: 43, Username: "username9", Skey: "y"})
+	d.UpdateDbTwoFactor("username8", "secret", true, nil)
+
+	if err := d.RenameDbUser("username8", "username9", 0); err != ErrUserExists {
+		t.Error("rename to a taken username should fail, got:", err)
+	}
+	if err := d.RenameDbUser("username8", "alice", time.Now().Unix()+100); err != nil {
+		t.Fatal("failed to rename:", err)
+	}
+	if user, err := d.GetDbUserInfo("alice"); err != nil || user.Nickname != "nickname8" || user.Uid != 42 {
+		t.Error("renamed user not found:", user, err)
+	}
+	if tf, err := d.GetDbTwoFactor("alice"); err != nil || !tf.Totpenabled {
+		t.Error("2fa should move with the user:", tf, err)
+	}
+	if _, err := d.GetDbUserInfo("username8"); err == nil {
+		t.Error("old username should be gone")
+	}
+	if user, err := d.GetDbUserByUid(42); err != nil || user.Username != "alice" {
+		t.Error("uid should lead to the new username:", user, err)
+	}
+
+	// the old name is reserved for its user only
+	if username, err := d.GetDbRenamedUser("username8"); err != nil || username != "alice" {
+		t.Error("old username should lead to the new one:", username, err)
+	}
+	if err := d.CreateDbUser(&types.User{Username: "username8"}); err != ErrUserExists {
+		t.Error("reserved username should not be registered, got:", err)
+	}
+	if err := d.RenameDbUser("username9", "username8", 0); err != ErrUserExists {
+		t.Error("reserved username should not be taken by others, got:", err)
+	}
+	if err := d.RenameDbUser("alice", "username8", 0); err != nil {
+		t.Error("user should take back its old name:", err)
+	}
+	if _, err := d.GetDbRenamedUser("username8"); err == nil {
+		t.Error("reservation of a name taken back should be gone")
+	}
+	if d.DelDbExpiredRenames(time.Now().Unix()+200) != 0 {
+		t.Error("no reservation should be left")
+	}
+}
+
+func Test_SQLiteAudit(t *testing.T) {
+	d := newTestDBClient(t)
+	for i, username := range []string{"alice", "bob", "alice", "alice"} {
+		event := types.AuditEvent{Time: int64(100 + i), Username: username, Actor: username, Action: "login", Outcome: types.AuditSuccess}
+		if err := d.AppendDbAudit(&event); err != nil || event.ID == 0 {
+			t.Fatal("failed to append audit event:", event.ID, err)
+		}
+	}
+	d.AppendDbAudit(&types.AuditEvent{Time: 104, Username: "alice", Action: "logout"})
+	d.AppendDbAudit(&types.AuditEvent{Time: 105, Username: "alice", Uid: 7, Action: "username.change"})
+	d.AppendDbAudit(&types.AuditEvent{Time: 106, Username: "carol", Uid: 7, Action: "login"})
+
+	events, err := d.QueryDbAudit(types.AuditFilter{Username: "alice", To: 105}, 0, 10)
+	if err != nil || len(events) != 4 || events[0].Action != "logout" || events[0].ID <= events[1].ID {
+		t.Fatal("events of alice should be queried newest first:", events, err)
+	}
+	if page, _ := d.QueryDbAudit(types.AuditFilter{Username: "alice"}, events[1].ID, 1); len(page) != 1 || page[0].ID != events[2].ID {
+		t.Error("events should be paged by id:", page)
+	}
+	if page, _ := d.QueryDbAudit(types.AuditFilter{Action: "login", From: 101, To: 104}, 0, 10); len(page) != 3 {
+		t.Error("events should be filtered by time and action:", page)
+	}
+	if page, _ := d.QueryDbAudit(types.AuditFilter{Uid: 7}, 0, 10); len(page) != 2 || page[0].Username != "carol" || page[1].Uid != 7 {
+		t.Error("events should be filtered by uid:", page)
+	}
+}
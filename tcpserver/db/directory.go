@@ -0,0 +1,88 @@
+package db
+
+import (
+	"fmt"
+
+	"user-management-system/tcpserver/types"
+
+	"github.com/jinzhu/gorm"
+)
+
+// DefaultDirectory table of the uid directory if shard.directory is empty
+const DefaultDirectory = "userinfo_dir"
+
+// uidEntry row of the uid directory, the table of a user is found by its username
+type uidEntry struct {
+	Uid      int64  `gorm:"primary_key;auto_increment:false"`
+	Username string `gorm:"type:varchar(64);unique;not null"`
+}
+
+// dir the uid directory, it's in the db section
+func (d *DBClient) dir() *gorm.DB {
+	return d.clients[0].Table(d.directory)
+}
+
+// addUid add uid of username to directory, an entry left by a failed register is replaced
+func (d *DBClient) addUid(uid int64, username string) error {
+	err := dupEntry(d.dir().Create(&uidEntry{Uid: uid, Username: username}).Error)
+	if err != ErrUserExists {
+		return err
+	}
+	if _, e := d.GetDbUserInfo(username); e == nil {
+		return err
+	}
+	d.dir().Where("`username` = ?", username).Delete(&uidEntry{})
+	return dupEntry(d.dir().Create(&uidEntry{Uid: uid, Username: username}).Error)
+}
+
+// GetDbUserByUid query by global uid
+func (d *DBClient) GetDbUserByUid(uid int64) (types.User, error) {
+	var entry uidEntry
+	if err := d.dir().Where("`uid` = ?", uid).First(&entry).Error; err != nil {
+		return types.User{}, fmt.Errorf("uid(%d) not exists", uid)
+	}
+	user, err := d.GetDbUserInfo(entry.Username)
+	if err == nil && user.Uid != uid {
+		err = fmt.Errorf("uid(%d) not exists", uid)
+	}
+	return user, err
+}
+
+// AssignUids give users of older versions a uid from next, return the number of users given one
+func (d *DBClient) AssignUids(next func() int64, batch int) (int64, error) {
+	var assigned int64
+	for i := 0; i < d.router.Tables(); i++ {
+		var lastID int32
+		for {
+			var rows []types.User
+			err := d.tableAt(i).Select("id, username").Where("`uid` = 0 AND `id` > ?", lastID).Order("`id`").Limit(batch).Find(&rows).Error
+			if err != nil {
+				return assigned, err
+			}
+			for _, row := range rows {
+				lastID = row.ID
+				// left behind by resharding
+				if d.router.Table(row.Username) != i {
+					continue
+				}
+				uid := next()
+				if err = d.addUid(uid, row.Username); err == ErrUserExists {
+					var entry uidEntry
+					err = d.dir().Where("`username` = ?", row.Username).First(&entry).Error
+					uid = entry.Uid
+				}
+				if err != nil {
+					return assigned, err
+				}
+				if err = d.tableAt(i).Where("`id` = ?", row.ID).Update("uid", uid).Error; err != nil {
+					return assigned, err
+				}
+				assigned++
+			}
+			if len(rows) < batch {
+				break
+			}
+		}
+	}
+	return assigned, nil
+}
@@ -0,0 +1,329 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"user-management-system/conf"
+
+	"github.com/jinzhu/gorm"
+)
+
+// SchemaVersionTable bookkeeping table of applied migrations, on every db instance
+const SchemaVersionTable = "schema_version"
+
+// ErrSchemaOutdated user tables need migrations tcpserver depends on
+var ErrSchemaOutdated = errors.New("schema of user tables is out of date, run tcpserver/cmd/migrate up")
+
+// Migration a versioned schema change, applied to every db instance
+type Migration struct {
+	Version int
+	Name    string
+	up      func(s *schema) error
+	// nil if it can't be undone
+	down func(s *schema) error
+}
+
+// SchemaStatus version of a db instance and the migrations it lacks, Missing are user tables
+// of the layout that haven't been created
+type SchemaStatus struct {
+	Instance string
+	Version  int
+	Pending  []Migration
+	Missing  []string
+}
+
+// schemaVersion row of an applied migration
+type schemaVersion struct {
+	Version     int    `gorm:"primary_key;auto_increment:false"`
+	Name        string `gorm:"type:varchar(128);not null"`
+	Appliedtime int64  `gorm:"type:bigint;not null"`
+}
+
+// schema tables of a db instance migrations are applied to, user tables of both layouts
+// if resharding, and the uid directory, reserved usernames and audit log on the db section
+type schema struct {
+	dbConf    conf.DbConf
+	client    *gorm.DB
+	tables    []string
+	directory string
+	renames   string
+	audit     string
+}
+
+// LatestVersion version of the newest migration
+func LatestVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// schemas every db instance with its tables, an instance shared by both layouts is listed once
+func (d *DBClient) schemas() []*schema {
+	var schemas []*schema
+	find := func(dbConf conf.DbConf, client *gorm.DB) *schema {
+		for _, s := range schemas {
+			if s.dbConf == dbConf {
+				return s
+			}
+		}
+		s := &schema{dbConf: dbConf, client: client}
+		schemas = append(schemas, s)
+		return s
+	}
+	for i, client := range d.clients {
+		find(d.dbs[i], client)
+	}
+	schemas[0].directory, schemas[0].renames, schemas[0].audit = d.directory, d.renames, d.audit
+	for _, layout := range []*DBClient{d, d.previous} {
+		if layout == nil {
+			continue
+		}
+		for i := 0; i < layout.router.Tables(); i++ {
+			instance := layout.router.Instance(i)
+			s := find(layout.dbs[instance], layout.clients[instance])
+			if tableName := layout.router.TableName(i); !s.hasTable(tableName) {
+				s.tables = append(s.tables, tableName)
+			}
+		}
+	}
+	return schemas
+}
+
+// instanceName name of a db instance in status and errors
+func instanceName(dbConf conf.DbConf) string {
+	if dbConf.Driver == DriverSQLite {
+		return dbConf.Path
+	}
+	return dbConf.Host + "/" + dbConf.Db
+}
+
+// version latest migration applied to s
+func (s *schema) version() (int, error) {
+	if !s.client.HasTable(SchemaVersionTable) {
+		return 0, nil
+	}
+	var row schemaVersion
+	err := s.client.Table(SchemaVersionTable).Order("`version` DESC").First(&row).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return 0, nil
+	}
+	return row.Version, err
+}
+
+// SchemaStatus versions of all db instances
+func (d *DBClient) SchemaStatus() ([]SchemaStatus, error) {
+	var statuses []SchemaStatus
+	for _, s := range d.schemas() {
+		version, err := s.version()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get schema version of %s: %s", instanceName(s.dbConf), err.Error())
+		}
+		status := SchemaStatus{Instance: instanceName(s.dbConf), Version: version, Missing: s.missing()}
+		for _, m := range migrations {
+			if m.Version > version {
+				status.Pending = append(status.Pending, m)
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// CheckSchema ErrSchemaOutdated if a db instance lacks migrations
+func (d *DBClient) CheckSchema() error {
+	statuses, err := d.SchemaStatus()
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		if len(status.Pending) > 0 {
+			return fmt.Errorf("%s: %s is at version %d of %d", ErrSchemaOutdated.Error(), status.Instance, status.Version, LatestVersion())
+		}
+		if len(status.Missing) > 0 {
+			return fmt.Errorf("%s: %s lacks tables %s", ErrSchemaOutdated.Error(), status.Instance, strings.Join(status.Missing, ", "))
+		}
+	}
+	return nil
+}
+
+// MigrateUp apply migrations up to version target (the latest if 0) to every db instance,
+// report is called before each one
+func (d *DBClient) MigrateUp(target int, report func(instance string, m Migration)) error {
+	return d.migrateUp(target, report, func(conf.DbConf) bool { return true })
+}
+
+// migrateUp MigrateUp of db instances of filter
+func (d *DBClient) migrateUp(target int, report func(instance string, m Migration), filter func(conf.DbConf) bool) error {
+	if target == 0 {
+		target = LatestVersion()
+	}
+	for _, s := range d.schemas() {
+		if !filter(s.dbConf) {
+			continue
+		}
+		version, err := s.version()
+		if err == nil {
+			err = s.client.Table(SchemaVersionTable).AutoMigrate(&schemaVersion{}).Error
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get schema version of %s: %s", instanceName(s.dbConf), err.Error())
+		}
+		// tables added to the layout since are brought up to the version of the instance first
+		if missing := s.missing(); version > 0 && len(missing) > 0 {
+			added := &schema{dbConf: s.dbConf, client: s.client, tables: missing}
+			for _, m := range migrations {
+				if m.Version > version {
+					break
+				}
+				if err = m.up(added); err != nil {
+					return fmt.Errorf("migration %d (%s) of new tables of %s failed: %s", m.Version, m.Name, instanceName(s.dbConf), err.Error())
+				}
+			}
+		}
+		for _, m := range migrations {
+			if m.Version <= version || m.Version > target {
+				continue
+			}
+			if report != nil {
+				report(instanceName(s.dbConf), m)
+			}
+			if err = m.up(s); err != nil {
+				return fmt.Errorf("migration %d (%s) of %s failed: %s", m.Version, m.Name, instanceName(s.dbConf), err.Error())
+			}
+			row := schemaVersion{Version: m.Version, Name: m.Name, Appliedtime: time.Now().Unix()}
+			if err = s.client.Table(SchemaVersionTable).Create(&row).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// MigrateDown undo migrations above version target of every db instance, newest first
+func (d *DBClient) MigrateDown(target int, report func(instance string, m Migration)) error {
+	for _, s := range d.schemas() {
+		version, err := s.version()
+		if err != nil {
+			return fmt.Errorf("failed to get schema version of %s: %s", instanceName(s.dbConf), err.Error())
+		}
+		for i := len(migrations) - 1; i >= 0; i-- {
+			m := migrations[i]
+			if m.Version > version || m.Version <= target {
+				continue
+			}
+			if m.down == nil {
+				return fmt.Errorf("migration %d (%s) of %s can't be undone", m.Version, m.Name, instanceName(s.dbConf))
+			}
+			if report != nil {
+				report(instanceName(s.dbConf), m)
+			}
+			if err = m.down(s); err != nil {
+				return fmt.Errorf("undo migration %d (%s) of %s failed: %s", m.Version, m.Name, instanceName(s.dbConf), err.Error())
+			}
+			if err = s.client.Table(SchemaVersionTable).Where("`version` = ?", m.Version).Delete(&schemaVersion{}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mysql whether s is on mysql, sqlite otherwise
+func (s *schema) mysql() bool {
+	return s.client.Dialect().GetName() == "mysql"
+}
+
+// hasTable whether tableName is one of the user tables of s
+func (s *schema) hasTable(tableName string) bool {
+	for _, t := range s.tables {
+		if t == tableName {
+			return true
+		}
+	}
+	return false
+}
+
+// missing user tables of s that don't exist
+func (s *schema) missing() []string {
+	var missing []string
+	for _, tableName := range s.tables {
+		if !s.client.HasTable(tableName) {
+			missing = append(missing, tableName)
+		}
+	}
+	return missing
+}
+
+// eachTable call fn with every user table of s, stop at the first error
+func (s *schema) eachTable(fn func(tableName string) error) error {
+	for _, tableName := range s.tables {
+		if err := fn(tableName); err != nil {
+			return fmt.Errorf("%s: %s", tableName, err.Error())
+		}
+	}
+	return nil
+}
+
+// createTable create tableName from model unless it exists
+func (s *schema) createTable(tableName string, model interface{}) error {
+	if s.client.HasTable(tableName) {
+		return nil
+	}
+	client := s.client
+	if s.mysql() {
+		client = client.Set("gorm:table_options", "ENGINE=InnoDB DEFAULT CHARSET=utf8")
+	}
+	return client.Table(tableName).CreateTable(model).Error
+}
+
+// dropTable drop tableName if it exists
+func (s *schema) dropTable(tableName string) error {
+	return s.client.DropTableIfExists(tableName).Error
+}
+
+// addColumn add column of definition to tableName unless it exists
+func (s *schema) addColumn(tableName, column, definition string) error {
+	if s.client.Dialect().HasColumn(tableName, column) {
+		return nil
+	}
+	return s.client.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", tableName, column, definition)).Error
+}
+
+// dropColumn drop column of tableName if it exists
+func (s *schema) dropColumn(tableName, column string) error {
+	if !s.client.Dialect().HasColumn(tableName, column) {
+		return nil
+	}
+	return s.client.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", tableName, column)).Error
+}
+
+// indexName index names are per table in mysql but per database in sqlite
+func (s *schema) indexName(tableName, index string) string {
+	if s.mysql() {
+		return index
+	}
+	return tableName + "_" + index
+}
+
+// addIndex add index of columns to tableName unless it exists
+func (s *schema) addIndex(tableName, index string, unique bool, columns ...string) error {
+	index = s.indexName(tableName, index)
+	if s.client.Dialect().HasIndex(tableName, index) {
+		return nil
+	}
+	kind := "INDEX"
+	if unique {
+		kind = "UNIQUE INDEX"
+	}
+	return s.client.Exec(fmt.Sprintf("CREATE %s %s ON %s (%s)", kind, index, tableName, strings.Join(columns, ", "))).Error
+}
+
+// dropIndex drop index of tableName if it exists
+func (s *schema) dropIndex(tableName, index string) error {
+	index = s.indexName(tableName, index)
+	if !s.client.Dialect().HasIndex(tableName, index) {
+		return nil
+	}
+	return s.client.Dialect().RemoveIndex(tableName, index)
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+
+	"user-management-system/conf"
+	"user-management-system/tcpserver/shard"
+	"user-management-system/tcpserver/types"
+)
+
+func Test_MigrateLegacyTables(t *testing.T) {
+	var config conf.TCPConf
+	config.Db.Driver = DriverSQLite
+	config.Db.Path = filepath.Join(t.TempDir(), "users.db")
+	config.Shard.Hash = shard.HashLegacy
+
+	// tables of the first test/initdb.go, without schema_version
+	client, err := Open(&config.Db)
+	if err != nil {
+		t.Fatal("failed to open sqlite db:", err)
+	}
+	router, _ := shard.NewRouterFromConf(&config)
+	for i := 0; i < router.Tables(); i++ {
+		client.Table(router.TableName(i)).CreateTable(&userTableV1{})
+	}
+	client.Table(router.TableName(router.Table("username8"))).Create(&userTableV1{Username: "username8", Passwd: "x", Skey: "y"})
+	client.Close()
+
+	d, err := NewDBClient(&config)
+	if err != nil {
+		t.Fatal("failed to migrate legacy tables:", err)
+	}
+	defer d.CloseDB()
+	if err = d.CheckSchema(); err != nil {
+		t.Error("sqlite should be migrated when opened:", err)
+	}
+	if user, err := d.GetDbUserInfo("username8"); err != nil || user.Status != types.StatusActive {
+		t.Error("legacy user should be kept:", user, err)
+	}
+	if err = d.CreateDbUser(&types.User{Uid: 1, Username: "username9", Skey: "y"}); err != nil {
+		t.Error("failed to create user in migrated tables:", err)
+	}
+
+	// undo down to passwd hashes, and again up
+	if err = d.MigrateDown(2, nil); err != nil {
+		t.Fatal("failed to migrate down:", err)
+	}
+	if d.clients[0].Dialect().HasColumn(d.router.TableName(0), "totpsecret") || d.clients[0].HasTable(d.directory) {
+		t.Error("columns and tables of undone migrations should be dropped")
+	}
+	if err = d.CheckSchema(); err == nil {
+		t.Error("outdated schema should fail the check")
+	}
+	if statuses, _ := d.SchemaStatus(); len(statuses) != 1 || statuses[0].Version != 2 || len(statuses[0].Pending) != LatestVersion()-2 {
+		t.Error("unexpected status:", statuses)
+	}
+	if err = d.MigrateUp(0, nil); err != nil || d.CheckSchema() != nil {
+		t.Error("failed to migrate up again:", err)
+	}
+	if _, err = d.GetDbUserInfo("username9"); err != nil {
+		t.Error("rows should be kept over down and up:", err)
+	}
+}
+
+func Test_MigrateNewTables(t *testing.T) {
+	var config conf.TCPConf
+	config.Db.Driver = DriverSQLite
+	config.Db.Path = filepath.Join(t.TempDir(), "users.db")
+	d, err := NewDBClient(&config)
+	if err != nil {
+		t.Fatal("failed to open sqlite db:", err)
+	}
+	d.CloseDB()
+
+	// tables added to the layout are created at the current version
+	config.Shard.Tables = 24
+	if d, err = NewDBClient(&config); err != nil {
+		t.Fatal("failed to open sqlite db:", err)
+	}
+	defer d.CloseDB()
+	if err = d.CheckSchema(); err != nil {
+		t.Error("new tables should be created:", err)
+	}
+	if !d.clients[0].Dialect().HasColumn(d.router.TableName(23), "uid") {
+		t.Error("new table should have columns of later migrations")
+	}
+}
@@ -0,0 +1,288 @@
+package db
+
+import (
+	"fmt"
+)
+
+// userTableV1 user tables as test/initdb.go first created them, later columns are added by migrations
+type userTableV1 struct {
+	ID       int32  `gorm:"primary_key"`
+	Username string `gorm:"type:varchar(64);not null"`
+	Nickname string `gorm:"type:varchar(128);not null;default:''"`
+	Passwd   string `gorm:"type:varchar(32);not null"`
+	Skey     string `gorm:"type:varchar(16);not null"`
+	Headurl  string `gorm:"type:varchar(128);not null;default:''"`
+	Uptime   int64  `gorm:"type:int(64);not null;default:0"`
+}
+
+// auditTableV1 audit log as migration 10 created it
+type auditTableV1 struct {
+	ID       int64  `gorm:"primary_key"`
+	Time     int64  `gorm:"type:bigint;not null"`
+	Username string `gorm:"type:varchar(64);not null"`
+	Actor    string `gorm:"type:varchar(64);not null"`
+	Action   string `gorm:"type:varchar(32);not null"`
+	IP       string `gorm:"type:varchar(64);not null"`
+	UUID     string `gorm:"type:varchar(64);not null"`
+	Outcome  string `gorm:"type:varchar(16);not null"`
+	Code     uint32 `gorm:"type:int;not null"`
+	Detail   string `gorm:"type:varchar(256);not null"`
+}
+
+// migrations of user tables, oldest first. each step skips what's already there, so tables
+// created or upgraded by older versions of test/initdb.go can be brought under migrations
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create user tables",
+		up: func(s *schema) error {
+			return s.eachTable(func(tableName string) error {
+				if s.client.HasTable(tableName) {
+					return nil
+				}
+				if err := s.createTable(tableName, &userTableV1{}); err != nil {
+					return err
+				}
+				return s.addIndex(tableName, "username_unique", true, "username")
+			})
+		},
+		down: func(s *schema) error {
+			return s.eachTable(s.dropTable)
+		},
+	},
+	{
+		Version: 2,
+		Name:    "passwd hashes of argon2id, bcrypt and scrypt",
+		up: func(s *schema) error {
+			// lengths aren't enforced by sqlite
+			if !s.mysql() {
+				return nil
+			}
+			return s.eachTable(func(tableName string) error {
+				return s.client.Exec("ALTER TABLE " + tableName + " MODIFY passwd VARCHAR(128) NOT NULL").Error
+			})
+		},
+		down: func(s *schema) error {
+			if !s.mysql() {
+				return nil
+			}
+			return s.eachTable(func(tableName string) error {
+				var count int
+				if err := s.client.Table(tableName).Where("CHAR_LENGTH(passwd) > 32").Count(&count).Error; err != nil {
+					return err
+				}
+				if count > 0 {
+					return fmt.Errorf("%d passwd hashes don't fit in VARCHAR(32), users need to reset them", count)
+				}
+				return s.client.Exec("ALTER TABLE " + tableName + " MODIFY passwd VARCHAR(32) NOT NULL").Error
+			})
+		},
+	},
+	{
+		Version: 3,
+		Name:    "totp 2fa",
+		up: func(s *schema) error {
+			return s.eachTable(func(tableName string) error {
+				if err := s.addColumn(tableName, "totpsecret", "VARCHAR(64) NOT NULL DEFAULT ''"); err != nil {
+					return err
+				}
+				if err := s.addColumn(tableName, "totpenabled", "TINYINT(1) NOT NULL DEFAULT 0"); err != nil {
+					return err
+				}
+				return s.addColumn(tableName, "recoverycodes", "VARCHAR(1024) NOT NULL DEFAULT ''")
+			})
+		},
+		down: func(s *schema) error {
+			return s.eachTable(func(tableName string) error {
+				for _, column := range []string{"totpsecret", "totpenabled", "recoverycodes"} {
+					if err := s.dropColumn(tableName, column); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		},
+	},
+	{
+		Version: 4,
+		Name:    "account deactivation",
+		up: func(s *schema) error {
+			return s.eachTable(func(tableName string) error {
+				if err := s.addColumn(tableName, "status", "TINYINT(1) NOT NULL DEFAULT 0"); err != nil {
+					return err
+				}
+				if err := s.addColumn(tableName, "deactivatetime", "INT(64) NOT NULL DEFAULT 0"); err != nil {
+					return err
+				}
+				return s.addIndex(tableName, "status_deactivatetime", false, "status", "deactivatetime")
+			})
+		},
+		down: func(s *schema) error {
+			return s.eachTable(func(tableName string) error {
+				if err := s.dropIndex(tableName, "status_deactivatetime"); err != nil {
+					return err
+				}
+				if err := s.dropColumn(tableName, "status"); err != nil {
+					return err
+				}
+				return s.dropColumn(tableName, "deactivatetime")
+			})
+		},
+	},
+	{
+		Version: 5,
+		Name:    "global user ids",
+		up: func(s *schema) error {
+			err := s.eachTable(func(tableName string) error {
+				return s.addColumn(tableName, "uid", "BIGINT NOT NULL DEFAULT 0")
+			})
+			if err != nil || s.directory == "" {
+				return err
+			}
+			return s.createTable(s.directory, &uidEntry{})
+		},
+		down: func(s *schema) error {
+			if s.directory != "" {
+				if err := s.dropTable(s.directory); err != nil {
+					return err
+				}
+			}
+			return s.eachTable(func(tableName string) error {
+				return s.dropColumn(tableName, "uid")
+			})
+		},
+	},
+	{
+		Version: 6,
+		Name:    "reserved usernames of renamed users",
+		up: func(s *schema) error {
+			if s.renames == "" {
+				return nil
+			}
+			return s.createTable(s.renames, &renameEntry{})
+		},
+		down: func(s *schema) error {
+			if s.renames == "" {
+				return nil
+			}
+			return s.dropTable(s.renames)
+		},
+	},
+	{
+		Version: 7,
+		Name:    "profile fields",
+		up: func(s *schema) error {
+			return s.eachTable(func(tableName string) error {
+				for _, column := range profileColumns {
+					if err := s.addColumn(tableName, column[0], column[1]); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		},
+		down: func(s *schema) error {
+			return s.eachTable(func(tableName string) error {
+				for _, column := range profileColumns {
+					if err := s.dropColumn(tableName, column[0]); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		},
+	},
+	{
+		Version: 8,
+		Name:    "email verification",
+		up: func(s *schema) error {
+			return s.eachTable(func(tableName string) error {
+				return s.addColumn(tableName, "emailverified", "TINYINT(1) NOT NULL DEFAULT 0")
+			})
+		},
+		down: func(s *schema) error {
+			return s.eachTable(func(tableName string) error {
+				return s.dropColumn(tableName, "emailverified")
+			})
+		},
+	},
+	{
+		Version: 9,
+		Name:    "rbac roles",
+		up: func(s *schema) error {
+			return s.eachTable(func(tableName string) error {
+				return s.addColumn(tableName, "roles", "VARCHAR(256) NOT NULL DEFAULT ''")
+			})
+		},
+		down: func(s *schema) error {
+			return s.eachTable(func(tableName string) error {
+				return s.dropColumn(tableName, "roles")
+			})
+		},
+	},
+	{
+		Version: 10,
+		Name:    "audit log",
+		up: func(s *schema) error {
+			if s.audit == "" {
+				return nil
+			}
+			if err := s.createTable(s.audit, &auditTableV1{}); err != nil {
+				return err
+			}
+			return s.addIndex(s.audit, "username_id", false, "username", "id")
+		},
+		down: func(s *schema) error {
+			if s.audit == "" {
+				return nil
+			}
+			return s.dropTable(s.audit)
+		},
+	},
+	{
+		Version: 11,
+		Name:    "uploaded avatars",
+		up: func(s *schema) error {
+			return s.eachTable(func(tableName string) error {
+				return s.addColumn(tableName, "avatar", "VARCHAR(64) NOT NULL DEFAULT ''")
+			})
+		},
+		down: func(s *schema) error {
+			return s.eachTable(func(tableName string) error {
+				return s.dropColumn(tableName, "avatar")
+			})
+		},
+	},
+	{
+		Version: 12,
+		Name:    "uids of audit events",
+		up: func(s *schema) error {
+			if s.audit == "" {
+				return nil
+			}
+			if err := s.addColumn(s.audit, "uid", "BIGINT NOT NULL DEFAULT 0"); err != nil {
+				return err
+			}
+			return s.addIndex(s.audit, "uid_id", false, "uid", "id")
+		},
+		down: func(s *schema) error {
+			if s.audit == "" {
+				return nil
+			}
+			if err := s.dropIndex(s.audit, "uid_id"); err != nil {
+				return err
+			}
+			return s.dropColumn(s.audit, "uid")
+		},
+	},
+}
+
+// profileColumns columns of migration 7 and their definitions
+var profileColumns = [][2]string{
+	{"email", "VARCHAR(128) NOT NULL DEFAULT ''"},
+	{"phone", "VARCHAR(32) NOT NULL DEFAULT ''"},
+	{"bio", "VARCHAR(512) NOT NULL DEFAULT ''"},
+	{"locale", "VARCHAR(16) NOT NULL DEFAULT ''"},
+	{"timezone", "VARCHAR(64) NOT NULL DEFAULT ''"},
+	{"birthday", "VARCHAR(10) NOT NULL DEFAULT ''"},
+}
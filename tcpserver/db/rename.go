@@ -0,0 +1,171 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"user-management-system/tcpserver/types"
+
+	"github.com/jinzhu/gorm"
+)
+
+// DefaultRenames table of reserved usernames if shard.renames is empty
+const DefaultRenames = "userinfo_rename"
+
+// renameEntry old username reserved for the user now named username until expiretime
+type renameEntry struct {
+	Oldname    string `gorm:"type:varchar(64);primary_key"`
+	Username   string `gorm:"type:varchar(64);not null;index"`
+	Expiretime int64  `gorm:"type:bigint;not null"`
+}
+
+// GetDbRenamedUser current username of a user renamed from oldname, while oldname is reserved
+func (d *DBClient) GetDbRenamedUser(oldname string) (string, error) {
+	var entry renameEntry
+	err := d.clients[0].Table(d.renames).Where("`oldname` = ? AND `expiretime` > ?", oldname, time.Now().Unix()).First(&entry).Error
+	return entry.Username, err
+}
+
+// DelDbExpiredRenames free usernames reserved until before expiretime, return the number of freed ones
+func (d *DBClient) DelDbExpiredRenames(expiretime int64) int64 {
+	return d.clients[0].Table(d.renames).Where("`expiretime` < ?", expiretime).Delete(&renameEntry{}).RowsAffected
+}
+
+// RenameDbUser move username to the table of newname, the old name is reserved until expiretime
+// (not at all if 0) and the uid directory follows. it's one transaction if the tables are in the
+// first db instance. otherwise the reservation is committed first, then the row is moved, and both
+// are undone if moving fails. a row left under newname by an interrupted rename is taken over, so a
+// retry completes it
+func (d *DBClient) RenameDbUser(username, newname string, expiretime int64) error {
+	var row userTable
+	err := d.table(username).Where("`username` = ?", username).First(&row).Error
+	if gorm.IsRecordNotFoundError(err) && d.previous != nil && d.moveUser(username) {
+		err = d.table(username).Where("`username` = ?", username).First(&row).Error
+	}
+	if err != nil {
+		return fmt.Errorf("user(%s) not exists", username)
+	}
+	user, err := d.GetDbUserInfo(newname)
+	resumed := err == nil
+	if resumed && (user.Uid == 0 || user.Uid != row.Uid) {
+		return ErrUserExists
+	}
+	// a user may take back its own old name
+	var taken *renameEntry
+	if owner, err := d.GetDbRenamedUser(newname); err == nil {
+		if owner != username {
+			return ErrUserExists
+		}
+		var entry renameEntry
+		if d.clients[0].Table(d.renames).Where("`oldname` = ?", newname).First(&entry).Error == nil {
+			taken = &entry
+		}
+	}
+
+	oldInstance, _ := d.router.Route(username)
+	newInstance, _ := d.router.Route(newname)
+	if oldInstance == 0 && newInstance == 0 {
+		err = d.clients[0].Transaction(func(tx *gorm.DB) error {
+			if err := d.reserveRename(tx, row, newname, expiretime); err != nil {
+				return err
+			}
+			return d.moveRenamedRow(tx, tx, row, newname, resumed)
+		})
+	} else {
+		err = d.clients[0].Transaction(func(tx *gorm.DB) error {
+			return d.reserveRename(tx, row, newname, expiretime)
+		})
+		if err == nil {
+			if err = d.moveRow(oldInstance, newInstance, row, newname, resumed); err != nil {
+				if undoErr := d.unreserveRename(row, newname, taken); undoErr != nil {
+					err = fmt.Errorf("%s, and failed to undo the reservation: %s", err.Error(), undoErr.Error())
+				}
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// the old row isn't read from the previous layout again
+	if d.previous != nil {
+		d.previous.table(username).Where("`username` = ?", username).Delete(&types.User{})
+	}
+	return nil
+}
+
+// moveRow move row to newname over db instances, in one transaction if both are on the same one.
+// a new row is removed again if the old one can't be deleted
+func (d *DBClient) moveRow(oldInstance, newInstance int, row userTable, newname string, resumed bool) error {
+	if oldInstance == newInstance {
+		return d.clients[oldInstance].Transaction(func(tx *gorm.DB) error {
+			return d.moveRenamedRow(tx, tx, row, newname, resumed)
+		})
+	}
+	err := d.moveRenamedRow(d.clients[oldInstance], d.clients[newInstance], row, newname, resumed)
+	if err != nil && !resumed {
+		d.table(newname).Where("`username` = ? AND `uid` = ?", newname, row.Uid).Delete(&types.User{})
+	}
+	return err
+}
+
+// moveRenamedRow create row as newname by newDB unless resumed, then delete the old row by oldDB
+func (d *DBClient) moveRenamedRow(oldDB, newDB *gorm.DB, row userTable, newname string, resumed bool) error {
+	username, id := row.Username, row.ID
+	_, tableName := d.router.Route(newname)
+	if !resumed {
+		row.ID, row.Username, row.Uptime = 0, newname, time.Now().Unix()
+		if err := dupEntry(newDB.Table(tableName).Create(&row).Error); err != nil {
+			return err
+		}
+	}
+	_, tableName = d.router.Route(username)
+	deleted := oldDB.Table(tableName).Where("`id` = ? AND `username` = ?", id, username).Delete(&types.User{})
+	if deleted.Error != nil {
+		return deleted.Error
+	}
+	if deleted.RowsAffected != 1 {
+		return fmt.Errorf("user(%s) changed while renaming", username)
+	}
+	return nil
+}
+
+// reserveRename point the uid directory and reserved names of row to newname, and reserve its old
+// name until expiretime. done again it changes nothing
+func (d *DBClient) reserveRename(tx *gorm.DB, row userTable, newname string, expiretime int64) error {
+	username := row.Username
+	if d.directory != "" && row.Uid != 0 {
+		if err := tx.Table(d.directory).Where("`uid` = ?", row.Uid).Update("username", newname).Error; err != nil {
+			return err
+		}
+	}
+	if d.renames == "" {
+		return nil
+	}
+	// taking back an old name frees it, older names of the user lead to the new one
+	if err := tx.Table(d.renames).Where("`oldname` = ?", newname).Delete(&renameEntry{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Table(d.renames).Where("`username` = ?", username).Update("username", newname).Error; err != nil {
+		return err
+	}
+	// an expired reservation of the old name may be left by its previous owner
+	if err := tx.Table(d.renames).Where("`oldname` = ?", username).Delete(&renameEntry{}).Error; err != nil {
+		return err
+	}
+	if expiretime == 0 {
+		return nil
+	}
+	return tx.Table(d.renames).Create(&renameEntry{Oldname: username, Username: newname, Expiretime: expiretime}).Error
+}
+
+// unreserveRename undo reserveRename of row, taken is the reservation of newname it freed if any
+func (d *DBClient) unreserveRename(row userTable, newname string, taken *renameEntry) error {
+	return d.clients[0].Transaction(func(tx *gorm.DB) error {
+		back := userTable{User: types.User{Uid: row.Uid, Username: newname}}
+		if err := d.reserveRename(tx, back, row.Username, 0); err != nil || taken == nil {
+			return err
+		}
+		return tx.Table(d.renames).Create(taken).Error
+	})
+}
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ReshardCopy, ReshardVerify and ReshardCleanup modes of a Checkpoint
+const (
+	ReshardCopy    = "copy"
+	ReshardVerify  = "verify"
+	ReshardCleanup = "cleanup"
+)
+
+// ErrNotResharding shard.previous isn't enabled
+var ErrNotResharding = errors.New("shard.previous is not enabled")
+
+// Checkpoint progress of walking the previous layout, rows of previous table Table
+// with id above Lastid are left
+type Checkpoint struct {
+	Mode     string `json:"mode"`
+	Table    int    `json:"table"`
+	Lastid   int32  `json:"lastid"`
+	Rows     int64  `json:"rows"`     // rows of the previous layout walked
+	Changed  int64  `json:"changed"`  // rows copied, or deleted by cleanup
+	Problems int64  `json:"problems"` // rows missing or mismatched in the current layout
+}
+
+// ReshardDone whether all tables of the previous layout have been walked
+func (d *DBClient) ReshardDone(cp *Checkpoint) bool {
+	return d.previous == nil || cp.Table >= d.previous.router.Tables()
+}
+
+// walkPrevious call fn with the next batch of rows of the previous layout, moved is whether
+// the row belongs somewhere else now, checkpoint is advanced past the batch
+func (d *DBClient) walkPrevious(cp *Checkpoint, batch int, fn func(row userTable, moved bool) error) error {
+	if d.previous == nil {
+		return ErrNotResharding
+	}
+	if d.ReshardDone(cp) {
+		return nil
+	}
+	var rows []userTable
+	err := d.previous.tableAt(cp.Table).Where("`id` > ?", cp.Lastid).Order("`id`").Limit(batch).Find(&rows).Error
+	if err != nil {
+		return err
+	}
+	prevConf, prevTable := d.previous.place(cp.Table)
+	for _, row := range rows {
+		// rows moved in from other tables of the same place aren't part of the previous layout
+		if d.previous.router.Table(row.Username) == cp.Table {
+			dbConf, table := d.place(d.router.Table(row.Username))
+			if err = fn(row, dbConf != prevConf || table != prevTable); err != nil {
+				return err
+			}
+			cp.Rows++
+		}
+		cp.Lastid = row.ID
+	}
+	if len(rows) < batch {
+		cp.Table++
+		cp.Lastid = 0
+	}
+	return nil
+}
+
+// CopyBatch copy the next batch of rows of the previous layout to where they belong now,
+// rows that are already there are kept as they may have been updated since
+func (d *DBClient) CopyBatch(cp *Checkpoint, batch int) error {
+	return d.walkPrevious(cp, batch, func(row userTable, moved bool) error {
+		if !moved {
+			return nil
+		}
+		row.ID = 0
+		err := dupEntry(d.table(row.Username).Create(&row).Error)
+		if err == nil {
+			cp.Changed++
+		}
+		if err == ErrUserExists {
+			return nil
+		}
+		return err
+	})
+}
+
+// VerifyBatch check the next batch of rows of the previous layout are in the current one,
+// report is called with username and what's wrong for each missing or mismatched row
+func (d *DBClient) VerifyBatch(cp *Checkpoint, batch int, report func(username, problem string)) error {
+	return d.walkPrevious(cp, batch, func(row userTable, moved bool) error {
+		if !moved {
+			return nil
+		}
+		var current userTable
+		err := d.table(row.Username).Where("`username` = ?", row.Username).First(&current).Error
+		if err != nil && !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+		problem := ""
+		if err != nil {
+			problem = "missing"
+		} else if current.Uptime < row.Uptime {
+			problem = "older than the previous row"
+		} else if current.Uptime == row.Uptime {
+			// ids are per table, so they differ once copied
+			current.ID = row.ID
+			if current != row {
+				problem = "mismatched"
+			}
+		}
+		if problem != "" {
+			cp.Problems++
+			report(row.Username, problem)
+		}
+		return nil
+	})
+}
+
+// CleanupBatch delete the next batch of rows of the previous layout which are in the current one
+func (d *DBClient) CleanupBatch(cp *Checkpoint, batch int) error {
+	return d.walkPrevious(cp, batch, func(row userTable, moved bool) error {
+		if !moved {
+			return nil
+		}
+		var count int
+		if err := d.table(row.Username).Where("`username` = ?", row.Username).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			cp.Problems++
+			return nil
+		}
+		err := d.previous.tableAt(cp.Table).Where("`id` = ?", row.ID).Delete(&userTable{}).Error
+		if err == nil {
+			cp.Changed++
+		}
+		return err
+	})
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"user-management-system/conf"
+	"user-management-system/tcpserver/shard"
+	"user-management-system/tcpserver/types"
+)
+
+// newReshardClient 30 users in legacy tables, reopened with jump hash over the same tables
+func newReshardClient(t *testing.T) (*DBClient, conf.TCPConf) {
+	var config conf.TCPConf
+	config.Db.Driver = DriverSQLite
+	config.Db.Path = filepath.Join(t.TempDir(), "users.db")
+	config.Shard.Hash = shard.HashLegacy
+	legacy, err := NewDBClient(&config)
+	if err != nil {
+		t.Fatal("failed to open sqlite db:", err)
+	}
+	for i := 0; i < 30; i++ {
+		legacy.CreateDbUser(&types.User{Username: fmt.Sprintf("username%d", i), Skey: "y", Uptime: 100})
+	}
+	legacy.CloseDB()
+
+	config.Shard.Hash = shard.HashJump
+	config.Shard.Previous.Enabled = true
+	config.Shard.Previous.Hash = shard.HashLegacy
+	d, err := NewDBClient(&config)
+	if err != nil {
+		t.Fatal("failed to open sqlite db:", err)
+	}
+	t.Cleanup(func() { d.CloseDB() })
+	return d, config
+}
+
+func Test_ReshardDualRead(t *testing.T) {
+	d, _ := newReshardClient(t)
+	if user, err := d.GetDbUserInfo("username3"); err != nil || user.Username != "username3" {
+		t.Error("user should be read from the previous layout:", err)
+	}
+	if err := d.CreateDbUser(&types.User{Username: "username3"}); err != ErrUserExists {
+		t.Error("username of the previous layout should be taken, got:", err)
+	}
+	if d.UpdateDbProfile("username3", types.User{Nickname: "nick"}, []string{"nickname"}) != 1 {
+		t.Error("user of the previous layout should be updated")
+	}
+	var count int
+	d.table("username3").Where("`username` = ? AND `nickname` = ?", "username3", "nick").Count(&count)
+	if count != 1 {
+		t.Error("updated user should be moved to the current layout")
+	}
+	if d.DeleteDbUser("username3") != 1 {
+		t.Error("user not deleted")
+	}
+	if _, err := d.GetDbUserInfo("username3"); err == nil {
+		t.Error("deleted user should be gone from both layouts")
+	}
+}
+
+func Test_Reshard(t *testing.T) {
+	d, config := newReshardClient(t)
+	d.UpdateDbProfile("username5", types.User{Nickname: "nick"}, []string{"nickname"})
+
+	run := func(mode string, step func(cp *Checkpoint) error) *Checkpoint {
+		cp := &Checkpoint{Mode: mode}
+		for !d.ReshardDone(cp) {
+			if err := step(cp); err != nil {
+				t.Fatal(mode, "failed:", err)
+			}
+		}
+		return cp
+	}
+	var reported []string
+	report := func(username, problem string) { reported = append(reported, username+" "+problem) }
+
+	// nothing copied yet: every moved user but the updated one is missing
+	verify := run(ReshardVerify, func(cp *Checkpoint) error { return d.VerifyBatch(cp, 4, report) })
+	if verify.Rows != 30 || verify.Problems == 0 || int64(len(reported)) != verify.Problems {
+		t.Error("missing users should be reported:", verify, reported)
+	}
+
+	copied := run(ReshardCopy, func(cp *Checkpoint) error { return d.CopyBatch(cp, 4) })
+	if copied.Changed != verify.Problems {
+		t.Error("missing users should be copied:", copied.Changed, verify.Problems)
+	}
+	reported = nil
+	if verify = run(ReshardVerify, func(cp *Checkpoint) error { return d.VerifyBatch(cp, 4, report) }); verify.Problems != 0 {
+		t.Error("copied users should be verified:", reported)
+	}
+	if cleanup := run(ReshardCleanup, func(cp *Checkpoint) error { return d.CleanupBatch(cp, 4) }); cleanup.Changed != copied.Changed+1 {
+		t.Error("copied rows should be deleted from the previous layout:", cleanup.Changed)
+	}
+
+	// all users are found without the previous layout
+	config.Shard.Previous.Enabled = false
+	current, err := NewDBClient(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer current.CloseDB()
+	for i := 0; i < 30; i++ {
+		user, err := current.GetDbUserInfo(fmt.Sprintf("username%d", i))
+		if err != nil || (i == 5) != (user.Nickname == "nick") {
+			t.Error("user not resharded:", i, err)
+		}
+	}
+}
+
+func Test_ReshardList(t *testing.T) {
+	d, _ := newReshardClient(t)
+	for _, username := range []string{"username3", "username12", "username27"} {
+		d.UpdateDbProfile(username, types.User{Nickname: "nick"}, []string{"nickname"})
+	}
+
+	// every user is listed once, from the current layout if moved and from the previous one if not
+	seen := make(map[string]int)
+	list := func(tables int, query func(index int, after string) ([]types.User, error)) {
+		for i := 0; i < tables; i++ {
+			after := ""
+			for {
+				users, err := query(i, after)
+				if err != nil {
+					t.Fatal("failed to list users:", err)
+				}
+				for _, user := range users {
+					seen[user.Username]++
+					after = user.Username
+				}
+				if len(users) < 2 {
+					break
+				}
+			}
+		}
+	}
+	list(d.TableNum(), func(index int, after string) ([]types.User, error) {
+		return d.ListDbUsers(index, types.UserFilter{}, after, 2)
+	})
+	if seen["username3"] != 1 {
+		t.Error("moved user should be listed from the current layout")
+	}
+	list(d.PreviousTableNum(), func(index int, after string) ([]types.User, error) {
+		return d.ListDbPreviousUsers(index, types.UserFilter{}, after, 2)
+	})
+	if len(seen) != 30 {
+		t.Error("users not moved yet should be listed from the previous layout, got:", len(seen))
+	}
+	for username, n := range seen {
+		if n != 1 {
+			t.Error("user should be listed once:", username, n)
+		}
+	}
+}
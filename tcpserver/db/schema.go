@@ -0,0 +1,19 @@
+package db
+
+import (
+	"user-management-system/tcpserver/types"
+)
+
+// userTable columns of user tables, types.User plus the ones only loaded on demand
+type userTable struct {
+	types.User
+	Totpsecret    string `gorm:"type:varchar(64);not null;default:''"`
+	Totpenabled   bool   `gorm:"type:tinyint(1);not null;default:0"`
+	Recoverycodes string `gorm:"type:varchar(1024);not null;default:''"`
+}
+
+// CreateTables create user tables on every db instance and bring them up to the latest
+// schema, see migrations
+func (d *DBClient) CreateTables() error {
+	return d.MigrateUp(0, nil)
+}
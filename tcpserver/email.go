@@ -0,0 +1,132 @@
+package tcpserver
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"user-management-system/conf"
+	"user-management-system/tcpserver/mailer"
+	"user-management-system/tcpserver/types"
+	"user-management-system/utils"
+
+	log "github.com/beego/beego/v2/adapter/logs"
+)
+
+var (
+	// ErrEmailToken verification token is unknown, expired or used, or the address has changed since
+	ErrEmailToken = errors.New("email verification token is invalid or expired")
+	// ErrEmailState user has no email, or it's verified already
+	ErrEmailState = errors.New("no email to verify")
+)
+
+// verifySubject subject of verification mails
+const verifySubject = "Verify your email address"
+
+// emailPolicy verification and passwd reset mails of config
+type emailPolicy struct {
+	mailer      mailer.Mailer
+	verifyURL   string
+	expire      int
+	resetURL    string
+	resetExpire int
+	maxResets   int
+	// mails sent in background, Finalize waits for them
+	pending sync.WaitGroup
+}
+
+func newEmailPolicy(config *conf.TCPConf) (*emailPolicy, error) {
+	m, err := mailer.NewMailerFromConf(config)
+	if err != nil {
+		return nil, err
+	}
+	policy := &emailPolicy{
+		mailer:      m,
+		verifyURL:   config.Mail.Verifyurl,
+		expire:      config.Mail.Verifyexpire,
+		resetURL:    config.Mail.Reseturl,
+		resetExpire: config.Mail.Resetexpire,
+		maxResets:   config.Mail.Maxresets,
+	}
+	if policy.verifyURL == "" {
+		policy.verifyURL = "http://localhost:8080/api/v1/verifyemail?token=%s"
+	}
+	if policy.resetURL == "" {
+		policy.resetURL = "http://localhost:8080/resetpasswd?token=%s"
+	}
+	for _, link := range []string{policy.verifyURL, policy.resetURL} {
+		if !strings.Contains(link, "%s") {
+			return nil, fmt.Errorf("links of mail section should have a %%s for the token: %s", link)
+		}
+	}
+	if policy.expire <= 0 {
+		policy.expire = 86400
+	}
+	if policy.resetExpire <= 0 {
+		policy.resetExpire = 900
+	}
+	return policy, nil
+}
+
+// sendAsync send a mail in background, failures are logged
+func (p *emailPolicy) sendAsync(to, subject, body string) {
+	p.pending.Add(1)
+	go func() {
+		defer p.pending.Done()
+		if err := p.mailer.Send(to, subject, body); err != nil {
+			log.Error("failed to send mail:", subject, " with err:", err.Error())
+		}
+	}()
+}
+
+// verifyMail create a single-use verification token for the email of user, return the body of its mail
+func (a *API) verifyMail(user types.User) (string, error) {
+	if user.Email == "" || user.Emailverified {
+		return "", ErrEmailState
+	}
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	if err = a.sessions.CreateEmailToken(token, user.Username, user.Email, a.email.expire); err != nil {
+		return "", err
+	}
+	link := fmt.Sprintf(a.email.verifyURL, url.QueryEscape(token))
+	return fmt.Sprintf("Hi %s,\n\nplease open the link below within %d minutes to verify your email address:\n\n%s\n\nIf you didn't ask for it, just ignore this mail.\n",
+		user.Username, a.email.expire/60, link), nil
+}
+
+// SendVerifyEmail mail a single-use verification link to the email of user
+func (a *API) SendVerifyEmail(user types.User) error {
+	body, err := a.verifyMail(user)
+	if err != nil {
+		return err
+	}
+	return a.email.mailer.Send(user.Email, verifySubject, body)
+}
+
+// sendVerifyEmail mail the link of a new address in background so register and profile updates
+// don't wait for the mail server, failures are logged, a link can be asked for again
+func (a *API) sendVerifyEmail(user types.User) {
+	body, err := a.verifyMail(user)
+	if err != nil {
+		log.Error("failed to create verification mail to user:", user.Username, " with err:", err.Error())
+		return
+	}
+	a.email.sendAsync(user.Email, verifySubject, body)
+}
+
+// VerifyEmail mark the address of a verification token verified, return the user of it
+func (a *API) VerifyEmail(token string) (types.User, error) {
+	username, email, err := a.sessions.TakeEmailToken(token)
+	if err != nil {
+		return types.User{}, ErrEmailToken
+	}
+	if a.users.VerifyDbEmail(username, email) != 1 {
+		return types.User{}, ErrEmailToken
+	}
+	log.Info("email verified of user:", username)
+	return a.refreshUser(username, "")
+}
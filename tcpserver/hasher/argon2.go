@@ -0,0 +1,99 @@
+package hasher
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	argon2idPrefix  = "$argon2id$"
+	argon2idSaltLen = 16
+	argon2idKeyLen  = 32
+)
+
+// Argon2id argon2id algorithm, encoded as $argon2id$v=19$m=<KiB>,t=<time>,p=<threads>$<salt>$<hash>
+type Argon2id struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+}
+
+// NewArgon2id create argon2id algorithm, invalid params fall back to m=65536,t=3,p=2
+func NewArgon2id(memory, time uint32, threads uint8) *Argon2id {
+	if memory == 0 {
+		memory = 64 * 1024
+	}
+	if time == 0 {
+		time = 3
+	}
+	if threads == 0 {
+		threads = 2
+	}
+	return &Argon2id{memory: memory, time: time, threads: threads}
+}
+
+// Name algorithm name
+func (a *Argon2id) Name() string {
+	return "argon2id"
+}
+
+// Match $argon2id$ prefix
+func (a *Argon2id) Match(encoded string) bool {
+	return strings.HasPrefix(encoded, argon2idPrefix)
+}
+
+// Hash hash passwd
+func (a *Argon2id) Hash(passwd string) (string, error) {
+	salt, err := randomSalt(argon2idSaltLen)
+	if err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(passwd), salt, a.time, a.memory, a.threads, argon2idKeyLen)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version, a.memory, a.time, a.threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify check passwd
+func (a *Argon2id) Verify(passwd, skey, encoded string) (bool, error) {
+	memory, time, threads, salt, key, err := a.decode(encoded)
+	if err != nil {
+		return false, err
+	}
+	other := argon2.IDKey([]byte(passwd), salt, time, memory, threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+// Outdated params differ from configured
+func (a *Argon2id) Outdated(encoded string) bool {
+	memory, time, threads, _, _, err := a.decode(encoded)
+	return err != nil || memory != a.memory || time != a.time || threads != a.threads
+}
+
+// decode split encoded hash into params, salt and key
+func (a *Argon2id) decode(encoded string) (memory, time uint32, threads uint8, salt, key []byte, err error) {
+	parts := strings.Split(strings.TrimPrefix(encoded, argon2idPrefix), "$")
+	if len(parts) != 4 {
+		return 0, 0, 0, nil, nil, ErrUnknownHash
+	}
+	var version int
+	if _, err = fmt.Sscanf(parts[0], "v=%d", &version); err != nil {
+		return 0, 0, 0, nil, nil, err
+	}
+	if version != argon2.Version {
+		return 0, 0, 0, nil, nil, fmt.Errorf("hasher: unsupported argon2 version %d", version)
+	}
+	if _, err = fmt.Sscanf(parts[1], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return 0, 0, 0, nil, nil, err
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[2]); err != nil {
+		return 0, 0, 0, nil, nil, err
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[3]); err != nil {
+		return 0, 0, 0, nil, nil, err
+	}
+	return memory, time, threads, salt, key, nil
+}
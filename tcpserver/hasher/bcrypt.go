@@ -0,0 +1,51 @@
+package hasher
+
+import (
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Bcrypt bcrypt algorithm, encoded as $2a$<cost>$<salt+hash>
+type Bcrypt struct {
+	cost int
+}
+
+// NewBcrypt create bcrypt algorithm, invalid cost falls back to bcrypt.DefaultCost
+func NewBcrypt(cost int) *Bcrypt {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+	return &Bcrypt{cost: cost}
+}
+
+// Name algorithm name
+func (b *Bcrypt) Name() string {
+	return "bcrypt"
+}
+
+// Match $2a$, $2b$ and $2y$ prefixes
+func (b *Bcrypt) Match(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// Hash hash passwd
+func (b *Bcrypt) Hash(passwd string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(passwd), b.cost)
+	return string(hash), err
+}
+
+// Verify check passwd
+func (b *Bcrypt) Verify(passwd, skey, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(passwd))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Outdated cost differs from configured
+func (b *Bcrypt) Outdated(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != b.cost
+}
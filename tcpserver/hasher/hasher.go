@@ -0,0 +1,92 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"user-management-system/conf"
+)
+
+// ErrUnknownHash encoded hash isn't produced by any supported algorithm
+var ErrUnknownHash = errors.New("hasher: unknown hash format")
+
+// Algorithm a password hashing algorithm with a self-describing encoded format
+type Algorithm interface {
+	// Name algorithm name used in config
+	Name() string
+	// Match report whether encoded was produced by this algorithm
+	Match(encoded string) bool
+	// Hash return encoded hash of passwd
+	Hash(passwd string) (string, error)
+	// Verify check passwd against encoded hash, skey is only used by legacy hashes
+	Verify(passwd, skey, encoded string) (bool, error)
+	// Outdated report whether encoded was produced with other params than configured
+	Outdated(encoded string) bool
+}
+
+// Hasher hash new passwords with the preferred algorithm and verify all supported ones
+type Hasher struct {
+	preferred  Algorithm
+	algorithms []Algorithm
+}
+
+// NewHasher create hasher from config, preferred algorithm is Passwd.Algorithm
+func NewHasher(config *conf.TCPConf) (*Hasher, error) {
+	cfg := config.Passwd
+	algorithms := []Algorithm{
+		NewBcrypt(cfg.Bcrypt.Cost),
+		NewScrypt(cfg.Scrypt.Ln, cfg.Scrypt.R, cfg.Scrypt.P),
+		NewArgon2id(cfg.Argon2id.Memory, cfg.Argon2id.Time, cfg.Argon2id.Threads),
+		Legacy{},
+	}
+	for _, algorithm := range algorithms {
+		if algorithm.Name() == cfg.Algorithm {
+			return New(algorithm, algorithms...)
+		}
+	}
+	return nil, fmt.Errorf("hasher: unsupported algorithm '%s'", cfg.Algorithm)
+}
+
+// New create hasher which hashes with preferred and verifies with preferred and others
+func New(preferred Algorithm, others ...Algorithm) (*Hasher, error) {
+	if _, ok := preferred.(Legacy); ok {
+		return nil, errors.New("hasher: legacy md5 can't be used for new passwords")
+	}
+	algorithms := []Algorithm{preferred}
+	for _, algorithm := range others {
+		if algorithm.Name() != preferred.Name() {
+			algorithms = append(algorithms, algorithm)
+		}
+	}
+	return &Hasher{preferred: preferred, algorithms: algorithms}, nil
+}
+
+// Hash hash passwd with preferred algorithm
+func (h *Hasher) Hash(passwd string) (string, error) {
+	return h.preferred.Hash(passwd)
+}
+
+// Verify check passwd against encoded, rehash is true when passwd matched but encoded
+// should be replaced by a new hash (other algorithm or outdated params)
+func (h *Hasher) Verify(passwd, skey, encoded string) (ok, rehash bool, err error) {
+	for _, algorithm := range h.algorithms {
+		if !algorithm.Match(encoded) {
+			continue
+		}
+		ok, err = algorithm.Verify(passwd, skey, encoded)
+		if !ok || err != nil {
+			return false, false, err
+		}
+		rehash = algorithm.Name() != h.preferred.Name() || algorithm.Outdated(encoded)
+		return true, rehash, nil
+	}
+	return false, false, ErrUnknownHash
+}
+
+// randomSalt return n random bytes
+func randomSalt(n int) ([]byte, error) {
+	salt := make([]byte, n)
+	_, err := rand.Read(salt)
+	return salt, err
+}
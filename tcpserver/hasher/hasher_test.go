@@ -0,0 +1,82 @@
+package hasher
+
+import (
+	"testing"
+
+	"user-management-system/utils"
+)
+
+func Test_Algorithms(t *testing.T) {
+	algorithms := []Algorithm{NewBcrypt(4), NewScrypt(10, 8, 1), NewArgon2id(1024, 1, 1)}
+	for _, algorithm := range algorithms {
+		encoded, err := algorithm.Hash("123456")
+		if err != nil {
+			t.Error(algorithm.Name(), " hash failed:", err.Error())
+			continue
+		}
+		if !algorithm.Match(encoded) {
+			t.Error(algorithm.Name(), " should match its own hash:", encoded)
+		}
+		if ok, err := algorithm.Verify("123456", "", encoded); !ok || err != nil {
+			t.Error(algorithm.Name(), " verify right passwd failed:", err)
+		}
+		if ok, _ := algorithm.Verify("1234567", "", encoded); ok {
+			t.Error(algorithm.Name(), " verify wrong passwd succ")
+		}
+		if algorithm.Outdated(encoded) {
+			t.Error(algorithm.Name(), " hash with same params should not be outdated")
+		}
+	}
+}
+
+func Test_Rehash(t *testing.T) {
+	h, err := New(NewArgon2id(1024, 1, 1), NewBcrypt(4), Legacy{})
+	if err != nil {
+		t.Fatal("new hasher failed:", err.Error())
+	}
+
+	// legacy md5 row created by test/initdb.go
+	skey := "dd987c"
+	legacy := utils.Md5String(utils.Md5String("123456") + skey)
+	ok, rehash, err := h.Verify("123456", skey, legacy)
+	if !ok || !rehash || err != nil {
+		t.Error("legacy hash should verify and need rehash, ok:", ok, " rehash:", rehash, " err:", err)
+	}
+
+	// other algorithm
+	bcryptHash, _ := NewBcrypt(4).Hash("123456")
+	ok, rehash, _ = h.Verify("123456", "", bcryptHash)
+	if !ok || !rehash {
+		t.Error("bcrypt hash should verify and need rehash")
+	}
+
+	// outdated params
+	weakHash, _ := NewArgon2id(512, 1, 1).Hash("123456")
+	ok, rehash, _ = h.Verify("123456", "", weakHash)
+	if !ok || !rehash {
+		t.Error("outdated argon2id hash should verify and need rehash")
+	}
+
+	// up to date
+	hash, _ := h.Hash("123456")
+	ok, rehash, _ = h.Verify("123456", "", hash)
+	if !ok || rehash {
+		t.Error("preferred hash should verify without rehash")
+	}
+
+	// wrong passwd never asks for rehash
+	ok, rehash, _ = h.Verify("654321", skey, legacy)
+	if ok || rehash {
+		t.Error("wrong passwd should fail without rehash")
+	}
+
+	// unknown format
+	if _, _, err = h.Verify("123456", "", "plain"); err != ErrUnknownHash {
+		t.Error("unknown format should return ErrUnknownHash, got:", err)
+	}
+
+	// legacy can't hash new passwds
+	if _, err = New(Legacy{}); err == nil {
+		t.Error("legacy should not be a preferred algorithm")
+	}
+}
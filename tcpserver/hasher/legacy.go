@@ -0,0 +1,43 @@
+package hasher
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+
+	"user-management-system/utils"
+)
+
+// Legacy md5(md5(passwd) + skey) stored as 32 hex chars, only used to verify
+// rows created before hashing was configurable so they can be rehashed on login
+type Legacy struct{}
+
+// Name algorithm name
+func (Legacy) Name() string {
+	return "md5"
+}
+
+// Match 32 hex chars
+func (Legacy) Match(encoded string) bool {
+	if len(encoded) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(encoded)
+	return err == nil
+}
+
+// Hash legacy hashes can't be created any more
+func (Legacy) Hash(passwd string) (string, error) {
+	return "", errors.New("hasher: legacy md5 can't be used for new passwords")
+}
+
+// Verify check passwd, the md5 of passwd was done by httpserver before
+func (Legacy) Verify(passwd, skey, encoded string) (bool, error) {
+	hash := utils.Md5String(utils.Md5String(passwd) + skey)
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(encoded)) == 1, nil
+}
+
+// Outdated legacy hashes are always outdated
+func (Legacy) Outdated(encoded string) bool {
+	return true
+}
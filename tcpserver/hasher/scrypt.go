@@ -0,0 +1,99 @@
+package hasher
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	scryptPrefix  = "$scrypt$"
+	scryptSaltLen = 16
+	scryptKeyLen  = 32
+)
+
+// Scrypt scrypt algorithm, encoded as $scrypt$ln=<log2 N>,r=<r>,p=<p>$<salt>$<hash>
+type Scrypt struct {
+	ln, r, p int
+}
+
+// NewScrypt create scrypt algorithm, invalid params fall back to ln=15,r=8,p=1
+func NewScrypt(ln, r, p int) *Scrypt {
+	if ln <= 0 || ln > 30 {
+		ln = 15
+	}
+	if r <= 0 {
+		r = 8
+	}
+	if p <= 0 {
+		p = 1
+	}
+	return &Scrypt{ln: ln, r: r, p: p}
+}
+
+// Name algorithm name
+func (s *Scrypt) Name() string {
+	return "scrypt"
+}
+
+// Match $scrypt$ prefix
+func (s *Scrypt) Match(encoded string) bool {
+	return strings.HasPrefix(encoded, scryptPrefix)
+}
+
+// Hash hash passwd
+func (s *Scrypt) Hash(passwd string) (string, error) {
+	salt, err := randomSalt(scryptSaltLen)
+	if err != nil {
+		return "", err
+	}
+	key, err := scrypt.Key([]byte(passwd), salt, 1<<uint(s.ln), s.r, s.p, scryptKeyLen)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%sln=%d,r=%d,p=%d$%s$%s", scryptPrefix, s.ln, s.r, s.p,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify check passwd
+func (s *Scrypt) Verify(passwd, skey, encoded string) (bool, error) {
+	ln, r, p, salt, key, err := s.decode(encoded)
+	if err != nil {
+		return false, err
+	}
+	other, err := scrypt.Key([]byte(passwd), salt, 1<<uint(ln), r, p, len(key))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+// Outdated params differ from configured
+func (s *Scrypt) Outdated(encoded string) bool {
+	ln, r, p, _, _, err := s.decode(encoded)
+	return err != nil || ln != s.ln || r != s.r || p != s.p
+}
+
+// decode split encoded hash into params, salt and key
+func (s *Scrypt) decode(encoded string) (ln, r, p int, salt, key []byte, err error) {
+	parts := strings.Split(strings.TrimPrefix(encoded, scryptPrefix), "$")
+	if len(parts) != 3 {
+		return 0, 0, 0, nil, nil, ErrUnknownHash
+	}
+	if _, err = fmt.Sscanf(parts[0], "ln=%d,r=%d,p=%d", &ln, &r, &p); err != nil {
+		return 0, 0, 0, nil, nil, err
+	}
+	if ln <= 0 || ln > 30 {
+		return 0, 0, 0, nil, nil, ErrUnknownHash
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[1]); err != nil {
+		return 0, 0, 0, nil, nil, err
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[2]); err != nil {
+		return 0, 0, 0, nil, nil, err
+	}
+	return ln, r, p, salt, key, nil
+}
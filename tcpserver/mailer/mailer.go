@@ -0,0 +1,150 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"user-management-system/conf"
+
+	log "github.com/beego/beego/v2/adapter/logs"
+)
+
+const (
+	// SenderSMTP mails are sent through an smtp server
+	SenderSMTP = "smtp"
+	// SenderFile mails are appended to a file, for local dev and tests
+	SenderFile = "file"
+	// SenderLog mails are written to the log, for local dev and tests
+	SenderLog = "log"
+)
+
+// Mailer sends plain text mails
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// NewMailerFromConf mailer of Mail.Sender, smtp by default. file and log senders keep the
+// verification and passwd reset links of every user in plain text, they're warned about
+func NewMailerFromConf(config *conf.TCPConf) (Mailer, error) {
+	cfg := config.Mail
+	switch cfg.Sender {
+	case SenderFile:
+		if cfg.File == "" {
+			return nil, fmt.Errorf("mailer: file needs mail.file")
+		}
+		log.Warn("!!! mail sender is file, verification and passwd reset links are written to ", cfg.File, ", use smtp in production !!!")
+		return NewFileMailer(cfg.From, cfg.File), nil
+	case SenderLog:
+		log.Warn("!!! mail sender is log, verification and passwd reset links are written to the log, use smtp in production !!!")
+		return &LogMailer{}, nil
+	case "", SenderSMTP:
+		if cfg.SMTP.Addr == "" || cfg.From == "" {
+			return nil, fmt.Errorf("mailer: smtp needs mail.smtp.addr and mail.from")
+		}
+		return NewSMTPMailer(cfg.SMTP.Addr, cfg.SMTP.Username, cfg.SMTP.Passwd, cfg.From), nil
+	}
+	return nil, fmt.Errorf("mailer: unsupported sender '%s'", cfg.Sender)
+}
+
+// message mail of headers and body, CRLF line endings
+func message(from, to, subject, body string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+// checkHeader header values can't carry more headers
+func checkHeader(values ...string) error {
+	for _, value := range values {
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("mailer: line break in header %q", value)
+		}
+	}
+	return nil
+}
+
+// SMTPMailer sends mails through an smtp server, with STARTTLS if the server offers it
+type SMTPMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPMailer mailer of smtp server addr (host:port), no auth if username is empty
+func NewSMTPMailer(addr, username, passwd, from string) *SMTPMailer {
+	m := &SMTPMailer{addr: addr, from: from}
+	if username != "" {
+		host := addr
+		if i := strings.LastIndex(addr, ":"); i >= 0 {
+			host = addr[:i]
+		}
+		m.auth = smtp.PlainAuth("", username, passwd, host)
+	}
+	return m
+}
+
+// Send send a mail to address to
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	if err := checkHeader(to, subject); err != nil {
+		return err
+	}
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{to}, message(m.from, to, subject, body))
+}
+
+// FileMailer appends mails to a file
+type FileMailer struct {
+	mu   sync.Mutex
+	from string
+	path string
+}
+
+// NewFileMailer mailer of file path
+func NewFileMailer(from, path string) *FileMailer {
+	return &FileMailer{from: from, path: path}
+}
+
+// Path file mails are appended to
+func (m *FileMailer) Path() string {
+	return m.path
+}
+
+// Send write a mail to address to
+func (m *FileMailer) Send(to, subject, body string) error {
+	if err := checkHeader(to, subject); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(message(m.from, to, subject, body))
+	return err
+}
+
+// LogMailer writes mails to the log at info level
+type LogMailer struct{}
+
+// Send log a mail to address to
+func (m *LogMailer) Send(to, subject, body string) error {
+	if err := checkHeader(to, subject); err != nil {
+		return err
+	}
+	log.Info("mail to:", to, " subject:", subject, " body:", body)
+	return nil
+}
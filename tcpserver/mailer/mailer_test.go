@@ -0,0 +1,59 @@
+package mailer
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"user-management-system/conf"
+)
+
+func Test_FileMailer(t *testing.T) {
+	var config conf.TCPConf
+	config.Mail.Sender, config.Mail.From = SenderFile, "no-reply@example.com"
+	config.Mail.File = filepath.Join(t.TempDir(), "mails.txt")
+	m, err := NewMailerFromConf(&config)
+	if err != nil {
+		t.Fatal("failed to new file mailer:", err)
+	}
+	if err = m.Send("alice@example.com", "hello", "line 1\nline 2"); err != nil {
+		t.Fatal("failed to send:", err)
+	}
+	m.Send("bob@example.com", "hello again", "bye")
+
+	b, _ := ioutil.ReadFile(config.Mail.File)
+	mails := string(b)
+	for _, want := range []string{"From: no-reply@example.com\r\n", "To: alice@example.com\r\n", "Subject: hello\r\n", "\r\n\r\nline 1\r\nline 2\r\n", "To: bob@example.com\r\n"} {
+		if !strings.Contains(mails, want) {
+			t.Errorf("mails should contain %q:\n%s", want, mails)
+		}
+	}
+	if err = m.Send("eve@example.com\r\nBcc: all@example.com", "hi", ""); err == nil {
+		t.Error("header injection should be rejected")
+	}
+}
+
+func Test_NewMailerFromConf(t *testing.T) {
+	var config conf.TCPConf
+	if _, err := NewMailerFromConf(&config); err == nil {
+		t.Error("smtp is the default, it should fail without addr")
+	}
+	config.Mail.Sender = SenderFile
+	if _, err := NewMailerFromConf(&config); err == nil {
+		t.Error("file without a path should fail")
+	}
+	config.Mail.Sender = SenderSMTP
+	config.Mail.SMTP.Addr, config.Mail.From = "smtp.example.com:587", "no-reply@example.com"
+	if m, err := NewMailerFromConf(&config); err != nil || m.(*SMTPMailer).auth != nil {
+		t.Error("smtp without username should have no auth:", err)
+	}
+	config.Mail.Sender = SenderLog
+	if m, err := NewMailerFromConf(&config); err != nil || m.Send("eve@example.com\nBcc: all@example.com", "hi", "") == nil {
+		t.Error("log mailer should reject header injection too:", err)
+	}
+	config.Mail.Sender = "pigeon"
+	if _, err := NewMailerFromConf(&config); err == nil {
+		t.Error("unknown sender should fail")
+	}
+}
@@ -0,0 +1,71 @@
+package tcpserver
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"user-management-system/tcpserver/consts"
+	"user-management-system/tcpserver/types"
+	"user-management-system/utils"
+
+	log "github.com/beego/beego/v2/adapter/logs"
+)
+
+// ErrResetToken passwd reset token is unknown, expired or used
+var ErrResetToken = errors.New("passwd reset token is invalid or expired")
+
+// RequestPasswdReset mail a one-time reset link to the verified email of username. nothing tells
+// callers whether it's been sent: unknown users, users without a verified email and requests over
+// the limit are only logged, and the mail is sent in background
+func (a *API) RequestPasswdReset(username string) {
+	user, err := a.GetUserInfo(username)
+	if err != nil || user.Status != types.StatusActive || user.Email == "" || !user.Emailverified {
+		log.Info("passwd reset not sent to user:", username, ", no active user of verified email")
+		return
+	}
+	if a.email.maxResets > 0 {
+		requests, err := a.sessions.IncrLoginFailures(consts.LoginKindReset, username, a.email.resetExpire)
+		if err != nil {
+			log.Error("failed to count passwd resets of user:", username, " with err:", err.Error())
+			return
+		}
+		if requests > a.email.maxResets {
+			log.Info("passwd reset not sent to user:", username, ", too many requests:", requests)
+			return
+		}
+	}
+
+	token, err := utils.GenerateToken()
+	if err == nil {
+		err = a.sessions.CreateResetToken(token, username, user.Skey, a.email.resetExpire)
+	}
+	if err != nil {
+		log.Error("failed to create passwd reset token of user:", username, " with err:", err.Error())
+		return
+	}
+	link := fmt.Sprintf(a.email.resetURL, url.QueryEscape(token))
+	body := fmt.Sprintf("Hi %s,\n\nopen the link below within %d minutes to set a new password, it works once:\n\n%s\n\nAll your sessions will be logged out. If you didn't ask for it, just ignore this mail.\n",
+		username, a.email.resetExpire/60, link)
+	a.email.sendAsync(user.Email, "Reset your password", body)
+	log.Info("passwd reset sent to user:", username)
+}
+
+// ResetPasswd set passwd of the user of a reset token, all its sessions are revoked and its login lock lifted.
+// tokens are bound to the skey of their time, so a passwd changed since voids them
+func (a *API) ResetPasswd(token, passwd string) (string, error) {
+	username, skey, err := a.sessions.TakeResetToken(token)
+	if err != nil {
+		return "", ErrResetToken
+	}
+	user, err := a.users.GetDbUserInfo(username)
+	if err != nil || user.Skey != skey || user.Status != types.StatusActive {
+		return "", ErrResetToken
+	}
+	if err = a.ChangePasswd(username, passwd, ""); err != nil {
+		return username, err
+	}
+	a.sessions.DelLoginFailures(consts.LoginKindUser, username)
+	a.sessions.DelLoginFailures(consts.LoginKindReset, username)
+	return username, nil
+}
@@ -0,0 +1,158 @@
+package tcpserver
+
+import (
+	"errors"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"user-management-system/tcpserver/types"
+
+	// timezones are checked against the embedded tz database, hosts may not have one
+	_ "time/tzdata"
+)
+
+var (
+	// ErrEmptyMask UpdateProfile was asked to update no field
+	ErrEmptyMask = errors.New("field mask is empty")
+
+	// E.164, e.g. +8613800138000
+	phoneRegexp = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+	// BCP 47 language, optional script and region, e.g. en, zh-Hans-CN, es-419
+	localeRegexp = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z][a-z]{3})?(-([A-Z]{2}|[0-9]{3}))?$`)
+)
+
+// ProfileError value of a field is invalid, or the field can't be updated
+type ProfileError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ProfileError) Error() string {
+	return e.Field + ": " + e.Reason
+}
+
+// profileRules validation of each of types.ProfileFields, an empty value clears the field and is always valid
+var profileRules = map[string]func(value string) string{
+	"nickname": func(value string) string {
+		return checkText(value, 128, false)
+	},
+	"headurl": func(value string) string {
+		if len(value) > 128 {
+			return "longer than 128 bytes"
+		}
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "not an http(s) url"
+		}
+		return ""
+	},
+	"email": func(value string) string {
+		if len(value) > 128 {
+			return "longer than 128 bytes"
+		}
+		addr, err := mail.ParseAddress(value)
+		if err != nil || addr.Name != "" || addr.Address != value {
+			return "not an email address"
+		}
+		return ""
+	},
+	"phone": func(value string) string {
+		if !phoneRegexp.MatchString(value) {
+			return "not an E.164 phone number"
+		}
+		return ""
+	},
+	"bio": func(value string) string {
+		return checkText(value, 512, true)
+	},
+	"locale": func(value string) string {
+		if !localeRegexp.MatchString(value) {
+			return "not a language tag like en or zh-Hans-CN"
+		}
+		return ""
+	},
+	"timezone": func(value string) string {
+		if value == "Local" {
+			return "not an IANA timezone"
+		}
+		if _, err := time.LoadLocation(value); err != nil {
+			return "not an IANA timezone"
+		}
+		return ""
+	},
+	"birthday": func(value string) string {
+		birthday, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return "not a YYYY-MM-DD date"
+		}
+		if birthday.Year() < 1900 || birthday.After(time.Now()) {
+			return "out of range"
+		}
+		return ""
+	},
+}
+
+// checkText value is at most maxLen runes of printable text, newlines are allowed if multiline
+func checkText(value string, maxLen int, multiline bool) string {
+	if !utf8.ValidString(value) {
+		return "not utf-8"
+	}
+	if utf8.RuneCountInString(value) > maxLen {
+		return "longer than " + strconv.Itoa(maxLen) + " characters"
+	}
+	for _, r := range value {
+		if unicode.IsControl(r) && !(multiline && r == '\n') {
+			return "control characters not allowed"
+		}
+	}
+	return ""
+}
+
+// CheckProfile validate the fields of mask in profile
+func CheckProfile(profile types.User, mask []string) error {
+	if len(mask) == 0 {
+		return ErrEmptyMask
+	}
+	for _, field := range mask {
+		check, ok := profileRules[field]
+		if !ok {
+			return &ProfileError{Field: field, Reason: "unknown field"}
+		}
+		if value, _ := profile.Profile(field); value != "" {
+			if reason := check(value); reason != "" {
+				return &ProfileError{Field: field, Reason: reason}
+			}
+		}
+	}
+	return nil
+}
+
+// UpdateProfile update fields of mask to their values in profile, return the updated user
+func (a *API) UpdateProfile(username, token string, profile types.User, mask []string) (types.User, error) {
+	if err := CheckProfile(profile, mask); err != nil {
+		return types.User{}, err
+	}
+	if a.users.UpdateDbProfile(username, profile, mask) != 1 {
+		return types.User{}, errors.New("failed to update profile of " + username)
+	}
+	user, err := a.refreshUser(username, a.SessionToken(token))
+	if err == nil && !user.Emailverified && user.Email != "" && inMask(mask, "email") {
+		a.sendVerifyEmail(user)
+	}
+	return user, err
+}
+
+// inMask whether field is one of mask
+func inMask(mask []string, field string) bool {
+	for _, f := range mask {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package rbac
+
+import (
+	"fmt"
+	"regexp"
+
+	"user-management-system/conf"
+)
+
+const (
+	// RoleAdmin role of Rbac.Admins, granted every permission unless configured otherwise
+	RoleAdmin = "admin"
+
+	// PermListUsers browse all users
+	PermListUsers = "users.list"
+	// PermLockUsers lock and unlock accounts
+	PermLockUsers = "users.lock"
+	// PermEditUsers edit profile of any user
+	PermEditUsers = "users.edit"
+	// PermRevokeSessions force logout of any user
+	PermRevokeSessions = "sessions.revoke"
+	// PermManageRoles grant and revoke roles
+	PermManageRoles = "roles.manage"
+	// PermReadAudit query audit log of any user
+	PermReadAudit = "audit.read"
+
+	// allPerms grants every permission in config
+	allPerms = "*"
+)
+
+// Permissions every permission of admin rpcs
+var Permissions = []string{PermListUsers, PermLockUsers, PermEditUsers, PermRevokeSessions, PermManageRoles, PermReadAudit}
+
+var roleRegexp = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,31}$`)
+
+// Policy permissions of each role, and the users who are admins whatever roles they have.
+// admins are uids, a username may be freed and taken by someone else
+type Policy struct {
+	roles  map[string]map[string]bool
+	admins map[int64]bool
+}
+
+// NewPolicy policy of roles to their permissions, "*" for all of them
+func NewPolicy(roles map[string][]string, admins []int64) (*Policy, error) {
+	known := make(map[string]bool, len(Permissions))
+	for _, perm := range Permissions {
+		known[perm] = true
+	}
+	p := &Policy{roles: make(map[string]map[string]bool, len(roles)), admins: make(map[int64]bool, len(admins))}
+	for role, perms := range roles {
+		if !roleRegexp.MatchString(role) {
+			return nil, fmt.Errorf("rbac: invalid role name '%s'", role)
+		}
+		p.roles[role] = make(map[string]bool, len(perms))
+		for _, perm := range perms {
+			if perm == allPerms {
+				p.roles[role] = known
+				break
+			}
+			if !known[perm] {
+				return nil, fmt.Errorf("rbac: unknown permission '%s' of role '%s'", perm, role)
+			}
+			p.roles[role][perm] = true
+		}
+	}
+	if len(admins) > 0 && p.roles[RoleAdmin] == nil {
+		return nil, fmt.Errorf("rbac: admins are set without role '%s'", RoleAdmin)
+	}
+	for _, uid := range admins {
+		if uid <= 0 {
+			return nil, fmt.Errorf("rbac: invalid admin uid %d", uid)
+		}
+		p.admins[uid] = true
+	}
+	return p, nil
+}
+
+// NewPolicyFromConf policy of Rbac, admin with every permission is the only role by default
+func NewPolicyFromConf(config *conf.TCPConf) (*Policy, error) {
+	roles := config.Rbac.Roles
+	if len(roles) == 0 {
+		roles = map[string][]string{RoleAdmin: {allPerms}}
+	}
+	return NewPolicy(roles, config.Rbac.Admins)
+}
+
+// Known whether role is configured, users can only be granted known roles
+func (p *Policy) Known(role string) bool {
+	_, ok := p.roles[role]
+	return ok
+}
+
+// Allowed whether user uid of roles has perm, roles no longer configured grant nothing
+func (p *Policy) Allowed(uid int64, roles []string, perm string) bool {
+	if p.admins[uid] && p.roles[RoleAdmin][perm] {
+		return true
+	}
+	for _, role := range roles {
+		if p.roles[role][perm] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package rbac
+
+import (
+	"testing"
+
+	"user-management-system/conf"
+)
+
+func Test_Policy(t *testing.T) {
+	p, err := NewPolicy(map[string][]string{
+		RoleAdmin: {"*"},
+		"support": {PermListUsers, PermLockUsers},
+	}, []int64{1})
+	if err != nil {
+		t.Fatal("failed to new policy:", err)
+	}
+	if !p.Allowed(2, []string{"support"}, PermLockUsers) || p.Allowed(2, []string{"support"}, PermManageRoles) {
+		t.Error("support should only have its own permissions")
+	}
+	if !p.Allowed(2, []string{"support", RoleAdmin}, PermManageRoles) {
+		t.Error("permissions of all roles should be granted")
+	}
+	if !p.Allowed(1, nil, PermManageRoles) {
+		t.Error("admins of config should be admin without roles")
+	}
+	if p.Allowed(0, nil, PermManageRoles) {
+		t.Error("users without uid should not be admins of config")
+	}
+	if p.Allowed(3, []string{"retired"}, PermListUsers) || p.Known("retired") || !p.Known("support") {
+		t.Error("unknown roles should grant nothing")
+	}
+
+	if _, err = NewPolicy(map[string][]string{"support": {"users.fly"}}, nil); err == nil {
+		t.Error("unknown permission should fail")
+	}
+	if _, err = NewPolicy(map[string][]string{"Bad Role": {PermListUsers}}, nil); err == nil {
+		t.Error("invalid role name should fail")
+	}
+	if _, err = NewPolicy(map[string][]string{"support": {PermListUsers}}, []int64{1}); err == nil {
+		t.Error("admins without admin role should fail")
+	}
+	if _, err = NewPolicy(map[string][]string{RoleAdmin: {"*"}}, []int64{0}); err == nil {
+		t.Error("admin without uid should fail")
+	}
+}
+
+func Test_NewPolicyFromConf(t *testing.T) {
+	var config conf.TCPConf
+	p, err := NewPolicyFromConf(&config)
+	if err != nil {
+		t.Fatal("failed to new default policy:", err)
+	}
+	for _, perm := range Permissions {
+		if !p.Allowed(2, []string{RoleAdmin}, perm) {
+			t.Error("admin should have every permission by default:", perm)
+		}
+	}
+	if p.Allowed(2, nil, PermListUsers) {
+		t.Error("users without roles should have no permission")
+	}
+}
@@ -0,0 +1,49 @@
+package tcpserver
+
+import (
+	"errors"
+	"time"
+
+	"user-management-system/tcpserver/types"
+
+	log "github.com/beego/beego/v2/adapter/logs"
+)
+
+// ErrSameUsername new username is the current one
+var ErrSameUsername = errors.New("new username is the current one")
+
+// ChangeUsername rename username to newname, the old name stays reserved for the user and leads
+// to newname for renamereserve seconds. cached userinfo and sessions move to newname
+func (a *API) ChangeUsername(username, newname string) (types.User, error) {
+	if newname == username {
+		return types.User{}, ErrSameUsername
+	}
+	var expiretime int64
+	if a.account.renameReserve > 0 {
+		expiretime = time.Now().Unix() + a.account.renameReserve
+	}
+	if err := a.users.RenameDbUser(username, newname, expiretime); err != nil {
+		return types.User{}, err
+	}
+	a.sessions.DelUserCacheInfo(username)
+
+	user, err := a.GetUserInfo(newname)
+	if err != nil {
+		// sessions keep the old userinfo and fail to auth, they're revoked instead
+		log.Error("failed to get renamed user:", newname, " with err:", err.Error())
+		a.sessions.DelUserTokens(username, "")
+		return types.User{Username: newname}, nil
+	}
+	moved, err := a.sessions.RenameUserSessions(username, user)
+	if err != nil {
+		log.Error("failed to move sessions of user:", username, " with err:", err.Error())
+	}
+	log.Info("username changed:", username, " to:", newname, ", moved sessions:", moved)
+	return user, nil
+}
+
+// RenamedUser current username of a user renamed from oldname, while oldname is reserved
+func (a *API) RenamedUser(oldname string) (string, bool) {
+	username, err := a.users.GetDbRenamedUser(oldname)
+	return username, err == nil && username != ""
+}
@@ -0,0 +1,117 @@
+package shard
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"user-management-system/conf"
+)
+
+const (
+	// HashJump jump consistent hash of fnv-1a of username, only ~1/n of users move when a table is added
+	HashJump = "jump"
+	// HashLegacy sum of runes of username, how tables of older versions were filled
+	HashLegacy = "legacy"
+
+	// DefaultTables number of tables of older versions
+	DefaultTables = 20
+	// DefaultTableName table name template of older versions
+	DefaultTableName = "userinfo_tab_%d"
+)
+
+// Router route usernames to user tables, tables are split over db instances in order
+type Router struct {
+	hash      string
+	tables    int
+	tableName string
+	instances int
+}
+
+// NewRouter create a router of tables named by tableName over instances db instances,
+// zero values fall back to the layout of older versions
+func NewRouter(hash string, tables int, tableName string, instances int) (*Router, error) {
+	if hash == "" {
+		hash = HashLegacy
+	}
+	if hash != HashJump && hash != HashLegacy {
+		return nil, fmt.Errorf("shard: unknown hash '%s'", hash)
+	}
+	if tables <= 0 {
+		tables = DefaultTables
+	}
+	if tableName == "" {
+		tableName = DefaultTableName
+	}
+	if strings.Count(tableName, "%") != 1 || strings.Count(tableName, "%d") != 1 {
+		return nil, fmt.Errorf("shard: table name '%s' must have a single %%d", tableName)
+	}
+	if instances <= 0 {
+		instances = 1
+	}
+	if instances > tables {
+		return nil, fmt.Errorf("shard: %d db instances for %d tables", instances, tables)
+	}
+	return &Router{hash: hash, tables: tables, tableName: tableName, instances: instances}, nil
+}
+
+// NewRouterFromConf create the router of the shard section, the db section is the first instance
+func NewRouterFromConf(config *conf.TCPConf) (*Router, error) {
+	return NewRouter(config.Shard.Hash, config.Shard.Tables, config.Shard.Tablename, 1+len(config.Shard.Instances))
+}
+
+// Tables number of user tables
+func (r *Router) Tables() int {
+	return r.tables
+}
+
+// Instances number of db instances
+func (r *Router) Instances() int {
+	return r.instances
+}
+
+// Table index of the table of username
+func (r *Router) Table(username string) int {
+	if r.hash == HashLegacy {
+		return Legacy(username, r.tables)
+	}
+	h := fnv.New64a()
+	h.Write([]byte(username))
+	return Jump(h.Sum64(), r.tables)
+}
+
+// TableName name of table index
+func (r *Router) TableName(index int) string {
+	return fmt.Sprintf(r.tableName, index)
+}
+
+// Instance db instance of table index, each instance holds a contiguous range of tables
+func (r *Router) Instance(index int) int {
+	return index * r.instances / r.tables
+}
+
+// Route db instance and table name of username
+func (r *Router) Route(username string) (int, string) {
+	index := r.Table(username)
+	return r.Instance(index), r.TableName(index)
+}
+
+// Jump consistent hash of key to [0, buckets), see https://arxiv.org/abs/1406.2294
+func Jump(key uint64, buckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
+
+// Legacy sum of runes of username mod tables
+func Legacy(username string, tables int) int {
+	var value int
+	for _, c := range []rune(username) {
+		value = value + int(c)
+	}
+	return value % tables
+}
@@ -0,0 +1,75 @@
+package shard
+
+import (
+	"fmt"
+	"testing"
+)
+
+func Test_Legacy(t *testing.T) {
+	r, _ := NewRouter("", 0, "", 0)
+	// what utils.GetTableName used to return
+	if name := r.TableName(r.Table("username8")); name != "userinfo_tab_0" {
+		t.Error("legacy route changed:", name)
+	}
+	if r.Table("ab") != r.Table("ba") {
+		t.Error("anagrams should collide in legacy mode")
+	}
+}
+
+func Test_Jump(t *testing.T) {
+	r, err := NewRouter(HashJump, 20, "users_%d", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := r.TableName(7); name != "users_7" {
+		t.Error("unexpected table name:", name)
+	}
+
+	// evenly spread, and only users of the new table move when one is added
+	grown, _ := NewRouter(HashJump, 21, "users_%d", 1)
+	counts := make([]int, 20)
+	var moved int
+	for i := 0; i < 100000; i++ {
+		username := fmt.Sprintf("username%d", i)
+		before, after := r.Table(username), grown.Table(username)
+		counts[before]++
+		if before != after {
+			moved++
+			if after != 20 {
+				t.Fatal("user moved to an old table:", username, after)
+			}
+		}
+	}
+	for i, n := range counts {
+		if n < 4500 || n > 5500 {
+			t.Error("table skewed:", i, n)
+		}
+	}
+	if moved < 4000 || moved > 5500 {
+		t.Error("about 1/21 of users should move, got:", moved)
+	}
+}
+
+func Test_Instance(t *testing.T) {
+	r, _ := NewRouter(HashJump, 20, "", 3)
+	var last int
+	for i := 0; i < 20; i++ {
+		instance := r.Instance(i)
+		if instance < last || instance > last+1 {
+			t.Error("tables should be split over instances in order:", i, instance)
+		}
+		last = instance
+	}
+	if r.Instance(0) != 0 || r.Instance(19) != 2 {
+		t.Error("unexpected instances of first and last table")
+	}
+	if _, err := NewRouter(HashJump, 2, "", 3); err == nil {
+		t.Error("more instances than tables should fail")
+	}
+	if _, err := NewRouter("md5", 20, "", 1); err == nil {
+		t.Error("unknown hash should fail")
+	}
+	if _, err := NewRouter(HashJump, 20, "users", 1); err == nil {
+		t.Error("table name without index should fail")
+	}
+}
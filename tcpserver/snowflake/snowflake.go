@@ -0,0 +1,64 @@
+package snowflake
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	nodeBits = 10
+	seqBits  = 12
+
+	// MaxNode largest node id, each tcpserver generating ids needs its own
+	MaxNode = 1<<nodeBits - 1
+	maxSeq  = 1<<seqBits - 1
+)
+
+// Epoch ids count milliseconds since 2021-01-01 UTC, good for ~69 years
+var Epoch = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Generator 63 bit ids ordered by time: 41 bits of milliseconds since Epoch,
+// 10 bits of node id and 12 bits of sequence in a millisecond
+type Generator struct {
+	mu   sync.Mutex
+	node int64
+	last int64
+	seq  int64
+	now  func() time.Time
+}
+
+// NewGenerator create a generator of node id in [0, MaxNode]
+func NewGenerator(node int) (*Generator, error) {
+	if node < 0 || node > MaxNode {
+		return nil, fmt.Errorf("snowflake: node id %d out of [0, %d]", node, MaxNode)
+	}
+	return &Generator{node: int64(node), now: time.Now}, nil
+}
+
+// Next a new id, never the same one twice even if the clock goes back:
+// ids just go on from the last millisecond until the clock catches up
+func (g *Generator) Next() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ms := g.now().Sub(Epoch).Milliseconds()
+	if ms > g.last {
+		g.last, g.seq = ms, 0
+	} else if g.seq < maxSeq {
+		g.seq++
+	} else {
+		// sequence used up, borrow the next millisecond
+		g.last, g.seq = g.last+1, 0
+	}
+	return g.last<<(nodeBits+seqBits) | g.node<<seqBits | g.seq
+}
+
+// Time when id was generated, to the millisecond
+func Time(id int64) time.Time {
+	return Epoch.Add(time.Duration(id>>(nodeBits+seqBits)) * time.Millisecond)
+}
+
+// Node node id of the generator of id
+func Node(id int64) int {
+	return int(id >> seqBits & MaxNode)
+}
@@ -0,0 +1,42 @@
+package snowflake
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Next(t *testing.T) {
+	g, _ := NewGenerator(7)
+	now := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	g.now = func() time.Time { return now }
+
+	// unique and increasing within a millisecond, past the sequence limit
+	var last int64
+	for i := 0; i < 3*(maxSeq+1); i++ {
+		id := g.Next()
+		if id <= last {
+			t.Fatal("ids should increase:", last, id)
+		}
+		last = id
+	}
+	if Node(last) != 7 || Time(last).Before(now) {
+		t.Error("unexpected node or time of id:", Node(last), Time(last))
+	}
+
+	// clock going back doesn't repeat ids
+	now = now.Add(-time.Second)
+	if id := g.Next(); id <= last {
+		t.Error("id should increase when clock goes back:", last, id)
+	}
+}
+
+func Test_Node(t *testing.T) {
+	if _, err := NewGenerator(MaxNode + 1); err == nil {
+		t.Error("node id out of range should fail")
+	}
+	a, _ := NewGenerator(1)
+	b, _ := NewGenerator(2)
+	if Node(a.Next()) != 1 || Node(b.Next()) != 2 {
+		t.Error("ids should carry node id of their generator")
+	}
+}
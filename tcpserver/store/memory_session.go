@@ -0,0 +1,421 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"user-management-system/conf"
+	"user-management-system/tcpserver/consts"
+	"user-management-system/tcpserver/types"
+)
+
+// memoryEntry a value with expire time like a redis key, zero expire never expires
+type memoryEntry struct {
+	value  interface{}
+	expire time.Time
+}
+
+// memorySession token info and session metadata of a token
+type memorySession struct {
+	user    types.User
+	session types.Session
+	expire  time.Time
+}
+
+// memoryChallenge pending 2fa login
+type memoryChallenge struct {
+	username string
+	attempts int
+}
+
+// memoryEmailToken pending email verification
+type memoryEmailToken struct {
+	username string
+	email    string
+}
+
+// MemorySessionStore SessionStore in process memory, with the same expiry rules as redis
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	entries  map[string]*memoryEntry
+	sessions map[string]*memorySession
+
+	tokenExpired     int
+	userExpired      int
+	sliding          bool
+	tokenMaxLifetime int
+}
+
+// NewMemorySessionStore create an empty session store with cache config of Redis.Cache
+func NewMemorySessionStore(config *conf.TCPConf) *MemorySessionStore {
+	return &MemorySessionStore{
+		now:              time.Now,
+		entries:          make(map[string]*memoryEntry),
+		sessions:         make(map[string]*memorySession),
+		tokenExpired:     config.Redis.Cache.Tokenexpired,
+		userExpired:      config.Redis.Cache.Userexpired,
+		sliding:          config.Redis.Cache.Sliding,
+		tokenMaxLifetime: config.Redis.Cache.Tokenmaxlifetime,
+	}
+}
+
+// SetClock replace time.Now, for tests
+func (m *MemorySessionStore) SetClock(now func() time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = now
+}
+
+// CloseCache nothing to close
+func (m *MemorySessionStore) CloseCache() error {
+	return nil
+}
+
+// get live entry of key, expired ones are dropped
+func (m *MemorySessionStore) get(key string) (*memoryEntry, bool) {
+	e, ok := m.entries[key]
+	if ok && !e.expire.IsZero() && !m.now().Before(e.expire) {
+		delete(m.entries, key)
+		return nil, false
+	}
+	return e, ok
+}
+
+// set value of key which expires in seconds, never if seconds is 0
+func (m *MemorySessionStore) set(key string, value interface{}, seconds int) {
+	e := &memoryEntry{value: value}
+	if seconds > 0 {
+		e.expire = m.now().Add(time.Duration(seconds) * time.Second)
+	}
+	m.entries[key] = e
+}
+
+// GetUserCacheInfo get cached userinfo
+func (m *MemorySessionStore) GetUserCacheInfo(username string) (types.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.get(consts.UserInfoPrefix + username)
+	if !ok {
+		return types.User{}, ErrNotFound
+	}
+	return e.value.(types.User), nil
+}
+
+// SetUserCacheInfo set cached userinfo
+func (m *MemorySessionStore) SetUserCacheInfo(user types.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(consts.UserInfoPrefix+user.Username, user, m.userExpired)
+	return nil
+}
+
+// UpdateCachedUserinfo update cached userinfo
+func (m *MemorySessionStore) UpdateCachedUserinfo(user types.User) error {
+	return m.SetUserCacheInfo(user)
+}
+
+// DelUserCacheInfo delete cached userinfo
+func (m *MemorySessionStore) DelUserCacheInfo(username string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, consts.UserInfoPrefix+username)
+	return nil
+}
+
+// IncrLoginFailures count a failed login of kind and name within window seconds
+func (m *MemorySessionStore) IncrLoginFailures(kind, name string, window int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := consts.LoginFailuresPrefix + kind + name
+	failures := 1
+	if e, ok := m.get(key); ok {
+		failures = e.value.(int) + 1
+	}
+	m.set(key, failures, window)
+	return failures, nil
+}
+
+// SetLoginLock lock logins of kind and name for seconds
+func (m *MemorySessionStore) SetLoginLock(kind, name string, seconds int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(consts.LoginLockPrefix+kind+name, 1, seconds)
+	return nil
+}
+
+// GetLoginLock seconds left of the login lock of kind and name, 0 if not locked
+func (m *MemorySessionStore) GetLoginLock(kind, name string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.get(consts.LoginLockPrefix + kind + name)
+	if !ok {
+		return 0, nil
+	}
+	return int(e.expire.Sub(m.now()) / time.Second), nil
+}
+
+// DelLoginFailures clear failed logins and lock of kind and name
+func (m *MemorySessionStore) DelLoginFailures(kind, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, consts.LoginFailuresPrefix+kind+name)
+	delete(m.entries, consts.LoginLockPrefix+kind+name)
+	return nil
+}
+
+// CreateTwoFactorChallenge create a 2fa challenge of username which expires in seconds
+func (m *MemorySessionStore) CreateTwoFactorChallenge(challenge, username string, seconds int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(consts.TwoFactorChallengePrefix+challenge, &memoryChallenge{username: username}, seconds)
+	return nil
+}
+
+// GetTwoFactorChallenge get username of a 2fa challenge
+func (m *MemorySessionStore) GetTwoFactorChallenge(challenge string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.get(consts.TwoFactorChallengePrefix + challenge)
+	if !ok {
+		return "", ErrNotFound
+	}
+	return e.value.(*memoryChallenge).username, nil
+}
+
+// FailTwoFactorChallenge count a wrong code of a 2fa challenge
+func (m *MemorySessionStore) FailTwoFactorChallenge(challenge string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.get(consts.TwoFactorChallengePrefix + challenge)
+	if !ok {
+		return 0, ErrNotFound
+	}
+	c := e.value.(*memoryChallenge)
+	c.attempts++
+	return c.attempts, nil
+}
+
+// DelTwoFactorChallenge delete a 2fa challenge, false if it's been deleted
+func (m *MemorySessionStore) DelTwoFactorChallenge(challenge string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := consts.TwoFactorChallengePrefix + challenge
+	_, ok := m.get(key)
+	delete(m.entries, key)
+	return ok, nil
+}
+
+// CreateEmailToken create an email verification token of username and email which expires in seconds
+func (m *MemorySessionStore) CreateEmailToken(token, username, email string, seconds int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(consts.EmailTokenPrefix+token, &memoryEmailToken{username: username, email: email}, seconds)
+	return nil
+}
+
+// TakeEmailToken get username and email of a verification token and delete it
+func (m *MemorySessionStore) TakeEmailToken(token string) (string, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := consts.EmailTokenPrefix + token
+	e, ok := m.get(key)
+	if !ok {
+		return "", "", ErrNotFound
+	}
+	delete(m.entries, key)
+	t := e.value.(*memoryEmailToken)
+	return t.username, t.email, nil
+}
+
+// CreateResetToken create a passwd reset token of username and its skey which expires in seconds
+func (m *MemorySessionStore) CreateResetToken(token, username, skey string, seconds int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(consts.PasswdResetPrefix+token, [2]string{username, skey}, seconds)
+	return nil
+}
+
+// TakeResetToken get username and skey of a passwd reset token and delete it
+func (m *MemorySessionStore) TakeResetToken(token string) (string, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := consts.PasswdResetPrefix + token
+	e, ok := m.get(key)
+	if !ok {
+		return "", "", ErrNotFound
+	}
+	delete(m.entries, key)
+	v := e.value.([2]string)
+	return v[0], v[1], nil
+}
+
+// UseTOTPCounter mark totp counter of username used for seconds, false if it's been used
+func (m *MemorySessionStore) UseTOTPCounter(username string, counter uint64, seconds int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := fmt.Sprintf("%s%s_%d", consts.TOTPUsedPrefix, username, counter)
+	if _, ok := m.get(key); ok {
+		return false, nil
+	}
+	m.set(key, 1, seconds)
+	return true, nil
+}
+
+// getSession get live session of token, expired ones are dropped
+func (m *MemorySessionStore) getSession(token string) (*memorySession, bool) {
+	s, ok := m.sessions[token]
+	if ok && !m.now().Before(s.expire) {
+		delete(m.sessions, token)
+		return nil, false
+	}
+	return s, ok
+}
+
+// sessionTTL ttl of a session: tokenexpired, capped by what's left of its max lifetime
+func (m *MemorySessionStore) sessionTTL(createtime int64) time.Duration {
+	ttl := time.Duration(m.tokenExpired) * time.Second
+	if m.tokenMaxLifetime <= 0 {
+		return ttl
+	}
+	left := time.Unix(createtime+int64(m.tokenMaxLifetime), 0).Sub(m.now())
+	if left < ttl {
+		return left
+	}
+	return ttl
+}
+
+// GetTokenInfo get token info, last seen time is updated and in sliding mode its ttl is extended
+func (m *MemorySessionStore) GetTokenInfo(token string) (types.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.getSession(token)
+	if !ok {
+		return types.User{}, ErrNotFound
+	}
+	s.session.Lastseen = m.now().Unix()
+	if m.sliding {
+		if ttl := m.sessionTTL(s.session.Createtime); ttl > 0 {
+			s.expire = m.now().Add(ttl)
+		}
+	}
+	return s.user, nil
+}
+
+// SetTokenInfo set userinfo of token, the session keeps its ttl
+func (m *MemorySessionStore) SetTokenInfo(user types.User, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.getSession(token)
+	if !ok {
+		return ErrNotFound
+	}
+	s.user = user
+	return nil
+}
+
+// RotateSession retire oldToken and move its session to newToken, false if oldToken has expired
+func (m *MemorySessionStore) RotateSession(username, oldToken, newToken string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.getSession(oldToken)
+	if !ok {
+		return false, nil
+	}
+	ttl := m.sessionTTL(s.session.Createtime)
+	if ttl <= 0 {
+		return false, nil
+	}
+	delete(m.sessions, oldToken)
+	rotated := *s
+	rotated.session.Token = newToken
+	rotated.session.Lastseen = m.now().Unix()
+	rotated.expire = m.now().Add(ttl)
+	m.sessions[newToken] = &rotated
+	return true, nil
+}
+
+// TokenLifetime how long a client may keep a new token
+func (m *MemorySessionStore) TokenLifetime() int {
+	if m.sliding && m.tokenMaxLifetime > 0 {
+		return m.tokenMaxLifetime
+	}
+	if m.tokenMaxLifetime > 0 && m.tokenMaxLifetime < m.tokenExpired {
+		return m.tokenMaxLifetime
+	}
+	return m.tokenExpired
+}
+
+// CreateSession create a session of token
+func (m *MemorySessionStore) CreateSession(user types.User, session types.Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	expired := m.tokenExpired
+	if m.tokenMaxLifetime > 0 && m.tokenMaxLifetime < m.tokenExpired {
+		expired = m.tokenMaxLifetime
+	}
+	session.Username = user.Username
+	m.sessions[session.Token] = &memorySession{
+		user:    user,
+		session: session,
+		expire:  m.now().Add(time.Duration(expired) * time.Second),
+	}
+	return nil
+}
+
+// GetUserSessions get active sessions of username, newest first
+func (m *MemorySessionStore) GetUserSessions(username string) ([]types.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sessions []types.Session
+	for token := range m.sessions {
+		if s, ok := m.getSession(token); ok && s.session.Username == username {
+			sessions = append(sessions, s.session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Createtime > sessions[j].Createtime })
+	return sessions, nil
+}
+
+// DelTokenInfo delete token and its session
+func (m *MemorySessionStore) DelTokenInfo(token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, token)
+	return nil
+}
+
+// DelUserTokens delete all sessions of username except the given token
+func (m *MemorySessionStore) DelUserTokens(username, except string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var deleted int
+	for token := range m.sessions {
+		s, ok := m.getSession(token)
+		if !ok || token == except || s.session.Username != username {
+			continue
+		}
+		delete(m.sessions, token)
+		deleted++
+	}
+	return deleted, nil
+}
+
+// RenameUserSessions move sessions of oldname to user, return the number of moved sessions
+func (m *MemorySessionStore) RenameUserSessions(oldname string, user types.User) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var moved int
+	for token := range m.sessions {
+		s, ok := m.getSession(token)
+		if !ok || s.session.Username != oldname {
+			continue
+		}
+		s.user = user
+		s.session.Username = user.Username
+		moved++
+	}
+	return moved, nil
+}
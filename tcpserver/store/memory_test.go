@@ -0,0 +1,137 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"user-management-system/conf"
+	"user-management-system/tcpserver/consts"
+	"user-management-system/tcpserver/db"
+	"user-management-system/tcpserver/types"
+)
+
+// fixed clock moved by tests
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func newTestSessionStore(sliding bool) (*MemorySessionStore, *clock) {
+	var config conf.TCPConf
+	config.Redis.Cache.Tokenexpired = 100
+	config.Redis.Cache.Userexpired = 10
+	config.Redis.Cache.Sliding = sliding
+	config.Redis.Cache.Tokenmaxlifetime = 250
+	c := &clock{now: time.Unix(1600000000, 0)}
+	m := NewMemorySessionStore(&config)
+	m.SetClock(c.Now)
+	return m, c
+}
+
+func Test_MemorySessionExpire(t *testing.T) {
+	for _, sliding := range []bool{false, true} {
+		m, c := newTestSessionStore(sliding)
+		user := types.User{Username: "username8"}
+		m.CreateSession(user, types.Session{Token: "t1", Createtime: c.now.Unix()})
+
+		// used every 60s: only sliding sessions outlive tokenexpired, never max lifetime
+		var alive int
+		for i := 1; i <= 5; i++ {
+			c.now = c.now.Add(60 * time.Second)
+			if _, err := m.GetTokenInfo("t1"); err == nil {
+				alive = i
+			}
+		}
+		if !sliding && alive != 1 {
+			t.Error("fixed session should expire after tokenexpired, alive:", alive)
+		}
+		if sliding && alive != 4 {
+			t.Error("sliding session should expire at max lifetime, alive:", alive)
+		}
+		if sessions, _ := m.GetUserSessions("username8"); len(sessions) != 0 {
+			t.Error("expired session should be gone:", sessions)
+		}
+	}
+}
+
+func Test_MemorySessionRevoke(t *testing.T) {
+	m, c := newTestSessionStore(false)
+	user := types.User{Username: "username8"}
+	for i, token := range []string{"t1", "t2", "t3"} {
+		m.CreateSession(user, types.Session{Token: token, Createtime: c.now.Unix() + int64(i)})
+	}
+	m.CreateSession(types.User{Username: "other"}, types.Session{Token: "t4"})
+
+	ok, _ := m.RotateSession("username8", "t1", "t5")
+	if _, err := m.GetTokenInfo("t1"); !ok || err == nil {
+		t.Error("rotated token should be retired")
+	}
+	sessions, _ := m.GetUserSessions("username8")
+	if len(sessions) != 3 || sessions[0].Token != "t3" {
+		t.Error("unexpected sessions:", sessions)
+	}
+	if deleted, _ := m.DelUserTokens("username8", "t2"); deleted != 2 {
+		t.Error("expected 2 revoked tokens, got:", deleted)
+	}
+	if _, err := m.GetTokenInfo("t2"); err != nil {
+		t.Error("kept token should be alive")
+	}
+	if _, err := m.GetTokenInfo("t4"); err != nil {
+		t.Error("token of other user should be alive")
+	}
+}
+
+func Test_MemoryLoginLock(t *testing.T) {
+	m, c := newTestSessionStore(false)
+	m.IncrLoginFailures(consts.LoginKindUser, "username8", 60)
+	c.now = c.now.Add(59 * time.Second)
+	if n, _ := m.IncrLoginFailures(consts.LoginKindUser, "username8", 60); n != 2 {
+		t.Error("failures within window should add up, got:", n)
+	}
+	m.SetLoginLock(consts.LoginKindUser, "username8", 30)
+	c.now = c.now.Add(10 * time.Second)
+	if left, _ := m.GetLoginLock(consts.LoginKindUser, "username8"); left != 20 {
+		t.Error("expected 20 seconds of lock, got:", left)
+	}
+	c.now = c.now.Add(20 * time.Second)
+	if left, _ := m.GetLoginLock(consts.LoginKindUser, "username8"); left != 0 {
+		t.Error("lock should expire, got:", left)
+	}
+}
+
+func Test_MemoryUserStore(t *testing.T) {
+	m := NewMemoryUserStore()
+	user := types.User{Username: "username8", Nickname: "nickname8"}
+	if err := m.CreateDbUser(&user); err != nil || user.ID != 1 {
+		t.Fatal("failed to create user:", err)
+	}
+	if err := m.CreateDbUser(&types.User{Username: "username8"}); err != db.ErrUserExists {
+		t.Error("duplicated username should fail, got:", err)
+	}
+	if m.UpdateDbProfile("username8", types.User{Nickname: "nick"}, []string{"nickname"}) != 1 || m.UpdateDbProfile("nobody", types.User{Nickname: "nick"}, []string{"nickname"}) != 0 {
+		t.Error("unexpected affected rows of update")
+	}
+	if got, _ := m.GetDbUserInfo("username8"); got.Nickname != "nick" {
+		t.Error("nickname not updated:", got)
+	}
+	if m.UpdateDbRecoveryCodes("username8", []string{"x"}, nil) != 0 {
+		t.Error("recovery codes should only be replaced if unchanged")
+	}
+	if m.DeactivateDbUser("username8", 100) != 1 || m.DeactivateDbUser("username8", 100) != 0 {
+		t.Error("user should be deactivated once")
+	}
+	var found int
+	for i := 0; i < m.TableNum(); i++ {
+		users, _ := m.GetDbDeactivatedUsers(i, 101, 10)
+		found += len(users)
+	}
+	if found != 1 || m.DeleteDbUser("username8") != 1 {
+		t.Error("deactivated user should be found and deleted")
+	}
+	if _, err := m.GetDbUserInfo("username8"); err == nil {
+		t.Error("deleted user should be gone")
+	}
+}
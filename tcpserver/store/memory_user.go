@@ -0,0 +1,386 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"user-management-system/tcpserver/db"
+	"user-management-system/tcpserver/types"
+)
+
+// memoryUser a user row with its totp settings
+type memoryUser struct {
+	user      types.User
+	twoFactor types.TwoFactor
+}
+
+// memoryRename old username reserved for the user now named username
+type memoryRename struct {
+	username   string
+	expiretime int64
+}
+
+// MemoryUserStore UserStore in process memory, rows are lost on exit
+type MemoryUserStore struct {
+	mu      sync.Mutex
+	users   map[string]*memoryUser
+	uids    map[int64]string
+	renames map[string]memoryRename
+	lastID  int32
+	// audit events in the order they're appended, ID is index+1
+	audit []types.AuditEvent
+}
+
+// NewMemoryUserStore create an empty user store
+func NewMemoryUserStore() *MemoryUserStore {
+	return &MemoryUserStore{users: make(map[string]*memoryUser), uids: make(map[int64]string), renames: make(map[string]memoryRename)}
+}
+
+// CloseDB nothing to close
+func (m *MemoryUserStore) CloseDB() error {
+	return nil
+}
+
+// TableNum all users are in a single table
+func (m *MemoryUserStore) TableNum() int {
+	return 1
+}
+
+// GetDbUserInfo query
+func (m *MemoryUserStore) GetDbUserInfo(username string) (types.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	row, ok := m.users[username]
+	if !ok {
+		return types.User{}, fmt.Errorf("user(%s) not exists", username)
+	}
+	return row.user, nil
+}
+
+// GetDbUserByUid query by global uid
+func (m *MemoryUserStore) GetDbUserByUid(uid int64) (types.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	row, ok := m.users[m.uids[uid]]
+	if !ok || uid == 0 {
+		return types.User{}, fmt.Errorf("uid(%d) not exists", uid)
+	}
+	return row.user, nil
+}
+
+// CreateDbUser insert a new user, return db.ErrUserExists if username has been taken or is reserved
+func (m *MemoryUserStore) CreateDbUser(user *types.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[user.Username]; ok {
+		return db.ErrUserExists
+	}
+	if _, ok := m.renamedUser(user.Username); ok {
+		return db.ErrUserExists
+	}
+	m.lastID++
+	user.ID = m.lastID
+	m.users[user.Username] = &memoryUser{user: *user, twoFactor: types.TwoFactor{Username: user.Username}}
+	if user.Uid != 0 {
+		m.uids[user.Uid] = user.Username
+	}
+	return nil
+}
+
+// GetDbTwoFactor query totp settings
+func (m *MemoryUserStore) GetDbTwoFactor(username string) (types.TwoFactor, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	row, ok := m.users[username]
+	if !ok {
+		return types.TwoFactor{}, fmt.Errorf("user(%s) not exists", username)
+	}
+	return row.twoFactor, nil
+}
+
+// UpdateDbTwoFactor update totp settings
+func (m *MemoryUserStore) UpdateDbTwoFactor(username, secret string, enabled bool, recoveryCodes []string) int64 {
+	return m.update(username, func(row *memoryUser) bool {
+		row.twoFactor.Totpsecret = secret
+		row.twoFactor.Totpenabled = enabled
+		row.twoFactor.Recoverycodes = strings.Join(recoveryCodes, ",")
+		return true
+	})
+}
+
+// UpdateDbRecoveryCodes replace recovery codes only if they're still old ones
+func (m *MemoryUserStore) UpdateDbRecoveryCodes(username string, old, recoveryCodes []string) int64 {
+	return m.update(username, func(row *memoryUser) bool {
+		if row.twoFactor.Recoverycodes != strings.Join(old, ",") {
+			return false
+		}
+		row.twoFactor.Recoverycodes = strings.Join(recoveryCodes, ",")
+		return true
+	})
+}
+
+// DeactivateDbUser deactivate an active user
+func (m *MemoryUserStore) DeactivateDbUser(username string, deactivatetime int64) int64 {
+	return m.update(username, func(row *memoryUser) bool {
+		if row.user.Status != types.StatusActive {
+			return false
+		}
+		row.user.Status, row.user.Deactivatetime = types.StatusDeactivated, deactivatetime
+		return true
+	})
+}
+
+// RestoreDbUser restore a deactivated user
+func (m *MemoryUserStore) RestoreDbUser(username string) int64 {
+	return m.update(username, func(row *memoryUser) bool {
+		if row.user.Status != types.StatusDeactivated {
+			return false
+		}
+		row.user.Status, row.user.Deactivatetime = types.StatusActive, 0
+		return true
+	})
+}
+
+// DeleteDbUser delete user
+func (m *MemoryUserStore) DeleteDbUser(username string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	row, ok := m.users[username]
+	if !ok {
+		return 0
+	}
+	delete(m.uids, row.user.Uid)
+	delete(m.users, username)
+	for oldname, r := range m.renames {
+		if r.username == username {
+			delete(m.renames, oldname)
+		}
+	}
+	return 1
+}
+
+// RenameDbUser rename username, the old name is reserved until expiretime (not at all if 0)
+func (m *MemoryUserStore) RenameDbUser(username, newname string, expiretime int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	row, ok := m.users[username]
+	if !ok {
+		return fmt.Errorf("user(%s) not exists", username)
+	}
+	if _, ok = m.users[newname]; ok {
+		return db.ErrUserExists
+	}
+	if owner, ok := m.renamedUser(newname); ok && owner != username {
+		return db.ErrUserExists
+	}
+	delete(m.users, username)
+	row.user.Username, row.twoFactor.Username = newname, newname
+	row.user.Uptime = time.Now().Unix()
+	m.users[newname] = row
+	if row.user.Uid != 0 {
+		m.uids[row.user.Uid] = newname
+	}
+
+	// taking back an old name frees it, older names of the user lead to the new one
+	delete(m.renames, newname)
+	for oldname, r := range m.renames {
+		if r.username == username {
+			m.renames[oldname] = memoryRename{username: newname, expiretime: r.expiretime}
+		}
+	}
+	if expiretime != 0 {
+		m.renames[username] = memoryRename{username: newname, expiretime: expiretime}
+	}
+	return nil
+}
+
+// GetDbRenamedUser current username of a user renamed from oldname, while oldname is reserved
+func (m *MemoryUserStore) GetDbRenamedUser(oldname string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	username, ok := m.renamedUser(oldname)
+	if !ok {
+		return "", fmt.Errorf("username(%s) not reserved", oldname)
+	}
+	return username, nil
+}
+
+// renamedUser GetDbRenamedUser with mu held
+func (m *MemoryUserStore) renamedUser(oldname string) (string, bool) {
+	r, ok := m.renames[oldname]
+	if !ok || r.expiretime <= time.Now().Unix() {
+		return "", false
+	}
+	return r.username, true
+}
+
+// DelDbExpiredRenames free usernames reserved until before expiretime
+func (m *MemoryUserStore) DelDbExpiredRenames(expiretime int64) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var deleted int64
+	for oldname, r := range m.renames {
+		if r.expiretime < expiretime {
+			delete(m.renames, oldname)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// GetDbDeactivatedUsers users deactivated before deactivatetime, there's only table 0
+func (m *MemoryUserStore) GetDbDeactivatedUsers(index int, deactivatetime int64, limit int) ([]types.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var users []types.User
+	for _, row := range m.users {
+		if index != 0 || len(users) >= limit {
+			break
+		}
+		if row.user.Status == types.StatusDeactivated && row.user.Deactivatetime < deactivatetime {
+			users = append(users, row.user)
+		}
+	}
+	return users, nil
+}
+
+// UpdateDbPasswd update passwd hash and skey
+func (m *MemoryUserStore) UpdateDbPasswd(username, passwd, skey string) int64 {
+	return m.update(username, func(row *memoryUser) bool {
+		row.user.Passwd, row.user.Skey = passwd, skey
+		return true
+	})
+}
+
+// UpdateDbProfile update profile fields of mask, nothing if one of them is unknown
+func (m *MemoryUserStore) UpdateDbProfile(username string, user types.User, mask []string) int64 {
+	return m.update(username, func(row *memoryUser) bool {
+		updated := row.user
+		for _, field := range mask {
+			value, ok := user.Profile(field)
+			if !ok {
+				return false
+			}
+			updated.SetProfile(field, value)
+			if field == "email" {
+				updated.Emailverified = false
+			}
+		}
+		row.user = updated
+		return true
+	})
+}
+
+// VerifyDbEmail mark email of username verified if it's still the unverified address
+func (m *MemoryUserStore) VerifyDbEmail(username, email string) int64 {
+	return m.update(username, func(row *memoryUser) bool {
+		if row.user.Email != email || row.user.Emailverified {
+			return false
+		}
+		row.user.Emailverified = true
+		return true
+	})
+}
+
+// UpdateDbAvatar set file name of the avatar uploaded by username
+func (m *MemoryUserStore) UpdateDbAvatar(username, avatar string) int64 {
+	return m.update(username, func(row *memoryUser) bool {
+		row.user.Avatar = avatar
+		return true
+	})
+}
+
+// UpdateDbRoles set roles of username
+func (m *MemoryUserStore) UpdateDbRoles(username string, roles []string) int64 {
+	return m.update(username, func(row *memoryUser) bool {
+		row.user.Roles = strings.Join(roles, ",")
+		return true
+	})
+}
+
+// LockDbUser lock an active user, or unlock a locked one
+func (m *MemoryUserStore) LockDbUser(username string, locked bool) int64 {
+	from, to := int8(types.StatusActive), int8(types.StatusLocked)
+	if !locked {
+		from, to = to, from
+	}
+	return m.update(username, func(row *memoryUser) bool {
+		if row.user.Status != from {
+			return false
+		}
+		row.user.Status = to
+		return true
+	})
+}
+
+// ListDbUsers users passing filter with usernames after after in username order, there's only table 0
+func (m *MemoryUserStore) ListDbUsers(index int, filter types.UserFilter, after string, limit int) ([]types.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var usernames []string
+	for username, row := range m.users {
+		if index == 0 && username > after && filter.Match(row.user) {
+			usernames = append(usernames, username)
+		}
+	}
+	sort.Strings(usernames)
+	if len(usernames) > limit {
+		usernames = usernames[:limit]
+	}
+	users := make([]types.User, 0, len(usernames))
+	for _, username := range usernames {
+		users = append(users, m.users[username].user)
+	}
+	return users, nil
+}
+
+// PreviousTableNum memory users are never resharded
+func (m *MemoryUserStore) PreviousTableNum() int {
+	return 0
+}
+
+// ListDbPreviousUsers nothing, there's no previous layout
+func (m *MemoryUserStore) ListDbPreviousUsers(index int, filter types.UserFilter, after string, limit int) ([]types.User, error) {
+	return nil, nil
+}
+
+// AppendDbAudit append event, its ID is set
+func (m *MemoryUserStore) AppendDbAudit(event *types.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	event.ID = int64(len(m.audit) + 1)
+	m.audit = append(m.audit, *event)
+	return nil
+}
+
+// QueryDbAudit at most limit events passing filter with ids below before, newest first
+func (m *MemoryUserStore) QueryDbAudit(filter types.AuditFilter, before int64, limit int) ([]types.AuditEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	end := int64(len(m.audit))
+	if before > 0 && before-1 < end {
+		end = before - 1
+	}
+	var events []types.AuditEvent
+	for i := end - 1; i >= 0 && len(events) < limit; i-- {
+		if filter.Match(m.audit[i]) {
+			events = append(events, m.audit[i])
+		}
+	}
+	return events, nil
+}
+
+// update apply fn to row of username, return affected rows like db does
+func (m *MemoryUserStore) update(username string, fn func(row *memoryUser) bool) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	row, ok := m.users[username]
+	if !ok || !fn(row) {
+		return 0
+	}
+	row.user.Uptime = time.Now().Unix()
+	return 1
+}
@@ -0,0 +1,142 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"user-management-system/conf"
+	"user-management-system/tcpserver/cache"
+	"user-management-system/tcpserver/db"
+	"user-management-system/tcpserver/types"
+)
+
+const (
+	// UsersMySQL users in sharded tables of the db section, mysql or sqlite by Db.Driver
+	UsersMySQL = "mysql"
+	// SessionsRedis sessions and counters in redis
+	SessionsRedis = "redis"
+	// Memory process memory, for tests and local dev only
+	Memory = "memory"
+)
+
+// ErrNotFound key doesn't exist or has expired in memory store
+var ErrNotFound = errors.New("store: not found")
+
+// UserStore persistent userinfo, implemented by db.DBClient
+type UserStore interface {
+	CloseDB() error
+	// TableNum number of tables GetDbDeactivatedUsers can be asked for
+	TableNum() int
+	GetDbUserInfo(username string) (types.User, error)
+	GetDbUserByUid(uid int64) (types.User, error)
+	// CreateDbUser return db.ErrUserExists if username has been taken
+	CreateDbUser(user *types.User) error
+	GetDbTwoFactor(username string) (types.TwoFactor, error)
+	UpdateDbTwoFactor(username, secret string, enabled bool, recoveryCodes []string) int64
+	UpdateDbRecoveryCodes(username string, old, recoveryCodes []string) int64
+	DeactivateDbUser(username string, deactivatetime int64) int64
+	RestoreDbUser(username string) int64
+	DeleteDbUser(username string) int64
+	// RenameDbUser return db.ErrUserExists if newname has been taken or is reserved for another user
+	RenameDbUser(username, newname string, expiretime int64) error
+	GetDbRenamedUser(oldname string) (string, error)
+	DelDbExpiredRenames(expiretime int64) int64
+	GetDbDeactivatedUsers(index int, deactivatetime int64, limit int) ([]types.User, error)
+	UpdateDbPasswd(username, passwd, skey string) int64
+	// UpdateDbProfile update fields of mask, names of types.ProfileFields, to their values in user
+	UpdateDbProfile(username string, user types.User, mask []string) int64
+	// VerifyDbEmail 0 if email isn't the unverified address of username anymore
+	VerifyDbEmail(username, email string) int64
+	UpdateDbRoles(username string, roles []string) int64
+	// UpdateDbAvatar set file name of the avatar uploaded by username
+	UpdateDbAvatar(username, avatar string) int64
+	// LockDbUser lock an active user, or unlock a locked one
+	LockDbUser(username string, locked bool) int64
+	// ListDbUsers at most limit users of table index passing filter with usernames after after, in bytewise username order
+	ListDbUsers(index int, filter types.UserFilter, after string, limit int) ([]types.User, error)
+	// PreviousTableNum number of tables of the layout users are being moved from, 0 if not resharding
+	PreviousTableNum() int
+	// ListDbPreviousUsers ListDbUsers of table index of the previous layout, users already moved are skipped
+	ListDbPreviousUsers(index int, filter types.UserFilter, after string, limit int) ([]types.User, error)
+	// AppendDbAudit append event to the audit log, its ID is set
+	AppendDbAudit(event *types.AuditEvent) error
+	// QueryDbAudit at most limit events passing filter with ids below before, newest first. before is 0 for the newest
+	QueryDbAudit(filter types.AuditFilter, before int64, limit int) ([]types.AuditEvent, error)
+}
+
+// SessionStore userinfo cache, sessions and short-lived counters, implemented by cache.RedisClient
+type SessionStore interface {
+	CloseCache() error
+	GetUserCacheInfo(username string) (types.User, error)
+	SetUserCacheInfo(user types.User) error
+	UpdateCachedUserinfo(user types.User) error
+	DelUserCacheInfo(username string) error
+
+	IncrLoginFailures(kind, name string, window int) (int, error)
+	SetLoginLock(kind, name string, seconds int) error
+	GetLoginLock(kind, name string) (int, error)
+	DelLoginFailures(kind, name string) error
+
+	CreateTwoFactorChallenge(challenge, username string, seconds int) error
+	GetTwoFactorChallenge(challenge string) (string, error)
+	FailTwoFactorChallenge(challenge string) (int, error)
+	DelTwoFactorChallenge(challenge string) (bool, error)
+	UseTOTPCounter(username string, counter uint64, seconds int) (bool, error)
+
+	CreateEmailToken(token, username, email string, seconds int) error
+	// TakeEmailToken get username and email of a verification token and delete it, an error if it has expired or been used
+	TakeEmailToken(token string) (string, string, error)
+	CreateResetToken(token, username, skey string, seconds int) error
+	// TakeResetToken get username and skey of a passwd reset token and delete it, an error if it has expired or been used
+	TakeResetToken(token string) (string, string, error)
+
+	GetTokenInfo(token string) (types.User, error)
+	SetTokenInfo(user types.User, token string) error
+	RotateSession(username, oldToken, newToken string) (bool, error)
+	TokenLifetime() int
+	CreateSession(user types.User, session types.Session) error
+	GetUserSessions(username string) ([]types.Session, error)
+	DelTokenInfo(token string) error
+	DelUserTokens(username, except string) (int, error)
+	RenameUserSessions(oldname string, user types.User) (int, error)
+}
+
+var (
+	_ UserStore    = (*db.DBClient)(nil)
+	_ SessionStore = (*cache.RedisClient)(nil)
+)
+
+// NewUserStore create user store of Store.Users, mysql by default
+func NewUserStore(config *conf.TCPConf) (UserStore, error) {
+	switch config.Store.Users {
+	case "", UsersMySQL:
+		client, err := db.NewDBClient(config)
+		if err != nil {
+			return nil, err
+		}
+		// columns tcpserver reads may not be there yet
+		if err = client.CheckSchema(); err != nil {
+			client.CloseDB()
+			return nil, err
+		}
+		return client, nil
+	case Memory:
+		return NewMemoryUserStore(), nil
+	}
+	return nil, fmt.Errorf("store: unsupported user store '%s'", config.Store.Users)
+}
+
+// NewSessionStore create session store of Store.Sessions, redis by default
+func NewSessionStore(config *conf.TCPConf) (SessionStore, error) {
+	switch config.Store.Sessions {
+	case "", SessionsRedis:
+		client, err := cache.NewRedisClient(config)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	case Memory:
+		return NewMemorySessionStore(config), nil
+	}
+	return nil, fmt.Errorf("store: unsupported session store '%s'", config.Store.Sessions)
+}
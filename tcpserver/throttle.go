@@ -0,0 +1,97 @@
+package tcpserver
+
+import (
+	"math"
+
+	"user-management-system/conf"
+	"user-management-system/tcpserver/consts"
+
+	log "github.com/beego/beego/v2/adapter/logs"
+)
+
+// loginThrottle lockout policy of failed logins, by username and by client ip
+type loginThrottle struct {
+	window          int
+	maxUserFailures int
+	maxIPFailures   int
+	baseLockout     int
+	maxLockout      int
+}
+
+func newLoginThrottle(config *conf.TCPConf) *loginThrottle {
+	cfg := config.Security.Login
+	return &loginThrottle{
+		window:          cfg.Window,
+		maxUserFailures: cfg.Maxuserfailures,
+		maxIPFailures:   cfg.Maxipfailures,
+		baseLockout:     cfg.Baselockout,
+		maxLockout:      cfg.Maxlockout,
+	}
+}
+
+// lockout seconds to lock after failures, doubled for each failure beyond threshold up to
+// maxLockout, 0 maxLockout is no cap but the lockout still fits in an int32 of redis expires
+func (t *loginThrottle) lockout(failures, threshold int) int {
+	if threshold <= 0 || failures < threshold {
+		return 0
+	}
+	lockout := t.baseLockout
+	for i := threshold; i < failures && (t.maxLockout <= 0 || lockout < t.maxLockout) && lockout <= math.MaxInt32/2; i++ {
+		lockout *= 2
+	}
+	if t.maxLockout > 0 && lockout > t.maxLockout {
+		lockout = t.maxLockout
+	}
+	return lockout
+}
+
+// LoginLocked seconds left before username may try to login again, from username's or ip's lock
+func (a *API) LoginLocked(username, ip string) (userLock, ipLock int) {
+	var err error
+	if a.throttle.maxUserFailures > 0 {
+		if userLock, err = a.sessions.GetLoginLock(consts.LoginKindUser, username); err != nil {
+			log.Error("failed to get login lock of user:", username, " with err:", err.Error())
+		}
+	}
+	if a.throttle.maxIPFailures > 0 && ip != "" {
+		if ipLock, err = a.sessions.GetLoginLock(consts.LoginKindIP, ip); err != nil {
+			log.Error("failed to get login lock of ip:", ip, " with err:", err.Error())
+		}
+	}
+	return userLock, ipLock
+}
+
+// LoginFailed count a failed login and lock username or ip once thresholds are reached
+func (a *API) LoginFailed(username, ip string) {
+	a.countLoginFailure(consts.LoginKindUser, username, a.throttle.maxUserFailures)
+	if ip != "" {
+		a.countLoginFailure(consts.LoginKindIP, ip, a.throttle.maxIPFailures)
+	}
+}
+
+// LoginSucceeded forget failed logins of username
+func (a *API) LoginSucceeded(username string) {
+	if err := a.sessions.DelLoginFailures(consts.LoginKindUser, username); err != nil {
+		log.Error("failed to reset login failures of user:", username, " with err:", err.Error())
+	}
+}
+
+func (a *API) countLoginFailure(kind, name string, threshold int) {
+	if threshold <= 0 {
+		return
+	}
+	failures, err := a.sessions.IncrLoginFailures(kind, name, a.throttle.window)
+	if err != nil {
+		log.Error("failed to count login failure of ", kind, name, " with err:", err.Error())
+		return
+	}
+	lockout := a.throttle.lockout(failures, threshold)
+	if lockout == 0 {
+		return
+	}
+	if err = a.sessions.SetLoginLock(kind, name, lockout); err != nil {
+		log.Error("failed to lock ", kind, name, " with err:", err.Error())
+		return
+	}
+	log.Info("login locked for ", kind, name, ", failures:", failures, ", lockout:", lockout, "s")
+}
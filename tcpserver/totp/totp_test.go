@@ -0,0 +1,78 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// secret of RFC 6238 test vectors
+var rfcSecret = b32.EncodeToString([]byte("12345678901234567890"))
+
+func Test_RFC6238(t *testing.T) {
+	key, _ := decodeSecret(rfcSecret)
+	vectors := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+	for unix, expected := range vectors {
+		if got := hotp(key, Counter(time.Unix(unix, 0)), 8); got != expected {
+			t.Error("time:", unix, " expected:", expected, " got:", got)
+		}
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code, err := Code(rfcSecret, now)
+	if err != nil || code != "005924" {
+		t.Fatal("unexpected code:", code, err)
+	}
+	if c, ok := Validate(rfcSecret, code, now, 1); !ok || c != Counter(now) {
+		t.Error("code should be valid at its time")
+	}
+	// one step of drift is accepted, two are not
+	if _, ok := Validate(rfcSecret, code, now.Add(Period*time.Second), 1); !ok {
+		t.Error("code of previous step should be valid with skew 1")
+	}
+	if _, ok := Validate(rfcSecret, code, now.Add(2*Period*time.Second), 1); ok {
+		t.Error("code of two steps ago should be invalid")
+	}
+	if _, ok := Validate(rfcSecret, "123", now, 1); ok {
+		t.Error("short code should be invalid")
+	}
+	lower := strings.ToLower(rfcSecret)
+	if _, ok := Validate(lower, code, now, 0); !ok {
+		t.Error("lowercase secret should be accepted")
+	}
+}
+
+func Test_URI(t *testing.T) {
+	uri := URI("UMS", "username8", "ABC")
+	expected := "otpauth://totp/UMS:username8?algorithm=SHA1&digits=6&issuer=UMS&period=30&secret=ABC"
+	if uri != expected {
+		t.Error("unexpected uri:", uri)
+	}
+}
+
+func Test_RecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil || len(codes) != 10 {
+		t.Fatal("failed to generate recovery codes:", err)
+	}
+	var hashes []string
+	for _, c := range codes {
+		hashes = append(hashes, HashRecoveryCode(c))
+	}
+	left, ok := UseRecoveryCode(hashes, strings.ToUpper(codes[3]))
+	if !ok || len(left) != 9 {
+		t.Error("recovery code should be used once")
+	}
+	if _, ok = UseRecoveryCode(left, codes[3]); ok {
+		t.Error("used recovery code should be rejected")
+	}
+}
@@ -0,0 +1,196 @@
+package tcpserver
+
+import (
+	"errors"
+	"time"
+
+	"user-management-system/conf"
+	"user-management-system/tcpserver/totp"
+	"user-management-system/tcpserver/types"
+	"user-management-system/utils"
+
+	log "github.com/beego/beego/v2/adapter/logs"
+)
+
+var (
+	// ErrTwoFactorState 2fa is already enabled, or isn't set up yet
+	ErrTwoFactorState = errors.New("2fa is not in the right state")
+	// ErrInvalidPasscode wrong totp code or recovery code
+	ErrInvalidPasscode = errors.New("invalid 2fa passcode")
+	// ErrChallengeExpired 2fa challenge expired or dropped after too many wrong codes
+	ErrChallengeExpired = errors.New("2fa challenge expired")
+)
+
+// twoFactorPolicy totp settings of config
+type twoFactorPolicy struct {
+	issuer          string
+	skew            int
+	challengeExpire int
+	maxAttempts     int
+	recoveryCodes   int
+}
+
+func newTwoFactorPolicy(config *conf.TCPConf) *twoFactorPolicy {
+	cfg := config.Security.Twofactor
+	policy := &twoFactorPolicy{
+		issuer:          cfg.Issuer,
+		skew:            cfg.Skew,
+		challengeExpire: cfg.Challengeexpire,
+		maxAttempts:     cfg.Maxattempts,
+		recoveryCodes:   cfg.Recoverycodes,
+	}
+	if policy.challengeExpire <= 0 {
+		policy.challengeExpire = 300
+	}
+	if policy.maxAttempts <= 0 {
+		policy.maxAttempts = 5
+	}
+	if policy.recoveryCodes <= 0 {
+		policy.recoveryCodes = 10
+	}
+	return policy
+}
+
+// TwoFactorEnabled whether login of username requires a 2fa code
+func (a *API) TwoFactorEnabled(username string) (bool, error) {
+	tf, err := a.users.GetDbTwoFactor(username)
+	return tf.Totpenabled, err
+}
+
+// SetupTwoFactor generate a pending totp secret of username, it takes effect once confirmed
+func (a *API) SetupTwoFactor(username string) (string, string, error) {
+	tf, err := a.users.GetDbTwoFactor(username)
+	if err != nil {
+		return "", "", err
+	}
+	if tf.Totpenabled {
+		return "", "", ErrTwoFactorState
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if a.users.UpdateDbTwoFactor(username, secret, false, nil) != 1 {
+		return "", "", errors.New("failed to save totp secret of " + username)
+	}
+	return secret, totp.URI(a.twoFactor.issuer, username, secret), nil
+}
+
+// ConfirmTwoFactor enable 2fa of username with a first code of the pending secret,
+// recovery codes are returned once and only their hashes are kept
+func (a *API) ConfirmTwoFactor(username, passcode string) ([]string, error) {
+	tf, err := a.users.GetDbTwoFactor(username)
+	if err != nil {
+		return nil, err
+	}
+	if tf.Totpenabled || tf.Totpsecret == "" {
+		return nil, ErrTwoFactorState
+	}
+	if !a.checkTOTP(username, tf.Totpsecret, passcode) {
+		return nil, ErrInvalidPasscode
+	}
+	codes, err := totp.GenerateRecoveryCodes(a.twoFactor.recoveryCodes)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, c := range codes {
+		hashes = append(hashes, totp.HashRecoveryCode(c))
+	}
+	if a.users.UpdateDbTwoFactor(username, tf.Totpsecret, true, hashes) != 1 {
+		return nil, errors.New("failed to enable 2fa of " + username)
+	}
+	log.Info("2fa enabled for user:", username)
+	return codes, nil
+}
+
+// DisableTwoFactor disable 2fa of username with a totp code or a recovery code
+func (a *API) DisableTwoFactor(username, passcode string) error {
+	tf, err := a.users.GetDbTwoFactor(username)
+	if err != nil {
+		return err
+	}
+	if !tf.Totpenabled {
+		return ErrTwoFactorState
+	}
+	if !a.verifyPasscode(tf, passcode) {
+		return ErrInvalidPasscode
+	}
+	if a.users.UpdateDbTwoFactor(username, "", false, nil) != 1 {
+		return errors.New("failed to disable 2fa of " + username)
+	}
+	log.Info("2fa disabled for user:", username)
+	return nil
+}
+
+// CreateChallenge start a 2fa login of username whose passwd has been verified
+func (a *API) CreateChallenge(username string) (string, error) {
+	challenge, err := utils.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	return challenge, a.sessions.CreateTwoFactorChallenge(challenge, username, a.twoFactor.challengeExpire)
+}
+
+// ChallengeUser username of a pending 2fa challenge
+func (a *API) ChallengeUser(challenge string) (string, error) {
+	username, err := a.sessions.GetTwoFactorChallenge(challenge)
+	if err != nil || username == "" {
+		return "", ErrChallengeExpired
+	}
+	return username, nil
+}
+
+// VerifyChallenge finish a 2fa login with a totp code or a recovery code, the challenge
+// is used up on success or after too many wrong codes
+func (a *API) VerifyChallenge(challenge, username, passcode string) (types.User, error) {
+	var user types.User
+	tf, err := a.users.GetDbTwoFactor(username)
+	if err != nil {
+		return user, err
+	}
+	if !tf.Totpenabled || !a.verifyPasscode(tf, passcode) {
+		attempts, err := a.sessions.FailTwoFactorChallenge(challenge)
+		if err != nil || attempts >= a.twoFactor.maxAttempts {
+			a.sessions.DelTwoFactorChallenge(challenge)
+		}
+		return user, ErrInvalidPasscode
+	}
+	if deleted, _ := a.sessions.DelTwoFactorChallenge(challenge); !deleted {
+		return user, ErrChallengeExpired
+	}
+	return a.GetUserInfo(username)
+}
+
+// verifyPasscode check a totp code, or use up a recovery code
+func (a *API) verifyPasscode(tf types.TwoFactor, passcode string) bool {
+	if len(passcode) == totp.Digits {
+		return a.checkTOTP(tf.Username, tf.Totpsecret, passcode)
+	}
+	hashes := tf.RecoveryCodeHashes()
+	left, ok := totp.UseRecoveryCode(hashes, passcode)
+	if !ok {
+		return false
+	}
+	if a.users.UpdateDbRecoveryCodes(tf.Username, hashes, left) != 1 {
+		log.Error("recovery code of user:", tf.Username, " used concurrently")
+		return false
+	}
+	log.Info("recovery code used by user:", tf.Username, ", left:", len(left))
+	return true
+}
+
+// checkTOTP validate code against secret, each code can be used only once
+func (a *API) checkTOTP(username, secret, passcode string) bool {
+	counter, ok := totp.Validate(secret, passcode, time.Now(), a.twoFactor.skew)
+	if !ok {
+		return false
+	}
+	// remember it as long as it's acceptable
+	unused, err := a.sessions.UseTOTPCounter(username, counter, (2*a.twoFactor.skew+1)*totp.Period)
+	if err != nil {
+		log.Error("failed to mark totp code used for user:", username, " with err:", err.Error())
+		return false
+	}
+	return unused
+}
@@ -1,24 +1,175 @@
 package types
 
-import "fmt"
+import (
+	"strings"
+)
 
 // User gorm user object
 type User struct {
-	ID       int32       `gorm:"types:int(11);primary key"`
-	Username string      `gorm:"types:varchar(64);unique;not null"`
-	Nickname string      `gorm:"types:varchar(128)"`
-	Passwd   string      `gorm:"types:varchar(32);not null"`
-	Skey     string      `gorm:"types:varchar(16);not null"`
-	Headurl  string      `gorm:"types:varchar(128);unique;not null"`
-	Uptime   int64       `gorm:"types:datetime"`
-}
-
-// TableName gorm use this to get tablename
-// NOTE : it only works int where caulse
-func (u User) TableName() string {
-	var value int
-	for _, c := range []rune(u.Username) {
-		value = value + int(c)
+	ID       int32       `gorm:"primary_key"`
+	// Uid global id, stable across renames and resharding, ID is only unique in a table
+	Uid      int64       `gorm:"type:bigint;not null;default:0"`
+	Username string      `gorm:"type:varchar(64);unique;not null"`
+	Nickname string      `gorm:"type:varchar(128);not null"`
+	Passwd   string      `gorm:"type:varchar(128);not null"`
+	Skey     string      `gorm:"type:varchar(16);not null"`
+	Headurl  string      `gorm:"type:varchar(128);not null"`
+	Uptime   int64       `gorm:"type:int(64);not null"`
+	// StatusActive or StatusDeactivated, deactivated accounts are purged after grace period
+	Status         int8  `gorm:"type:tinyint(1);not null"`
+	Deactivatetime int64 `gorm:"type:int(64);not null"`
+	// profile, empty if not set
+	Email    string `gorm:"type:varchar(128);not null"`
+	Phone    string `gorm:"type:varchar(32);not null"`
+	Bio      string `gorm:"type:varchar(512);not null"`
+	Locale   string `gorm:"type:varchar(16);not null"`
+	Timezone string `gorm:"type:varchar(64);not null"`
+	// Birthday as YYYY-MM-DD
+	Birthday string `gorm:"type:varchar(10);not null"`
+	// Emailverified Email has been verified by a link mailed to it, reset when Email changes
+	Emailverified bool `gorm:"type:tinyint(1);not null"`
+	// Roles comma separated roles of rbac, carried in sessions along with the rest
+	Roles string `gorm:"type:varchar(256);not null"`
+	// Avatar file name of the last avatar uploaded by the user, the only file deleted along with it
+	Avatar string `gorm:"type:varchar(64);not null"`
+}
+
+// RoleList roles of u
+func (u User) RoleList() []string {
+	if u.Roles == "" {
+		return nil
+	}
+	return strings.Split(u.Roles, ",")
+}
+
+// ProfileFields fields of a user that can be updated by UpdateProfile, named as in field masks and columns
+var ProfileFields = []string{"nickname", "headurl", "email", "phone", "bio", "locale", "timezone", "birthday"}
+
+// profile pointers to profile fields of u
+func (u *User) profile() map[string]*string {
+	return map[string]*string{
+		"nickname": &u.Nickname,
+		"headurl":  &u.Headurl,
+		"email":    &u.Email,
+		"phone":    &u.Phone,
+		"bio":      &u.Bio,
+		"locale":   &u.Locale,
+		"timezone": &u.Timezone,
+		"birthday": &u.Birthday,
+	}
+}
+
+// Profile value of profile field of u, false if field isn't one of ProfileFields
+func (u User) Profile(field string) (string, bool) {
+	value, ok := u.profile()[field]
+	if !ok {
+		return "", false
+	}
+	return *value, true
+}
+
+// SetProfile set profile field of u, false if field isn't one of ProfileFields
+func (u *User) SetProfile(field, value string) bool {
+	p, ok := u.profile()[field]
+	if ok {
+		*p = value
 	}
-	return fmt.Sprintf("userinfo_tab_%d", value % 20)
+	return ok
+}
+
+// UserFilter conditions of users to list, zero values match all
+type UserFilter struct {
+	// Prefix of username
+	Prefix   string
+	// Nickname substring of nickname
+	Nickname string
+	// Upfrom Upto range of uptime, Upto excluded and unlimited if 0
+	Upfrom   int64
+	Upto     int64
+}
+
+// Match whether u passes f, username and nickname are matched ignoring case as db collations do
+func (f UserFilter) Match(u User) bool {
+	return strings.HasPrefix(strings.ToLower(u.Username), strings.ToLower(f.Prefix)) &&
+		strings.Contains(strings.ToLower(u.Nickname), strings.ToLower(f.Nickname)) &&
+		u.Uptime >= f.Upfrom && (f.Upto == 0 || u.Uptime < f.Upto)
+}
+
+const (
+	// StatusActive account can login
+	StatusActive = 0
+	// StatusDeactivated account can't login but can be restored within grace period
+	StatusDeactivated = 1
+	// StatusLocked account can't login until an admin unlocks it
+	StatusLocked = 2
+)
+
+// TwoFactor totp settings of a user, kept out of User so they're never cached
+type TwoFactor struct {
+	Username      string `gorm:"type:varchar(64)"`
+	Totpsecret    string `gorm:"type:varchar(64)"`
+	Totpenabled   bool
+	Recoverycodes string `gorm:"type:varchar(1024)"`
+}
+
+// RecoveryCodeHashes hashes of unused recovery codes
+func (t TwoFactor) RecoveryCodeHashes() []string {
+	if t.Recoverycodes == "" {
+		return nil
+	}
+	return strings.Split(t.Recoverycodes, ",")
+}
+
+// Session metadata of a login session, cached along with its token
+type Session struct {
+	Token      string `json:"-"`
+	Username   string `json:"username"`
+	Createtime int64  `json:"createtime"`
+	Lastseen   int64  `json:"lastseen"`
+	IP         string `json:"ip"`
+	Useragent  string `json:"useragent"`
+}
+
+const (
+	// AuditSuccess the audited request succeeded
+	AuditSuccess = "success"
+	// AuditFailure the audited request was refused or failed
+	AuditFailure = "failure"
+	// AuditChallenged login is waiting for a 2fa passcode
+	AuditChallenged = "challenged"
+)
+
+// AuditEvent a security-relevant event of a user, appended to the audit log and never changed
+type AuditEvent struct {
+	// ID increasing in the order events are appended
+	ID       int64  `gorm:"primary_key" json:"id"`
+	Time     int64  `gorm:"type:bigint;not null" json:"time"`
+	// Username user the event is about, Actor user who caused it, an admin or the user itself
+	Username string `gorm:"type:varchar(64);not null" json:"username"`
+	// Uid global id of the user, it follows the user across renames. 0 if there's no such user
+	Uid      int64  `gorm:"type:bigint;not null;default:0" json:"uid"`
+	Actor    string `gorm:"type:varchar(64);not null" json:"actor"`
+	Action   string `gorm:"type:varchar(32);not null" json:"action"`
+	IP       string `gorm:"type:varchar(64);not null" json:"ip"`
+	UUID     string `gorm:"type:varchar(64);not null" json:"uuid"`
+	// Outcome AuditSuccess, AuditFailure or AuditChallenged, Code is the result code of the request
+	Outcome  string `gorm:"type:varchar(16);not null" json:"outcome"`
+	Code     uint32 `gorm:"type:int;not null" json:"code"`
+	Detail   string `gorm:"type:varchar(256);not null" json:"detail"`
+}
+
+// AuditFilter conditions of audit events to query, zero values match all
+type AuditFilter struct {
+	Username string
+	Uid      int64
+	Action   string
+	// From To range of time, To excluded and unlimited if 0
+	From     int64
+	To       int64
+}
+
+// Match whether e passes f
+func (f AuditFilter) Match(e AuditEvent) bool {
+	return (f.Username == "" || e.Username == f.Username) && (f.Uid == 0 || e.Uid == f.Uid) && (f.Action == "" || e.Action == f.Action) &&
+		e.Time >= f.From && (f.To == 0 || e.Time < f.To)
 }
@@ -2,10 +2,15 @@ package tcpserver
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	"user-management-system/tcpserver/db"
+	"user-management-system/tcpserver/types"
 	"user-management-system/type/code"
 	pb "user-management-system/type/proto"
 	"user-management-system/utils"
+	"user-management-system/utils/jwt"
 
 	log "github.com/beego/beego/v2/adapter/logs"
 	"google.golang.org/grpc/metadata"
@@ -16,50 +21,248 @@ type UserServer struct {
 	API *API
 }
 
-func getUUID(ctx context.Context) string {
-	var uuid string
+// getMetadata get value of key from incoming metadata set by httpserver
+func getMetadata(ctx context.Context, key string) string {
+	var value string
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return uuid
+		return value
 	}
-	uuids := md.Get("uuid")
-	if len(uuids) == 1 {
-		uuid = uuids[0]
+	values := md.Get(key)
+	if len(values) == 1 {
+		value = values[0]
 	}
-	return uuid
+	return value
+}
+
+func getUUID(ctx context.Context) string {
+	return getMetadata(ctx, "uuid")
 }
 
 // Login login handler
 func (s *UserServer) Login(ctx context.Context, in *pb.LoginRequest) (*pb.LoginResponse, error) {
+	return s.login(ctx, in, false), nil
+}
+
+// RestoreAccount restore a deactivated account within grace period, then login as usual
+func (s *UserServer) RestoreAccount(ctx context.Context, in *pb.LoginRequest) (*pb.LoginResponse, error) {
+	return s.login(ctx, in, true), nil
+}
+
+// login verify passwd and create a session, or a 2fa challenge if it's enabled
+func (s *UserServer) login(ctx context.Context, in *pb.LoginRequest, restore bool) *pb.LoginResponse {
 	// get uuid
 	uuid := getUUID(ctx)
-	log.Debug(uuid, " -- Login access from:", in.Username, "@", in.Passwd)
+	clientIP := getMetadata(ctx, "clientip")
+	log.Debug(uuid, " -- Login access from:", in.Username, " ip:", clientIP)
+	// throttle failed logins
+	userLock, ipLock := s.API.LoginLocked(in.Username, clientIP)
+	if userLock > 0 {
+		log.Error(uuid, " -- Account locked:", in.Username, " retry after:", userLock)
+		return lockedResponse(code.CodeTCPAccountLocked, userLock)
+	}
+	if ipLock > 0 {
+		log.Error(uuid, " -- IP locked:", clientIP, " retry after:", ipLock)
+		return lockedResponse(code.CodeTCPTooManyAttempts, ipLock)
+	}
+
 	// query userinfo
 	log.Debug("try to get user info...")
 	user, err := s.API.GetUserInfo(in.Username)
-    log.Debug("get user: ", user)
 	if err != nil {
-		log.Error(uuid, " -- Failed to getUserInfo, ", in.Username, "@", in.Passwd, ", err:", err.Error())
-		return &pb.LoginResponse{Code: code.CodeTCPFailedGetUserInfo, Msg: code.CodeMsg[code.CodeTCPFailedGetUserInfo]}, nil
+		log.Error(uuid, " -- Failed to getUserInfo, ", in.Username, ", err:", err.Error())
+		s.API.LoginFailed(in.Username, clientIP)
+		return &pb.LoginResponse{Code: code.CodeTCPFailedGetUserInfo, Msg: code.CodeMsg[code.CodeTCPFailedGetUserInfo]}
 	}
 
 	// verify passwd
-    log.Debug("passwd: ", utils.Md5String("123456" + user.Skey))
-    log.Debug("db passwd:", user.Passwd)
-	if utils.Md5String(in.Passwd+user.Skey) != user.Passwd {
-		log.Error(uuid, " -- Failed to match passwd ", in.Username, "@", in.Passwd, " salt:", user.Skey, " realpwd:", user.Passwd)
-		return &pb.LoginResponse{Code: code.CodeTCPPasswdErr, Msg: code.CodeMsg[code.CodeTCPPasswdErr]}, nil
+	if !s.API.VerifyPasswd(user, in.Passwd) {
+		log.Error(uuid, " -- Failed to match passwd ", in.Username)
+		s.API.LoginFailed(in.Username, clientIP)
+		return &pb.LoginResponse{Code: code.CodeTCPPasswdErr, Msg: code.CodeMsg[code.CodeTCPPasswdErr]}
+	}
+
+	if user.Status == types.StatusLocked {
+		log.Error(uuid, " -- Account locked by admin:", user.Username)
+		return &pb.LoginResponse{Code: code.CodeTCPAccountDisabled, Msg: code.CodeMsg[code.CodeTCPAccountDisabled]}
+	}
+	// deactivated accounts can only login by restoring them
+	if user.Status == types.StatusDeactivated {
+		if !restore && time.Now().Unix() < s.API.PurgeTime(user) {
+			log.Error(uuid, " -- Account deactivated:", user.Username)
+			return &pb.LoginResponse{Username: user.Username, Purgetime: s.API.PurgeTime(user),
+				Code: code.CodeTCPAccountDeactivated, Msg: code.CodeMsg[code.CodeTCPAccountDeactivated]}
+		}
+		if err = s.API.RestoreAccount(user); err != nil {
+			log.Error(uuid, " -- Failed to restore account:", user.Username, " err:", err.Error())
+			return &pb.LoginResponse{Code: code.CodeTCPFailedGetUserInfo, Msg: code.CodeMsg[code.CodeTCPFailedGetUserInfo]}
+		}
+		user.Status, user.Deactivatetime = types.StatusActive, 0
+	}
+
+	// 2fa, failures are only forgotten after the code is verified too
+	enabled, err := s.API.TwoFactorEnabled(user.Username)
+	if err != nil {
+		log.Error(uuid, " -- Failed to get 2fa of user:", user.Username, " err:", err.Error())
+		return &pb.LoginResponse{Code: code.CodeTCPInternelErr, Msg: code.CodeMsg[code.CodeTCPInternelErr]}
+	}
+	if enabled {
+		challenge, err := s.API.CreateChallenge(user.Username)
+		if err != nil {
+			log.Error(uuid, " -- Failed to create 2fa challenge for user:", user.Username, " err:", err.Error())
+			return &pb.LoginResponse{Code: code.CodeTCPInternelErr, Msg: code.CodeMsg[code.CodeTCPInternelErr]}
+		}
+		log.Debug(uuid, " -- 2fa required for user:", user.Username)
+		return &pb.LoginResponse{Uid: user.Uid, Username: user.Username, Challenge: challenge,
+			Code: code.CodeTCPTwoFactorRequired, Msg: code.CodeMsg[code.CodeTCPTwoFactorRequired]}
+	}
+	return s.createSession(ctx, uuid, user)
+}
+
+// VerifyTwoFactor exchange a 2fa challenge and code for a session token
+func (s *UserServer) VerifyTwoFactor(ctx context.Context, in *pb.VerifyTwoFactorRequest) (*pb.LoginResponse, error) {
+	// get uuid
+	uuid := getUUID(ctx)
+	clientIP := getMetadata(ctx, "clientip")
+	log.Debug(uuid, " -- VerifyTwoFactor access from ip:", clientIP)
+	username, err := s.API.ChallengeUser(in.Challenge)
+	if err != nil {
+		log.Error(uuid, " -- Failed to get 2fa challenge, err:", err.Error())
+		return &pb.LoginResponse{Code: code.CodeTCPChallengeExpired, Msg: code.CodeMsg[code.CodeTCPChallengeExpired]}, nil
 	}
+	auditUser(ctx, username)
+	// throttle failed codes as failed logins
+	userLock, ipLock := s.API.LoginLocked(username, clientIP)
+	if userLock > 0 {
+		log.Error(uuid, " -- Account locked:", username, " retry after:", userLock)
+		return lockedResponse(code.CodeTCPAccountLocked, userLock), nil
+	}
+	if ipLock > 0 {
+		log.Error(uuid, " -- IP locked:", clientIP, " retry after:", ipLock)
+		return lockedResponse(code.CodeTCPTooManyAttempts, ipLock), nil
+	}
+
+	user, err := s.API.VerifyChallenge(in.Challenge, username, in.Passcode)
+	switch err {
+	case nil:
+	case ErrInvalidPasscode:
+		log.Error(uuid, " -- Invalid 2fa code of user:", username)
+		s.API.LoginFailed(username, clientIP)
+		return &pb.LoginResponse{Code: code.CodeTCPInvalidPasscode, Msg: code.CodeMsg[code.CodeTCPInvalidPasscode]}, nil
+	case ErrChallengeExpired:
+		log.Error(uuid, " -- 2fa challenge used by others, user:", username)
+		return &pb.LoginResponse{Code: code.CodeTCPChallengeExpired, Msg: code.CodeMsg[code.CodeTCPChallengeExpired]}, nil
+	default:
+		log.Error(uuid, " -- Failed to verify 2fa of user:", username, " err:", err.Error())
+		return &pb.LoginResponse{Code: code.CodeTCPInternelErr, Msg: code.CodeMsg[code.CodeTCPInternelErr]}, nil
+	}
+	// locked while the challenge was pending
+	if user.Status == types.StatusLocked {
+		log.Error(uuid, " -- Account locked by admin:", user.Username)
+		return &pb.LoginResponse{Code: code.CodeTCPAccountDisabled, Msg: code.CodeMsg[code.CodeTCPAccountDisabled]}, nil
+	}
+	return s.createSession(ctx, uuid, user), nil
+}
+
+// createSession create a session of user after a successful login
+func (s *UserServer) createSession(ctx context.Context, uuid string, user types.User) *pb.LoginResponse {
+	clientIP := getMetadata(ctx, "clientip")
+	s.API.LoginSucceeded(user.Username)
 
 	// set cache
-	token := utils.GenerateToken(user.Username)
-	err = s.API.redisClient.SetTokenInfo(user, token)
+	token, err := utils.GenerateToken()
+	if err != nil {
+		log.Error(uuid, " -- Failed to generate token for user:", user.Username, " err:", err.Error())
+		return &pb.LoginResponse{Code: code.CodeTCPInternelErr, Msg: code.CodeMsg[code.CodeTCPInternelErr]}
+	}
+	now := time.Now().Unix()
+	session := types.Session{
+		Token:      token,
+		Username:   user.Username,
+		Createtime: now,
+		Lastseen:   now,
+		IP:         clientIP,
+		Useragent:  getMetadata(ctx, "useragent"),
+	}
+	err = s.API.sessions.CreateSession(user, session)
 	if err != nil {
 		log.Error(uuid, " -- Failed to set token for user:", user.Username, " err:", err.Error())
+		return &pb.LoginResponse{Code: code.CodeTCPInternelErr, Msg: code.CodeMsg[code.CodeTCPInternelErr]}
+	}
+	log.Debug(uuid, " -- Login succesfully, ", user.Username, " with token:", token)
+	return s.tokenResponse(uuid, user, token)
+}
+
+// lockedResponse response of a locked login with seconds to wait
+func lockedResponse(c, retryAfter int) *pb.LoginResponse {
+	msg := fmt.Sprintf("%s (%d seconds)", code.CodeMsg[c], retryAfter)
+	return &pb.LoginResponse{Code: uint32(c), Msg: msg, Retryafter: int64(retryAfter)}
+}
+
+// userResponse succ response of userinfo and profile of user
+func userResponse(user types.User) *pb.LoginResponse {
+	return &pb.LoginResponse{Uid: user.Uid, Username: user.Username, Nickname: user.Nickname, Headurl: user.Headurl,
+		Email: user.Email, Phone: user.Phone, Bio: user.Bio, Locale: user.Locale, Timezone: user.Timezone, Birthday: user.Birthday,
+		Emailverified: user.Emailverified, Roles: user.RoleList(), Status: int32(user.Status), Code: code.CodeSucc}
+}
+
+// tokenResponse response of a new session token, in signed mode it becomes
+// refresh token of a new signed access token
+func (s *UserServer) tokenResponse(uuid string, user types.User, token string) *pb.LoginResponse {
+	rsp := userResponse(user)
+	rsp.Token, rsp.Expire = token, int64(s.API.sessions.TokenLifetime())
+	if !s.API.Signed() {
+		return rsp
+	}
+
+	accessToken, err := s.API.IssueAccessToken(user, token)
+	if err != nil {
+		log.Error(uuid, " -- Failed to sign access token for user:", user.Username, " err:", err.Error())
+		s.API.sessions.DelTokenInfo(token)
+		return &pb.LoginResponse{Code: code.CodeTCPInternelErr, Msg: code.CodeMsg[code.CodeTCPInternelErr]}
+	}
+	rsp.Refreshtoken, rsp.Refreshexpire = rsp.Token, rsp.Expire
+	rsp.Token, rsp.Expire = accessToken, s.API.accessExpire
+	return rsp
+}
+
+// RefreshToken retire a session token (refresh token in signed mode) and issue a new one
+func (s *UserServer) RefreshToken(ctx context.Context, in *pb.CommRequest) (*pb.LoginResponse, error) {
+	// get uuid
+	uuid := getUUID(ctx)
+	log.Debug(uuid, " -- RefreshToken access from:", in.Username, " with token:", in.Token)
+	// only session tokens can be refreshed
+	token := in.Token
+	if !utils.CheckToken(token) {
+		log.Error(uuid, " -- Error: invalid token:", in.Token)
+		return &pb.LoginResponse{Code: code.CodeTCPInvalidToken, Msg: code.CodeMsg[code.CodeTCPInvalidToken]}, nil
+	}
+	user, err := s.API.sessions.GetTokenInfo(token)
+	if err != nil {
+		log.Error(uuid, " -- Failed to get token:", in.Token, " with err:", err.Error())
+		return &pb.LoginResponse{Code: code.CodeTCPTokenExpired, Msg: code.CodeMsg[code.CodeTCPTokenExpired]}, nil
+	}
+	if user.Username != in.Username {
+		log.Error(uuid, " -- Error: token info not match:", in.Username, " while cache:", user.Username)
+		return &pb.LoginResponse{Code: code.CodeTCPUserInfoNotMatch, Msg: code.CodeMsg[code.CodeTCPUserInfoNotMatch]}, nil
+	}
+
+	newToken, ok, err := s.API.RotateToken(user.Username, token)
+	if err != nil {
+		log.Error(uuid, " -- Failed to rotate token:", in.Token, " with err:", err.Error())
 		return &pb.LoginResponse{Code: code.CodeTCPInternelErr, Msg: code.CodeMsg[code.CodeTCPInternelErr]}, nil
 	}
-	log.Debug(uuid, " -- Login succesfully, ", in.Username, "@", in.Passwd, " with token:", token)
-	return &pb.LoginResponse{Username: user.Username, Nickname: user.Nickname, Headurl: user.Headurl, Token: token, Code: code.CodeSucc}, nil
+	if !ok {
+		log.Error(uuid, " -- Token expired while rotating:", in.Token)
+		return &pb.LoginResponse{Code: code.CodeTCPTokenExpired, Msg: code.CodeMsg[code.CodeTCPTokenExpired]}, nil
+	}
+
+	// latest userinfo for the response and access token
+	if latest, err := s.API.GetUserInfo(user.Username); err == nil {
+		user = latest
+	}
+	log.Debug(uuid, " -- Succ to refresh token:", in.Token, " to:", newToken)
+	return s.tokenResponse(uuid, user, newToken), nil
 }
 
 // GetUserInfo get user info
@@ -69,27 +272,55 @@ func (s *UserServer) GetUserInfo(ctx context.Context, in *pb.CommRequest) (*pb.L
 	log.Debug(uuid, " -- GetUserInfo access from:", in.Username, " with token:", in.Token)
 	// get and verify token
 	token := in.Token
-	if len(token) != 32 {
+	if !utils.CheckToken(token) && !jwt.IsSigned(token) {
 		log.Error(uuid, " -- Error: invalid token:", in.Token)
 		return &pb.LoginResponse{Code: code.CodeTCPInvalidToken, Msg: code.CodeMsg[code.CodeTCPInvalidToken]}, nil
 	}
 	// get userinfo and compare username
-	user, err := s.API.redisClient.GetTokenInfo(token)
+	user, err := s.API.TokenUser(token)
 	if err != nil {
 		log.Error(uuid, " -- Failed to get token:", in.Token, " with err:", err.Error())
 		return &pb.LoginResponse{Code: code.CodeTCPTokenExpired, Msg: code.CodeMsg[code.CodeTCPTokenExpired]}, nil
 	}
 
-	// check if username is the same
+	// check if username is the same, or renamed from it lately. renames are only looked up on a mismatch
 	if user.Username != in.Username {
-		log.Error(uuid, " -- Error: token info not match:", in.Username, " while cache:", user.Username)
-		return &pb.LoginResponse{Code: code.CodeTCPUserInfoNotMatch, Msg: code.CodeMsg[code.CodeTCPUserInfoNotMatch]}, nil
+		if renamed, ok := s.API.RenamedUser(in.Username); !ok || renamed != user.Username {
+			log.Error(uuid, " -- Error: token info not match:", in.Username, " while cache:", user.Username)
+			return &pb.LoginResponse{Code: code.CodeTCPUserInfoNotMatch, Msg: code.CodeMsg[code.CodeTCPUserInfoNotMatch]}, nil
+		}
+	}
+	// userinfo of token may be stale, edited by other sessions or verified by mail, and signed
+	// access tokens only carry nickname and headurl; the profile is read from cache
+	if user, err = s.API.GetUserInfo(user.Username); err != nil {
+		log.Error(uuid, " -- Failed to get profile of:", in.Username, " with err:", err.Error())
+		return &pb.LoginResponse{Code: code.CodeTCPFailedGetUserInfo, Msg: code.CodeMsg[code.CodeTCPFailedGetUserInfo]}, nil
 	}
 	log.Debug(uuid, " -- Succ to GetUserInfo :", in.Username, " with token:", in.Token)
-	return &pb.LoginResponse{Username: user.Username, Nickname: user.Nickname, Headurl: user.Headurl, Token: token, Code: code.CodeSucc}, nil
+	rsp := userResponse(user)
+	rsp.Token = token
+	return rsp, nil
+}
+
+// GetUserByID get public userinfo by global uid, for services which keep uids instead of usernames
+func (s *UserServer) GetUserByID(ctx context.Context, in *pb.UserIDRequest) (*pb.LoginResponse, error) {
+	// get uuid
+	uuid := getUUID(ctx)
+	log.Debug(uuid, " -- GetUserByID access for uid:", in.Uid)
+	user, err := s.API.GetUserByUid(in.Uid)
+	if err != nil {
+		log.Error(uuid, " -- Failed to get user of uid:", in.Uid, " err:", err.Error())
+		return &pb.LoginResponse{Code: code.CodeTCPFailedGetUserInfo, Msg: code.CodeMsg[code.CodeTCPFailedGetUserInfo]}, nil
+	}
+	if user.Status == types.StatusDeactivated {
+		return &pb.LoginResponse{Uid: user.Uid, Username: user.Username, Purgetime: s.API.PurgeTime(user),
+			Code: code.CodeTCPAccountDeactivated, Msg: code.CodeMsg[code.CodeTCPAccountDeactivated]}, nil
+	}
+	log.Debug(uuid, " -- Succ to GetUserByID :", in.Uid)
+	return userResponse(user), nil
 }
 
-// EditUserInfo edit userinfo (nickname, headurl or both)
+// EditUserInfo edit nickname and headurl by field mask, or by edit mode of older clients
 func (s *UserServer) EditUserInfo(ctx context.Context, in *pb.EditRequest) (*pb.EditResponse, error) {
 	// get uuid
 	uuid := getUUID(ctx)
@@ -100,20 +331,622 @@ func (s *UserServer) EditUserInfo(ctx context.Context, in *pb.EditRequest) (*pb.
 		log.Error(uuid, " -- Failed to auth for user:", in.Username, " with token:", in.Token)
 		return &pb.EditResponse{Code: code.CodeTCPTokenExpired, Msg: code.CodeMsg[code.CodeTCPTokenExpired]}, nil
 	}
-	affectRows := s.API.EditUserInfo(in.Username, in.Nickname, in.Headurl, in.Token, in.Mode)
-	log.Error(uuid, " -- Succ to edit userinfo, affected rows is:", affectRows)
+	_, err := s.API.EditUserInfo(in.Username, in.Nickname, in.Headurl, in.Avatar, in.Token, in.GetMask().GetPaths(), in.Mode)
+	if _, ok := err.(*ProfileError); ok || err == ErrEmptyMask {
+		log.Error(uuid, " -- Invalid userinfo of:", in.Username, " err:", err.Error())
+		return &pb.EditResponse{Code: code.CodeTCPInvalidProfile, Msg: code.CodeMsg[code.CodeTCPInvalidProfile] + ": " + err.Error()}, nil
+	}
+	if err != nil {
+		log.Error(uuid, " -- Failed to edit userinfo of:", in.Username, " err:", err.Error())
+		return &pb.EditResponse{Code: code.CodeTCPFailedUpdateUserInfo, Msg: code.CodeMsg[code.CodeTCPFailedUpdateUserInfo]}, nil
+	}
+	log.Debug(uuid, " -- Succ to edit userinfo of:", in.Username)
+	return &pb.EditResponse{Code: code.CodeSucc, Msg: code.CodeMsg[code.CodeSucc]}, nil
+}
+
+// UpdateProfile update the profile fields of mask, return the updated profile
+func (s *UserServer) UpdateProfile(ctx context.Context, in *pb.ProfileRequest) (*pb.LoginResponse, error) {
+	// get uuid
+	uuid := getUUID(ctx)
+	log.Debug(uuid, " -- UpdateProfile access from:", in.Username, " with token:", in.Token, " mask:", in.Mask)
+	// auth
+	pass := s.API.Auth(in.Username, in.Token)
+	if !pass {
+		log.Error(uuid, " -- Failed to auth for user:", in.Username, " with token:", in.Token)
+		return &pb.LoginResponse{Code: code.CodeTCPTokenExpired, Msg: code.CodeMsg[code.CodeTCPTokenExpired]}, nil
+	}
+
+	profile := types.User{Nickname: in.Nickname, Headurl: in.Headurl, Email: in.Email, Phone: in.Phone,
+		Bio: in.Bio, Locale: in.Locale, Timezone: in.Timezone, Birthday: in.Birthday}
+	user, err := s.API.UpdateProfile(in.Username, in.Token, profile, in.Mask)
+	if _, ok := err.(*ProfileError); ok || err == ErrEmptyMask {
+		log.Error(uuid, " -- Invalid profile of:", in.Username, " err:", err.Error())
+		return &pb.LoginResponse{Code: code.CodeTCPInvalidProfile, Msg: code.CodeMsg[code.CodeTCPInvalidProfile] + ": " + err.Error()}, nil
+	}
+	if err != nil {
+		log.Error(uuid, " -- Failed to update profile of:", in.Username, " err:", err.Error())
+		return &pb.LoginResponse{Code: code.CodeTCPFailedUpdateUserInfo, Msg: code.CodeMsg[code.CodeTCPFailedUpdateUserInfo]}, nil
+	}
+	log.Debug(uuid, " -- Succ to update profile of:", in.Username)
+	return userResponse(user), nil
+}
+
+// SendVerifyEmail mail a new verification link to the email of user
+func (s *UserServer) SendVerifyEmail(ctx context.Context, in *pb.CommRequest) (*pb.EditResponse, error) {
+	// get uuid
+	uuid := getUUID(ctx)
+	log.Debug(uuid, " -- SendVerifyEmail access from:", in.Username, " with token:", in.Token)
+	// auth
+	pass := s.API.Auth(in.Username, in.Token)
+	if !pass {
+		log.Error(uuid, " -- Failed to auth for user:", in.Username, " with token:", in.Token)
+		return &pb.EditResponse{Code: code.CodeTCPTokenExpired, Msg: code.CodeMsg[code.CodeTCPTokenExpired]}, nil
+	}
+	user, err := s.API.GetUserInfo(in.Username)
+	if err != nil {
+		log.Error(uuid, " -- Failed to get userinfo of:", in.Username, " err:", err.Error())
+		return &pb.EditResponse{Code: code.CodeTCPFailedGetUserInfo, Msg: code.CodeMsg[code.CodeTCPFailedGetUserInfo]}, nil
+	}
+
+	err = s.API.SendVerifyEmail(user)
+	if err == ErrEmailState {
+		log.Error(uuid, " -- No email to verify of:", in.Username)
+		return &pb.EditResponse{Code: code.CodeTCPEmailState, Msg: code.CodeMsg[code.CodeTCPEmailState]}, nil
+	}
+	if err != nil {
+		log.Error(uuid, " -- Failed to send verification mail to:", in.Username, " err:", err.Error())
+		return &pb.EditResponse{Code: code.CodeTCPFailedSendMail, Msg: code.CodeMsg[code.CodeTCPFailedSendMail]}, nil
+	}
+	log.Debug(uuid, " -- Succ to send verification mail to:", in.Username)
+	return &pb.EditResponse{Code: code.CodeSucc, Msg: code.CodeMsg[code.CodeSucc]}, nil
+}
+
+// VerifyEmail verify the email of a mailed link, the token is all it takes
+func (s *UserServer) VerifyEmail(ctx context.Context, in *pb.VerifyEmailRequest) (*pb.EditResponse, error) {
+	// get uuid
+	uuid := getUUID(ctx)
+	log.Debug(uuid, " -- VerifyEmail access with token:", in.Token)
+	user, err := s.API.VerifyEmail(in.Token)
+	auditUser(ctx, user.Username)
+	if err == ErrEmailToken {
+		log.Error(uuid, " -- Invalid email verification token:", in.Token)
+		return &pb.EditResponse{Code: code.CodeTCPInvalidEmailToken, Msg: code.CodeMsg[code.CodeTCPInvalidEmailToken]}, nil
+	}
+	if err != nil {
+		log.Error(uuid, " -- Failed to verify email with token:", in.Token, " err:", err.Error())
+		return &pb.EditResponse{Code: code.CodeTCPInternelErr, Msg: code.CodeMsg[code.CodeTCPInternelErr]}, nil
+	}
+	log.Debug(uuid, " -- Succ to verify email of:", user.Username)
+	return &pb.EditResponse{Code: code.CodeSucc, Msg: code.CodeMsg[code.CodeSucc]}, nil
+}
+
+// RequestPasswordReset mail a passwd reset link to the verified email of user, the response is
+// the same whether it's been sent or not
+func (s *UserServer) RequestPasswordReset(ctx context.Context, in *pb.PasswordResetRequest) (*pb.EditResponse, error) {
+	// get uuid
+	uuid := getUUID(ctx)
+	log.Debug(uuid, " -- RequestPasswordReset access for:", in.Username)
+	if utils.CheckUsername(in.Username) {
+		s.API.RequestPasswdReset(in.Username)
+	}
+	return &pb.EditResponse{Code: code.CodeSucc, Msg: code.CodeMsg[code.CodeSucc]}, nil
+}
+
+// ResetPassword set a new passwd with the token of a reset link, all sessions of the user are revoked
+func (s *UserServer) ResetPassword(ctx context.Context, in *pb.ResetPasswordRequest) (*pb.EditResponse, error) {
+	// get uuid
+	uuid := getUUID(ctx)
+	log.Debug(uuid, " -- ResetPassword access with token:", in.Token)
+	// check before the token is used up
+	if !utils.CheckPasswd(in.Newpasswd) {
+		log.Error(uuid, " -- Error: invalid new passwd")
+		return &pb.EditResponse{Code: code.CodeTCPInvalidPasswd, Msg: code.CodeMsg[code.CodeTCPInvalidPasswd]}, nil
+	}
+
+	username, err := s.API.ResetPasswd(in.Token, in.Newpasswd)
+	auditUser(ctx, username)
+	if err == ErrResetToken {
+		log.Error(uuid, " -- Invalid passwd reset token:", in.Token)
+		return &pb.EditResponse{Code: code.CodeTCPInvalidResetToken, Msg: code.CodeMsg[code.CodeTCPInvalidResetToken]}, nil
+	}
+	if err != nil {
+		log.Error(uuid, " -- Failed to reset passwd of:", username, " err:", err.Error())
+		return &pb.EditResponse{Code: code.CodeTCPFailedUpdateUserInfo, Msg: code.CodeMsg[code.CodeTCPFailedUpdateUserInfo]}, nil
+	}
+	log.Debug(uuid, " -- Succ to reset passwd of:", username)
+	return &pb.EditResponse{Code: code.CodeSucc, Msg: code.CodeMsg[code.CodeSucc]}, nil
+}
+
+// ChangeUsername move user to a new username, sessions go with it and the old name is reserved for a while
+func (s *UserServer) ChangeUsername(ctx context.Context, in *pb.ChangeUsernameRequest) (*pb.LoginResponse, error) {
+	// get uuid
+	uuid := getUUID(ctx)
+	log.Debug(uuid, " -- ChangeUsername access from:", in.Username, " with token:", in.Token, " to:", in.Newname)
+	if !utils.CheckUsername(in.Newname) {
+		log.Error(uuid, " -- Error: invalid username:", in.Newname)
+		return &pb.LoginResponse{Code: code.CodeTCPInvalidUsername, Msg: code.CodeMsg[code.CodeTCPInvalidUsername]}, nil
+	}
+	// auth
+	pass := s.API.Auth(in.Username, in.Token)
+	if !pass {
+		log.Error(uuid, " -- Failed to auth for user:", in.Username, " with token:", in.Token)
+		return &pb.LoginResponse{Code: code.CodeTCPTokenExpired, Msg: code.CodeMsg[code.CodeTCPTokenExpired]}, nil
+	}
+	sessionToken := s.API.SessionToken(in.Token)
+
+	user, err := s.API.ChangeUsername(in.Username, in.Newname)
+	if err == db.ErrUserExists || err == ErrSameUsername {
+		log.Error(uuid, " -- Failed to change username, taken:", in.Newname)
+		return &pb.LoginResponse{Code: code.CodeTCPUserExists, Msg: code.CodeMsg[code.CodeTCPUserExists]}, nil
+	}
+	if err != nil {
+		log.Error(uuid, " -- Failed to change username of:", in.Username, " err:", err.Error())
+		return &pb.LoginResponse{Code: code.CodeTCPFailedUpdateUserInfo, Msg: code.CodeMsg[code.CodeTCPFailedUpdateUserInfo]}, nil
+	}
+	log.Debug(uuid, " -- Succ to change username:", in.Username, " to:", user.Username)
+	// signed access tokens carry the username, a new one is issued for the session
+	if s.API.Signed() && sessionToken != "" {
+		return s.tokenResponse(uuid, user, sessionToken), nil
+	}
+	return userResponse(user), nil
+}
+
+// ChangePasswd change passwd after verifying the current one, then revoke other sessions
+func (s *UserServer) ChangePasswd(ctx context.Context, in *pb.ChangePasswdRequest) (*pb.EditResponse, error) {
+	// get uuid
+	uuid := getUUID(ctx)
+	log.Debug(uuid, " -- ChangePasswd access from:", in.Username, " with token:", in.Token)
+	// auth
+	pass := s.API.Auth(in.Username, in.Token)
+	if !pass {
+		log.Error(uuid, " -- Failed to auth for user:", in.Username, " with token:", in.Token)
+		return &pb.EditResponse{Code: code.CodeTCPTokenExpired, Msg: code.CodeMsg[code.CodeTCPTokenExpired]}, nil
+	}
+	if !utils.CheckPasswd(in.Newpasswd) {
+		log.Error(uuid, " -- Error: invalid new passwd for user:", in.Username)
+		return &pb.EditResponse{Code: code.CodeTCPInvalidPasswd, Msg: code.CodeMsg[code.CodeTCPInvalidPasswd]}, nil
+	}
+
+	// verify current passwd
+	user, err := s.API.GetUserInfo(in.Username)
+	if err != nil {
+		log.Error(uuid, " -- Failed to getUserInfo, ", in.Username, ", err:", err.Error())
+		return &pb.EditResponse{Code: code.CodeTCPFailedGetUserInfo, Msg: code.CodeMsg[code.CodeTCPFailedGetUserInfo]}, nil
+	}
+	if !s.API.VerifyPasswd(user, in.Oldpasswd) {
+		log.Error(uuid, " -- Failed to match passwd ", in.Username)
+		return &pb.EditResponse{Code: code.CodeTCPPasswdErr, Msg: code.CodeMsg[code.CodeTCPPasswdErr]}, nil
+	}
+
+	var keepToken string
+	if in.Keepsession {
+		keepToken = s.API.SessionToken(in.Token)
+	}
+	if err = s.API.ChangePasswd(in.Username, in.Newpasswd, keepToken); err != nil {
+		log.Error(uuid, " -- Failed to change passwd for user:", in.Username, " err:", err.Error())
+		return &pb.EditResponse{Code: code.CodeTCPFailedUpdateUserInfo, Msg: code.CodeMsg[code.CodeTCPFailedUpdateUserInfo]}, nil
+	}
+	log.Debug(uuid, " -- Succ to change passwd for user:", in.Username)
+	return &pb.EditResponse{Code: code.CodeSucc, Msg: code.CodeMsg[code.CodeSucc]}, nil
+}
+
+// ListSessions list active sessions of user
+func (s *UserServer) ListSessions(ctx context.Context, in *pb.CommRequest) (*pb.SessionsResponse, error) {
+	// get uuid
+	uuid := getUUID(ctx)
+	log.Debug(uuid, " -- ListSessions access from:", in.Username, " with token:", in.Token)
+	// auth
+	pass := s.API.Auth(in.Username, in.Token)
+	if !pass {
+		log.Error(uuid, " -- Failed to auth for user:", in.Username, " with token:", in.Token)
+		return &pb.SessionsResponse{Code: code.CodeTCPTokenExpired, Msg: code.CodeMsg[code.CodeTCPTokenExpired]}, nil
+	}
+
+	sessions, err := s.API.ListSessions(in.Username)
+	if err != nil {
+		log.Error(uuid, " -- Failed to list sessions for user:", in.Username, " err:", err.Error())
+		return &pb.SessionsResponse{Code: code.CodeTCPInternelErr, Msg: code.CodeMsg[code.CodeTCPInternelErr]}, nil
+	}
+	rsp := &pb.SessionsResponse{Code: code.CodeSucc, Msg: code.CodeMsg[code.CodeSucc]}
+	current := s.API.SessionToken(in.Token)
+	for _, session := range sessions {
+		rsp.Sessions = append(rsp.Sessions, &pb.SessionInfo{
+			Id:         SessionID(session.Token),
+			Createtime: session.Createtime,
+			Lastseen:   session.Lastseen,
+			Ip:         session.IP,
+			Useragent:  session.Useragent,
+			Current:    session.Token == current,
+		})
+	}
+	log.Debug(uuid, " -- Succ to list sessions for user:", in.Username, ", count:", len(rsp.Sessions))
+	return rsp, nil
+}
+
+// RevokeSession revoke one or all sessions of user
+func (s *UserServer) RevokeSession(ctx context.Context, in *pb.RevokeSessionRequest) (*pb.EditResponse, error) {
+	// get uuid
+	uuid := getUUID(ctx)
+	log.Debug(uuid, " -- RevokeSession access from:", in.Username, " with token:", in.Token, " session:", in.Sessionid, " all:", in.All)
+	// auth
+	pass := s.API.Auth(in.Username, in.Token)
+	if !pass {
+		log.Error(uuid, " -- Failed to auth for user:", in.Username, " with token:", in.Token)
+		return &pb.EditResponse{Code: code.CodeTCPTokenExpired, Msg: code.CodeMsg[code.CodeTCPTokenExpired]}, nil
+	}
+
+	if in.All {
+		revoked, err := s.API.RevokeAllSessions(in.Username)
+		if err != nil {
+			log.Error(uuid, " -- Failed to revoke sessions for user:", in.Username, " err:", err.Error())
+			return &pb.EditResponse{Code: code.CodeTCPInternelErr, Msg: code.CodeMsg[code.CodeTCPInternelErr]}, nil
+		}
+		log.Debug(uuid, " -- Succ to revoke all sessions for user:", in.Username, ", count:", revoked)
+		return &pb.EditResponse{Code: code.CodeSucc, Msg: code.CodeMsg[code.CodeSucc]}, nil
+	}
+
+	found, err := s.API.RevokeSession(in.Username, in.Sessionid)
+	if err != nil {
+		log.Error(uuid, " -- Failed to revoke session:", in.Sessionid, " err:", err.Error())
+		return &pb.EditResponse{Code: code.CodeTCPInternelErr, Msg: code.CodeMsg[code.CodeTCPInternelErr]}, nil
+	}
+	if !found {
+		log.Error(uuid, " -- Session not found:", in.Sessionid)
+		return &pb.EditResponse{Code: code.CodeTCPSessionNotFound, Msg: code.CodeMsg[code.CodeTCPSessionNotFound]}, nil
+	}
+	log.Debug(uuid, " -- Succ to revoke session:", in.Sessionid)
+	return &pb.EditResponse{Code: code.CodeSucc, Msg: code.CodeMsg[code.CodeSucc]}, nil
+}
+
+// SetupTwoFactor generate a pending totp secret of user
+func (s *UserServer) SetupTwoFactor(ctx context.Context, in *pb.CommRequest) (*pb.TwoFactorSetupResponse, error) {
+	// get uuid
+	uuid := getUUID(ctx)
+	log.Debug(uuid, " -- SetupTwoFactor access from:", in.Username, " with token:", in.Token)
+	// auth
+	pass := s.API.Auth(in.Username, in.Token)
+	if !pass {
+		log.Error(uuid, " -- Failed to auth for user:", in.Username, " with token:", in.Token)
+		return &pb.TwoFactorSetupResponse{Code: code.CodeTCPTokenExpired, Msg: code.CodeMsg[code.CodeTCPTokenExpired]}, nil
+	}
+
+	secret, uri, err := s.API.SetupTwoFactor(in.Username)
+	if err == ErrTwoFactorState {
+		log.Error(uuid, " -- 2fa already enabled for user:", in.Username)
+		return &pb.TwoFactorSetupResponse{Code: code.CodeTCPTwoFactorState, Msg: code.CodeMsg[code.CodeTCPTwoFactorState]}, nil
+	}
+	if err != nil {
+		log.Error(uuid, " -- Failed to setup 2fa for user:", in.Username, " err:", err.Error())
+		return &pb.TwoFactorSetupResponse{Code: code.CodeTCPFailedUpdateUserInfo, Msg: code.CodeMsg[code.CodeTCPFailedUpdateUserInfo]}, nil
+	}
+	log.Debug(uuid, " -- Succ to setup 2fa for user:", in.Username)
+	return &pb.TwoFactorSetupResponse{Secret: secret, Uri: uri, Code: code.CodeSucc, Msg: code.CodeMsg[code.CodeSucc]}, nil
+}
+
+// ConfirmTwoFactor enable 2fa with a first code, recovery codes are returned once
+func (s *UserServer) ConfirmTwoFactor(ctx context.Context, in *pb.TwoFactorRequest) (*pb.TwoFactorSetupResponse, error) {
+	// get uuid
+	uuid := getUUID(ctx)
+	log.Debug(uuid, " -- ConfirmTwoFactor access from:", in.Username, " with token:", in.Token)
+	// auth
+	pass := s.API.Auth(in.Username, in.Token)
+	if !pass {
+		log.Error(uuid, " -- Failed to auth for user:", in.Username, " with token:", in.Token)
+		return &pb.TwoFactorSetupResponse{Code: code.CodeTCPTokenExpired, Msg: code.CodeMsg[code.CodeTCPTokenExpired]}, nil
+	}
+
+	codes, err := s.API.ConfirmTwoFactor(in.Username, in.Passcode)
+	if err != nil {
+		log.Error(uuid, " -- Failed to confirm 2fa for user:", in.Username, " err:", err.Error())
+		c := twoFactorErrCode(err)
+		return &pb.TwoFactorSetupResponse{Code: uint32(c), Msg: code.CodeMsg[c]}, nil
+	}
+	log.Debug(uuid, " -- Succ to enable 2fa for user:", in.Username)
+	return &pb.TwoFactorSetupResponse{Recoverycodes: codes, Code: code.CodeSucc, Msg: code.CodeMsg[code.CodeSucc]}, nil
+}
+
+// DisableTwoFactor disable 2fa with a totp code or a recovery code
+func (s *UserServer) DisableTwoFactor(ctx context.Context, in *pb.TwoFactorRequest) (*pb.EditResponse, error) {
+	// get uuid
+	uuid := getUUID(ctx)
+	log.Debug(uuid, " -- DisableTwoFactor access from:", in.Username, " with token:", in.Token)
+	// auth
+	pass := s.API.Auth(in.Username, in.Token)
+	if !pass {
+		log.Error(uuid, " -- Failed to auth for user:", in.Username, " with token:", in.Token)
+		return &pb.EditResponse{Code: code.CodeTCPTokenExpired, Msg: code.CodeMsg[code.CodeTCPTokenExpired]}, nil
+	}
+
+	if err := s.API.DisableTwoFactor(in.Username, in.Passcode); err != nil {
+		log.Error(uuid, " -- Failed to disable 2fa for user:", in.Username, " err:", err.Error())
+		c := twoFactorErrCode(err)
+		return &pb.EditResponse{Code: uint32(c), Msg: code.CodeMsg[c]}, nil
+	}
+	log.Debug(uuid, " -- Succ to disable 2fa for user:", in.Username)
+	return &pb.EditResponse{Code: code.CodeSucc, Msg: code.CodeMsg[code.CodeSucc]}, nil
+}
+
+// DeactivateAccount deactivate account after verifying passwd, it can be restored within grace period
+func (s *UserServer) DeactivateAccount(ctx context.Context, in *pb.AccountRequest) (*pb.EditResponse, error) {
+	// get uuid
+	uuid := getUUID(ctx)
+	log.Debug(uuid, " -- DeactivateAccount access from:", in.Username, " with token:", in.Token)
+	user, rsp := s.verifyAccount(uuid, in)
+	if rsp != nil {
+		return rsp, nil
+	}
+
+	if err := s.API.DeactivateAccount(user.Username); err != nil {
+		log.Error(uuid, " -- Failed to deactivate user:", in.Username, " err:", err.Error())
+		return &pb.EditResponse{Code: code.CodeTCPFailedUpdateUserInfo, Msg: code.CodeMsg[code.CodeTCPFailedUpdateUserInfo]}, nil
+	}
+	log.Debug(uuid, " -- Succ to deactivate user:", in.Username)
 	return &pb.EditResponse{Code: code.CodeSucc, Msg: code.CodeMsg[code.CodeSucc]}, nil
 }
 
+// DeleteAccount delete account at once after verifying passwd
+func (s *UserServer) DeleteAccount(ctx context.Context, in *pb.AccountRequest) (*pb.EditResponse, error) {
+	// get uuid
+	uuid := getUUID(ctx)
+	log.Debug(uuid, " -- DeleteAccount access from:", in.Username, " with token:", in.Token)
+	user, rsp := s.verifyAccount(uuid, in)
+	if rsp != nil {
+		return rsp, nil
+	}
+
+	if err := s.API.DeleteAccount(user); err != nil {
+		log.Error(uuid, " -- Failed to delete user:", in.Username, " err:", err.Error())
+		return &pb.EditResponse{Code: code.CodeTCPFailedUpdateUserInfo, Msg: code.CodeMsg[code.CodeTCPFailedUpdateUserInfo]}, nil
+	}
+	log.Debug(uuid, " -- Succ to delete user:", in.Username)
+	return &pb.EditResponse{Code: code.CodeSucc, Msg: code.CodeMsg[code.CodeSucc]}, nil
+}
+
+// verifyAccount auth token and passwd of an account request, rsp is set on failure
+func (s *UserServer) verifyAccount(uuid string, in *pb.AccountRequest) (types.User, *pb.EditResponse) {
+	// auth
+	pass := s.API.Auth(in.Username, in.Token)
+	if !pass {
+		log.Error(uuid, " -- Failed to auth for user:", in.Username, " with token:", in.Token)
+		return types.User{}, &pb.EditResponse{Code: code.CodeTCPTokenExpired, Msg: code.CodeMsg[code.CodeTCPTokenExpired]}
+	}
+	user, err := s.API.GetUserInfo(in.Username)
+	if err != nil {
+		log.Error(uuid, " -- Failed to getUserInfo, ", in.Username, ", err:", err.Error())
+		return user, &pb.EditResponse{Code: code.CodeTCPFailedGetUserInfo, Msg: code.CodeMsg[code.CodeTCPFailedGetUserInfo]}
+	}
+	if !s.API.VerifyPasswd(user, in.Passwd) {
+		log.Error(uuid, " -- Failed to match passwd ", in.Username)
+		return user, &pb.EditResponse{Code: code.CodeTCPPasswdErr, Msg: code.CodeMsg[code.CodeTCPPasswdErr]}
+	}
+	return user, nil
+}
+
+// twoFactorErrCode code of errors from 2fa enrolment
+func twoFactorErrCode(err error) int {
+	switch err {
+	case ErrTwoFactorState:
+		return code.CodeTCPTwoFactorState
+	case ErrInvalidPasscode:
+		return code.CodeTCPInvalidPasscode
+	}
+	return code.CodeTCPFailedUpdateUserInfo
+}
+
 // Logout logout
 func (s *UserServer) Logout(ctx context.Context, in *pb.CommRequest) (*pb.EditResponse, error) {
 	// get uuid
 	uuid := getUUID(ctx)
 	log.Debug(uuid, " -- Logout access from:", in.Token)
-	err := s.API.redisClient.DelTokenInfo(in.Token)
+	err := s.API.sessions.DelTokenInfo(s.API.SessionToken(in.Token))
 	if err != nil {
 		log.Error(uuid, " -- Failed to delTokenInfo :", err.Error())
 	}
 	log.Debug(uuid, " -- Succ to logout:", in.Token)
 	return &pb.EditResponse{Code: code.CodeSucc, Msg: code.CodeMsg[code.CodeSucc]}, nil
 }
+
+// Register register a new user
+func (s *UserServer) Register(ctx context.Context, in *pb.RegisterRequest) (*pb.LoginResponse, error) {
+	// get uuid
+	uuid := getUUID(ctx)
+	log.Debug(uuid, " -- Register access from:", in.Username)
+	// check params
+	if !utils.CheckUsername(in.Username) {
+		log.Error(uuid, " -- Error: invalid username:", in.Username)
+		return &pb.LoginResponse{Code: code.CodeTCPInvalidUsername, Msg: code.CodeMsg[code.CodeTCPInvalidUsername]}, nil
+	}
+	if !utils.CheckPasswd(in.Passwd) {
+		log.Error(uuid, " -- Error: invalid passwd for user:", in.Username)
+		return &pb.LoginResponse{Code: code.CodeTCPInvalidPasswd, Msg: code.CodeMsg[code.CodeTCPInvalidPasswd]}, nil
+	}
+
+	if in.Email != "" {
+		if err := CheckProfile(types.User{Email: in.Email}, []string{"email"}); err != nil {
+			log.Error(uuid, " -- Error: invalid email for user:", in.Username)
+			return &pb.LoginResponse{Code: code.CodeTCPInvalidProfile, Msg: code.CodeMsg[code.CodeTCPInvalidProfile] + ": " + err.Error()}, nil
+		}
+	}
+
+	user, err := s.API.Register(in.Username, in.Passwd, in.Nickname, in.Email)
+	if err == db.ErrUserExists {
+		log.Error(uuid, " -- Failed to register, username exists:", in.Username)
+		return &pb.LoginResponse{Code: code.CodeTCPUserExists, Msg: code.CodeMsg[code.CodeTCPUserExists]}, nil
+	}
+	if err != nil {
+		log.Error(uuid, " -- Failed to register user:", in.Username, " err:", err.Error())
+		return &pb.LoginResponse{Code: code.CodeTCPFailedCreateUser, Msg: code.CodeMsg[code.CodeTCPFailedCreateUser]}, nil
+	}
+	log.Debug(uuid, " -- Succ to register user:", user.Username)
+	return userResponse(user), nil
+}
+
+// ListUsers a page of users of all tables passing the filters, admin only
+func (s *UserServer) ListUsers(ctx context.Context, in *pb.ListUsersRequest) (*pb.UsersResponse, error) {
+	// get uuid
+	uuid := getUUID(ctx)
+	if adminCaller(ctx).Username == "" {
+		return &pb.UsersResponse{Code: code.CodeTCPPermissionDenied, Msg: code.CodeMsg[code.CodeTCPPermissionDenied]}, nil
+	}
+	log.Debug(uuid, " -- ListUsers access from:", in.Username, " cursor:", in.Cursor, " limit:", in.Limit,
+		" prefix:", in.Prefix, " nickname:", in.Nickname, " uptime:", in.Upfrom, "~", in.Upto)
+
+	filter := types.UserFilter{Prefix: in.Prefix, Nickname: in.Nickname, Upfrom: in.Upfrom, Upto: in.Upto}
+	users, next, err := s.API.ListUsers(filter, in.Cursor, int(in.Limit))
+	if _, ok := err.(*FilterError); ok {
+		log.Error(uuid, " -- Invalid filter, err:", err.Error())
+		return &pb.UsersResponse{Code: code.CodeTCPInvalidFilter, Msg: code.CodeMsg[code.CodeTCPInvalidFilter] + ": " + err.Error()}, nil
+	}
+	if err == ErrCursor {
+		log.Error(uuid, " -- Invalid cursor:", in.Cursor)
+		return &pb.UsersResponse{Code: code.CodeTCPInvalidCursor, Msg: code.CodeMsg[code.CodeTCPInvalidCursor]}, nil
+	}
+	if err != nil {
+		log.Error(uuid, " -- Failed to list users, err:", err.Error())
+		return &pb.UsersResponse{Code: code.CodeTCPInternelErr, Msg: code.CodeMsg[code.CodeTCPInternelErr]}, nil
+	}
+	rsp := &pb.UsersResponse{Nextcursor: next, Code: code.CodeSucc, Msg: code.CodeMsg[code.CodeSucc]}
+	for _, user := range users {
+		rsp.Users = append(rsp.Users, userResponse(user))
+	}
+	log.Debug(uuid, " -- Succ to list users, count:", len(rsp.Users))
+	return rsp, nil
+}
+
+// LockUser lock an account and log it out everywhere, admin only
+func (s *UserServer) LockUser(ctx context.Context, in *pb.AdminRequest) (*pb.EditResponse, error) {
+	return s.lockUser(ctx, in, true), nil
+}
+
+// UnlockUser unlock an account locked by admin, admin only
+func (s *UserServer) UnlockUser(ctx context.Context, in *pb.AdminRequest) (*pb.EditResponse, error) {
+	return s.lockUser(ctx, in, false), nil
+}
+
+// lockUser lock or unlock target, admins can't lock themselves
+func (s *UserServer) lockUser(ctx context.Context, in *pb.AdminRequest, locked bool) *pb.EditResponse {
+	// get uuid
+	uuid := getUUID(ctx)
+	caller := adminCaller(ctx)
+	if caller.Username == "" || caller.Username == in.Target {
+		return &pb.EditResponse{Code: code.CodeTCPPermissionDenied, Msg: code.CodeMsg[code.CodeTCPPermissionDenied]}
+	}
+	log.Debug(uuid, " -- LockUser access from:", in.Username, " for:", in.Target, " locked:", locked)
+
+	var err error
+	if locked {
+		err = s.API.LockUser(in.Target)
+	} else {
+		err = s.API.UnlockUser(in.Target)
+	}
+	if err != nil {
+		log.Error(uuid, " -- Failed to lock user:", in.Target, " locked:", locked, " err:", err.Error())
+		return &pb.EditResponse{Code: code.CodeTCPFailedUpdateUserInfo, Msg: code.CodeMsg[code.CodeTCPFailedUpdateUserInfo]}
+	}
+	log.Info(uuid, " -- Succ to lock user:", in.Target, " locked:", locked, " by:", caller.Username)
+	return &pb.EditResponse{Code: code.CodeSucc, Msg: code.CodeMsg[code.CodeSucc]}
+}
+
+// ForceLogout revoke all sessions of a user, admin only
+func (s *UserServer) ForceLogout(ctx context.Context, in *pb.AdminRequest) (*pb.EditResponse, error) {
+	// get uuid
+	uuid := getUUID(ctx)
+	caller := adminCaller(ctx)
+	if caller.Username == "" {
+		return &pb.EditResponse{Code: code.CodeTCPPermissionDenied, Msg: code.CodeMsg[code.CodeTCPPermissionDenied]}, nil
+	}
+	log.Debug(uuid, " -- ForceLogout access from:", in.Username, " for:", in.Target)
+
+	revoked, err := s.API.RevokeAllSessions(in.Target)
+	if err != nil {
+		log.Error(uuid, " -- Failed to revoke sessions for user:", in.Target, " err:", err.Error())
+		return &pb.EditResponse{Code: code.CodeTCPInternelErr, Msg: code.CodeMsg[code.CodeTCPInternelErr]}, nil
+	}
+	log.Info(uuid, " -- Succ to force logout user:", in.Target, " by:", caller.Username, ", count:", revoked)
+	return &pb.EditResponse{Code: code.CodeSucc, Msg: code.CodeMsg[code.CodeSucc]}, nil
+}
+
+// AdminUpdateProfile update the profile fields of mask of any user, admin only
+func (s *UserServer) AdminUpdateProfile(ctx context.Context, in *pb.AdminProfileRequest) (*pb.LoginResponse, error) {
+	// get uuid
+	uuid := getUUID(ctx)
+	caller := adminCaller(ctx)
+	if caller.Username == "" {
+		return &pb.LoginResponse{Code: code.CodeTCPPermissionDenied, Msg: code.CodeMsg[code.CodeTCPPermissionDenied]}, nil
+	}
+	p := in.GetProfile()
+	if p == nil {
+		return &pb.LoginResponse{Code: code.CodeTCPInvalidProfile, Msg: code.CodeMsg[code.CodeTCPInvalidProfile]}, nil
+	}
+	log.Debug(uuid, " -- AdminUpdateProfile access from:", in.Username, " for:", p.Username, " mask:", p.Mask)
+
+	profile := types.User{Nickname: p.Nickname, Headurl: p.Headurl, Email: p.Email, Phone: p.Phone,
+		Bio: p.Bio, Locale: p.Locale, Timezone: p.Timezone, Birthday: p.Birthday}
+	user, err := s.API.UpdateProfile(p.Username, "", profile, p.Mask)
+	if _, ok := err.(*ProfileError); ok || err == ErrEmptyMask {
+		log.Error(uuid, " -- Invalid profile of:", p.Username, " err:", err.Error())
+		return &pb.LoginResponse{Code: code.CodeTCPInvalidProfile, Msg: code.CodeMsg[code.CodeTCPInvalidProfile] + ": " + err.Error()}, nil
+	}
+	if err != nil {
+		log.Error(uuid, " -- Failed to update profile of:", p.Username, " err:", err.Error())
+		return &pb.LoginResponse{Code: code.CodeTCPFailedUpdateUserInfo, Msg: code.CodeMsg[code.CodeTCPFailedUpdateUserInfo]}, nil
+	}
+	log.Info(uuid, " -- Succ to update profile of:", p.Username, " mask:", p.Mask, " by:", caller.Username)
+	return userResponse(user), nil
+}
+
+// SetUserRoles replace roles of a user and log it out, admin only. admins can't change their own roles
+func (s *UserServer) SetUserRoles(ctx context.Context, in *pb.SetRolesRequest) (*pb.LoginResponse, error) {
+	// get uuid
+	uuid := getUUID(ctx)
+	caller := adminCaller(ctx)
+	if caller.Username == "" || caller.Username == in.Target {
+		return &pb.LoginResponse{Code: code.CodeTCPPermissionDenied, Msg: code.CodeMsg[code.CodeTCPPermissionDenied]}, nil
+	}
+	log.Debug(uuid, " -- SetUserRoles access from:", in.Username, " for:", in.Target, " roles:", in.Roles)
+
+	user, err := s.API.SetRoles(in.Target, in.Roles)
+	if err == ErrUnknownRole {
+		log.Error(uuid, " -- Unknown role in:", in.Roles)
+		return &pb.LoginResponse{Code: code.CodeTCPUnknownRole, Msg: code.CodeMsg[code.CodeTCPUnknownRole]}, nil
+	}
+	if err != nil {
+		log.Error(uuid, " -- Failed to set roles of:", in.Target, " err:", err.Error())
+		return &pb.LoginResponse{Code: code.CodeTCPFailedUpdateUserInfo, Msg: code.CodeMsg[code.CodeTCPFailedUpdateUserInfo]}, nil
+	}
+	log.Info(uuid, " -- Succ to set roles of:", in.Target, " to:", user.Roles, " by:", caller.Username)
+	return userResponse(user), nil
+}
+
+// QueryAuditLog a page of audit events of a user, or of all users, newest first. admin only
+func (s *UserServer) QueryAuditLog(ctx context.Context, in *pb.AuditLogRequest) (*pb.AuditLogResponse, error) {
+	// get uuid
+	uuid := getUUID(ctx)
+	if adminCaller(ctx).Username == "" {
+		return &pb.AuditLogResponse{Code: code.CodeTCPPermissionDenied, Msg: code.CodeMsg[code.CodeTCPPermissionDenied]}, nil
+	}
+	log.Debug(uuid, " -- QueryAuditLog access from:", in.Username, " for:", in.Target, " uid:", in.Targetuid, " action:", in.Action,
+		" time:", in.From, "~", in.To, " cursor:", in.Cursor, " limit:", in.Limit)
+
+	filter := types.AuditFilter{Username: in.Target, Uid: in.Targetuid, Action: in.Action, From: in.From, To: in.To}
+	events, next, err := s.API.AuditLog(filter, in.Cursor, int(in.Limit))
+	if _, ok := err.(*FilterError); ok {
+		log.Error(uuid, " -- Invalid filter, err:", err.Error())
+		return &pb.AuditLogResponse{Code: code.CodeTCPInvalidFilter, Msg: code.CodeMsg[code.CodeTCPInvalidFilter] + ": " + err.Error()}, nil
+	}
+	if err == ErrCursor {
+		log.Error(uuid, " -- Invalid cursor:", in.Cursor)
+		return &pb.AuditLogResponse{Code: code.CodeTCPInvalidCursor, Msg: code.CodeMsg[code.CodeTCPInvalidCursor]}, nil
+	}
+	if err != nil {
+		log.Error(uuid, " -- Failed to query audit log, err:", err.Error())
+		return &pb.AuditLogResponse{Code: code.CodeTCPInternelErr, Msg: code.CodeMsg[code.CodeTCPInternelErr]}, nil
+	}
+	rsp := &pb.AuditLogResponse{Nextcursor: next, Code: code.CodeSucc, Msg: code.CodeMsg[code.CodeSucc]}
+	for _, e := range events {
+		rsp.Events = append(rsp.Events, &pb.AuditEvent{Id: e.ID, Time: e.Time, Username: e.Username, Uid: e.Uid, Actor: e.Actor,
+			Action: e.Action, Ip: e.IP, Uuid: e.UUID, Outcome: e.Outcome, Resultcode: e.Code, Detail: e.Detail})
+	}
+	log.Debug(uuid, " -- Succ to query audit log, count:", len(rsp.Events))
+	return rsp, nil
+}
@@ -0,0 +1,1039 @@
+package tcpserver
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"user-management-system/conf"
+	"user-management-system/tcpserver/consts"
+	"user-management-system/tcpserver/db"
+	"user-management-system/tcpserver/mailer"
+	"user-management-system/tcpserver/rbac"
+	"user-management-system/tcpserver/store"
+	"user-management-system/tcpserver/totp"
+	"user-management-system/tcpserver/types"
+	"user-management-system/type/code"
+	"user-management-system/utils/jwt"
+	pb "user-management-system/type/proto"
+
+	log "github.com/beego/beego/v2/adapter/logs"
+	"google.golang.org/genproto/protobuf/field_mask"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// newTestServer user server on memory stores with username8/123456 registered
+func newTestServer(t *testing.T) *UserServer {
+	return newTestServerOf(t, testConf(t))
+}
+
+// testConf config of memory stores and fast hashing
+func testConf(t *testing.T) *conf.TCPConf {
+	var config conf.TCPConf
+	config.Store.Users, config.Store.Sessions = store.Memory, store.Memory
+	config.Redis.Cache.Tokenexpired = 7200
+	config.Redis.Cache.Userexpired = 300
+	config.Passwd.Algorithm = "bcrypt"
+	config.Passwd.Bcrypt.Cost = 4
+	config.Security.Login.Window = 900
+	config.Security.Login.Maxuserfailures = 3
+	config.Security.Login.Baselockout = 60
+	config.Security.Login.Maxlockout = 3600
+	config.Security.Twofactor.Skew = 1
+	config.Account.Renamereserve = 3600
+	config.Mail.Sender, config.Mail.File = mailer.SenderFile, filepath.Join(t.TempDir(), "mails.txt")
+	return &config
+}
+
+// newTestServerOf user server of config with username8/123456 registered
+func newTestServerOf(t *testing.T, config *conf.TCPConf) *UserServer {
+	log.SetLevel(log.LevelEmergency)
+	api, err := NewAPI(config)
+	if err != nil {
+		t.Fatal("NewAPI failed:", err.Error())
+	}
+
+	s := &UserServer{API: api}
+	rsp, _ := s.Register(context.Background(), &pb.RegisterRequest{Username: "username8", Passwd: "123456", Nickname: "nickname8"})
+	if rsp.Code != code.CodeSucc {
+		t.Fatal("register failed:", rsp.Msg)
+	}
+	return s
+}
+
+func testContext() context.Context {
+	md := metadata.Pairs("uuid", "test", "clientip", "127.0.0.1", "useragent", "go test")
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+func Test_LoginEditLogout(t *testing.T) {
+	s := newTestServer(t)
+	ctx := testContext()
+
+	login, _ := s.Login(ctx, &pb.LoginRequest{Username: "username8", Passwd: "123456"})
+	if login.Code != code.CodeSucc || login.Token == "" || login.Nickname != "nickname8" {
+		t.Fatal("login failed:", login)
+	}
+
+	edit, _ := s.EditUserInfo(ctx, &pb.EditRequest{Username: "username8", Token: login.Token, Nickname: "nick", Mode: consts.EditUsername})
+	if edit.Code != code.CodeSucc {
+		t.Error("edit failed:", edit.Msg)
+	}
+	info, _ := s.GetUserInfo(ctx, &pb.CommRequest{Username: "username8", Token: login.Token})
+	if info.Code != code.CodeSucc || info.Nickname != "nick" {
+		t.Error("edited nickname should be seen by token:", info)
+	}
+	if other, _ := s.GetUserInfo(ctx, &pb.CommRequest{Username: "username9", Token: login.Token}); other.Code != code.CodeTCPUserInfoNotMatch {
+		t.Error("token of other user should not match:", other.Code)
+	}
+
+	sessions, _ := s.ListSessions(ctx, &pb.CommRequest{Username: "username8", Token: login.Token})
+	if len(sessions.Sessions) != 1 || !sessions.Sessions[0].Current || sessions.Sessions[0].Ip != "127.0.0.1" {
+		t.Error("unexpected sessions:", sessions.Sessions)
+	}
+
+	s.Logout(ctx, &pb.CommRequest{Username: "username8", Token: login.Token})
+	if info, _ = s.GetUserInfo(ctx, &pb.CommRequest{Username: "username8", Token: login.Token}); info.Code != code.CodeTCPTokenExpired {
+		t.Error("token should be revoked by logout:", info.Code)
+	}
+}
+
+func Test_LoginLockout(t *testing.T) {
+	s := newTestServer(t)
+	ctx := testContext()
+
+	for i := 0; i < 3; i++ {
+		if rsp, _ := s.Login(ctx, &pb.LoginRequest{Username: "username8", Passwd: "wrong-passwd"}); rsp.Code != code.CodeTCPPasswdErr {
+			t.Error("wrong passwd should fail:", rsp.Code)
+		}
+	}
+	rsp, _ := s.Login(ctx, &pb.LoginRequest{Username: "username8", Passwd: "123456"})
+	if rsp.Code != code.CodeTCPAccountLocked || rsp.Retryafter <= 0 || rsp.Token != "" {
+		t.Error("account should be locked:", rsp)
+	}
+}
+
+func Test_LockoutGrowth(t *testing.T) {
+	throttle := &loginThrottle{baseLockout: 60, maxLockout: 300}
+	for failures, want := range map[int]int{2: 0, 3: 60, 4: 120, 5: 240, 6: 300, 100: 300} {
+		if lockout := throttle.lockout(failures, 3); lockout != want {
+			t.Error("unexpected lockout after failures:", failures, lockout)
+		}
+	}
+	// no cap, doubled until it would overflow
+	throttle.maxLockout = 0
+	if lockout := throttle.lockout(5, 3); lockout != 240 {
+		t.Error("lockout should be doubled without cap:", lockout)
+	}
+	if lockout := throttle.lockout(100, 3); lockout <= 0 || lockout > math.MaxInt32 {
+		t.Error("lockout should not overflow:", lockout)
+	}
+}
+
+func Test_ChangePasswd(t *testing.T) {
+	s := newTestServer(t)
+	ctx := testContext()
+
+	first, _ := s.Login(ctx, &pb.LoginRequest{Username: "username8", Passwd: "123456"})
+	second, _ := s.Login(ctx, &pb.LoginRequest{Username: "username8", Passwd: "123456"})
+	rsp, _ := s.ChangePasswd(ctx, &pb.ChangePasswdRequest{Username: "username8", Token: first.Token,
+		Oldpasswd: "123456", Newpasswd: "654321", Keepsession: true})
+	if rsp.Code != code.CodeSucc {
+		t.Fatal("change passwd failed:", rsp.Msg)
+	}
+	if info, _ := s.GetUserInfo(ctx, &pb.CommRequest{Username: "username8", Token: first.Token}); info.Code != code.CodeSucc {
+		t.Error("kept session should be alive:", info.Code)
+	}
+	if info, _ := s.GetUserInfo(ctx, &pb.CommRequest{Username: "username8", Token: second.Token}); info.Code != code.CodeTCPTokenExpired {
+		t.Error("other session should be revoked:", info.Code)
+	}
+	if login, _ := s.Login(ctx, &pb.LoginRequest{Username: "username8", Passwd: "654321"}); login.Code != code.CodeSucc {
+		t.Error("login with new passwd failed:", login.Code)
+	}
+}
+
+func Test_TwoFactorLogin(t *testing.T) {
+	s := newTestServer(t)
+	ctx := testContext()
+	login, _ := s.Login(ctx, &pb.LoginRequest{Username: "username8", Passwd: "123456"})
+
+	setup, _ := s.SetupTwoFactor(ctx, &pb.CommRequest{Username: "username8", Token: login.Token})
+	if setup.Code != code.CodeSucc || setup.Secret == "" {
+		t.Fatal("setup 2fa failed:", setup.Msg)
+	}
+	passcode, _ := totp.Code(setup.Secret, time.Now())
+	confirm, _ := s.ConfirmTwoFactor(ctx, &pb.TwoFactorRequest{Username: "username8", Token: login.Token, Passcode: passcode})
+	if confirm.Code != code.CodeSucc || len(confirm.Recoverycodes) == 0 {
+		t.Fatal("confirm 2fa failed:", confirm.Msg)
+	}
+
+	// passwd alone only gets a challenge
+	rsp, _ := s.Login(ctx, &pb.LoginRequest{Username: "username8", Passwd: "123456"})
+	if rsp.Code != code.CodeTCPTwoFactorRequired || rsp.Challenge == "" || rsp.Token != "" {
+		t.Fatal("2fa should be required:", rsp)
+	}
+	// a used code can't be replayed
+	verify, _ := s.VerifyTwoFactor(ctx, &pb.VerifyTwoFactorRequest{Challenge: rsp.Challenge, Passcode: passcode})
+	if verify.Code != code.CodeTCPInvalidPasscode {
+		t.Error("used totp code should be rejected:", verify.Code)
+	}
+	verify, _ = s.VerifyTwoFactor(ctx, &pb.VerifyTwoFactorRequest{Challenge: rsp.Challenge, Passcode: confirm.Recoverycodes[0]})
+	if verify.Code != code.CodeSucc || verify.Token == "" {
+		t.Error("recovery code should login:", verify)
+	}
+	verify, _ = s.VerifyTwoFactor(ctx, &pb.VerifyTwoFactorRequest{Challenge: rsp.Challenge, Passcode: confirm.Recoverycodes[1]})
+	if verify.Code != code.CodeTCPChallengeExpired {
+		t.Error("challenge should be used up:", verify.Code)
+	}
+}
+
+func Test_GetUserByID(t *testing.T) {
+	s := newTestServer(t)
+	ctx := testContext()
+	login, _ := s.Login(ctx, &pb.LoginRequest{Username: "username8", Passwd: "123456"})
+	if login.Code != code.CodeSucc || login.Uid == 0 {
+		t.Fatal("registered user should have a uid:", login)
+	}
+	user, _ := s.GetUserByID(ctx, &pb.UserIDRequest{Uid: login.Uid})
+	if user.Code != code.CodeSucc || user.Username != "username8" || user.Nickname != "nickname8" {
+		t.Error("user not found by uid:", user)
+	}
+	if other, _ := s.GetUserByID(ctx, &pb.UserIDRequest{Uid: login.Uid + 1}); other.Code != code.CodeTCPFailedGetUserInfo {
+		t.Error("unknown uid should fail:", other.Code)
+	}
+}
+
+func Test_ChangeUsername(t *testing.T) {
+	s := newTestServer(t)
+	ctx := testContext()
+	login, _ := s.Login(ctx, &pb.LoginRequest{Username: "username8", Passwd: "123456"})
+	s.Register(ctx, &pb.RegisterRequest{Username: "username9", Passwd: "123456"})
+
+	if rsp, _ := s.ChangeUsername(ctx, &pb.ChangeUsernameRequest{Username: "username8", Token: login.Token, Newname: "username9"}); rsp.Code != code.CodeTCPUserExists {
+		t.Error("rename to a taken username should fail:", rsp.Code)
+	}
+	rsp, _ := s.ChangeUsername(ctx, &pb.ChangeUsernameRequest{Username: "username8", Token: login.Token, Newname: "alice"})
+	if rsp.Code != code.CodeSucc || rsp.Username != "alice" || rsp.Uid != login.Uid {
+		t.Fatal("rename failed:", rsp)
+	}
+
+	// the session moves with the user, the old name leads to the new one
+	if info, _ := s.GetUserInfo(ctx, &pb.CommRequest{Username: "alice", Token: login.Token}); info.Code != code.CodeSucc || info.Nickname != "nickname8" {
+		t.Error("session should move to the new username:", info)
+	}
+	if info, _ := s.GetUserInfo(ctx, &pb.CommRequest{Username: "username8", Token: login.Token}); info.Code != code.CodeSucc || info.Username != "alice" {
+		t.Error("old username should lead to the new one:", info)
+	}
+	if sessions, _ := s.ListSessions(ctx, &pb.CommRequest{Username: "alice", Token: login.Token}); len(sessions.Sessions) != 1 {
+		t.Error("session should be listed under the new username:", sessions.Sessions)
+	}
+	if reg, _ := s.Register(ctx, &pb.RegisterRequest{Username: "username8", Passwd: "123456"}); reg.Code != code.CodeTCPUserExists {
+		t.Error("old username should be reserved:", reg.Code)
+	}
+	if relogin, _ := s.Login(ctx, &pb.LoginRequest{Username: "alice", Passwd: "123456"}); relogin.Code != code.CodeSucc {
+		t.Error("login with the new username failed:", relogin.Code)
+	}
+}
+
+func Test_UpdateProfile(t *testing.T) {
+	s := newTestServer(t)
+	ctx := testContext()
+	login, _ := s.Login(ctx, &pb.LoginRequest{Username: "username8", Passwd: "123456"})
+
+	rsp, _ := s.UpdateProfile(ctx, &pb.ProfileRequest{Username: "username8", Token: login.Token, Email: "u8@example.com",
+		Phone: "+8613800138000", Timezone: "Asia/Shanghai", Birthday: "1990-02-28", Nickname: "ignored", Mask: []string{"email", "phone", "timezone", "birthday"}})
+	if rsp.Code != code.CodeSucc || rsp.Email != "u8@example.com" || rsp.Timezone != "Asia/Shanghai" || rsp.Nickname != "nickname8" {
+		t.Fatal("update profile failed:", rsp)
+	}
+	info, _ := s.GetUserInfo(ctx, &pb.CommRequest{Username: "username8", Token: login.Token})
+	if info.Phone != "+8613800138000" || info.Birthday != "1990-02-28" {
+		t.Error("updated profile should be seen by token:", info)
+	}
+
+	// a masked field left empty is cleared, the others are kept
+	rsp, _ = s.UpdateProfile(ctx, &pb.ProfileRequest{Username: "username8", Token: login.Token, Locale: "zh-Hans-CN", Mask: []string{"phone", "locale"}})
+	if rsp.Code != code.CodeSucc || rsp.Phone != "" || rsp.Locale != "zh-Hans-CN" || rsp.Email != "u8@example.com" {
+		t.Error("unexpected profile:", rsp)
+	}
+
+	for _, in := range []*pb.ProfileRequest{
+		{Email: "not an email", Mask: []string{"email"}},
+		{Phone: "13800138000", Mask: []string{"phone"}},
+		{Locale: "english", Mask: []string{"locale"}},
+		{Timezone: "Mars/Olympus", Mask: []string{"timezone"}},
+		{Birthday: "1990-02-30", Mask: []string{"birthday"}},
+		{Birthday: "2990-01-01", Mask: []string{"birthday"}},
+		{Headurl: "javascript:alert(1)", Mask: []string{"headurl"}},
+		{Nickname: "a\x00b", Mask: []string{"nickname"}},
+		{Mask: []string{"passwd"}},
+		{Email: "u9@example.com"},
+	} {
+		in.Username, in.Token = "username8", login.Token
+		if rsp, _ = s.UpdateProfile(ctx, in); rsp.Code != code.CodeTCPInvalidProfile {
+			t.Error("invalid profile should be rejected:", in, rsp.Code)
+		}
+	}
+	if info, _ = s.GetUserInfo(ctx, &pb.CommRequest{Username: "username8", Token: login.Token}); info.Email != "u8@example.com" {
+		t.Error("rejected update should change nothing:", info)
+	}
+	if rsp, _ = s.UpdateProfile(ctx, &pb.ProfileRequest{Username: "username8", Token: "bad", Mask: []string{"bio"}}); rsp.Code != code.CodeTCPTokenExpired {
+		t.Error("update without a valid token should fail:", rsp.Code)
+	}
+}
+
+func Test_EditUserInfoMask(t *testing.T) {
+	s := newTestServer(t)
+	ctx := testContext()
+	login, _ := s.Login(ctx, &pb.LoginRequest{Username: "username8", Passwd: "123456"})
+
+	edit, _ := s.EditUserInfo(ctx, &pb.EditRequest{Username: "username8", Token: login.Token, Nickname: "nick", Headurl: "http://localhost/a.png",
+		Mask: &field_mask.FieldMask{Paths: []string{"headurl"}}})
+	info, _ := s.GetUserInfo(ctx, &pb.CommRequest{Username: "username8", Token: login.Token})
+	if edit.Code != code.CodeSucc || info.Headurl != "http://localhost/a.png" || info.Nickname != "nickname8" {
+		t.Error("only headurl of mask should be edited:", edit.Msg, info)
+	}
+
+	// mask wins over mode, mode is translated without it
+	edit, _ = s.EditUserInfo(ctx, &pb.EditRequest{Username: "username8", Token: login.Token, Nickname: "nick", Mode: consts.EditBoth,
+		Mask: &field_mask.FieldMask{Paths: []string{"nickname"}}})
+	info, _ = s.GetUserInfo(ctx, &pb.CommRequest{Username: "username8", Token: login.Token})
+	if edit.Code != code.CodeSucc || info.Nickname != "nick" || info.Headurl != "http://localhost/a.png" {
+		t.Error("only nickname of mask should be edited:", edit.Msg, info)
+	}
+	edit, _ = s.EditUserInfo(ctx, &pb.EditRequest{Username: "username8", Token: login.Token, Mode: consts.EditHeadurl})
+	info, _ = s.GetUserInfo(ctx, &pb.CommRequest{Username: "username8", Token: login.Token})
+	if edit.Code != code.CodeSucc || info.Headurl != "" || info.Nickname != "nick" {
+		t.Error("headurl should be cleared by mode:", edit.Msg, info)
+	}
+
+	for _, in := range []*pb.EditRequest{
+		{Mask: &field_mask.FieldMask{Paths: []string{"email"}}},
+		{Mode: 4},
+		{Headurl: "ftp://localhost/a.png", Mode: consts.EditHeadurl},
+	} {
+		in.Username, in.Token = "username8", login.Token
+		if edit, _ = s.EditUserInfo(ctx, in); edit.Code != code.CodeTCPInvalidProfile {
+			t.Error("invalid edit should be rejected:", in, edit.Code)
+		}
+	}
+}
+
+// mailedTokens tokens of verification links mailed so far, oldest first
+func mailedTokens(t *testing.T, s *UserServer) []string {
+	s.API.email.pending.Wait()
+	b, _ := ioutil.ReadFile(s.API.email.mailer.(*mailer.FileMailer).Path())
+	var tokens []string
+	for _, m := range regexp.MustCompile(`token=(\S+)`).FindAllStringSubmatch(string(b), -1) {
+		token, err := url.QueryUnescape(m[1])
+		if err != nil {
+			t.Fatal("bad link in mail:", m[0])
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+func Test_RefreshToken(t *testing.T) {
+	config := testConf(t)
+	config.Redis.Cache.Tokenexpired = 100
+	config.Redis.Cache.Sliding = true
+	config.Redis.Cache.Tokenmaxlifetime = 250
+	s := newTestServerOf(t, config)
+	ctx := testContext()
+	now := time.Now()
+	s.API.sessions.(*store.MemorySessionStore).SetClock(func() time.Time { return now })
+	userinfo := func(token string) uint32 {
+		rsp, _ := s.GetUserInfo(ctx, &pb.CommRequest{Username: "username8", Token: token})
+		return rsp.Code
+	}
+
+	login, _ := s.Login(ctx, &pb.LoginRequest{Username: "username8", Passwd: "123456"})
+	if login.Code != code.CodeSucc || login.Expire != 250 {
+		t.Fatal("sliding tokens should be kept for their max lifetime:", login.Code, login.Expire)
+	}
+	now = now.Add(60 * time.Second)
+	rsp, _ := s.RefreshToken(ctx, &pb.CommRequest{Username: "username8", Token: login.Token})
+	if rsp.Code != code.CodeSucc || rsp.Token == "" || rsp.Token == login.Token || rsp.Username != "username8" {
+		t.Fatal("failed to refresh token:", rsp.Code, rsp.Msg)
+	}
+	// the old token is retired, it can't be used or refreshed again
+	if c := userinfo(login.Token); c == code.CodeSucc {
+		t.Error("refreshed token should be retired")
+	}
+	if again, _ := s.RefreshToken(ctx, &pb.CommRequest{Username: "username8", Token: login.Token}); again.Code != code.CodeTCPTokenExpired {
+		t.Error("refreshed token should not be refreshed again:", again.Code)
+	}
+	if again, _ := s.RefreshToken(ctx, &pb.CommRequest{Username: "username9", Token: rsp.Token}); again.Code != code.CodeTCPUserInfoNotMatch {
+		t.Error("token should be refreshed by its own user only:", again.Code)
+	}
+
+	// uses slide the expiry by tokenexpired, up to max lifetime since login
+	for _, step := range []time.Duration{90, 90} {
+		now = now.Add(step * time.Second)
+		if c := userinfo(rsp.Token); c != code.CodeSucc {
+			t.Fatal("used token should slide its expiry:", c)
+		}
+	}
+	now = now.Add(20 * time.Second)
+	if c := userinfo(rsp.Token); c == code.CodeSucc {
+		t.Error("token should expire at max lifetime since login, even though refreshed and used")
+	}
+	if again, _ := s.RefreshToken(ctx, &pb.CommRequest{Username: "username8", Token: rsp.Token}); again.Code != code.CodeTCPTokenExpired {
+		t.Error("expired token should not be refreshed:", again.Code)
+	}
+}
+
+func Test_DeleteAccountAvatar(t *testing.T) {
+	config := testConf(t)
+	config.Image.Savepath = t.TempDir()
+	s := newTestServerOf(t, config)
+	ctx := testContext()
+	s.Register(ctx, &pb.RegisterRequest{Username: "username9", Passwd: "123456"})
+	// uploads are named after the uid of the uploader
+	upload := func(username string) string {
+		login, _ := s.Login(ctx, &pb.LoginRequest{Username: username, Passwd: "123456"})
+		name := strconv.FormatInt(login.Uid, 10) + "_a.png"
+		if err := ioutil.WriteFile(filepath.Join(config.Image.Savepath, name), []byte("png"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+	a8, a9 := upload("username8"), upload("username9")
+	edit := func(username, headurl, avatar string) uint32 {
+		login, _ := s.Login(ctx, &pb.LoginRequest{Username: username, Passwd: "123456"})
+		rsp, _ := s.EditUserInfo(ctx, &pb.EditRequest{Username: username, Token: login.Token, Headurl: headurl, Avatar: avatar, Mode: consts.EditHeadurl})
+		return rsp.Code
+	}
+	if edit("username8", "http://localhost/upload/images/"+a8, a8) != code.CodeSucc {
+		t.Fatal("failed to edit uploaded avatar")
+	}
+	if edit("username9", "http://localhost/upload/images/"+a9, "../"+a8) != code.CodeTCPInvalidProfile {
+		t.Error("avatar should be a file name of headurl")
+	}
+	// headurl of username9 is the avatar of username8, which it can't claim as its upload
+	if edit("username9", "http://localhost/upload/images/"+a8, a8) != code.CodeTCPInvalidProfile {
+		t.Error("avatar uploaded by others should be refused")
+	}
+	if edit("username9", "http://localhost/upload/images/"+a8, "") != code.CodeSucc {
+		t.Fatal("failed to edit headurl")
+	}
+
+	login, _ := s.Login(ctx, &pb.LoginRequest{Username: "username9", Passwd: "123456"})
+	if rsp, _ := s.DeleteAccount(ctx, &pb.AccountRequest{Username: "username9", Token: login.Token, Passwd: "123456"}); rsp.Code != code.CodeSucc {
+		t.Fatal("failed to delete username9:", rsp.Msg)
+	}
+	if _, err := ioutil.ReadFile(filepath.Join(config.Image.Savepath, a8)); err != nil {
+		t.Error("avatar of others should not be deleted:", err)
+	}
+	login, _ = s.Login(ctx, &pb.LoginRequest{Username: "username8", Passwd: "123456"})
+	if rsp, _ := s.DeleteAccount(ctx, &pb.AccountRequest{Username: "username8", Token: login.Token, Passwd: "123456"}); rsp.Code != code.CodeSucc {
+		t.Fatal("failed to delete username8:", rsp.Msg)
+	}
+	if _, err := ioutil.ReadFile(filepath.Join(config.Image.Savepath, a8)); err == nil {
+		t.Error("uploaded avatar should be deleted with its user")
+	}
+}
+
+func Test_DeactivateAccount(t *testing.T) {
+	config := testConf(t)
+	config.Account.Graceperiod = 3600
+	s := newTestServerOf(t, config)
+	ctx := testContext()
+	s.Register(ctx, &pb.RegisterRequest{Username: "username9", Passwd: "123456"})
+	login, _ := s.Login(ctx, &pb.LoginRequest{Username: "username8", Passwd: "123456"})
+	if rsp, _ := s.DeactivateAccount(ctx, &pb.AccountRequest{Username: "username8", Token: login.Token, Passwd: "wrong"}); rsp.Code == code.CodeSucc {
+		t.Error("deactivation should need the passwd")
+	}
+	if rsp, _ := s.DeactivateAccount(ctx, &pb.AccountRequest{Username: "username8", Token: login.Token, Passwd: "123456"}); rsp.Code != code.CodeSucc {
+		t.Fatal("failed to deactivate username8:", rsp.Msg)
+	}
+	if rsp, _ := s.GetUserInfo(ctx, &pb.CommRequest{Username: "username8", Token: login.Token}); rsp.Code == code.CodeSucc {
+		t.Error("sessions should be revoked by deactivation")
+	}
+
+	// within grace period login is refused, the account is only restored on request
+	now := time.Now().Unix()
+	rsp, _ := s.Login(ctx, &pb.LoginRequest{Username: "username8", Passwd: "123456"})
+	if rsp.Code != code.CodeTCPAccountDeactivated || rsp.Token != "" || rsp.Purgetime < now+3590 || rsp.Purgetime > now+3600 {
+		t.Fatal("login of deactivated user should be refused with its purge time:", rsp.Code, rsp.Purgetime)
+	}
+	if purged := s.API.SweepAccounts(); purged != 0 {
+		t.Error("users within grace period should not be purged:", purged)
+	}
+	if rsp, _ := s.RestoreAccount(ctx, &pb.LoginRequest{Username: "username8", Passwd: "wrong"}); rsp.Code != code.CodeTCPPasswdErr {
+		t.Error("restore should need the passwd:", rsp.Code)
+	}
+	if rsp, _ := s.RestoreAccount(ctx, &pb.LoginRequest{Username: "username8", Passwd: "123456"}); rsp.Code != code.CodeSucc || rsp.Token == "" {
+		t.Fatal("failed to restore username8:", rsp.Code, rsp.Msg)
+	}
+	if rsp, _ := s.Login(ctx, &pb.LoginRequest{Username: "username8", Passwd: "123456"}); rsp.Code != code.CodeSucc {
+		t.Error("restored user should login:", rsp.Code)
+	}
+
+	// after grace period it can't be restored, and the sweeper purges it
+	if s.API.users.DeactivateDbUser("username9", now-3601) != 1 {
+		t.Fatal("failed to deactivate username9")
+	}
+	s.API.sessions.DelUserCacheInfo("username9")
+	if rsp, _ := s.RestoreAccount(ctx, &pb.LoginRequest{Username: "username9", Passwd: "123456"}); rsp.Code == code.CodeSucc {
+		t.Error("expired account should not be restored")
+	}
+	if purged := s.API.SweepAccounts(); purged != 1 {
+		t.Fatal("expired account should be purged:", purged)
+	}
+	if _, err := s.API.users.GetDbUserInfo("username9"); err == nil {
+		t.Error("purged user should be deleted")
+	}
+	if user, err := s.API.users.GetDbUserInfo("username8"); err != nil || user.Status != types.StatusActive {
+		t.Error("restored user should be kept:", user.Status, err)
+	}
+}
+
+func Test_VerifyEmail(t *testing.T) {
+	s := newTestServer(t)
+	ctx := testContext()
+
+	if rsp, _ := s.Register(ctx, &pb.RegisterRequest{Username: "username9", Passwd: "123456", Email: "bad"}); rsp.Code != code.CodeTCPInvalidProfile {
+		t.Error("invalid email should be rejected:", rsp.Code)
+	}
+	rsp, _ := s.Register(ctx, &pb.RegisterRequest{Username: "username9", Passwd: "123456", Email: "u9@example.com"})
+	if rsp.Code != code.CodeSucc || rsp.Email != "u9@example.com" || rsp.Emailverified {
+		t.Fatal("register with email failed:", rsp)
+	}
+	tokens := mailedTokens(t, s)
+	if len(tokens) != 1 {
+		t.Fatal("verification link should be mailed on register:", tokens)
+	}
+	login, _ := s.Login(ctx, &pb.LoginRequest{Username: "username9", Passwd: "123456"})
+	if login.Code != code.CodeSucc || login.Emailverified {
+		t.Error("unverified account should be flagged on login:", login)
+	}
+
+	if edit, _ := s.VerifyEmail(ctx, &pb.VerifyEmailRequest{Token: tokens[0]}); edit.Code != code.CodeSucc {
+		t.Fatal("verify email failed:", edit.Msg)
+	}
+	if edit, _ := s.VerifyEmail(ctx, &pb.VerifyEmailRequest{Token: tokens[0]}); edit.Code != code.CodeTCPInvalidEmailToken {
+		t.Error("verification token should be single-use:", edit.Code)
+	}
+	if info, _ := s.GetUserInfo(ctx, &pb.CommRequest{Username: "username9", Token: login.Token}); !info.Emailverified {
+		t.Error("verified email should be seen by token:", info)
+	}
+	if edit, _ := s.SendVerifyEmail(ctx, &pb.CommRequest{Username: "username9", Token: login.Token}); edit.Code != code.CodeTCPEmailState {
+		t.Error("verified email should not be mailed again:", edit.Code)
+	}
+
+	// a new address has to be verified again, links to an address replaced since don't work
+	// links are mailed in background, waited for to keep their order
+	s.UpdateProfile(ctx, &pb.ProfileRequest{Username: "username9", Token: login.Token, Email: "u9b@example.com", Mask: []string{"email"}})
+	s.API.email.pending.Wait()
+	profile, _ := s.UpdateProfile(ctx, &pb.ProfileRequest{Username: "username9", Token: login.Token, Email: "u9c@example.com", Mask: []string{"email"}})
+	s.API.email.pending.Wait()
+	if profile.Emailverified {
+		t.Error("changed email should be unverified:", profile)
+	}
+	if edit, _ := s.SendVerifyEmail(ctx, &pb.CommRequest{Username: "username9", Token: login.Token}); edit.Code != code.CodeSucc {
+		t.Error("failed to resend verification mail:", edit.Msg)
+	}
+	if tokens = mailedTokens(t, s); len(tokens) != 4 {
+		t.Fatal("unexpected mails:", tokens)
+	}
+	if edit, _ := s.VerifyEmail(ctx, &pb.VerifyEmailRequest{Token: tokens[1]}); edit.Code != code.CodeTCPInvalidEmailToken {
+		t.Error("link of a replaced address should not verify:", edit.Code)
+	}
+	if edit, _ := s.VerifyEmail(ctx, &pb.VerifyEmailRequest{Token: tokens[2]}); edit.Code != code.CodeSucc {
+		t.Error("link of the current address should verify:", edit.Msg)
+	}
+	if edit, _ := s.VerifyEmail(ctx, &pb.VerifyEmailRequest{Token: tokens[3]}); edit.Code != code.CodeTCPInvalidEmailToken {
+		t.Error("address should be verified once:", edit.Code)
+	}
+	if edit, _ := s.VerifyEmail(ctx, &pb.VerifyEmailRequest{Token: "unknown"}); edit.Code != code.CodeTCPInvalidEmailToken {
+		t.Error("unknown token should fail:", edit.Code)
+	}
+}
+
+func Test_ResetPassword(t *testing.T) {
+	s := newTestServer(t)
+	ctx := testContext()
+	s.API.email.maxResets = 2
+
+	s.Register(ctx, &pb.RegisterRequest{Username: "username9", Passwd: "123456", Email: "u9@example.com"})
+	s.VerifyEmail(ctx, &pb.VerifyEmailRequest{Token: mailedTokens(t, s)[0]})
+	login, _ := s.Login(ctx, &pb.LoginRequest{Username: "username9", Passwd: "123456"})
+
+	// same response whether or not a link is mailed
+	for _, username := range []string{"username8", "nobody", "bad name!", "username9", "username9", "username9"} {
+		if rsp, _ := s.RequestPasswordReset(ctx, &pb.PasswordResetRequest{Username: username}); rsp.Code != code.CodeSucc {
+			t.Error("reset request should always succeed:", username, rsp.Code)
+		}
+	}
+	s.API.email.pending.Wait()
+	tokens := mailedTokens(t, s)
+	if len(tokens) != 3 {
+		t.Fatal("reset links should only be mailed to verified emails, within the limit:", tokens)
+	}
+
+	for i := 0; i < 3; i++ {
+		s.Login(ctx, &pb.LoginRequest{Username: "username9", Passwd: "wrong-passwd"})
+	}
+	if rsp, _ := s.ResetPassword(ctx, &pb.ResetPasswordRequest{Token: tokens[2], Newpasswd: "123"}); rsp.Code != code.CodeTCPInvalidPasswd {
+		t.Error("short passwd should be rejected:", rsp.Code)
+	}
+	if rsp, _ := s.ResetPassword(ctx, &pb.ResetPasswordRequest{Token: tokens[2], Newpasswd: "654321"}); rsp.Code != code.CodeSucc {
+		t.Fatal("reset passwd failed:", rsp.Msg)
+	}
+	if rsp, _ := s.ResetPassword(ctx, &pb.ResetPasswordRequest{Token: tokens[2], Newpasswd: "abcdef"}); rsp.Code != code.CodeTCPInvalidResetToken {
+		t.Error("reset token should be single-use:", rsp.Code)
+	}
+	if rsp, _ := s.ResetPassword(ctx, &pb.ResetPasswordRequest{Token: tokens[1], Newpasswd: "abcdef"}); rsp.Code != code.CodeTCPInvalidResetToken {
+		t.Error("links mailed before a passwd change should not work:", rsp.Code)
+	}
+	if info, _ := s.GetUserInfo(ctx, &pb.CommRequest{Username: "username9", Token: login.Token}); info.Code != code.CodeTCPTokenExpired {
+		t.Error("sessions should be revoked by reset:", info.Code)
+	}
+	if rsp, _ := s.Login(ctx, &pb.LoginRequest{Username: "username9", Passwd: "123456"}); rsp.Code != code.CodeTCPPasswdErr {
+		t.Error("old passwd should not work:", rsp.Code)
+	}
+	if rsp, _ := s.Login(ctx, &pb.LoginRequest{Username: "username9", Passwd: "654321"}); rsp.Code != code.CodeSucc {
+		t.Error("login with new passwd failed, lock should be lifted by reset:", rsp.Code)
+	}
+
+	// the request limit starts over after a reset
+	s.RequestPasswordReset(ctx, &pb.PasswordResetRequest{Username: "username9"})
+	s.API.email.pending.Wait()
+	if tokens = mailedTokens(t, s); len(tokens) != 4 {
+		t.Error("reset link should be mailed after the limit is lifted:", tokens)
+	}
+}
+
+// adminCall call admin rpc method through AuthInterceptor, as the grpc server does
+func adminCall(s *UserServer, method string, req interface{}, handler grpc.UnaryHandler) interface{} {
+	rsp, _ := s.AuthInterceptor(testContext(), req, &grpc.UnaryServerInfo{FullMethod: "/proto.UserService/" + method}, handler)
+	return rsp
+}
+
+func Test_AdminRPCs(t *testing.T) {
+	s := newTestServer(t)
+	ctx := testContext()
+	var err error
+	s.API.rbac, err = rbac.NewPolicy(map[string][]string{rbac.RoleAdmin: {"*"}, "support": {rbac.PermListUsers}}, nil)
+	if err != nil {
+		t.Fatal("failed to new policy:", err)
+	}
+	s.Register(ctx, &pb.RegisterRequest{Username: "username9", Passwd: "123456"})
+	if _, err = s.API.SetRoles("username8", []string{rbac.RoleAdmin}); err != nil {
+		t.Fatal("failed to grant admin:", err)
+	}
+	admin, _ := s.Login(ctx, &pb.LoginRequest{Username: "username8", Passwd: "123456"})
+	if len(admin.Roles) != 1 || admin.Roles[0] != rbac.RoleAdmin {
+		t.Error("roles should be returned by login:", admin.Roles)
+	}
+	user, _ := s.Login(ctx, &pb.LoginRequest{Username: "username9", Passwd: "123456"})
+
+	lock := func(username, token, target string, locked bool) uint32 {
+		method, handler := "LockUser", func(ctx context.Context, req interface{}) (interface{}, error) {
+			return s.LockUser(ctx, req.(*pb.AdminRequest))
+		}
+		if !locked {
+			method, handler = "UnlockUser", func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.UnlockUser(ctx, req.(*pb.AdminRequest))
+			}
+		}
+		return adminCall(s, method, &pb.AdminRequest{Username: username, Token: token, Target: target}, handler).(*pb.EditResponse).Code
+	}
+	if c := lock("username9", user.Token, "username8", true); c != code.CodeTCPPermissionDenied {
+		t.Error("users without roles should be denied:", c)
+	}
+	if c := lock("username8", user.Token, "username9", true); c != code.CodeTCPUserInfoNotMatch {
+		t.Error("token of other user should not match:", c)
+	}
+	if rsp, _ := s.LockUser(ctx, &pb.AdminRequest{Username: "username8", Token: admin.Token, Target: "username9"}); rsp.Code != code.CodeTCPPermissionDenied {
+		t.Error("admin rpcs should be denied without the interceptor:", rsp.Code)
+	}
+	if c := lock("username8", admin.Token, "username8", true); c != code.CodeTCPPermissionDenied {
+		t.Error("admins should not lock themselves:", c)
+	}
+
+	if c := lock("username8", admin.Token, "username9", true); c != code.CodeSucc {
+		t.Fatal("lock user failed:", c)
+	}
+	if info, _ := s.GetUserInfo(ctx, &pb.CommRequest{Username: "username9", Token: user.Token}); info.Code != code.CodeTCPTokenExpired {
+		t.Error("sessions should be revoked by lock:", info.Code)
+	}
+	if login, _ := s.Login(ctx, &pb.LoginRequest{Username: "username9", Passwd: "123456"}); login.Code != code.CodeTCPAccountDisabled {
+		t.Error("locked user should not login:", login.Code)
+	}
+	if c := lock("username8", admin.Token, "username9", false); c != code.CodeSucc {
+		t.Error("unlock user failed:", c)
+	}
+	user, _ = s.Login(ctx, &pb.LoginRequest{Username: "username9", Passwd: "123456"})
+	if user.Code != code.CodeSucc {
+		t.Fatal("unlocked user should login:", user.Code)
+	}
+
+	logout := adminCall(s, "ForceLogout", &pb.AdminRequest{Username: "username8", Token: admin.Token, Target: "username9"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return s.ForceLogout(ctx, req.(*pb.AdminRequest))
+		}).(*pb.EditResponse)
+	if info, _ := s.GetUserInfo(ctx, &pb.CommRequest{Username: "username9", Token: user.Token}); logout.Code != code.CodeSucc || info.Code != code.CodeTCPTokenExpired {
+		t.Error("sessions should be revoked by force logout:", logout.Code, info.Code)
+	}
+
+	edit := func(profile *pb.ProfileRequest) *pb.LoginResponse {
+		return adminCall(s, "AdminUpdateProfile", &pb.AdminProfileRequest{Username: "username8", Token: admin.Token, Profile: profile},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.AdminUpdateProfile(ctx, req.(*pb.AdminProfileRequest))
+			}).(*pb.LoginResponse)
+	}
+	if rsp := edit(&pb.ProfileRequest{Username: "username9", Nickname: "by admin", Mask: []string{"nickname"}}); rsp.Code != code.CodeSucc || rsp.Nickname != "by admin" {
+		t.Error("admin should edit profile of others:", rsp)
+	}
+	if rsp := edit(&pb.ProfileRequest{Username: "username9", Mask: []string{"roles"}}); rsp.Code != code.CodeTCPInvalidProfile {
+		t.Error("roles should not be edited as profile:", rsp.Code)
+	}
+
+	setRoles := func(target string, roles ...string) *pb.LoginResponse {
+		return adminCall(s, "SetUserRoles", &pb.SetRolesRequest{Username: "username8", Token: admin.Token, Target: target, Roles: roles},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.SetUserRoles(ctx, req.(*pb.SetRolesRequest))
+			}).(*pb.LoginResponse)
+	}
+	if rsp := setRoles("username9", "root"); rsp.Code != code.CodeTCPUnknownRole {
+		t.Error("unknown role should be rejected:", rsp.Code)
+	}
+	if rsp := setRoles("username8"); rsp.Code != code.CodeTCPPermissionDenied {
+		t.Error("admins should not change their own roles:", rsp.Code)
+	}
+	if rsp := setRoles("username9", "support", "support"); rsp.Code != code.CodeSucc || len(rsp.Roles) != 1 {
+		t.Error("set roles failed:", rsp)
+	}
+	support, _ := s.Login(ctx, &pb.LoginRequest{Username: "username9", Passwd: "123456"})
+	if c := lock("username9", support.Token, "username8", true); c != code.CodeTCPPermissionDenied {
+		t.Error("support should not lock users:", c)
+	}
+
+	list := func(cursor string) *pb.UsersResponse {
+		return adminCall(s, "ListUsers", &pb.ListUsersRequest{Username: "username9", Token: support.Token, Cursor: cursor, Limit: 1},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.ListUsers(ctx, req.(*pb.ListUsersRequest))
+			}).(*pb.UsersResponse)
+	}
+	var listed []string
+	for page, cursor := 0, ""; page < 5; page++ {
+		rsp := list(cursor)
+		if rsp.Code != code.CodeSucc {
+			t.Fatal("list users failed:", rsp.Code)
+		}
+		for _, u := range rsp.Users {
+			listed = append(listed, u.Username)
+		}
+		if cursor = rsp.Nextcursor; cursor == "" {
+			break
+		}
+	}
+	if len(listed) != 2 || listed[0] != "username8" || listed[1] != "username9" {
+		t.Error("users should be listed page by page:", listed)
+	}
+	if rsp := list("!!"); rsp.Code != code.CodeTCPInvalidCursor {
+		t.Error("invalid cursor should be rejected:", rsp.Code)
+	}
+}
+
+func Test_SignedAdminRevoked(t *testing.T) {
+	s := newTestServer(t)
+	ctx := testContext()
+	key, _ := jwt.NewHS256Key("k1", "test-secret-of-at-least-32-bytes")
+	s.API.keySet, _ = jwt.NewKeySet("k1", key)
+	s.API.accessExpire = 300
+	if _, err := s.API.SetRoles("username8", []string{rbac.RoleAdmin}); err != nil {
+		t.Fatal("failed to grant admin:", err)
+	}
+	login, _ := s.Login(ctx, &pb.LoginRequest{Username: "username8", Passwd: "123456"})
+	if !jwt.IsSigned(login.Token) {
+		t.Fatal("login should issue a signed access token:", login)
+	}
+	list := func() uint32 {
+		return adminCall(s, "ListUsers", &pb.ListUsersRequest{Username: "username8", Token: login.Token},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.ListUsers(ctx, req.(*pb.ListUsersRequest))
+			}).(*pb.UsersResponse).Code
+	}
+	if c := list(); c != code.CodeSucc {
+		t.Fatal("admin should list users:", c)
+	}
+	// roles taken away log the session out, its access token is turned away before it expires
+	if _, err := s.API.SetRoles("username8", nil); err != nil {
+		t.Fatal("failed to revoke admin:", err)
+	}
+	if c := list(); c != code.CodeTCPTokenExpired {
+		t.Error("access token of a revoked session should not pass admin checks:", c)
+	}
+}
+
+func Test_SignedAccountRevoked(t *testing.T) {
+	s := newTestServer(t)
+	ctx := testContext()
+	key, _ := jwt.NewHS256Key("k1", "test-secret-of-at-least-32-bytes")
+	s.API.keySet, _ = jwt.NewKeySet("k1", key)
+	s.API.accessExpire = 300
+	stolen, _ := s.Login(ctx, &pb.LoginRequest{Username: "username8", Passwd: "123456"})
+	login, _ := s.Login(ctx, &pb.LoginRequest{Username: "username8", Passwd: "123456"})
+	if !jwt.IsSigned(stolen.Token) {
+		t.Fatal("login should issue a signed access token:", stolen)
+	}
+	s.Logout(ctx, &pb.CommRequest{Username: "username8", Token: stolen.Token})
+
+	// reads trust the access token until it expires, changes of the account need its session
+	if rsp, _ := s.GetUserInfo(ctx, &pb.CommRequest{Username: "username8", Token: stolen.Token}); rsp.Code != code.CodeSucc {
+		t.Error("userinfo should be read by a signed access token alone:", rsp.Code)
+	}
+	if rsp, _ := s.ChangePasswd(ctx, &pb.ChangePasswdRequest{Username: "username8", Token: stolen.Token, Oldpasswd: "123456", Newpasswd: "654321"}); rsp.Code != code.CodeTCPTokenExpired {
+		t.Error("access token of a logged out session should not change the passwd:", rsp.Code)
+	}
+	if rsp, _ := s.DeleteAccount(ctx, &pb.AccountRequest{Username: "username8", Token: stolen.Token, Passwd: "123456"}); rsp.Code != code.CodeTCPTokenExpired {
+		t.Error("access token of a logged out session should not delete the account:", rsp.Code)
+	}
+	if rsp, _ := s.UpdateProfile(ctx, &pb.ProfileRequest{Username: "username8", Token: login.Token, Bio: "hi", Mask: []string{"bio"}}); rsp.Code != code.CodeSucc {
+		t.Error("access token of a live session should change the account:", rsp.Code)
+	}
+}
+
+func Test_BootstrapAdminUid(t *testing.T) {
+	config := testConf(t)
+	s := newTestServerOf(t, config)
+	ctx := testContext()
+	login, _ := s.Login(ctx, &pb.LoginRequest{Username: "username8", Passwd: "123456"})
+	config.Rbac.Admins = []int64{login.Uid}
+	var err error
+	if s.API.rbac, err = rbac.NewPolicyFromConf(config); err != nil {
+		t.Fatal("failed to new policy:", err)
+	}
+	list := func(username string) uint32 {
+		login, _ := s.Login(ctx, &pb.LoginRequest{Username: username, Passwd: "123456"})
+		in := &pb.ListUsersRequest{Username: username, Token: login.Token}
+		return adminCall(s, "ListUsers", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return s.ListUsers(ctx, req.(*pb.ListUsersRequest))
+		}).(*pb.UsersResponse).Code
+	}
+
+	// the admin renames itself, its old username is freed and taken by someone else
+	if rsp, _ := s.ChangeUsername(ctx, &pb.ChangeUsernameRequest{Username: "username8", Token: login.Token, Newname: "boss"}); rsp.Code != code.CodeSucc {
+		t.Fatal("failed to rename admin:", rsp.Code)
+	}
+	s.API.users.DelDbExpiredRenames(time.Now().Unix() + 7200)
+	if rsp, _ := s.Register(ctx, &pb.RegisterRequest{Username: "username8", Passwd: "123456"}); rsp.Code != code.CodeSucc {
+		t.Fatal("failed to register freed username:", rsp.Code)
+	}
+	if c := list("username8"); c != code.CodeTCPPermissionDenied {
+		t.Error("new owner of the username of an admin should not be admin:", c)
+	}
+	if c := list("boss"); c != code.CodeSucc {
+		t.Error("admin of config should stay admin across renames:", c)
+	}
+}
+
+func Test_ListUsers(t *testing.T) {
+	// users spread over the tables of a sqlite db
+	config := testConf(t)
+	config.Store.Users = store.UsersMySQL
+	config.Db.Driver, config.Db.Path = db.DriverSQLite, filepath.Join(t.TempDir(), "users.db")
+	s := newTestServerOf(t, config)
+	t.Cleanup(s.API.Finalize)
+	ctx := testContext()
+	for i := 0; i < 25; i++ {
+		s.Register(ctx, &pb.RegisterRequest{Username: fmt.Sprintf("user%02d", i), Passwd: "123456", Nickname: fmt.Sprintf("nick%02d", i)})
+	}
+	admin, _ := s.Login(ctx, &pb.LoginRequest{Username: "username8", Passwd: "123456"})
+	// admins of config are bound to uids
+	config.Rbac.Admins = []int64{admin.Uid}
+	var err error
+	if s.API.rbac, err = rbac.NewPolicyFromConf(config); err != nil {
+		t.Fatal("failed to new policy:", err)
+	}
+
+	list := func(in *pb.ListUsersRequest) *pb.UsersResponse {
+		in.Username, in.Token = "username8", admin.Token
+		return adminCall(s, "ListUsers", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return s.ListUsers(ctx, req.(*pb.ListUsersRequest))
+		}).(*pb.UsersResponse)
+	}
+	var listed []string
+	for page, cursor := 0, ""; page < 10; page++ {
+		rsp := list(&pb.ListUsersRequest{Cursor: cursor, Limit: 7})
+		if rsp.Code != code.CodeSucc || len(rsp.Users) > 7 {
+			t.Fatal("list users failed:", rsp.Code, len(rsp.Users))
+		}
+		for _, u := range rsp.Users {
+			listed = append(listed, u.Username)
+		}
+		// users coming and going before the cursor don't shift the pages after it
+		if page == 0 {
+			s.Register(ctx, &pb.RegisterRequest{Username: "aaa", Passwd: "123456"})
+			s.API.users.DeleteDbUser(listed[0])
+		}
+		if cursor = rsp.Nextcursor; cursor == "" {
+			break
+		}
+	}
+	if len(listed) != 26 || !sort.StringsAreSorted(listed) {
+		t.Error("all users should be listed once in username order:", listed)
+	}
+
+	rsp := list(&pb.ListUsersRequest{Prefix: "user1", Nickname: "ICK1"})
+	if rsp.Code != code.CodeSucc || len(rsp.Users) != 10 || rsp.Nextcursor != "" {
+		t.Error("users should be filtered:", rsp.Code, len(rsp.Users))
+	}
+	now := time.Now().Unix()
+	if rsp = list(&pb.ListUsersRequest{Upfrom: now - 60, Upto: now + 60, Limit: 100}); len(rsp.Users) != 26 {
+		t.Error("users should be filtered by uptime:", len(rsp.Users))
+	}
+	if rsp = list(&pb.ListUsersRequest{Prefix: "user%"}); rsp.Code != code.CodeTCPInvalidFilter {
+		t.Error("invalid prefix should be rejected:", rsp.Code)
+	}
+	if rsp = list(&pb.ListUsersRequest{Upfrom: now, Upto: now - 1}); rsp.Code != code.CodeTCPInvalidFilter {
+		t.Error("empty uptime range should be rejected:", rsp.Code)
+	}
+	if rsp = list(&pb.ListUsersRequest{Cursor: "not base64!"}); rsp.Code != code.CodeTCPInvalidCursor {
+		t.Error("invalid cursor should be rejected:", rsp.Code)
+	}
+}
+
+// auditedCall call rpc method through AuditInterceptor and AuthInterceptor, chained as the grpc server does
+func auditedCall(s *UserServer, method string, req interface{}, handler grpc.UnaryHandler) interface{} {
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.UserService/" + method}
+	rsp, _ := s.AuditInterceptor(testContext(), req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return s.AuthInterceptor(ctx, req, info, handler)
+	})
+	return rsp
+}
+
+func Test_AuditLog(t *testing.T) {
+	s := newTestServer(t)
+	ctx := testContext()
+	s.Register(ctx, &pb.RegisterRequest{Username: "username9", Passwd: "123456"})
+	login := func(username, passwd string) *pb.LoginResponse {
+		return auditedCall(s, "Login", &pb.LoginRequest{Username: username, Passwd: passwd}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return s.Login(ctx, req.(*pb.LoginRequest))
+		}).(*pb.LoginResponse)
+	}
+	login("username8", "wrong")
+	rsp := login("username8", "123456")
+	auditedCall(s, "EditUserInfo", &pb.EditRequest{Username: "username8", Token: rsp.Token, Headurl: "http://a.com/a.png", Mode: consts.EditHeadurl},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return s.EditUserInfo(ctx, req.(*pb.EditRequest))
+		})
+	auditedCall(s, "UpdateProfile", &pb.ProfileRequest{Username: "username8", Token: rsp.Token, Bio: "hi", Mask: []string{"bio"}},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return s.UpdateProfile(ctx, req.(*pb.ProfileRequest))
+		})
+	auditedCall(s, "Logout", &pb.CommRequest{Username: "username8", Token: rsp.Token}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return s.Logout(ctx, req.(*pb.CommRequest))
+	})
+	// admin rpcs turned away are recorded against their target
+	user := login("username9", "123456")
+	auditedCall(s, "LockUser", &pb.AdminRequest{Username: "username9", Token: user.Token, Target: "username8"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return s.LockUser(ctx, req.(*pb.AdminRequest))
+		})
+
+	query := func(username, token string, in *pb.AuditLogRequest) *pb.AuditLogResponse {
+		in.Username, in.Token = username, token
+		return auditedCall(s, "QueryAuditLog", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return s.QueryAuditLog(ctx, req.(*pb.AuditLogRequest))
+		}).(*pb.AuditLogResponse)
+	}
+	if rsp := query("username9", user.Token, &pb.AuditLogRequest{Target: "username8"}); rsp.Code != code.CodeTCPPermissionDenied {
+		t.Error("audit log should need audit.read:", rsp.Code)
+	}
+	if _, err := s.API.SetRoles("username8", []string{rbac.RoleAdmin}); err != nil {
+		t.Fatal("failed to grant admin:", err)
+	}
+	admin := login("username8", "123456")
+
+	want := []struct{ action, actor, outcome string }{
+		{"login", "username8", types.AuditSuccess},
+		{"admin.lock", "username9", types.AuditFailure},
+		{"logout", "username8", types.AuditSuccess},
+		{"profile.update", "username8", types.AuditSuccess},
+		{"avatar.upload", "username8", types.AuditSuccess},
+		{"login", "username8", types.AuditSuccess},
+		// nobody is known to have tried a wrong passwd
+		{"login", "", types.AuditFailure},
+	}
+	var events []*pb.AuditEvent
+	for page, cursor := 0, ""; page < 10; page++ {
+		rsp := query("username8", admin.Token, &pb.AuditLogRequest{Target: "username8", Cursor: cursor, Limit: 3})
+		if rsp.Code != code.CodeSucc || len(rsp.Events) > 3 {
+			t.Fatal("query audit log failed:", rsp.Code, rsp.Msg)
+		}
+		events = append(events, rsp.Events...)
+		if cursor = rsp.Nextcursor; cursor == "" {
+			break
+		}
+	}
+	if len(events) != len(want) {
+		t.Fatal("events of username8 should be paged newest first:", events)
+	}
+	for i, e := range events {
+		if e.Username != "username8" || e.Action != want[i].action || e.Actor != want[i].actor || e.Outcome != want[i].outcome ||
+			e.Ip != "127.0.0.1" || e.Uuid != "test" || e.Time == 0 || e.Uid != rsp.Uid || e.Uid == 0 {
+			t.Error("unexpected event:", i, e)
+		}
+	}
+	if events[3].Detail != "fields: bio" || events[6].Resultcode != code.CodeTCPPasswdErr || events[6].Detail == "" {
+		t.Error("detail and result code should be recorded:", events[3], events[6])
+	}
+
+	now := time.Now().Unix()
+	if rsp := query("username8", admin.Token, &pb.AuditLogRequest{Action: "login", From: now - 60, To: now + 60}); len(rsp.Events) != 4 {
+		t.Error("events should be filtered by action and time:", rsp.Events)
+	}
+	if rsp := query("username8", admin.Token, &pb.AuditLogRequest{From: now, To: now}); rsp.Code != code.CodeTCPInvalidFilter {
+		t.Error("empty time range should be rejected:", rsp.Code)
+	}
+	if rsp := query("username8", admin.Token, &pb.AuditLogRequest{Cursor: "abc"}); rsp.Code != code.CodeTCPInvalidCursor {
+		t.Error("invalid cursor should be rejected:", rsp.Code)
+	}
+
+	// uid follows the user across renames, the freed name doesn't carry its events to the next owner
+	login("nobody", "123456")
+	if rsp := query("username8", admin.Token, &pb.AuditLogRequest{Target: "nobody"}); len(rsp.Events) != 1 || rsp.Events[0].Uid != 0 {
+		t.Error("events of unknown users should have no uid:", rsp.Events)
+	}
+	auditedCall(s, "ChangeUsername", &pb.ChangeUsernameRequest{Username: "username9", Token: user.Token, Newname: "username7"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return s.ChangeUsername(ctx, req.(*pb.ChangeUsernameRequest))
+		})
+	renamed := login("username7", "123456")
+	s.Register(ctx, &pb.RegisterRequest{Username: "username9", Passwd: "123456"})
+	login("username9", "123456")
+	byUid := query("username8", admin.Token, &pb.AuditLogRequest{Targetuid: renamed.Uid})
+	if rsp := byUid; rsp.Code != code.CodeSucc || len(rsp.Events) != 3 || rsp.Events[0].Username != "username7" || rsp.Events[1].Action != "username.change" ||
+		rsp.Events[1].Username != "username9" || rsp.Events[1].Outcome != types.AuditSuccess || rsp.Events[2].Action != "login" {
+		t.Error("events should be queried by uid across renames:", rsp.Code, rsp.Events)
+	}
+	if rsp := query("username8", admin.Token, &pb.AuditLogRequest{Target: "username9"}); len(rsp.Events) != 3 || rsp.Events[0].Uid == renamed.Uid {
+		t.Error("events of a freed username should keep the uid of who had it:", rsp.Events)
+	}
+
+	// actors are who the token belongs to, usernames sent by clients are taken for the subject only
+	for _, token := range []string{"bogus", user.Token} {
+		auditedCall(s, "ChangePasswd", &pb.ChangePasswdRequest{Username: "username8", Token: token, Oldpasswd: "123456", Newpasswd: "654321"},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.ChangePasswd(ctx, req.(*pb.ChangePasswdRequest))
+			})
+	}
+	changes := query("username8", admin.Token, &pb.AuditLogRequest{Target: "username8", Action: "passwd.change"}).Events
+	if len(changes) != 2 || changes[0].Actor != "username7" || changes[1].Actor != "" ||
+		changes[0].Outcome != types.AuditFailure || changes[1].Outcome != types.AuditFailure || changes[1].Uid != admin.Uid {
+		t.Error("actors should only be taken from tokens:", changes)
+	}
+}
@@ -7,49 +7,25 @@ import (
     "time"
 
     "user-management-system/conf"
+    tcpdb "user-management-system/tcpserver/db"
+    "user-management-system/tcpserver/snowflake"
+    "user-management-system/tcpserver/types"
     "user-management-system/utils"
-
-    "github.com/jinzhu/gorm"
-    _ "github.com/jinzhu/gorm/dialects/mysql"
 )
 
 var config conf.TCPConf
-var db *gorm.DB
-
-// User gorm db struct
-type User struct {
-    ID       int32       `gorm:"types:int(11);primary key"`
-    Username string      `gorm:"types:varchar(64);unique;not null"`
-    Nickname string      `gorm:"types:varchar(128)"`
-    Passwd   string      `gorm:"types:varchar(32);not null"`
-    Skey     string      `gorm:"types:varchar(16);not null"`
-    Headurl  string      `gorm:"types:varchar(128);unique;not null"`
-    Uptime   int64       `gorm:"types:datetime"`
-}
-
-// TableName generate tablename
-func (u User) TableName() string {
-    var value int
-    for _, c := range []rune(u.Username) {
-        value = value + int(c)
-    }
-    return fmt.Sprintf("userinfo_tab_%d", value % 20)
-}
-
-// getTableName return a new table name
-func getTableName(username string) string {
-    var value int
-    for _, c := range []rune(username) {
-        value = value + int(c)
-    }
-    return fmt.Sprintf("userinfo_tab_%d", value % 20)
-}
+var db *tcpdb.DBClient
+var uids *snowflake.Generator
+var upgrade bool
+var totalNum int
 
 // init parse config and init db
 func init() {
     // parser config
     var confFile string
     flag.StringVar(&confFile, "c", "conf/tcpserver.yaml", "config file")
+    flag.BoolVar(&upgrade, "upgrade", false, "migrate existing tables and assign uids only, no data is created")
+    flag.IntVar(&totalNum, "n", 10000000, "number of users to create")
     flag.Parse()
 
     err := utils.ConfParser(confFile, &config)
@@ -58,16 +34,17 @@ func init() {
         os.Exit(-1)
     }
 
-    // init db
-    conninfo := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8", config.Db.User, config.Db.Passwd, config.Db.Host, config.Db.Db)
-    db, err = gorm.Open("mysql", conninfo)
+    // init db instances and shard router as tcpserver does
+    db, err = tcpdb.NewDBClient(&config)
     if err != nil {
         fmt.Println("connect to db failed:", err.Error())
         os.Exit(-1)
     }
-    db.DB().SetMaxIdleConns(config.Db.Conn.Maxidle)
-    db.DB().SetMaxOpenConns(config.Db.Conn.Maxopen)
-    db.LogMode(true)
+    uids, err = snowflake.NewGenerator(config.Uid.Node)
+    if err != nil {
+        fmt.Println("init uid generator failed:", err.Error())
+        os.Exit(-1)
+    }
 }
 
 // generateSkey generate secret key
@@ -77,31 +54,26 @@ func generateSkey() string {
     return str[0:6]
 }
 
-// createTable create a new table schema
+// createTable create user tables of the shard section and migrate them to the latest schema
 func createTable() {
-    sql := `CREATE TABLE IF NOT EXISTS userinfo_tab_0 (
-id INT(11) NOT NULL AUTO_INCREMENT COMMENT 'primary key',
-username VARCHAR(64) NOT NULL COMMENT 'unique id',
-nickname VARCHAR(128) NOT NULL DEFAULT '' COMMENT 'user nickname, can be empty',
-passwd VARCHAR(32) NOT NULL COMMENT 'md5 result of real password and key',
-skey VARCHAR(16) NOT NULL COMMENT 'secure key of each user',
-headurl VARCHAR(128) NOT NULL DEFAULT '' COMMENT 'user headurl, can be empty',
-uptime int(64) NOT NULL DEFAULT 0 COMMENT 'update time: unix timestamp',
-PRIMARY KEY(id),
-UNIQUE KEY username_unique (username)
-) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='user info table';`
-    db.Exec(sql)
-    for i := 1; i < 20; i++ {
-        tableName := fmt.Sprintf("userinfo_tab_%d", i)
-        db.Exec(fmt.Sprintf("create table if not exists %s like userinfo_tab_0", tableName))
+    if err := db.CreateTables(); err != nil {
+        fmt.Println("create tables failed:", err.Error())
+        os.Exit(-1)
     }
 }
 
+// assignUids give every user without a uid one
+func assignUids() {
+    assigned, err := db.AssignUids(uids.Next, 1000)
+    if err != nil {
+        fmt.Println("assign uids failed:", err.Error())
+        os.Exit(-1)
+    }
+    fmt.Println("Assigned uids to", assigned, "users.")
+}
 
 // insertRecord insert records into db
 func insertRecord() {
-    db.LogMode(false)
-
     var ch chan int64
     var cnum chan int
     maxProcs := 50
@@ -111,7 +83,7 @@ func insertRecord() {
     for i := 0; i < maxProcs; i++ {
         go func(ch chan int64, cnum chan int) {
             var uid int64
-            var tableName, username, nickname, skey, password string
+            var username, nickname, skey, password string
             for {
                 uid = <-ch
                 if uid == 0 {
@@ -119,16 +91,14 @@ func insertRecord() {
                     break
                 }
                 username = fmt.Sprintf("username%d", uid)
-                tableName = getTableName(username)
                 nickname = fmt.Sprintf("nickname%d", uid)
                 skey = generateSkey()
                 password = utils.Md5String(utils.Md5String("123456") + skey)
-                db.Table(tableName).Create(&User{Username: username, Nickname: nickname, Passwd: password, Skey: skey, Uptime: time.Now().Unix()})
+                db.CreateDbUser(&types.User{Uid: uids.Next(), Username: username, Nickname: nickname, Passwd: password, Skey: skey, Uptime: time.Now().Unix()})
             }
         }(ch, cnum)
     }
     fmt.Println("Start to create user data,Please wait...")
-    totalNum := 10000000
     for i := 1; i <= totalNum; i++ {
         if int64(i)%20000 == 0 {
             fmt.Println(time.Now().Format("2000-01-01 00:00:00"), fmt.Sprintf("Completed %.1f%%", float64(i*100)/float64(totalNum)))
@@ -148,6 +118,11 @@ func insertRecord() {
 
 // main
 func main() {
+    if upgrade {
+        createTable()
+        assignUids()
+        return
+    }
     createTable()
     insertRecord()
 }
@@ -9,16 +9,60 @@ const (
     CodeTCPFailedGetUserInfo    = 1101
     // CodeTCPPasswdErr password error
     CodeTCPPasswdErr            = 1102
+    // CodeTCPUserExists username has been registered
+    CodeTCPUserExists           = 1103
+    // CodeTCPInvalidUsername username format isn't right
+    CodeTCPInvalidUsername      = 1104
+    // CodeTCPInvalidPasswd passwd format isn't right
+    CodeTCPInvalidPasswd        = 1105
+    // CodeTCPAccountLocked too many failed logins of the account
+    CodeTCPAccountLocked        = 1106
+    // CodeTCPTooManyAttempts too many failed logins from client ip
+    CodeTCPTooManyAttempts      = 1107
+    // CodeTCPTwoFactorRequired passwd is right, 2fa code is required with challenge
+    CodeTCPTwoFactorRequired    = 1108
+    // CodeTCPInvalidPasscode wrong 2fa code or recovery code
+    CodeTCPInvalidPasscode      = 1109
+    // CodeTCPChallengeExpired 2fa challenge expired or used up
+    CodeTCPChallengeExpired     = 1110
+    // CodeTCPTwoFactorState 2fa enrolment isn't in the right state for the request
+    CodeTCPTwoFactorState       = 1111
+    // CodeTCPAccountDeactivated account is deactivated, it can be restored before purgetime
+    CodeTCPAccountDeactivated   = 1112
+    // CodeTCPInvalidProfile a profile field is unknown or its value is invalid, msg tells which
+    CodeTCPInvalidProfile       = 1113
+    // CodeTCPInvalidEmailToken email verification link is invalid, expired or used
+    CodeTCPInvalidEmailToken    = 1114
+    // CodeTCPEmailState user has no email to verify, or it's verified already
+    CodeTCPEmailState           = 1115
+    // CodeTCPInvalidResetToken passwd reset link is invalid, expired or used
+    CodeTCPInvalidResetToken    = 1116
+    // CodeTCPAccountDisabled account is locked by an admin until it's unlocked
+    CodeTCPAccountDisabled      = 1117
+    // CodeTCPUnknownRole role isn't configured in rbac of tcpserver
+    CodeTCPUnknownRole          = 1118
+    // CodeTCPInvalidCursor cursor isn't the nextcursor of a page
+    CodeTCPInvalidCursor        = 1119
+    // CodeTCPInvalidFilter a filter of user listing is invalid, msg tells which
+    CodeTCPInvalidFilter        = 1120
     // CodeTCPInvalidToken invalid token
     CodeTCPInvalidToken         = 1200
     // CodeTCPTokenExpired token expired
     CodeTCPTokenExpired         = 1201
     // CodeTCPUserInfoNotMatch token info not match userinfo
     CodeTCPUserInfoNotMatch     = 1202
+    // CodeTCPSessionNotFound session to revoke not found
+    CodeTCPSessionNotFound      = 1203
+    // CodeTCPPermissionDenied roles of the caller don't grant the admin rpc
+    CodeTCPPermissionDenied     = 1204
     // CodeTCPFailedUpdateUserInfo update userinfo failed
     CodeTCPFailedUpdateUserInfo = 1301
+    // CodeTCPFailedCreateUser create user failed
+    CodeTCPFailedCreateUser     = 1302
     // CodeTCPInternelErr internel error
     CodeTCPInternelErr          = 1401
+    // CodeTCPFailedSendMail failed to send mail
+    CodeTCPFailedSendMail       = 1402
 
     // HTTP 2000 ~ 3000
     // CodeInternalErr   internel err
@@ -31,6 +75,12 @@ const (
     CodeErrBackend      = 2201
     // CodeInvalidPasswd passwd format isn't right
     CodeInvalidPasswd   = 2301
+    // CodeInvalidUsername username format isn't right
+    CodeInvalidUsername = 2302
+    // CodeInvalidPasscode missing 2fa challenge or passcode
+    CodeInvalidPasscode = 2303
+    // CodeInvalidParam  a query or form param isn't in the right format
+    CodeInvalidParam    = 2304
     // CodeFormFileFailed formFile get error
     CodeFormFileFailed  = 2401
     // CodeFileSizeErr file size not match (too small or too large)
@@ -46,15 +96,40 @@ var CodeMsg = map[int]string {
     CodeInvalidToken  : "invalid token",
     CodeErrBackend    : "Error found!please try again!",
     CodeInvalidPasswd : "username/passwd error!",
+    CodeInvalidUsername: "invalid username (3~64 letters, digits or '_')!",
+    CodeInvalidPasscode: "invalid 2fa challenge or passcode!",
+    CodeInvalidParam  : "invalid param!",
     CodeFormFileFailed: "fetch file failed!",
     CodeFileSizeErr   : "File size err (should less than 5MB)!",
 
     // tcp
     CodeTCPFailedGetUserInfo    : "tcp server: failed to get userinfo",
     CodeTCPPasswdErr            : "tcp server: wrong passwd",
+    CodeTCPUserExists           : "tcp server: username already exists",
+    CodeTCPInvalidUsername      : "tcp server: invalid username format",
+    CodeTCPInvalidPasswd        : "tcp server: invalid passwd format",
+    CodeTCPAccountLocked        : "tcp server: account locked, retry later",
+    CodeTCPTooManyAttempts      : "tcp server: too many failed attempts, retry later",
+    CodeTCPTwoFactorRequired    : "tcp server: 2fa code required",
+    CodeTCPInvalidPasscode      : "tcp server: invalid 2fa code",
+    CodeTCPChallengeExpired     : "tcp server: 2fa challenge expired, login again",
+    CodeTCPTwoFactorState       : "tcp server: 2fa already enabled or not set up",
+    CodeTCPAccountDeactivated   : "tcp server: account deactivated, restore it before purgetime",
+    CodeTCPInvalidProfile       : "tcp server: invalid profile",
+    CodeTCPInvalidEmailToken    : "tcp server: invalid or expired email verification link",
+    CodeTCPEmailState           : "tcp server: no email to verify or it's verified already",
+    CodeTCPInvalidResetToken    : "tcp server: invalid or expired passwd reset link",
+    CodeTCPAccountDisabled      : "tcp server: account locked by admin",
+    CodeTCPUnknownRole          : "tcp server: unknown role",
+    CodeTCPInvalidCursor        : "tcp server: invalid page cursor",
+    CodeTCPInvalidFilter        : "tcp server: invalid filter",
     CodeTCPInvalidToken         : "tcp server: invalid token format",
     CodeTCPTokenExpired         : "tcp server: token expired",
     CodeTCPUserInfoNotMatch     : "tcp server: token cache info not match",
+    CodeTCPSessionNotFound      : "tcp server: session not found",
+    CodeTCPPermissionDenied     : "tcp server: permission denied",
     CodeTCPFailedUpdateUserInfo : "tcp server: failed to update userinfo",
+    CodeTCPFailedCreateUser     : "tcp server: failed to create user",
     CodeTCPInternelErr          : "tcp server: internel error",
+    CodeTCPFailedSendMail       : "tcp server: failed to send mail, try again later",
 }
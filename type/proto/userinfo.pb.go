@@ -5,13 +5,38 @@
 Package proto is a generated protocol buffer package.
 
 It is generated from these files:
+
 	userinfo.proto
 
-It has these top-level messag
+It has these top-level messages:
+
 	LoginRequest
 	LoginResponse
 	CommRequest
 	EditRequest
+	ProfileRequest
+	RegisterRequest
+	ChangePasswdRequest
+	SessionInfo
+	SessionsResponse
+	RevokeSessionRequest
+	TwoFactorRequest
+	TwoFactorSetupResponse
+	VerifyTwoFactorRequest
+	AccountRequest
+	UserIDRequest
+	ChangeUsernameRequest
+	VerifyEmailRequest
+	PasswordResetRequest
+	ResetPasswordRequest
+	AdminRequest
+	AdminProfileRequest
+	SetRolesRequest
+	ListUsersRequest
+	UsersResponse
+	AuditLogRequest
+	AuditEvent
+	AuditLogResponse
 	EditResponse
 */
 package proto
@@ -19,6 +44,7 @@ package proto
 import proto1 "github.com/golang/protobuf/proto"
 import fmt "fmt"
 import math "math"
+import google_protobuf "google.golang.org/genproto/protobuf/field_mask"
 
 import (
 	context "golang.org/x/net/context"
@@ -74,6 +100,37 @@ type LoginResponse struct {
 	Code uint32 `protobuf:"varint,5,opt,name=code" json:"code,omitempty"`
 	// result msg
 	Msg string `protobuf:"bytes,6,opt,name=msg" json:"msg,omitempty"`
+	// refresh token, only set when token is a signed access token
+	Refreshtoken string `protobuf:"bytes,7,opt,name=refreshtoken" json:"refreshtoken,omitempty"`
+	// seconds token can be kept by client
+	Expire int64 `protobuf:"varint,8,opt,name=expire" json:"expire,omitempty"`
+	// seconds refresh token can be kept by client
+	Refreshexpire int64 `protobuf:"varint,9,opt,name=refreshexpire" json:"refreshexpire,omitempty"`
+	// seconds to wait before next login when account or ip is locked
+	Retryafter int64 `protobuf:"varint,10,opt,name=retryafter" json:"retryafter,omitempty"`
+	// 2fa challenge, set with code of 2fa required instead of token
+	Challenge string `protobuf:"bytes,11,opt,name=challenge" json:"challenge,omitempty"`
+	// unix time a deactivated account will be purged, set with code of account deactivated
+	Purgetime int64 `protobuf:"varint,12,opt,name=purgetime" json:"purgetime,omitempty"`
+	// global user id, stable across renames
+	Uid int64 `protobuf:"varint,13,opt,name=uid" json:"uid,omitempty"`
+	// profile, empty if not set
+	Email string `protobuf:"bytes,14,opt,name=email" json:"email,omitempty"`
+	// E.164 phone number
+	Phone string `protobuf:"bytes,15,opt,name=phone" json:"phone,omitempty"`
+	Bio   string `protobuf:"bytes,16,opt,name=bio" json:"bio,omitempty"`
+	// BCP 47 language tag
+	Locale string `protobuf:"bytes,17,opt,name=locale" json:"locale,omitempty"`
+	// IANA timezone
+	Timezone string `protobuf:"bytes,18,opt,name=timezone" json:"timezone,omitempty"`
+	// YYYY-MM-DD
+	Birthday string `protobuf:"bytes,19,opt,name=birthday" json:"birthday,omitempty"`
+	// false until email is verified by the mailed link, unverified accounts have an email and this unset
+	Emailverified bool `protobuf:"varint,20,opt,name=emailverified" json:"emailverified,omitempty"`
+	// rbac roles, granting permissions of admin rpcs
+	Roles []string `protobuf:"bytes,21,rep,name=roles" json:"roles,omitempty"`
+	// 0 active, 1 deactivated, 2 locked by admin
+	Status int32 `protobuf:"varint,22,opt,name=status" json:"status,omitempty"`
 }
 
 func (m *LoginResponse) Reset()                    { *m = LoginResponse{} }
@@ -123,6 +180,118 @@ func (m *LoginResponse) GetMsg() string {
 	return ""
 }
 
+func (m *LoginResponse) GetRefreshtoken() string {
+	if m != nil {
+		return m.Refreshtoken
+	}
+	return ""
+}
+
+func (m *LoginResponse) GetExpire() int64 {
+	if m != nil {
+		return m.Expire
+	}
+	return 0
+}
+
+func (m *LoginResponse) GetRefreshexpire() int64 {
+	if m != nil {
+		return m.Refreshexpire
+	}
+	return 0
+}
+
+func (m *LoginResponse) GetRetryafter() int64 {
+	if m != nil {
+		return m.Retryafter
+	}
+	return 0
+}
+
+func (m *LoginResponse) GetChallenge() string {
+	if m != nil {
+		return m.Challenge
+	}
+	return ""
+}
+
+func (m *LoginResponse) GetPurgetime() int64 {
+	if m != nil {
+		return m.Purgetime
+	}
+	return 0
+}
+
+func (m *LoginResponse) GetUid() int64 {
+	if m != nil {
+		return m.Uid
+	}
+	return 0
+}
+
+func (m *LoginResponse) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *LoginResponse) GetPhone() string {
+	if m != nil {
+		return m.Phone
+	}
+	return ""
+}
+
+func (m *LoginResponse) GetBio() string {
+	if m != nil {
+		return m.Bio
+	}
+	return ""
+}
+
+func (m *LoginResponse) GetLocale() string {
+	if m != nil {
+		return m.Locale
+	}
+	return ""
+}
+
+func (m *LoginResponse) GetTimezone() string {
+	if m != nil {
+		return m.Timezone
+	}
+	return ""
+}
+
+func (m *LoginResponse) GetBirthday() string {
+	if m != nil {
+		return m.Birthday
+	}
+	return ""
+}
+
+func (m *LoginResponse) GetEmailverified() bool {
+	if m != nil {
+		return m.Emailverified
+	}
+	return false
+}
+
+func (m *LoginResponse) GetRoles() []string {
+	if m != nil {
+		return m.Roles
+	}
+	return nil
+}
+
+func (m *LoginResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
 type CommRequest struct {
 	// token
 	Token string `protobuf:"bytes,1,opt,name=token" json:"token,omitempty"`
@@ -160,7 +329,14 @@ type EditRequest struct {
 	Headurl string `protobuf:"bytes,4,opt,name=headurl" json:"headurl,omitempty"`
 	// edit mode
 	// 1 for nickname; 2 for headurl; 3 for all
+	// deprecated, translated to mask if mask is empty
 	Mode uint32 `protobuf:"varint,5,opt,name=mode" json:"mode,omitempty"`
+	// fields to update, paths are nickname and headurl
+	Mask *google_protobuf.FieldMask `protobuf:"bytes,6,opt,name=mask" json:"mask,omitempty"`
+	// file name under image.savepath of the avatar uploaded by httpserver as headurl, named
+	// "<uid>_<md5>.<ext>" after the uploader. it's deleted along with the account, names of
+	// other uids are refused
+	Avatar string `protobuf:"bytes,7,opt,name=avatar" json:"avatar,omitempty"`
 }
 
 func (m *EditRequest) Reset()                    { *m = EditRequest{} }
@@ -203,180 +379,2042 @@ func (m *EditRequest) GetMode() uint32 {
 	return 0
 }
 
-type EditResponse struct {
-	Code uint32 `protobuf:"varint,1,opt,name=code" json:"code,omitempty"`
-	Msg  string `protobuf:"bytes,2,opt,name=msg" json:"msg,omitempty"`
-}
-
-func (m *EditResponse) Reset()                    { *m = EditResponse{} }
-func (m *EditResponse) String() string            { return proto1.CompactTextString(m) }
-func (*EditResponse) ProtoMessage()               {}
-func (*EditResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
-
-func (m *EditResponse) GetCode() uint32 {
+func (m *EditRequest) GetMask() *google_protobuf.FieldMask {
 	if m != nil {
-		return m.Code
+		return m.Mask
 	}
-	return 0
+	return nil
 }
 
-func (m *EditResponse) GetMsg() string {
+func (m *EditRequest) GetAvatar() string {
 	if m != nil {
-		return m.Msg
+		return m.Avatar
 	}
 	return ""
 }
 
-func init() {
-	proto1.RegisterType((*LoginRequest)(nil), "proto.loginRequest")
-	proto1.RegisterType((*LoginResponse)(nil), "proto.loginResponse")
-	proto1.RegisterType((*CommRequest)(nil), "proto.commRequest")
-	proto1.RegisterType((*EditRequest)(nil), "proto.editRequest")
-	proto1.RegisterType((*EditResponse)(nil), "proto.editResponse")
+type ProfileRequest struct {
+	// username
+	Username string `protobuf:"bytes,1,opt,name=username" json:"username,omitempty"`
+	// token
+	Token    string `protobuf:"bytes,2,opt,name=token" json:"token,omitempty"`
+	Nickname string `protobuf:"bytes,3,opt,name=nickname" json:"nickname,omitempty"`
+	Headurl  string `protobuf:"bytes,4,opt,name=headurl" json:"headurl,omitempty"`
+	Email    string `protobuf:"bytes,5,opt,name=email" json:"email,omitempty"`
+	Phone    string `protobuf:"bytes,6,opt,name=phone" json:"phone,omitempty"`
+	Bio      string `protobuf:"bytes,7,opt,name=bio" json:"bio,omitempty"`
+	Locale   string `protobuf:"bytes,8,opt,name=locale" json:"locale,omitempty"`
+	Timezone string `protobuf:"bytes,9,opt,name=timezone" json:"timezone,omitempty"`
+	Birthday string `protobuf:"bytes,10,opt,name=birthday" json:"birthday,omitempty"`
+	// field mask, names of the fields above to update, e.g. ["email", "phone"]
+	// a field in mask with an empty value is cleared
+	Mask []string `protobuf:"bytes,11,rep,name=mask" json:"mask,omitempty"`
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
+func (m *ProfileRequest) Reset()                    { *m = ProfileRequest{} }
+func (m *ProfileRequest) String() string            { return proto1.CompactTextString(m) }
+func (*ProfileRequest) ProtoMessage()               {}
+func (*ProfileRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+func (m *ProfileRequest) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
 
-// Client API for UserService service
+func (m *ProfileRequest) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
 
-type UserServiceClient interface {
-	Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*LoginResponse, error)
-	GetUserInfo(ctx context.Context, in *CommRequest, opts ...grpc.CallOption) (*LoginResponse, error)
-	EditUserInfo(ctx context.Context, in *EditRequest, opts ...grpc.CallOption) (*EditResponse, error)
-	Logout(ctx context.Context, in *CommRequest, opts ...grpc.CallOption) (*EditResponse, error)
+func (m *ProfileRequest) GetNickname() string {
+	if m != nil {
+		return m.Nickname
+	}
+	return ""
 }
 
-type userServiceClient struct {
-	cc *grpc.ClientConn
+func (m *ProfileRequest) GetHeadurl() string {
+	if m != nil {
+		return m.Headurl
+	}
+	return ""
 }
 
-func NewUserServiceClient(cc *grpc.ClientConn) UserServiceClient {
-	return &userServiceClient{cc}
+func (m *ProfileRequest) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
 }
 
-func (c *userServiceClient) Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
-	out := new(LoginResponse)
-	err := grpc.Invoke(ctx, "/proto.UserService/login", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
+func (m *ProfileRequest) GetPhone() string {
+	if m != nil {
+		return m.Phone
 	}
-	return out, nil
+	return ""
 }
 
-func (c *userServiceClient) GetUserInfo(ctx context.Context, in *CommRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
-	out := new(LoginResponse)
-	err := grpc.Invoke(ctx, "/proto.UserService/getUserInfo", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
+func (m *ProfileRequest) GetBio() string {
+	if m != nil {
+		return m.Bio
 	}
-	return out, nil
+	return ""
 }
 
-func (c *userServiceClient) EditUserInfo(ctx context.Context, in *EditRequest, opts ...grpc.CallOption) (*EditResponse, error) {
-	out := new(EditResponse)
-	err := grpc.Invoke(ctx, "/proto.UserService/editUserInfo", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
+func (m *ProfileRequest) GetLocale() string {
+	if m != nil {
+		return m.Locale
 	}
-	return out, nil
+	return ""
 }
 
-func (c *userServiceClient) Logout(ctx context.Context, in *CommRequest, opts ...grpc.CallOption) (*EditResponse, error) {
-	out := new(EditResponse)
-	err := grpc.Invoke(ctx, "/proto.UserService/logout", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
+func (m *ProfileRequest) GetTimezone() string {
+	if m != nil {
+		return m.Timezone
 	}
-	return out, nil
+	return ""
 }
 
-// Server API for UserService service
+func (m *ProfileRequest) GetBirthday() string {
+	if m != nil {
+		return m.Birthday
+	}
+	return ""
+}
 
-type UserServiceServer interface {
-	Login(context.Context, *LoginRequest) (*LoginResponse, error)
-	GetUserInfo(context.Context, *CommRequest) (*LoginResponse, error)
-	EditUserInfo(context.Context, *EditRequest) (*EditResponse, error)
-	Logout(context.Context, *CommRequest) (*EditResponse, error)
+func (m *ProfileRequest) GetMask() []string {
+	if m != nil {
+		return m.Mask
+	}
+	return nil
 }
 
-func RegisterUserServiceServer(s *grpc.Server, srv UserServiceServer) {
-	s.RegisterService(&_UserService_serviceDesc, srv)
+type RegisterRequest struct {
+	// user name
+	Username string `protobuf:"bytes,1,opt,name=username" json:"username,omitempty"`
+	// passwd
+	Passwd string `protobuf:"bytes,2,opt,name=passwd" json:"passwd,omitempty"`
+	// nickname, can be empty
+	Nickname string `protobuf:"bytes,3,opt,name=nickname" json:"nickname,omitempty"`
+	// email, can be empty, a verification link is mailed to it
+	Email string `protobuf:"bytes,4,opt,name=email" json:"email,omitempty"`
 }
 
-func _UserService_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(LoginRequest)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(UserServiceServer).Login(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/proto.UserService/Login",
+func (m *RegisterRequest) Reset()                    { *m = RegisterRequest{} }
+func (m *RegisterRequest) String() string            { return proto1.CompactTextString(m) }
+func (*RegisterRequest) ProtoMessage()               {}
+func (*RegisterRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *RegisterRequest) GetUsername() string {
+	if m != nil {
+		return m.Username
 	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserServiceServer).Login(ctx, req.(*LoginRequest))
+	return ""
+}
+
+func (m *RegisterRequest) GetPasswd() string {
+	if m != nil {
+		return m.Passwd
 	}
-	return interceptor(ctx, in, info, handler)
+	return ""
 }
 
-func _UserService_GetUserInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CommRequest)
-	if err := dec(in); err != nil {
-		return nil, err
+func (m *RegisterRequest) GetNickname() string {
+	if m != nil {
+		return m.Nickname
 	}
-	if interceptor == nil {
-		return srv.(UserServiceServer).GetUserInfo(ctx, in)
+	return ""
+}
+
+func (m *RegisterRequest) GetEmail() string {
+	if m != nil {
+		return m.Email
 	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/proto.UserService/GetUserInfo",
+	return ""
+}
+
+type ChangePasswdRequest struct {
+	// username
+	Username string `protobuf:"bytes,1,opt,name=username" json:"username,omitempty"`
+	// token
+	Token string `protobuf:"bytes,2,opt,name=token" json:"token,omitempty"`
+	// current passwd
+	Oldpasswd string `protobuf:"bytes,3,opt,name=oldpasswd" json:"oldpasswd,omitempty"`
+	// new passwd
+	Newpasswd string `protobuf:"bytes,4,opt,name=newpasswd" json:"newpasswd,omitempty"`
+	// keep the session of token, all the others are revoked
+	Keepsession bool `protobuf:"varint,5,opt,name=keepsession" json:"keepsession,omitempty"`
+}
+
+func (m *ChangePasswdRequest) Reset()                    { *m = ChangePasswdRequest{} }
+func (m *ChangePasswdRequest) String() string            { return proto1.CompactTextString(m) }
+func (*ChangePasswdRequest) ProtoMessage()               {}
+func (*ChangePasswdRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *ChangePasswdRequest) GetUsername() string {
+	if m != nil {
+		return m.Username
 	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserServiceServer).GetUserInfo(ctx, req.(*CommRequest))
+	return ""
+}
+
+func (m *ChangePasswdRequest) GetToken() string {
+	if m != nil {
+		return m.Token
 	}
-	return interceptor(ctx, in, info, handler)
+	return ""
 }
 
-func _UserService_EditUserInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(EditRequest)
-	if err := dec(in); err != nil {
-		return nil, err
+func (m *ChangePasswdRequest) GetOldpasswd() string {
+	if m != nil {
+		return m.Oldpasswd
+	}
+	return ""
+}
+
+func (m *ChangePasswdRequest) GetNewpasswd() string {
+	if m != nil {
+		return m.Newpasswd
+	}
+	return ""
+}
+
+func (m *ChangePasswdRequest) GetKeepsession() bool {
+	if m != nil {
+		return m.Keepsession
+	}
+	return false
+}
+
+type SessionInfo struct {
+	// session id
+	Id string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	// create time: unix timestamp
+	Createtime int64 `protobuf:"varint,2,opt,name=createtime" json:"createtime,omitempty"`
+	// last seen time: unix timestamp
+	Lastseen int64 `protobuf:"varint,3,opt,name=lastseen" json:"lastseen,omitempty"`
+	// client ip
+	Ip string `protobuf:"bytes,4,opt,name=ip" json:"ip,omitempty"`
+	// client user agent
+	Useragent string `protobuf:"bytes,5,opt,name=useragent" json:"useragent,omitempty"`
+	// whether it's the session of request token
+	Current bool `protobuf:"varint,6,opt,name=current" json:"current,omitempty"`
+}
+
+func (m *SessionInfo) Reset()                    { *m = SessionInfo{} }
+func (m *SessionInfo) String() string            { return proto1.CompactTextString(m) }
+func (*SessionInfo) ProtoMessage()               {}
+func (*SessionInfo) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *SessionInfo) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *SessionInfo) GetCreatetime() int64 {
+	if m != nil {
+		return m.Createtime
+	}
+	return 0
+}
+
+func (m *SessionInfo) GetLastseen() int64 {
+	if m != nil {
+		return m.Lastseen
+	}
+	return 0
+}
+
+func (m *SessionInfo) GetIp() string {
+	if m != nil {
+		return m.Ip
+	}
+	return ""
+}
+
+func (m *SessionInfo) GetUseragent() string {
+	if m != nil {
+		return m.Useragent
+	}
+	return ""
+}
+
+func (m *SessionInfo) GetCurrent() bool {
+	if m != nil {
+		return m.Current
+	}
+	return false
+}
+
+type SessionsResponse struct {
+	// active sessions
+	Sessions []*SessionInfo `protobuf:"bytes,1,rep,name=sessions" json:"sessions,omitempty"`
+	// result code
+	Code uint32 `protobuf:"varint,2,opt,name=code" json:"code,omitempty"`
+	// result msg
+	Msg string `protobuf:"bytes,3,opt,name=msg" json:"msg,omitempty"`
+}
+
+func (m *SessionsResponse) Reset()                    { *m = SessionsResponse{} }
+func (m *SessionsResponse) String() string            { return proto1.CompactTextString(m) }
+func (*SessionsResponse) ProtoMessage()               {}
+func (*SessionsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *SessionsResponse) GetSessions() []*SessionInfo {
+	if m != nil {
+		return m.Sessions
+	}
+	return nil
+}
+
+func (m *SessionsResponse) GetCode() uint32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *SessionsResponse) GetMsg() string {
+	if m != nil {
+		return m.Msg
+	}
+	return ""
+}
+
+type RevokeSessionRequest struct {
+	// username
+	Username string `protobuf:"bytes,1,opt,name=username" json:"username,omitempty"`
+	// token
+	Token string `protobuf:"bytes,2,opt,name=token" json:"token,omitempty"`
+	// id of session to revoke, ignored if all is set
+	Sessionid string `protobuf:"bytes,3,opt,name=sessionid" json:"sessionid,omitempty"`
+	// revoke all sessions of user (current one included)
+	All bool `protobuf:"varint,4,opt,name=all" json:"all,omitempty"`
+}
+
+func (m *RevokeSessionRequest) Reset()                    { *m = RevokeSessionRequest{} }
+func (m *RevokeSessionRequest) String() string            { return proto1.CompactTextString(m) }
+func (*RevokeSessionRequest) ProtoMessage()               {}
+func (*RevokeSessionRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *RevokeSessionRequest) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *RevokeSessionRequest) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+func (m *RevokeSessionRequest) GetSessionid() string {
+	if m != nil {
+		return m.Sessionid
+	}
+	return ""
+}
+
+func (m *RevokeSessionRequest) GetAll() bool {
+	if m != nil {
+		return m.All
+	}
+	return false
+}
+
+type TwoFactorRequest struct {
+	// username
+	Username string `protobuf:"bytes,1,opt,name=username" json:"username,omitempty"`
+	// token
+	Token string `protobuf:"bytes,2,opt,name=token" json:"token,omitempty"`
+	// current totp code (or a recovery code to disable 2fa)
+	Passcode string `protobuf:"bytes,3,opt,name=passcode" json:"passcode,omitempty"`
+}
+
+func (m *TwoFactorRequest) Reset()                    { *m = TwoFactorRequest{} }
+func (m *TwoFactorRequest) String() string            { return proto1.CompactTextString(m) }
+func (*TwoFactorRequest) ProtoMessage()               {}
+func (*TwoFactorRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *TwoFactorRequest) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *TwoFactorRequest) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+func (m *TwoFactorRequest) GetPasscode() string {
+	if m != nil {
+		return m.Passcode
+	}
+	return ""
+}
+
+type TwoFactorSetupResponse struct {
+	// base32 totp secret, only returned by setup
+	Secret string `protobuf:"bytes,1,opt,name=secret" json:"secret,omitempty"`
+	// otpauth uri of secret, only returned by setup
+	Uri string `protobuf:"bytes,2,opt,name=uri" json:"uri,omitempty"`
+	// one-time recovery codes, only returned by confirm
+	Recoverycodes []string `protobuf:"bytes,3,rep,name=recoverycodes" json:"recoverycodes,omitempty"`
+	// result code
+	Code uint32 `protobuf:"varint,4,opt,name=code" json:"code,omitempty"`
+	// result msg
+	Msg string `protobuf:"bytes,5,opt,name=msg" json:"msg,omitempty"`
+}
+
+func (m *TwoFactorSetupResponse) Reset()                    { *m = TwoFactorSetupResponse{} }
+func (m *TwoFactorSetupResponse) String() string            { return proto1.CompactTextString(m) }
+func (*TwoFactorSetupResponse) ProtoMessage()               {}
+func (*TwoFactorSetupResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *TwoFactorSetupResponse) GetSecret() string {
+	if m != nil {
+		return m.Secret
+	}
+	return ""
+}
+
+func (m *TwoFactorSetupResponse) GetUri() string {
+	if m != nil {
+		return m.Uri
+	}
+	return ""
+}
+
+func (m *TwoFactorSetupResponse) GetRecoverycodes() []string {
+	if m != nil {
+		return m.Recoverycodes
+	}
+	return nil
+}
+
+func (m *TwoFactorSetupResponse) GetCode() uint32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *TwoFactorSetupResponse) GetMsg() string {
+	if m != nil {
+		return m.Msg
+	}
+	return ""
+}
+
+type VerifyTwoFactorRequest struct {
+	// challenge returned by login
+	Challenge string `protobuf:"bytes,1,opt,name=challenge" json:"challenge,omitempty"`
+	// totp code or a recovery code
+	Passcode string `protobuf:"bytes,2,opt,name=passcode" json:"passcode,omitempty"`
+}
+
+func (m *VerifyTwoFactorRequest) Reset()                    { *m = VerifyTwoFactorRequest{} }
+func (m *VerifyTwoFactorRequest) String() string            { return proto1.CompactTextString(m) }
+func (*VerifyTwoFactorRequest) ProtoMessage()               {}
+func (*VerifyTwoFactorRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *VerifyTwoFactorRequest) GetChallenge() string {
+	if m != nil {
+		return m.Challenge
+	}
+	return ""
+}
+
+func (m *VerifyTwoFactorRequest) GetPasscode() string {
+	if m != nil {
+		return m.Passcode
+	}
+	return ""
+}
+
+type AccountRequest struct {
+	// username
+	Username string `protobuf:"bytes,1,opt,name=username" json:"username,omitempty"`
+	// token
+	Token string `protobuf:"bytes,2,opt,name=token" json:"token,omitempty"`
+	// current passwd, required to deactivate or delete account
+	Passwd string `protobuf:"bytes,3,opt,name=passwd" json:"passwd,omitempty"`
+}
+
+func (m *AccountRequest) Reset()                    { *m = AccountRequest{} }
+func (m *AccountRequest) String() string            { return proto1.CompactTextString(m) }
+func (*AccountRequest) ProtoMessage()               {}
+func (*AccountRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *AccountRequest) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *AccountRequest) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+func (m *AccountRequest) GetPasswd() string {
+	if m != nil {
+		return m.Passwd
+	}
+	return ""
+}
+
+type UserIDRequest struct {
+	// global user id
+	Uid int64 `protobuf:"varint,1,opt,name=uid" json:"uid,omitempty"`
+}
+
+func (m *UserIDRequest) Reset()                    { *m = UserIDRequest{} }
+func (m *UserIDRequest) String() string            { return proto1.CompactTextString(m) }
+func (*UserIDRequest) ProtoMessage()               {}
+func (*UserIDRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *UserIDRequest) GetUid() int64 {
+	if m != nil {
+		return m.Uid
+	}
+	return 0
+}
+
+type ChangeUsernameRequest struct {
+	// username
+	Username string `protobuf:"bytes,1,opt,name=username" json:"username,omitempty"`
+	// token
+	Token string `protobuf:"bytes,2,opt,name=token" json:"token,omitempty"`
+	// new username, the old one is reserved for a while
+	Newname string `protobuf:"bytes,3,opt,name=newname" json:"newname,omitempty"`
+}
+
+func (m *ChangeUsernameRequest) Reset()                    { *m = ChangeUsernameRequest{} }
+func (m *ChangeUsernameRequest) String() string            { return proto1.CompactTextString(m) }
+func (*ChangeUsernameRequest) ProtoMessage()               {}
+func (*ChangeUsernameRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+func (m *ChangeUsernameRequest) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *ChangeUsernameRequest) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+func (m *ChangeUsernameRequest) GetNewname() string {
+	if m != nil {
+		return m.Newname
+	}
+	return ""
+}
+
+type VerifyEmailRequest struct {
+	// token of the mailed verification link
+	Token string `protobuf:"bytes,1,opt,name=token" json:"token,omitempty"`
+}
+
+func (m *VerifyEmailRequest) Reset()                    { *m = VerifyEmailRequest{} }
+func (m *VerifyEmailRequest) String() string            { return proto1.CompactTextString(m) }
+func (*VerifyEmailRequest) ProtoMessage()               {}
+func (*VerifyEmailRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *VerifyEmailRequest) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+type PasswordResetRequest struct {
+	// user to mail a reset link to, at its verified email
+	Username string `protobuf:"bytes,1,opt,name=username" json:"username,omitempty"`
+}
+
+func (m *PasswordResetRequest) Reset()                    { *m = PasswordResetRequest{} }
+func (m *PasswordResetRequest) String() string            { return proto1.CompactTextString(m) }
+func (*PasswordResetRequest) ProtoMessage()               {}
+func (*PasswordResetRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+func (m *PasswordResetRequest) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+type ResetPasswordRequest struct {
+	// token of the mailed reset link
+	Token string `protobuf:"bytes,1,opt,name=token" json:"token,omitempty"`
+	// new passwd
+	Newpasswd string `protobuf:"bytes,2,opt,name=newpasswd" json:"newpasswd,omitempty"`
+}
+
+func (m *ResetPasswordRequest) Reset()                    { *m = ResetPasswordRequest{} }
+func (m *ResetPasswordRequest) String() string            { return proto1.CompactTextString(m) }
+func (*ResetPasswordRequest) ProtoMessage()               {}
+func (*ResetPasswordRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+func (m *ResetPasswordRequest) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+func (m *ResetPasswordRequest) GetNewpasswd() string {
+	if m != nil {
+		return m.Newpasswd
+	}
+	return ""
+}
+
+type AdminRequest struct {
+	// username of admin
+	Username string `protobuf:"bytes,1,opt,name=username" json:"username,omitempty"`
+	// token of admin
+	Token string `protobuf:"bytes,2,opt,name=token" json:"token,omitempty"`
+	// user to act on
+	Target string `protobuf:"bytes,3,opt,name=target" json:"target,omitempty"`
+}
+
+func (m *AdminRequest) Reset()                    { *m = AdminRequest{} }
+func (m *AdminRequest) String() string            { return proto1.CompactTextString(m) }
+func (*AdminRequest) ProtoMessage()               {}
+func (*AdminRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+
+func (m *AdminRequest) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *AdminRequest) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+func (m *AdminRequest) GetTarget() string {
+	if m != nil {
+		return m.Target
+	}
+	return ""
+}
+
+type AdminProfileRequest struct {
+	// username of admin
+	Username string `protobuf:"bytes,1,opt,name=username" json:"username,omitempty"`
+	// token of admin
+	Token string `protobuf:"bytes,2,opt,name=token" json:"token,omitempty"`
+	// profile and mask to update, username is the user to act on and token is ignored
+	Profile *ProfileRequest `protobuf:"bytes,3,opt,name=profile" json:"profile,omitempty"`
+}
+
+func (m *AdminProfileRequest) Reset()                    { *m = AdminProfileRequest{} }
+func (m *AdminProfileRequest) String() string            { return proto1.CompactTextString(m) }
+func (*AdminProfileRequest) ProtoMessage()               {}
+func (*AdminProfileRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+
+func (m *AdminProfileRequest) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *AdminProfileRequest) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+func (m *AdminProfileRequest) GetProfile() *ProfileRequest {
+	if m != nil {
+		return m.Profile
+	}
+	return nil
+}
+
+type SetRolesRequest struct {
+	// username of admin
+	Username string `protobuf:"bytes,1,opt,name=username" json:"username,omitempty"`
+	// token of admin
+	Token string `protobuf:"bytes,2,opt,name=token" json:"token,omitempty"`
+	// user to act on
+	Target string `protobuf:"bytes,3,opt,name=target" json:"target,omitempty"`
+	// roles replacing the current ones, empty to revoke all
+	Roles []string `protobuf:"bytes,4,rep,name=roles" json:"roles,omitempty"`
+}
+
+func (m *SetRolesRequest) Reset()                    { *m = SetRolesRequest{} }
+func (m *SetRolesRequest) String() string            { return proto1.CompactTextString(m) }
+func (*SetRolesRequest) ProtoMessage()               {}
+func (*SetRolesRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
+
+func (m *SetRolesRequest) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *SetRolesRequest) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+func (m *SetRolesRequest) GetTarget() string {
+	if m != nil {
+		return m.Target
+	}
+	return ""
+}
+
+func (m *SetRolesRequest) GetRoles() []string {
+	if m != nil {
+		return m.Roles
+	}
+	return nil
+}
+
+type ListUsersRequest struct {
+	// username of admin
+	Username string `protobuf:"bytes,1,opt,name=username" json:"username,omitempty"`
+	// token of admin
+	Token string `protobuf:"bytes,2,opt,name=token" json:"token,omitempty"`
+	// nextcursor of the previous page, empty for the first page
+	Cursor string `protobuf:"bytes,3,opt,name=cursor" json:"cursor,omitempty"`
+	// users per page, 20 if 0, at most 100
+	Limit uint32 `protobuf:"varint,4,opt,name=limit" json:"limit,omitempty"`
+	// filters, users are listed if they pass all that are set
+	// username prefix, ignoring case
+	Prefix string `protobuf:"bytes,5,opt,name=prefix" json:"prefix,omitempty"`
+	// nickname substring, ignoring case
+	Nickname string `protobuf:"bytes,6,opt,name=nickname" json:"nickname,omitempty"`
+	// range of last update time (unix time), upto excluded
+	Upfrom int64 `protobuf:"varint,7,opt,name=upfrom" json:"upfrom,omitempty"`
+	Upto   int64 `protobuf:"varint,8,opt,name=upto" json:"upto,omitempty"`
+}
+
+func (m *ListUsersRequest) Reset()                    { *m = ListUsersRequest{} }
+func (m *ListUsersRequest) String() string            { return proto1.CompactTextString(m) }
+func (*ListUsersRequest) ProtoMessage()               {}
+func (*ListUsersRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
+
+func (m *ListUsersRequest) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *ListUsersRequest) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+func (m *ListUsersRequest) GetCursor() string {
+	if m != nil {
+		return m.Cursor
+	}
+	return ""
+}
+
+func (m *ListUsersRequest) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *ListUsersRequest) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+func (m *ListUsersRequest) GetNickname() string {
+	if m != nil {
+		return m.Nickname
+	}
+	return ""
+}
+
+func (m *ListUsersRequest) GetUpfrom() int64 {
+	if m != nil {
+		return m.Upfrom
+	}
+	return 0
+}
+
+func (m *ListUsersRequest) GetUpto() int64 {
+	if m != nil {
+		return m.Upto
+	}
+	return 0
+}
+
+type UsersResponse struct {
+	Users []*LoginResponse `protobuf:"bytes,1,rep,name=users" json:"users,omitempty"`
+	// cursor of the next page, empty after the last page
+	Nextcursor string `protobuf:"bytes,2,opt,name=nextcursor" json:"nextcursor,omitempty"`
+	// result code
+	Code uint32 `protobuf:"varint,3,opt,name=code" json:"code,omitempty"`
+	// result msg
+	Msg string `protobuf:"bytes,4,opt,name=msg" json:"msg,omitempty"`
+}
+
+func (m *UsersResponse) Reset()                    { *m = UsersResponse{} }
+func (m *UsersResponse) String() string            { return proto1.CompactTextString(m) }
+func (*UsersResponse) ProtoMessage()               {}
+func (*UsersResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
+
+func (m *UsersResponse) GetUsers() []*LoginResponse {
+	if m != nil {
+		return m.Users
+	}
+	return nil
+}
+
+func (m *UsersResponse) GetNextcursor() string {
+	if m != nil {
+		return m.Nextcursor
+	}
+	return ""
+}
+
+func (m *UsersResponse) GetCode() uint32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *UsersResponse) GetMsg() string {
+	if m != nil {
+		return m.Msg
+	}
+	return ""
+}
+
+type AuditLogRequest struct {
+	// username of admin
+	Username string `protobuf:"bytes,1,opt,name=username" json:"username,omitempty"`
+	// token of admin
+	Token string `protobuf:"bytes,2,opt,name=token" json:"token,omitempty"`
+	// user whose events are queried, empty for all users
+	Target string `protobuf:"bytes,3,opt,name=target" json:"target,omitempty"`
+	// action of events, empty for all actions
+	Action string `protobuf:"bytes,4,opt,name=action" json:"action,omitempty"`
+	// range of event time (unix time), to excluded and unlimited if 0
+	From int64 `protobuf:"varint,5,opt,name=from" json:"from,omitempty"`
+	To   int64 `protobuf:"varint,6,opt,name=to" json:"to,omitempty"`
+	// nextcursor of the previous page, empty for the first page
+	Cursor string `protobuf:"bytes,7,opt,name=cursor" json:"cursor,omitempty"`
+	// events per page, 50 if 0, at most 500
+	Limit uint32 `protobuf:"varint,8,opt,name=limit" json:"limit,omitempty"`
+	// uid of the user whose events are queried, 0 for all users. unlike target, it
+	// follows the user across renames and isn't shared with later owners of a username
+	Targetuid int64 `protobuf:"varint,9,opt,name=targetuid" json:"targetuid,omitempty"`
+}
+
+func (m *AuditLogRequest) Reset()                    { *m = AuditLogRequest{} }
+func (m *AuditLogRequest) String() string            { return proto1.CompactTextString(m) }
+func (*AuditLogRequest) ProtoMessage()               {}
+func (*AuditLogRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{24} }
+
+func (m *AuditLogRequest) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *AuditLogRequest) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+func (m *AuditLogRequest) GetTarget() string {
+	if m != nil {
+		return m.Target
+	}
+	return ""
+}
+
+func (m *AuditLogRequest) GetAction() string {
+	if m != nil {
+		return m.Action
+	}
+	return ""
+}
+
+func (m *AuditLogRequest) GetFrom() int64 {
+	if m != nil {
+		return m.From
+	}
+	return 0
+}
+
+func (m *AuditLogRequest) GetTo() int64 {
+	if m != nil {
+		return m.To
+	}
+	return 0
+}
+
+func (m *AuditLogRequest) GetCursor() string {
+	if m != nil {
+		return m.Cursor
+	}
+	return ""
+}
+
+func (m *AuditLogRequest) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *AuditLogRequest) GetTargetuid() int64 {
+	if m != nil {
+		return m.Targetuid
+	}
+	return 0
+}
+
+type AuditEvent struct {
+	Id int64 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	// unix time
+	Time int64 `protobuf:"varint,2,opt,name=time" json:"time,omitempty"`
+	// user the event is about
+	Username string `protobuf:"bytes,3,opt,name=username" json:"username,omitempty"`
+	// user who caused it, an admin or the user itself
+	Actor  string `protobuf:"bytes,4,opt,name=actor" json:"actor,omitempty"`
+	Action string `protobuf:"bytes,5,opt,name=action" json:"action,omitempty"`
+	Ip     string `protobuf:"bytes,6,opt,name=ip" json:"ip,omitempty"`
+	Uuid   string `protobuf:"bytes,7,opt,name=uuid" json:"uuid,omitempty"`
+	// success, failure, or challenged for logins waiting for a 2fa passcode
+	Outcome string `protobuf:"bytes,8,opt,name=outcome" json:"outcome,omitempty"`
+	// result code of the request
+	Resultcode uint32 `protobuf:"varint,9,opt,name=resultcode" json:"resultcode,omitempty"`
+	Detail     string `protobuf:"bytes,10,opt,name=detail" json:"detail,omitempty"`
+	// uid of username, 0 if there's no such user
+	Uid int64 `protobuf:"varint,11,opt,name=uid" json:"uid,omitempty"`
+}
+
+func (m *AuditEvent) Reset()                    { *m = AuditEvent{} }
+func (m *AuditEvent) String() string            { return proto1.CompactTextString(m) }
+func (*AuditEvent) ProtoMessage()               {}
+func (*AuditEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{25} }
+
+func (m *AuditEvent) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *AuditEvent) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *AuditEvent) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetActor() string {
+	if m != nil {
+		return m.Actor
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetAction() string {
+	if m != nil {
+		return m.Action
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetIp() string {
+	if m != nil {
+		return m.Ip
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetUuid() string {
+	if m != nil {
+		return m.Uuid
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetOutcome() string {
+	if m != nil {
+		return m.Outcome
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetResultcode() uint32 {
+	if m != nil {
+		return m.Resultcode
+	}
+	return 0
+}
+
+func (m *AuditEvent) GetDetail() string {
+	if m != nil {
+		return m.Detail
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetUid() int64 {
+	if m != nil {
+		return m.Uid
+	}
+	return 0
+}
+
+type AuditLogResponse struct {
+	// newest first
+	Events []*AuditEvent `protobuf:"bytes,1,rep,name=events" json:"events,omitempty"`
+	// cursor of the next page, empty after the last page
+	Nextcursor string `protobuf:"bytes,2,opt,name=nextcursor" json:"nextcursor,omitempty"`
+	// result code
+	Code uint32 `protobuf:"varint,3,opt,name=code" json:"code,omitempty"`
+	// result msg
+	Msg string `protobuf:"bytes,4,opt,name=msg" json:"msg,omitempty"`
+}
+
+func (m *AuditLogResponse) Reset()                    { *m = AuditLogResponse{} }
+func (m *AuditLogResponse) String() string            { return proto1.CompactTextString(m) }
+func (*AuditLogResponse) ProtoMessage()               {}
+func (*AuditLogResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{26} }
+
+func (m *AuditLogResponse) GetEvents() []*AuditEvent {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+func (m *AuditLogResponse) GetNextcursor() string {
+	if m != nil {
+		return m.Nextcursor
+	}
+	return ""
+}
+
+func (m *AuditLogResponse) GetCode() uint32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *AuditLogResponse) GetMsg() string {
+	if m != nil {
+		return m.Msg
+	}
+	return ""
+}
+
+type EditResponse struct {
+	Code uint32 `protobuf:"varint,1,opt,name=code" json:"code,omitempty"`
+	Msg  string `protobuf:"bytes,2,opt,name=msg" json:"msg,omitempty"`
+}
+
+func (m *EditResponse) Reset()                    { *m = EditResponse{} }
+func (m *EditResponse) String() string            { return proto1.CompactTextString(m) }
+func (*EditResponse) ProtoMessage()               {}
+func (*EditResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{27} }
+
+func (m *EditResponse) GetCode() uint32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *EditResponse) GetMsg() string {
+	if m != nil {
+		return m.Msg
+	}
+	return ""
+}
+
+func init() {
+	proto1.RegisterType((*LoginRequest)(nil), "proto.loginRequest")
+	proto1.RegisterType((*LoginResponse)(nil), "proto.loginResponse")
+	proto1.RegisterType((*CommRequest)(nil), "proto.commRequest")
+	proto1.RegisterType((*EditRequest)(nil), "proto.editRequest")
+	proto1.RegisterType((*ProfileRequest)(nil), "proto.profileRequest")
+	proto1.RegisterType((*RegisterRequest)(nil), "proto.registerRequest")
+	proto1.RegisterType((*ChangePasswdRequest)(nil), "proto.changePasswdRequest")
+	proto1.RegisterType((*SessionInfo)(nil), "proto.sessionInfo")
+	proto1.RegisterType((*SessionsResponse)(nil), "proto.sessionsResponse")
+	proto1.RegisterType((*RevokeSessionRequest)(nil), "proto.revokeSessionRequest")
+	proto1.RegisterType((*TwoFactorRequest)(nil), "proto.twoFactorRequest")
+	proto1.RegisterType((*TwoFactorSetupResponse)(nil), "proto.twoFactorSetupResponse")
+	proto1.RegisterType((*VerifyTwoFactorRequest)(nil), "proto.verifyTwoFactorRequest")
+	proto1.RegisterType((*AccountRequest)(nil), "proto.accountRequest")
+	proto1.RegisterType((*UserIDRequest)(nil), "proto.userIDRequest")
+	proto1.RegisterType((*ChangeUsernameRequest)(nil), "proto.changeUsernameRequest")
+	proto1.RegisterType((*VerifyEmailRequest)(nil), "proto.verifyEmailRequest")
+	proto1.RegisterType((*PasswordResetRequest)(nil), "proto.passwordResetRequest")
+	proto1.RegisterType((*ResetPasswordRequest)(nil), "proto.resetPasswordRequest")
+	proto1.RegisterType((*AdminRequest)(nil), "proto.adminRequest")
+	proto1.RegisterType((*AdminProfileRequest)(nil), "proto.adminProfileRequest")
+	proto1.RegisterType((*SetRolesRequest)(nil), "proto.setRolesRequest")
+	proto1.RegisterType((*ListUsersRequest)(nil), "proto.listUsersRequest")
+	proto1.RegisterType((*UsersResponse)(nil), "proto.usersResponse")
+	proto1.RegisterType((*AuditLogRequest)(nil), "proto.auditLogRequest")
+	proto1.RegisterType((*AuditEvent)(nil), "proto.auditEvent")
+	proto1.RegisterType((*AuditLogResponse)(nil), "proto.auditLogResponse")
+	proto1.RegisterType((*EditResponse)(nil), "proto.editResponse")
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// Client API for UserService service
+
+type UserServiceClient interface {
+	Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*LoginResponse, error)
+	GetUserInfo(ctx context.Context, in *CommRequest, opts ...grpc.CallOption) (*LoginResponse, error)
+	EditUserInfo(ctx context.Context, in *EditRequest, opts ...grpc.CallOption) (*EditResponse, error)
+	UpdateProfile(ctx context.Context, in *ProfileRequest, opts ...grpc.CallOption) (*LoginResponse, error)
+	SendVerifyEmail(ctx context.Context, in *CommRequest, opts ...grpc.CallOption) (*EditResponse, error)
+	VerifyEmail(ctx context.Context, in *VerifyEmailRequest, opts ...grpc.CallOption) (*EditResponse, error)
+	RequestPasswordReset(ctx context.Context, in *PasswordResetRequest, opts ...grpc.CallOption) (*EditResponse, error)
+	ResetPassword(ctx context.Context, in *ResetPasswordRequest, opts ...grpc.CallOption) (*EditResponse, error)
+	Logout(ctx context.Context, in *CommRequest, opts ...grpc.CallOption) (*EditResponse, error)
+	Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*LoginResponse, error)
+	ChangePasswd(ctx context.Context, in *ChangePasswdRequest, opts ...grpc.CallOption) (*EditResponse, error)
+	RefreshToken(ctx context.Context, in *CommRequest, opts ...grpc.CallOption) (*LoginResponse, error)
+	ListSessions(ctx context.Context, in *CommRequest, opts ...grpc.CallOption) (*SessionsResponse, error)
+	RevokeSession(ctx context.Context, in *RevokeSessionRequest, opts ...grpc.CallOption) (*EditResponse, error)
+	SetupTwoFactor(ctx context.Context, in *CommRequest, opts ...grpc.CallOption) (*TwoFactorSetupResponse, error)
+	ConfirmTwoFactor(ctx context.Context, in *TwoFactorRequest, opts ...grpc.CallOption) (*TwoFactorSetupResponse, error)
+	DisableTwoFactor(ctx context.Context, in *TwoFactorRequest, opts ...grpc.CallOption) (*EditResponse, error)
+	VerifyTwoFactor(ctx context.Context, in *VerifyTwoFactorRequest, opts ...grpc.CallOption) (*LoginResponse, error)
+	DeactivateAccount(ctx context.Context, in *AccountRequest, opts ...grpc.CallOption) (*EditResponse, error)
+	RestoreAccount(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*LoginResponse, error)
+	DeleteAccount(ctx context.Context, in *AccountRequest, opts ...grpc.CallOption) (*EditResponse, error)
+	GetUserByID(ctx context.Context, in *UserIDRequest, opts ...grpc.CallOption) (*LoginResponse, error)
+	ChangeUsername(ctx context.Context, in *ChangeUsernameRequest, opts ...grpc.CallOption) (*LoginResponse, error)
+	ListUsers(ctx context.Context, in *ListUsersRequest, opts ...grpc.CallOption) (*UsersResponse, error)
+	LockUser(ctx context.Context, in *AdminRequest, opts ...grpc.CallOption) (*EditResponse, error)
+	UnlockUser(ctx context.Context, in *AdminRequest, opts ...grpc.CallOption) (*EditResponse, error)
+	ForceLogout(ctx context.Context, in *AdminRequest, opts ...grpc.CallOption) (*EditResponse, error)
+	AdminUpdateProfile(ctx context.Context, in *AdminProfileRequest, opts ...grpc.CallOption) (*LoginResponse, error)
+	SetUserRoles(ctx context.Context, in *SetRolesRequest, opts ...grpc.CallOption) (*LoginResponse, error)
+	QueryAuditLog(ctx context.Context, in *AuditLogRequest, opts ...grpc.CallOption) (*AuditLogResponse, error)
+}
+
+type userServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewUserServiceClient(cc *grpc.ClientConn) UserServiceClient {
+	return &userServiceClient{cc}
+}
+
+func (c *userServiceClient) Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/login", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) GetUserInfo(ctx context.Context, in *CommRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/getUserInfo", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) EditUserInfo(ctx context.Context, in *EditRequest, opts ...grpc.CallOption) (*EditResponse, error) {
+	out := new(EditResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/editUserInfo", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) UpdateProfile(ctx context.Context, in *ProfileRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/updateProfile", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) SendVerifyEmail(ctx context.Context, in *CommRequest, opts ...grpc.CallOption) (*EditResponse, error) {
+	out := new(EditResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/sendVerifyEmail", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) VerifyEmail(ctx context.Context, in *VerifyEmailRequest, opts ...grpc.CallOption) (*EditResponse, error) {
+	out := new(EditResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/verifyEmail", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) RequestPasswordReset(ctx context.Context, in *PasswordResetRequest, opts ...grpc.CallOption) (*EditResponse, error) {
+	out := new(EditResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/requestPasswordReset", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) ResetPassword(ctx context.Context, in *ResetPasswordRequest, opts ...grpc.CallOption) (*EditResponse, error) {
+	out := new(EditResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/resetPassword", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) Logout(ctx context.Context, in *CommRequest, opts ...grpc.CallOption) (*EditResponse, error) {
+	out := new(EditResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/logout", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/register", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) ChangePasswd(ctx context.Context, in *ChangePasswdRequest, opts ...grpc.CallOption) (*EditResponse, error) {
+	out := new(EditResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/changePasswd", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) RefreshToken(ctx context.Context, in *CommRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/refreshToken", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) ListSessions(ctx context.Context, in *CommRequest, opts ...grpc.CallOption) (*SessionsResponse, error) {
+	out := new(SessionsResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/listSessions", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) RevokeSession(ctx context.Context, in *RevokeSessionRequest, opts ...grpc.CallOption) (*EditResponse, error) {
+	out := new(EditResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/revokeSession", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) SetupTwoFactor(ctx context.Context, in *CommRequest, opts ...grpc.CallOption) (*TwoFactorSetupResponse, error) {
+	out := new(TwoFactorSetupResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/setupTwoFactor", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) ConfirmTwoFactor(ctx context.Context, in *TwoFactorRequest, opts ...grpc.CallOption) (*TwoFactorSetupResponse, error) {
+	out := new(TwoFactorSetupResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/confirmTwoFactor", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) DisableTwoFactor(ctx context.Context, in *TwoFactorRequest, opts ...grpc.CallOption) (*EditResponse, error) {
+	out := new(EditResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/disableTwoFactor", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) VerifyTwoFactor(ctx context.Context, in *VerifyTwoFactorRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/verifyTwoFactor", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) DeactivateAccount(ctx context.Context, in *AccountRequest, opts ...grpc.CallOption) (*EditResponse, error) {
+	out := new(EditResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/deactivateAccount", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) RestoreAccount(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/restoreAccount", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) DeleteAccount(ctx context.Context, in *AccountRequest, opts ...grpc.CallOption) (*EditResponse, error) {
+	out := new(EditResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/deleteAccount", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) GetUserByID(ctx context.Context, in *UserIDRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/getUserByID", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) ChangeUsername(ctx context.Context, in *ChangeUsernameRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/changeUsername", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) ListUsers(ctx context.Context, in *ListUsersRequest, opts ...grpc.CallOption) (*UsersResponse, error) {
+	out := new(UsersResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/listUsers", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) LockUser(ctx context.Context, in *AdminRequest, opts ...grpc.CallOption) (*EditResponse, error) {
+	out := new(EditResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/lockUser", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) UnlockUser(ctx context.Context, in *AdminRequest, opts ...grpc.CallOption) (*EditResponse, error) {
+	out := new(EditResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/unlockUser", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) ForceLogout(ctx context.Context, in *AdminRequest, opts ...grpc.CallOption) (*EditResponse, error) {
+	out := new(EditResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/forceLogout", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) AdminUpdateProfile(ctx context.Context, in *AdminProfileRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/adminUpdateProfile", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) SetUserRoles(ctx context.Context, in *SetRolesRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/setUserRoles", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) QueryAuditLog(ctx context.Context, in *AuditLogRequest, opts ...grpc.CallOption) (*AuditLogResponse, error) {
+	out := new(AuditLogResponse)
+	err := grpc.Invoke(ctx, "/proto.UserService/queryAuditLog", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for UserService service
+
+type UserServiceServer interface {
+	Login(context.Context, *LoginRequest) (*LoginResponse, error)
+	GetUserInfo(context.Context, *CommRequest) (*LoginResponse, error)
+	EditUserInfo(context.Context, *EditRequest) (*EditResponse, error)
+	UpdateProfile(context.Context, *ProfileRequest) (*LoginResponse, error)
+	SendVerifyEmail(context.Context, *CommRequest) (*EditResponse, error)
+	VerifyEmail(context.Context, *VerifyEmailRequest) (*EditResponse, error)
+	RequestPasswordReset(context.Context, *PasswordResetRequest) (*EditResponse, error)
+	ResetPassword(context.Context, *ResetPasswordRequest) (*EditResponse, error)
+	Logout(context.Context, *CommRequest) (*EditResponse, error)
+	Register(context.Context, *RegisterRequest) (*LoginResponse, error)
+	ChangePasswd(context.Context, *ChangePasswdRequest) (*EditResponse, error)
+	RefreshToken(context.Context, *CommRequest) (*LoginResponse, error)
+	ListSessions(context.Context, *CommRequest) (*SessionsResponse, error)
+	RevokeSession(context.Context, *RevokeSessionRequest) (*EditResponse, error)
+	SetupTwoFactor(context.Context, *CommRequest) (*TwoFactorSetupResponse, error)
+	ConfirmTwoFactor(context.Context, *TwoFactorRequest) (*TwoFactorSetupResponse, error)
+	DisableTwoFactor(context.Context, *TwoFactorRequest) (*EditResponse, error)
+	VerifyTwoFactor(context.Context, *VerifyTwoFactorRequest) (*LoginResponse, error)
+	DeactivateAccount(context.Context, *AccountRequest) (*EditResponse, error)
+	RestoreAccount(context.Context, *LoginRequest) (*LoginResponse, error)
+	DeleteAccount(context.Context, *AccountRequest) (*EditResponse, error)
+	GetUserByID(context.Context, *UserIDRequest) (*LoginResponse, error)
+	ChangeUsername(context.Context, *ChangeUsernameRequest) (*LoginResponse, error)
+	ListUsers(context.Context, *ListUsersRequest) (*UsersResponse, error)
+	LockUser(context.Context, *AdminRequest) (*EditResponse, error)
+	UnlockUser(context.Context, *AdminRequest) (*EditResponse, error)
+	ForceLogout(context.Context, *AdminRequest) (*EditResponse, error)
+	AdminUpdateProfile(context.Context, *AdminProfileRequest) (*LoginResponse, error)
+	SetUserRoles(context.Context, *SetRolesRequest) (*LoginResponse, error)
+	QueryAuditLog(context.Context, *AuditLogRequest) (*AuditLogResponse, error)
+}
+
+func RegisterUserServiceServer(s *grpc.Server, srv UserServiceServer) {
+	s.RegisterService(&_UserService_serviceDesc, srv)
+}
+
+func _UserService_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).Login(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/Login",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).Login(ctx, req.(*LoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_GetUserInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).GetUserInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/GetUserInfo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).GetUserInfo(ctx, req.(*CommRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_EditUserInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EditRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).EditUserInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/EditUserInfo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).EditUserInfo(ctx, req.(*EditRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_UpdateProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProfileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).UpdateProfile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/UpdateProfile",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).UpdateProfile(ctx, req.(*ProfileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_SendVerifyEmail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).SendVerifyEmail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/SendVerifyEmail",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).SendVerifyEmail(ctx, req.(*CommRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_VerifyEmail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyEmailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).VerifyEmail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/VerifyEmail",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).VerifyEmail(ctx, req.(*VerifyEmailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_RequestPasswordReset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PasswordResetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).RequestPasswordReset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/RequestPasswordReset",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).RequestPasswordReset(ctx, req.(*PasswordResetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_ResetPassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetPasswordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).ResetPassword(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/ResetPassword",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).ResetPassword(ctx, req.(*ResetPasswordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_Logout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).Logout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/Logout",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).Logout(ctx, req.(*CommRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).Register(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/Register",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).Register(ctx, req.(*RegisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_ChangePasswd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangePasswdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).ChangePasswd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/ChangePasswd",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).ChangePasswd(ctx, req.(*ChangePasswdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_RefreshToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).RefreshToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/RefreshToken",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).RefreshToken(ctx, req.(*CommRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_ListSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).ListSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/ListSessions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).ListSessions(ctx, req.(*CommRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_RevokeSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UserServiceServer).EditUserInfo(ctx, in)
+		return srv.(UserServiceServer).RevokeSession(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/proto.UserService/EditUserInfo",
+		FullMethod: "/proto.UserService/RevokeSession",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserServiceServer).EditUserInfo(ctx, req.(*EditRequest))
+		return srv.(UserServiceServer).RevokeSession(ctx, req.(*RevokeSessionRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _UserService_Logout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+func _UserService_SetupTwoFactor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(CommRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UserServiceServer).Logout(ctx, in)
+		return srv.(UserServiceServer).SetupTwoFactor(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/proto.UserService/Logout",
+		FullMethod: "/proto.UserService/SetupTwoFactor",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserServiceServer).Logout(ctx, req.(*CommRequest))
+		return srv.(UserServiceServer).SetupTwoFactor(ctx, req.(*CommRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_ConfirmTwoFactor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TwoFactorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).ConfirmTwoFactor(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/ConfirmTwoFactor",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).ConfirmTwoFactor(ctx, req.(*TwoFactorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_DisableTwoFactor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TwoFactorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).DisableTwoFactor(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/DisableTwoFactor",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).DisableTwoFactor(ctx, req.(*TwoFactorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_VerifyTwoFactor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyTwoFactorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).VerifyTwoFactor(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/VerifyTwoFactor",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).VerifyTwoFactor(ctx, req.(*VerifyTwoFactorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_DeactivateAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).DeactivateAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/DeactivateAccount",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).DeactivateAccount(ctx, req.(*AccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_RestoreAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).RestoreAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/RestoreAccount",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).RestoreAccount(ctx, req.(*LoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_DeleteAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).DeleteAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/DeleteAccount",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).DeleteAccount(ctx, req.(*AccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_GetUserByID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserIDRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).GetUserByID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/GetUserByID",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).GetUserByID(ctx, req.(*UserIDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_ChangeUsername_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeUsernameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).ChangeUsername(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/ChangeUsername",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).ChangeUsername(ctx, req.(*ChangeUsernameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_ListUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUsersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).ListUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/ListUsers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).ListUsers(ctx, req.(*ListUsersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_LockUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).LockUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/LockUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).LockUser(ctx, req.(*AdminRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_UnlockUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).UnlockUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/UnlockUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).UnlockUser(ctx, req.(*AdminRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_ForceLogout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).ForceLogout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/ForceLogout",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).ForceLogout(ctx, req.(*AdminRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_AdminUpdateProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminProfileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).AdminUpdateProfile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/AdminUpdateProfile",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).AdminUpdateProfile(ctx, req.(*AdminProfileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_SetUserRoles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRolesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).SetUserRoles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/SetUserRoles",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).SetUserRoles(ctx, req.(*SetRolesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_QueryAuditLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuditLogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).QueryAuditLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/QueryAuditLog",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).QueryAuditLog(ctx, req.(*AuditLogRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -397,10 +2435,114 @@ var _UserService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "editUserInfo",
 			Handler:    _UserService_EditUserInfo_Handler,
 		},
+		{
+			MethodName: "updateProfile",
+			Handler:    _UserService_UpdateProfile_Handler,
+		},
+		{
+			MethodName: "sendVerifyEmail",
+			Handler:    _UserService_SendVerifyEmail_Handler,
+		},
+		{
+			MethodName: "verifyEmail",
+			Handler:    _UserService_VerifyEmail_Handler,
+		},
+		{
+			MethodName: "requestPasswordReset",
+			Handler:    _UserService_RequestPasswordReset_Handler,
+		},
+		{
+			MethodName: "resetPassword",
+			Handler:    _UserService_ResetPassword_Handler,
+		},
 		{
 			MethodName: "logout",
 			Handler:    _UserService_Logout_Handler,
 		},
+		{
+			MethodName: "register",
+			Handler:    _UserService_Register_Handler,
+		},
+		{
+			MethodName: "changePasswd",
+			Handler:    _UserService_ChangePasswd_Handler,
+		},
+		{
+			MethodName: "refreshToken",
+			Handler:    _UserService_RefreshToken_Handler,
+		},
+		{
+			MethodName: "listSessions",
+			Handler:    _UserService_ListSessions_Handler,
+		},
+		{
+			MethodName: "revokeSession",
+			Handler:    _UserService_RevokeSession_Handler,
+		},
+		{
+			MethodName: "setupTwoFactor",
+			Handler:    _UserService_SetupTwoFactor_Handler,
+		},
+		{
+			MethodName: "confirmTwoFactor",
+			Handler:    _UserService_ConfirmTwoFactor_Handler,
+		},
+		{
+			MethodName: "disableTwoFactor",
+			Handler:    _UserService_DisableTwoFactor_Handler,
+		},
+		{
+			MethodName: "verifyTwoFactor",
+			Handler:    _UserService_VerifyTwoFactor_Handler,
+		},
+		{
+			MethodName: "deactivateAccount",
+			Handler:    _UserService_DeactivateAccount_Handler,
+		},
+		{
+			MethodName: "restoreAccount",
+			Handler:    _UserService_RestoreAccount_Handler,
+		},
+		{
+			MethodName: "deleteAccount",
+			Handler:    _UserService_DeleteAccount_Handler,
+		},
+		{
+			MethodName: "getUserByID",
+			Handler:    _UserService_GetUserByID_Handler,
+		},
+		{
+			MethodName: "changeUsername",
+			Handler:    _UserService_ChangeUsername_Handler,
+		},
+		{
+			MethodName: "listUsers",
+			Handler:    _UserService_ListUsers_Handler,
+		},
+		{
+			MethodName: "lockUser",
+			Handler:    _UserService_LockUser_Handler,
+		},
+		{
+			MethodName: "unlockUser",
+			Handler:    _UserService_UnlockUser_Handler,
+		},
+		{
+			MethodName: "forceLogout",
+			Handler:    _UserService_ForceLogout_Handler,
+		},
+		{
+			MethodName: "adminUpdateProfile",
+			Handler:    _UserService_AdminUpdateProfile_Handler,
+		},
+		{
+			MethodName: "setUserRoles",
+			Handler:    _UserService_SetUserRoles_Handler,
+		},
+		{
+			MethodName: "queryAuditLog",
+			Handler:    _UserService_QueryAuditLog_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "userinfo.proto",
@@ -409,26 +2551,115 @@ var _UserService_serviceDesc = grpc.ServiceDesc{
 func init() { proto1.RegisterFile("userinfo.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 333 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x52, 0xcf, 0x4e, 0xbb, 0x40,
-	0x10, 0xfe, 0x2d, 0x05, 0x7e, 0x75, 0x68, 0x8d, 0x99, 0x36, 0x66, 0xc3, 0xa9, 0xe1, 0xd4, 0x53,
-	0x0f, 0xb6, 0x17, 0x4f, 0x26, 0xde, 0xbc, 0xd6, 0xf8, 0x00, 0x15, 0xa6, 0x48, 0x5a, 0x76, 0x2b,
-	0x0b, 0xfa, 0x0c, 0x3e, 0x84, 0xaf, 0xe8, 0x33, 0x18, 0x16, 0xd8, 0x2c, 0xb6, 0x36, 0x9e, 0x98,
-	0x6f, 0xfe, 0x7c, 0x3b, 0xdf, 0xc7, 0xc0, 0x65, 0xa5, 0xa8, 0xc8, 0xc4, 0x56, 0x2e, 0x0e, 0x85,
-	0x2c, 0x25, 0x7a, 0xfa, 0x13, 0xdd, 0xc3, 0x68, 0x2f, 0xd3, 0x4c, 0xac, 0xe9, 0xb5, 0x22, 0x55,
-	0x62, 0x08, 0xc3, 0xba, 0x51, 0x6c, 0x72, 0xe2, 0x6c, 0xc6, 0xe6, 0x17, 0x6b, 0x83, 0xf1, 0x1a,
-	0xfc, 0xc3, 0x46, 0xa9, 0xf7, 0x84, 0x3b, 0xba, 0xd2, 0xa2, 0xe8, 0x93, 0xc1, 0xb8, 0x25, 0x51,
-	0x07, 0x29, 0x14, 0x9d, 0x65, 0x09, 0x61, 0x28, 0xb2, 0x78, 0xa7, 0x6b, 0x0d, 0x8f, 0xc1, 0xc8,
-	0xe1, 0xff, 0x0b, 0x6d, 0x92, 0xaa, 0xd8, 0xf3, 0x81, 0x2e, 0x75, 0x10, 0xa7, 0xe0, 0x95, 0x72,
-	0x47, 0x82, 0xbb, 0x3a, 0xdf, 0x00, 0x44, 0x70, 0x63, 0x99, 0x10, 0xf7, 0x66, 0x6c, 0x3e, 0x5e,
-	0xeb, 0x18, 0xaf, 0x60, 0x90, 0xab, 0x94, 0xfb, 0xba, 0xaf, 0x0e, 0xa3, 0x3b, 0x08, 0x62, 0x99,
-	0xe7, 0x9d, 0x44, 0x43, 0xc5, 0x6c, 0x2a, 0x7b, 0x65, 0xa7, 0xbf, 0x72, 0xf4, 0xc1, 0x20, 0xa0,
-	0x24, 0x2b, 0xff, 0x62, 0x92, 0x61, 0x77, 0x7e, 0xb0, 0x1b, 0xd1, 0x83, 0xdf, 0x45, 0xbb, 0x7d,
-	0xd1, 0x08, 0x6e, 0x6e, 0xc9, 0xab, 0xe3, 0x68, 0x05, 0xa3, 0x66, 0x95, 0xd6, 0xea, 0xce, 0x02,
-	0x76, 0x6c, 0x81, 0x63, 0x2c, 0xb8, 0xf9, 0x62, 0x10, 0x3c, 0x29, 0x2a, 0x1e, 0xa9, 0x78, 0xcb,
-	0x62, 0xc2, 0x15, 0x78, 0xfa, 0x8f, 0xe1, 0xa4, 0x39, 0x87, 0x85, 0x7d, 0x04, 0xe1, 0xb4, 0x9f,
-	0x6c, 0x5e, 0x8a, 0xfe, 0xe1, 0x2d, 0x04, 0x29, 0x95, 0x35, 0xcf, 0x83, 0xd8, 0x4a, 0xc4, 0xb6,
-	0xcd, 0x32, 0xf7, 0xcc, 0xa8, 0x5e, 0xfb, 0x68, 0xd6, 0xb2, 0x35, 0x9c, 0xf4, 0x72, 0x66, 0x74,
-	0x09, 0xfe, 0x5e, 0xa6, 0xb2, 0x2a, 0x4f, 0x3e, 0x78, 0x7a, 0xe8, 0xd9, 0xd7, 0xd9, 0xe5, 0x77,
-	0x00, 0x00, 0x00, 0xff, 0xff, 0xd4, 0x07, 0x98, 0x6c, 0xf7, 0x02, 0x00, 0x00,
+	// 1749 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x58, 0x4b, 0x6f, 0x1c, 0x4b,
+	0x15, 0xbe, 0x3d, 0x2f, 0xcf, 0x9c, 0x79, 0x78, 0x52, 0x76, 0x9c, 0x66, 0xf0, 0x45, 0x43, 0x8b,
+	0x85, 0xb9, 0x0b, 0x47, 0xf2, 0xbd, 0x42, 0x04, 0x48, 0xa2, 0x98, 0x24, 0x4a, 0x50, 0x90, 0xac,
+	0x76, 0x82, 0x10, 0x1b, 0x68, 0x77, 0x9f, 0x19, 0xb7, 0xdc, 0xd3, 0x35, 0xa9, 0xaa, 0xf6, 0x83,
+	0x1d, 0xb0, 0xe7, 0x07, 0xb0, 0x41, 0x2c, 0xf8, 0x3d, 0x08, 0xfe, 0x02, 0x7f, 0x04, 0xd5, 0xa3,
+	0xab, 0xbb, 0xe7, 0x25, 0x3f, 0x22, 0x56, 0xd3, 0xe7, 0x54, 0xd5, 0x79, 0xd7, 0xf9, 0x4e, 0x0d,
+	0x0c, 0x32, 0x8e, 0x2c, 0x4e, 0x27, 0xf4, 0x70, 0xce, 0xa8, 0xa0, 0xa4, 0xa9, 0x7e, 0x46, 0xe3,
+	0x29, 0xa5, 0xd3, 0x04, 0x9f, 0x2a, 0xea, 0x2c, 0x9b, 0x3c, 0x9d, 0xc4, 0x98, 0x44, 0xbf, 0x9f,
+	0x05, 0xfc, 0x42, 0x6f, 0xf4, 0x8e, 0xa1, 0x97, 0xd0, 0x69, 0x9c, 0xfa, 0xf8, 0x39, 0x43, 0x2e,
+	0xc8, 0x08, 0xda, 0x52, 0x54, 0x1a, 0xcc, 0xd0, 0x75, 0xc6, 0xce, 0x41, 0xc7, 0xb7, 0x34, 0xd9,
+	0x83, 0xd6, 0x3c, 0xe0, 0xfc, 0x2a, 0x72, 0x6b, 0x6a, 0xc5, 0x50, 0xde, 0xbf, 0x1a, 0xd0, 0x37,
+	0x42, 0xf8, 0x9c, 0xa6, 0x1c, 0x37, 0x4a, 0x19, 0x41, 0x3b, 0x8d, 0xc3, 0x0b, 0xb5, 0xa6, 0xe5,
+	0x58, 0x9a, 0xb8, 0xb0, 0x75, 0x8e, 0x41, 0x94, 0xb1, 0xc4, 0xad, 0xab, 0xa5, 0x9c, 0x24, 0xbb,
+	0xd0, 0x14, 0xf4, 0x02, 0x53, 0xb7, 0xa1, 0xf8, 0x9a, 0x20, 0x04, 0x1a, 0x21, 0x8d, 0xd0, 0x6d,
+	0x8e, 0x9d, 0x83, 0xbe, 0xaf, 0xbe, 0xc9, 0x10, 0xea, 0x33, 0x3e, 0x75, 0x5b, 0x6a, 0x9f, 0xfc,
+	0x24, 0x1e, 0xf4, 0x18, 0x4e, 0x18, 0xf2, 0x73, 0x2d, 0x62, 0x4b, 0x2d, 0x55, 0x78, 0xd2, 0x37,
+	0xbc, 0x9e, 0xc7, 0x0c, 0xdd, 0xf6, 0xd8, 0x39, 0xa8, 0xfb, 0x86, 0x22, 0x3f, 0x82, 0xbe, 0xd9,
+	0x67, 0x96, 0x3b, 0x6a, 0xb9, 0xca, 0x24, 0x3f, 0x00, 0x60, 0x28, 0xd8, 0x4d, 0x30, 0x11, 0xc8,
+	0x5c, 0x50, 0x5b, 0x4a, 0x1c, 0xb2, 0x0f, 0x9d, 0xf0, 0x3c, 0x48, 0x12, 0x4c, 0xa7, 0xe8, 0x76,
+	0x95, 0xfa, 0x82, 0x21, 0x57, 0xe7, 0x19, 0x9b, 0xa2, 0x88, 0x67, 0xe8, 0xf6, 0xd4, 0xe1, 0x82,
+	0x21, 0xfd, 0xc9, 0xe2, 0xc8, 0xed, 0x2b, 0xbe, 0xfc, 0x94, 0xb1, 0xc0, 0x59, 0x10, 0x27, 0xee,
+	0x40, 0xc7, 0x42, 0x11, 0x92, 0x3b, 0x3f, 0xa7, 0x29, 0xba, 0xdb, 0x9a, 0xab, 0x08, 0x79, 0xfa,
+	0x2c, 0xa6, 0xee, 0x50, 0x47, 0xe3, 0x2c, 0xa6, 0xd2, 0xd3, 0x84, 0x86, 0x41, 0x82, 0xee, 0x23,
+	0x9d, 0x45, 0x4d, 0xc9, 0xbc, 0x48, 0x7d, 0x7f, 0x94, 0x22, 0x88, 0xce, 0x4b, 0x4e, 0xcb, 0xb5,
+	0xb3, 0x98, 0x89, 0xf3, 0x28, 0xb8, 0x71, 0x77, 0xf4, 0x5a, 0x4e, 0xcb, 0x08, 0x29, 0x03, 0x2e,
+	0x91, 0xc5, 0x93, 0x18, 0x23, 0x77, 0x77, 0xec, 0x1c, 0xb4, 0xfd, 0x2a, 0x53, 0x5a, 0xc7, 0x68,
+	0x82, 0xdc, 0x7d, 0x3c, 0xae, 0x4b, 0xeb, 0x14, 0x21, 0x6d, 0xe1, 0x22, 0x10, 0x19, 0x77, 0xf7,
+	0xc6, 0xce, 0x41, 0xd3, 0x37, 0x94, 0xf7, 0x12, 0xba, 0x21, 0x9d, 0xcd, 0xf2, 0xa2, 0xb4, 0xc9,
+	0x77, 0xca, 0xc9, 0x2f, 0x17, 0x59, 0xad, 0x5a, 0x64, 0xde, 0xbf, 0x1d, 0xe8, 0x62, 0x14, 0x8b,
+	0xdb, 0x94, 0xb5, 0x95, 0x5e, 0x5b, 0x90, 0x6e, 0xcb, 0xb4, 0xbe, 0xbe, 0x4c, 0x1b, 0xd5, 0x32,
+	0x25, 0xd0, 0x98, 0x95, 0x0a, 0x52, 0x7e, 0x93, 0x43, 0x68, 0xc8, 0x0b, 0xa7, 0x2a, 0xb2, 0x7b,
+	0x34, 0x3a, 0xd4, 0x77, 0xf2, 0x30, 0xbf, 0x93, 0x87, 0x6f, 0xe5, 0x9d, 0xfc, 0x75, 0xc0, 0x2f,
+	0x7c, 0xb5, 0x4f, 0x06, 0x25, 0xb8, 0x0c, 0x44, 0xc0, 0x4c, 0xa1, 0x1a, 0xca, 0xfb, 0x5b, 0x0d,
+	0x06, 0x73, 0x46, 0x27, 0x71, 0x82, 0xff, 0x6f, 0xb7, 0x6c, 0xc5, 0x35, 0x57, 0x56, 0x5c, 0x6b,
+	0x45, 0xc5, 0x6d, 0xad, 0xaa, 0xb8, 0xf6, 0xda, 0x8a, 0xeb, 0x6c, 0xa8, 0x38, 0x58, 0xa8, 0x38,
+	0x62, 0x02, 0xda, 0x55, 0xa5, 0xa4, 0xbe, 0xbd, 0x2b, 0xd8, 0x66, 0x38, 0x8d, 0xb9, 0x40, 0xf6,
+	0x80, 0x56, 0xb6, 0x31, 0x3c, 0x36, 0x08, 0x8d, 0x52, 0x10, 0xbc, 0x7f, 0x3a, 0xb0, 0x13, 0x9e,
+	0x07, 0xe9, 0x14, 0x4f, 0x94, 0x88, 0xfb, 0xa7, 0x66, 0x1f, 0x3a, 0x34, 0x89, 0x8c, 0x59, 0x5a,
+	0x79, 0xc1, 0x90, 0xab, 0x29, 0x5e, 0x99, 0x55, 0x6d, 0x41, 0xc1, 0x20, 0x63, 0xe8, 0x5e, 0x20,
+	0xce, 0x39, 0x72, 0x1e, 0xd3, 0x54, 0xa5, 0xa9, 0xed, 0x97, 0x59, 0xde, 0x3f, 0x1c, 0xe8, 0x9a,
+	0xef, 0xf7, 0xe9, 0x84, 0x92, 0x01, 0xd4, 0xe2, 0xc8, 0x58, 0x56, 0x8b, 0x23, 0xd9, 0xc2, 0x42,
+	0x86, 0x81, 0xd0, 0x5d, 0xa8, 0xa6, 0x5b, 0x58, 0xc1, 0x91, 0xfe, 0x24, 0x01, 0x17, 0x1c, 0x31,
+	0x55, 0xc6, 0xd5, 0x7d, 0x4b, 0x2b, 0x59, 0x73, 0x63, 0x54, 0x2d, 0x9e, 0x4b, 0x5b, 0xa5, 0xaf,
+	0xc1, 0x14, 0x53, 0x61, 0x4a, 0xa6, 0x60, 0xc8, 0x32, 0x0b, 0x33, 0xc6, 0xe4, 0x5a, 0x4b, 0xd9,
+	0x99, 0x93, 0xde, 0x39, 0x0c, 0x8d, 0x89, 0xdc, 0x42, 0xc9, 0x21, 0xb4, 0x73, 0x9e, 0xeb, 0x8c,
+	0xeb, 0x07, 0xdd, 0x23, 0xa2, 0xaf, 0xce, 0x61, 0xc9, 0x1b, 0xdf, 0xee, 0xb1, 0x90, 0x50, 0x5b,
+	0x86, 0x84, 0xba, 0x85, 0x04, 0xef, 0x1a, 0x76, 0x19, 0x5e, 0xd2, 0x0b, 0x3c, 0xd5, 0xe7, 0x1e,
+	0x94, 0x35, 0xa3, 0x3b, 0xb6, 0x59, 0xb3, 0x0c, 0xa9, 0x39, 0x48, 0x74, 0xc5, 0xb4, 0x7d, 0xf9,
+	0xe9, 0xfd, 0x01, 0x86, 0xe2, 0x8a, 0xbe, 0x0d, 0x42, 0x41, 0xd9, 0x83, 0xae, 0xb1, 0xcc, 0xbc,
+	0xf2, 0xd4, 0xd4, 0x69, 0x4e, 0x7b, 0x7f, 0x75, 0x60, 0xcf, 0xaa, 0x38, 0x45, 0x91, 0xcd, 0x6d,
+	0x30, 0x65, 0xbf, 0xc5, 0x90, 0xa1, 0x30, 0x6a, 0x0c, 0xa5, 0x30, 0x86, 0xc5, 0x46, 0x85, 0xfc,
+	0xd4, 0xb8, 0x17, 0xd2, 0x4b, 0x64, 0x37, 0x52, 0x28, 0x77, 0xeb, 0xea, 0xb2, 0x55, 0x99, 0x36,
+	0xd8, 0x8d, 0xe5, 0x60, 0x37, 0x8b, 0x60, 0xfb, 0xb0, 0xa7, 0x70, 0xe0, 0xe6, 0xe3, 0xa2, 0xe3,
+	0x15, 0x5c, 0x74, 0x16, 0x71, 0xb1, 0xec, 0x64, 0x6d, 0xc1, 0xc9, 0xdf, 0xc1, 0x20, 0x08, 0x43,
+	0x9a, 0xa5, 0x0f, 0x68, 0xf1, 0x45, 0x13, 0xa8, 0x57, 0xe6, 0x99, 0x1f, 0x42, 0x5f, 0x9e, 0x7c,
+	0xff, 0x3a, 0x17, 0x6d, 0x20, 0xd8, 0xb1, 0x10, 0xec, 0x85, 0xf0, 0x58, 0x5f, 0xfa, 0x4f, 0x46,
+	0xc5, 0xfd, 0xad, 0x70, 0x61, 0x2b, 0xc5, 0xab, 0x52, 0xc7, 0xc9, 0x49, 0xef, 0x1b, 0x20, 0x3a,
+	0x6e, 0x6f, 0x64, 0xa7, 0xd9, 0x08, 0x86, 0xde, 0x11, 0xec, 0x2a, 0xeb, 0x29, 0x8b, 0x7c, 0xe4,
+	0x78, 0x9b, 0xa8, 0x78, 0xbf, 0x92, 0x97, 0x80, 0xa3, 0x38, 0xb1, 0x07, 0x37, 0xc1, 0x6d, 0xa5,
+	0x01, 0xd5, 0x16, 0x1a, 0x90, 0xf7, 0x5b, 0xe8, 0x05, 0xd1, 0x2c, 0x4e, 0x1f, 0x94, 0x0d, 0x11,
+	0xc8, 0xa1, 0x27, 0xcf, 0x86, 0xa6, 0xbc, 0x6b, 0xd8, 0x51, 0x92, 0x4f, 0x1e, 0x0a, 0x7d, 0x4f,
+	0x61, 0xcb, 0xc0, 0xa7, 0xd2, 0xd0, 0x3d, 0x7a, 0x6c, 0x1a, 0x49, 0x15, 0x54, 0xfd, 0x7c, 0x97,
+	0xf7, 0x19, 0xb6, 0x65, 0x24, 0x69, 0x82, 0xfc, 0x8b, 0xbb, 0x55, 0x0c, 0x44, 0x8d, 0xd2, 0x40,
+	0xe4, 0xfd, 0xc7, 0x81, 0x61, 0x12, 0x73, 0x21, 0xcb, 0xea, 0x61, 0x4a, 0xc3, 0x8c, 0x71, 0xca,
+	0x72, 0xa5, 0x9a, 0x92, 0xbb, 0x93, 0x78, 0x16, 0x0b, 0x73, 0x61, 0x35, 0xa1, 0xee, 0x01, 0xc3,
+	0x49, 0x7c, 0x6d, 0x2e, 0xad, 0xa1, 0x2a, 0x60, 0xd8, 0x5a, 0x00, 0xc3, 0x3d, 0x68, 0x65, 0xf3,
+	0x09, 0xa3, 0x33, 0x05, 0xf4, 0x75, 0xdf, 0x50, 0xb2, 0x23, 0x64, 0x73, 0x41, 0xcd, 0x14, 0xad,
+	0xbe, 0xbd, 0x3f, 0x39, 0xfa, 0x42, 0x15, 0x4d, 0xfd, 0x1b, 0x68, 0x2a, 0x86, 0xe9, 0xe8, 0xbb,
+	0x26, 0x11, 0x95, 0x47, 0x84, 0xaf, 0xb7, 0x48, 0x60, 0x4a, 0xf1, 0x5a, 0x18, 0x7f, 0xb4, 0x9b,
+	0x25, 0x8e, 0xed, 0x41, 0xf5, 0xe5, 0x1e, 0xd4, 0x28, 0x7a, 0xd0, 0x7f, 0x1d, 0xd8, 0x0e, 0xb2,
+	0x28, 0x16, 0x1f, 0xe8, 0xf4, 0xcb, 0x27, 0x53, 0x8e, 0x6c, 0xa1, 0x90, 0xc8, 0xab, 0x55, 0x1a,
+	0x4a, 0xda, 0xa6, 0x62, 0xd4, 0xd4, 0xd1, 0x90, 0xdf, 0x12, 0x2c, 0x05, 0x55, 0xf1, 0xac, 0xfb,
+	0x35, 0x41, 0x4b, 0xb9, 0xda, 0x5a, 0x9d, 0xab, 0x76, 0x39, 0x57, 0xfb, 0xd0, 0xd1, 0x3a, 0x65,
+	0x43, 0xd2, 0x6f, 0x91, 0x82, 0xe1, 0xfd, 0xb9, 0x06, 0xa0, 0xbc, 0x7c, 0x73, 0x29, 0x91, 0xb6,
+	0xc0, 0xf8, 0xba, 0xc2, 0x78, 0x02, 0x8d, 0x12, 0xba, 0x37, 0x72, 0x5c, 0xb7, 0x41, 0xa8, 0x2f,
+	0x07, 0x41, 0xb5, 0xeb, 0x7c, 0xe2, 0x51, 0x44, 0xc9, 0xd9, 0x66, 0xc5, 0x59, 0x3d, 0x05, 0xb4,
+	0xec, 0x14, 0x20, 0x4b, 0x41, 0x5a, 0xa9, 0xdd, 0x52, 0xdf, 0xb2, 0xd9, 0xd1, 0x4c, 0x84, 0x74,
+	0x96, 0xcf, 0x82, 0x39, 0xa9, 0x9f, 0x50, 0x3c, 0x4b, 0x84, 0x4a, 0x66, 0x47, 0xf9, 0x5c, 0xe2,
+	0x48, 0xad, 0x11, 0x0a, 0x39, 0x7e, 0xe9, 0x71, 0xd0, 0x50, 0x79, 0x6f, 0xee, 0x16, 0xbd, 0xf9,
+	0x2f, 0x0e, 0x0c, 0x8b, 0x54, 0x9b, 0x8a, 0xfb, 0x31, 0xb4, 0x50, 0xc6, 0x24, 0x2f, 0xb9, 0x47,
+	0xa6, 0xe4, 0x8a, 0x68, 0xf9, 0x66, 0xc3, 0x17, 0x2a, 0xb8, 0xef, 0xa0, 0xa7, 0x1f, 0x20, 0xc6,
+	0x80, 0xfc, 0x94, 0xb3, 0x7c, 0xaa, 0x66, 0x4f, 0x1d, 0xfd, 0x7d, 0x1b, 0xba, 0xf2, 0xee, 0x9f,
+	0x22, 0xbb, 0x8c, 0x43, 0x24, 0xdf, 0x41, 0x53, 0x5d, 0x0a, 0xb2, 0x53, 0xbd, 0x22, 0xaa, 0x80,
+	0x47, 0x2b, 0xef, 0x8d, 0xf7, 0x15, 0x79, 0x06, 0xdd, 0x29, 0xaa, 0x1e, 0xa2, 0x46, 0xbd, 0x7c,
+	0x60, 0x2a, 0x3d, 0xa9, 0x36, 0x1c, 0x55, 0x66, 0x2f, 0x9d, 0x2d, 0x3d, 0xa6, 0x46, 0x3b, 0x15,
+	0x9e, 0x3d, 0xfa, 0x02, 0xfa, 0xd9, 0x3c, 0x0a, 0x04, 0x9a, 0x4e, 0x4d, 0x56, 0xf7, 0xd7, 0xb5,
+	0xaa, 0x7f, 0x21, 0xdb, 0x6d, 0x1a, 0xfd, 0xa6, 0x80, 0xbc, 0x95, 0x96, 0xaf, 0xd1, 0xfe, 0x12,
+	0xba, 0x25, 0xb0, 0x24, 0xdf, 0x33, 0xbb, 0x96, 0x01, 0x74, 0x9d, 0x00, 0x85, 0x86, 0x6a, 0xc7,
+	0x49, 0x19, 0x48, 0xc9, 0xf7, 0x73, 0x2f, 0x56, 0xc0, 0xeb, 0x3a, 0x59, 0xbf, 0x84, 0x7e, 0x05,
+	0x59, 0xad, 0x90, 0x55, 0x78, 0xbb, 0x4e, 0xc8, 0xb7, 0xf2, 0xd9, 0x34, 0xa5, 0x99, 0xb8, 0x4b,
+	0x18, 0x7e, 0x06, 0xed, 0xfc, 0x1d, 0x44, 0xf6, 0xac, 0xd2, 0xca, 0xc3, 0x68, 0x6d, 0x02, 0x5e,
+	0x41, 0xaf, 0xfc, 0x92, 0x21, 0xa3, 0x5c, 0xed, 0xf2, 0xf3, 0x66, 0xbd, 0xfa, 0xfc, 0x6f, 0x95,
+	0x8f, 0xfa, 0x0f, 0x9a, 0x3b, 0x94, 0xde, 0x73, 0xe8, 0x49, 0xe8, 0x3b, 0xb5, 0x93, 0xfc, 0x8a,
+	0xb3, 0x4f, 0xaa, 0xb3, 0x3f, 0x5f, 0x8c, 0x79, 0x69, 0xa4, 0x2f, 0xc5, 0x7c, 0x79, 0xd0, 0x5f,
+	0x67, 0xff, 0x1b, 0x18, 0x70, 0x14, 0xd9, 0xdc, 0x4e, 0xaa, 0x2b, 0xad, 0xf8, 0xda, 0xf0, 0x56,
+	0x4f, 0xd9, 0xde, 0x57, 0xe4, 0x03, 0x0c, 0x43, 0x9a, 0x4e, 0x62, 0x36, 0x2b, 0x04, 0x3d, 0x59,
+	0x3c, 0x74, 0x6b, 0x69, 0xc7, 0x30, 0x8c, 0x62, 0x1e, 0x9c, 0x25, 0x78, 0x0b, 0x69, 0x6b, 0x1c,
+	0x7b, 0x07, 0xdb, 0x0b, 0x33, 0x38, 0xf9, 0xba, 0x72, 0x45, 0x16, 0x67, 0xf3, 0x0d, 0x55, 0xf2,
+	0x28, 0x42, 0xd9, 0xf2, 0x2f, 0x03, 0x81, 0xaf, 0xf4, 0x0c, 0x6e, 0xaf, 0x7a, 0x75, 0x26, 0x5f,
+	0x67, 0xcc, 0x73, 0x18, 0x30, 0xe4, 0x82, 0x32, 0x7b, 0xfe, 0x4e, 0xed, 0xed, 0x39, 0xf4, 0x23,
+	0x4c, 0xf0, 0xbe, 0xda, 0x7f, 0x6e, 0xbb, 0xe3, 0xf1, 0xcd, 0xfb, 0xd7, 0x24, 0xd7, 0x52, 0x19,
+	0xf9, 0xd7, 0xea, 0x7e, 0x0b, 0x83, 0xea, 0xe0, 0x4f, 0xf6, 0x2b, 0xb7, 0x64, 0xe1, 0x3d, 0xb0,
+	0xa1, 0xd9, 0x75, 0xec, 0x9c, 0x67, 0x93, 0xb9, 0x38, 0xf9, 0x8d, 0xca, 0xb6, 0x95, 0x6b, 0xfd,
+	0x27, 0xd0, 0x4e, 0x68, 0x78, 0x21, 0xf7, 0xda, 0xd0, 0x95, 0xc7, 0xef, 0x75, 0xae, 0xff, 0x14,
+	0x20, 0x4b, 0xef, 0x75, 0xf2, 0x19, 0x74, 0x27, 0x94, 0x85, 0xf8, 0x41, 0x77, 0xa4, 0xbb, 0x1c,
+	0x7d, 0x07, 0x44, 0x6d, 0xfb, 0x54, 0x01, 0x87, 0x51, 0x59, 0xc2, 0xc9, 0xed, 0x10, 0xe2, 0x05,
+	0xf4, 0xb8, 0xce, 0x9c, 0xaf, 0xff, 0x3e, 0xb4, 0xdd, 0xa0, 0x32, 0xa5, 0xaf, 0x3d, 0x7f, 0x0c,
+	0xfd, 0xcf, 0x19, 0xb2, 0x9b, 0x57, 0x66, 0x3a, 0x20, 0x7b, 0xe5, 0x29, 0xa0, 0x98, 0x0c, 0x47,
+	0x4f, 0x96, 0xf8, 0xb9, 0x8c, 0xb3, 0x96, 0x5a, 0xf9, 0xf6, 0x7f, 0x03, 0x00, 0x30, 0xac, 0x9a,
+	0x5b, 0x72, 0x17, 0x00, 0x00,
 }
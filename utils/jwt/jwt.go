@@ -0,0 +1,233 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"user-management-system/conf"
+)
+
+const (
+	// AlgHS256 HMAC-SHA256 with a shared secret
+	AlgHS256 = "HS256"
+	// AlgEdDSA Ed25519 signature
+	AlgEdDSA = "EdDSA"
+
+	// TokenModeOpaque random tokens checked against redis sessions
+	TokenModeOpaque = "opaque"
+	// TokenModeSigned signed access tokens verified locally
+	TokenModeSigned = "signed"
+)
+
+var (
+	// ErrMalformed token isn't header.claims.signature
+	ErrMalformed = errors.New("jwt: malformed token")
+	// ErrUnknownKey token was signed by a key not in key set
+	ErrUnknownKey = errors.New("jwt: unknown key id")
+	// ErrSignature signature doesn't match
+	ErrSignature = errors.New("jwt: invalid signature")
+	// ErrExpired token has expired
+	ErrExpired = errors.New("jwt: token expired")
+)
+
+// Claims payload of access token
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Uid       int64    `json:"uid,omitempty"`
+	Nickname  string   `json:"nickname"`
+	Headurl   string   `json:"headurl"`
+	Roles     []string `json:"roles,omitempty"`
+	SessionID string   `json:"sid"`
+	IssuedAt  int64    `json:"iat"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+// Key a signing key identified by kid
+type Key struct {
+	ID      string
+	Alg     string
+	secret  []byte
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+}
+
+// NewHS256Key create HMAC key, secret should be at least 32 bytes
+func NewHS256Key(kid, secret string) (*Key, error) {
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("jwt: secret of key '%s' should be at least 32 bytes", kid)
+	}
+	return &Key{ID: kid, Alg: AlgHS256, secret: []byte(secret)}, nil
+}
+
+// NewEdDSAKey create Ed25519 key from base64 seed and/or public key,
+// a key without private part can only verify tokens
+func NewEdDSAKey(kid, privateKey, publicKey string) (*Key, error) {
+	key := &Key{ID: kid, Alg: AlgEdDSA}
+	if privateKey != "" {
+		seed, err := base64.StdEncoding.DecodeString(privateKey)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("jwt: invalid ed25519 private key of '%s'", kid)
+		}
+		key.private = ed25519.NewKeyFromSeed(seed)
+		key.public = key.private.Public().(ed25519.PublicKey)
+		return key, nil
+	}
+	public, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(public) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("jwt: invalid ed25519 public key of '%s'", kid)
+	}
+	key.public = public
+	return key, nil
+}
+
+// sign return signature of data
+func (k *Key) sign(data []byte) ([]byte, error) {
+	switch k.Alg {
+	case AlgHS256:
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(data)
+		return mac.Sum(nil), nil
+	case AlgEdDSA:
+		if k.private == nil {
+			return nil, fmt.Errorf("jwt: key '%s' can't sign without private key", k.ID)
+		}
+		return ed25519.Sign(k.private, data), nil
+	}
+	return nil, fmt.Errorf("jwt: unsupported alg '%s'", k.Alg)
+}
+
+// verify check signature of data
+func (k *Key) verify(data, signature []byte) bool {
+	switch k.Alg {
+	case AlgHS256:
+		expected, _ := k.sign(data)
+		return hmac.Equal(expected, signature)
+	case AlgEdDSA:
+		return ed25519.Verify(k.public, data, signature)
+	}
+	return false
+}
+
+// KeySet keys accepted for verification, new tokens are signed by the active one
+type KeySet struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+// NewKeySet create key set, active must be the id of one of keys
+func NewKeySet(active string, keys ...*Key) (*KeySet, error) {
+	ks := &KeySet{keys: make(map[string]*Key)}
+	for _, key := range keys {
+		if _, ok := ks.keys[key.ID]; ok {
+			return nil, fmt.Errorf("jwt: duplicated key id '%s'", key.ID)
+		}
+		ks.keys[key.ID] = key
+	}
+	ks.active = ks.keys[active]
+	if ks.active == nil {
+		return nil, fmt.Errorf("jwt: active key '%s' not found", active)
+	}
+	return ks, nil
+}
+
+// NewKeySetFromConf create key set from token config
+func NewKeySetFromConf(config *conf.TokenConf) (*KeySet, error) {
+	var keys []*Key
+	for _, k := range config.Signed.Keys {
+		var key *Key
+		var err error
+		switch k.Alg {
+		case AlgHS256:
+			key, err = NewHS256Key(k.Kid, k.Secret)
+		case AlgEdDSA:
+			key, err = NewEdDSAKey(k.Kid, k.Privatekey, k.Publickey)
+		default:
+			err = fmt.Errorf("jwt: unsupported alg '%s' of key '%s'", k.Alg, k.Kid)
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return NewKeySet(config.Signed.Activekid, keys...)
+}
+
+// Sign sign claims with active key
+func (ks *KeySet) Sign(claims Claims) (string, error) {
+	h, err := json.Marshal(header{Alg: ks.active.Alg, Kid: ks.active.ID, Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	data := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	signature, err := ks.active.sign([]byte(data))
+	if err != nil {
+		return "", err
+	}
+	return data + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Verify check signature and expire time of token, return its claims
+func (ks *KeySet) Verify(token string, now time.Time) (Claims, error) {
+	var claims Claims
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, ErrMalformed
+	}
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return claims, ErrMalformed
+	}
+	key := ks.keys[h.Kid]
+	if key == nil {
+		return claims, ErrUnknownKey
+	}
+	// alg must be the one of key, never trust header alone
+	if h.Alg != key.Alg {
+		return claims, ErrSignature
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return claims, ErrMalformed
+	}
+	if !key.verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return claims, ErrSignature
+	}
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return claims, ErrMalformed
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return claims, ErrExpired
+	}
+	return claims, nil
+}
+
+// IsSigned report whether token looks like a signed token rather than an opaque one
+func IsSigned(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// decodeSegment decode base64url json segment into v
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
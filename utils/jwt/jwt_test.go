@@ -0,0 +1,86 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func Test_SignVerify(t *testing.T) {
+	hs, _ := NewHS256Key("k1", testSecret)
+	ed, err := NewEdDSAKey("k2", base64.StdEncoding.EncodeToString(make([]byte, 32)), "")
+	if err != nil {
+		t.Fatal("NewEdDSAKey failed:", err.Error())
+	}
+
+	now := time.Unix(1600000000, 0)
+	claims := Claims{Subject: "username8", Nickname: "nickname8", Roles: []string{"admin"}, SessionID: "abc", IssuedAt: now.Unix(), ExpiresAt: now.Unix() + 300}
+	for _, active := range []string{"k1", "k2"} {
+		ks, err := NewKeySet(active, hs, ed)
+		if err != nil {
+			t.Fatal("NewKeySet failed:", err.Error())
+		}
+		token, err := ks.Sign(claims)
+		if err != nil || !IsSigned(token) {
+			t.Error(active, " sign failed:", err)
+			continue
+		}
+		got, err := ks.Verify(token, now)
+		if err != nil || !reflect.DeepEqual(got, claims) {
+			t.Error(active, " verify failed:", err, got)
+		}
+		if _, err = ks.Verify(token, now.Add(300*time.Second)); err != ErrExpired {
+			t.Error(active, " token should expire, got:", err)
+		}
+		parts := strings.Split(token, ".")
+		forged := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"admin","exp":9999999999}`)) + "." + parts[2]
+		if _, err = ks.Verify(forged, now); err != ErrSignature {
+			t.Error(active, " forged token should fail, got:", err)
+		}
+	}
+}
+
+func Test_Rotation(t *testing.T) {
+	old, _ := NewHS256Key("k1", testSecret)
+	ks1, _ := NewKeySet("k1", old)
+	now := time.Unix(1600000000, 0)
+	token, _ := ks1.Sign(Claims{Subject: "username8", ExpiresAt: now.Unix() + 300})
+
+	// new key is active, old one still verifies
+	next, _ := NewHS256Key("k2", testSecret+"-next")
+	ks2, _ := NewKeySet("k2", old, next)
+	if _, err := ks2.Verify(token, now); err != nil {
+		t.Error("token of old key should verify during rotation:", err)
+	}
+
+	// old key removed
+	ks3, _ := NewKeySet("k2", next)
+	if _, err := ks3.Verify(token, now); err != ErrUnknownKey {
+		t.Error("token of removed key should fail, got:", err)
+	}
+
+	// verify-only ed25519 key can't sign
+	seed := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	full, _ := NewEdDSAKey("k3", seed, "")
+	public, err := NewEdDSAKey("k3", "", base64.StdEncoding.EncodeToString(full.public))
+	if err != nil {
+		t.Fatal("NewEdDSAKey failed:", err.Error())
+	}
+	signer, _ := NewKeySet("k3", full)
+	verifier, _ := NewKeySet("k3", public)
+	token, _ = signer.Sign(Claims{Subject: "username8", ExpiresAt: now.Unix() + 300})
+	if _, err = verifier.Verify(token, now); err != nil {
+		t.Error("public key should verify:", err)
+	}
+	if _, err = verifier.Sign(Claims{}); err == nil {
+		t.Error("public key should not sign")
+	}
+
+	if _, err = NewHS256Key("k4", "short"); err == nil {
+		t.Error("short secret should be rejected")
+	}
+}
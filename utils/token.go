@@ -0,0 +1,77 @@
+package utils
+
+import (
+    "crypto/rand"
+    "encoding/base64"
+    "encoding/hex"
+    "fmt"
+)
+
+const (
+    // TokenEncodingHex lowercase hex, 2 chars per byte
+    TokenEncodingHex       = "hex"
+    // TokenEncodingBase64URL unpadded url-safe base64
+    TokenEncodingBase64URL = "base64url"
+)
+
+// tokenFormat format of session tokens shared by httpserver and tcpserver
+type tokenFormat struct {
+    length   int    // random bytes
+    encoding string // TokenEncodingHex or TokenEncodingBase64URL
+}
+
+// 16 bytes in hex keeps tokens 32 chars long as before
+var format = tokenFormat{length: 16, encoding: TokenEncodingHex}
+
+// SetTokenFormat set length (random bytes, at least 16) and encoding of tokens,
+// httpserver and tcpserver must use the same format
+func SetTokenFormat(length int, encoding string) error {
+    if length < 16 {
+        return fmt.Errorf("token length should be at least 16 bytes, got %d", length)
+    }
+    if encoding != TokenEncodingHex && encoding != TokenEncodingBase64URL {
+        return fmt.Errorf("unsupported token encoding '%s'", encoding)
+    }
+    format = tokenFormat{length: length, encoding: encoding}
+    return nil
+}
+
+// randomBytes return n bytes read from crypto/rand
+func randomBytes(n int) ([]byte, error) {
+    b := make([]byte, n)
+    _, err := rand.Read(b)
+    return b, err
+}
+
+// GenerateToken return a random token in configured format
+func GenerateToken() (string, error) {
+    b, err := randomBytes(format.length)
+    if err != nil {
+        return "", err
+    }
+    if format.encoding == TokenEncodingBase64URL {
+        return base64.RawURLEncoding.EncodeToString(b), nil
+    }
+    return hex.EncodeToString(b), nil
+}
+
+// CheckToken check whether token is in configured format
+func CheckToken(token string) bool {
+    var b []byte
+    var err error
+    if format.encoding == TokenEncodingBase64URL {
+        b, err = base64.RawURLEncoding.DecodeString(token)
+    } else {
+        b, err = hex.DecodeString(token)
+    }
+    return err == nil && len(b) == format.length
+}
+
+// GenerateUUID return a random id to trace a request
+func GenerateUUID() string {
+    b, err := randomBytes(16)
+    if err != nil {
+        return ""
+    }
+    return hex.EncodeToString(b)
+}
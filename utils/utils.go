@@ -1,14 +1,15 @@
 package utils
 
 import (
-    "fmt"
     "io/ioutil"
-    "math/rand"
+    "regexp"
     "crypto/md5"
     "encoding/hex"
     "mime/multipart"
 )
 
+var usernameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_]{3,64}$`)
+
 // Md5String return md5 value of source string
 func Md5String(s string) string {
     h := md5.New()
@@ -17,9 +18,23 @@ func Md5String(s string) string {
     return str
 }
 
-// GenerateToken return encrypt token of string
-func GenerateToken(uname string) string {
-    return Md5String(fmt.Sprintf("%s:%d", uname, rand.Intn(999999)))
+// GenerateSkey return a random secret key (16 hex chars) for a new user
+func GenerateSkey() (string, error) {
+    b, err := randomBytes(8)
+    if err != nil {
+        return "", err
+    }
+    return hex.EncodeToString(b), nil
+}
+
+// CheckUsername username should be 3~64 letters, digits or '_'
+func CheckUsername(username string) bool {
+    return usernameRegexp.MatchString(username)
+}
+
+// CheckPasswd passwd should be 6~64 chars
+func CheckPasswd(passwd string) bool {
+    return len(passwd) >= 6 && len(passwd) <= 64
 }
 
 // GetFileSize get size of file
@@ -27,12 +42,3 @@ func GetFileSize(f multipart.File) (int, error) {
     content, err := ioutil.ReadAll(f)
     return len(content), err
 }
-
-// for table
-func GetTableName(username string) string {
-    var value int
-    for _, c := range []rune(username) {
-        value = value + int(c)
-    }
-    return fmt.Sprintf("userinfo_tab_%d", value % 20)
-}
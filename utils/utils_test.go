@@ -20,9 +20,44 @@ func Test_Md5String(t *testing.T) {
 }
 
 func Test_GenerateToken(t *testing.T) {
-    s := "abcdefg"
-    result := GenerateToken(s)
-    if result != "6d020719bc3fcafe85b125501b9f9737" {
+    // default: 16 bytes in hex
+    result, err := GenerateToken()
+    if err != nil || len(result) != 32 || !CheckToken(result) {
+        t.Error("test failed: ", result, err)
+    }
+    other, _ := GenerateToken()
+    if other == result {
+        t.Error("tokens should be random: ", result)
+    }
+    if CheckToken(result[1:]) || CheckToken("zz" + result[2:]) {
+        t.Error("malformed token should be invalid")
+    }
+
+    // base64url
+    if err = SetTokenFormat(32, TokenEncodingBase64URL); err != nil {
+        t.Error("SetTokenFormat failed: ", err.Error())
+    }
+    defer SetTokenFormat(16, TokenEncodingHex)
+    result, _ = GenerateToken()
+    if len(result) != 43 || !CheckToken(result) {
         t.Error("test failed: ", result)
     }
+    if CheckToken(other) {
+        t.Error("hex token should be invalid in base64url format: ", other)
+    }
+
+    // invalid format
+    if SetTokenFormat(8, TokenEncodingHex) == nil || SetTokenFormat(16, "base32") == nil {
+        t.Error("invalid format should be rejected")
+    }
+}
+
+func Test_GenerateSkey(t *testing.T) {
+    skey, err := GenerateSkey()
+    if err != nil || len(skey) != 16 {
+        t.Error("test failed: ", skey, err)
+    }
+    if other, _ := GenerateSkey(); other == skey {
+        t.Error("skeys should be random: ", skey)
+    }
 }